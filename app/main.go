@@ -1,17 +1,32 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/catalog"
+	"github.com/bimbims125/clean-arch/internal/event"
+	"github.com/bimbims125/clean-arch/internal/imaging"
+	"github.com/bimbims125/clean-arch/internal/notification"
+	"github.com/bimbims125/clean-arch/internal/payment"
+	"github.com/bimbims125/clean-arch/internal/pricing"
+	memoryRepo "github.com/bimbims125/clean-arch/internal/repository/memory"
 	mysqlRepo "github.com/bimbims125/clean-arch/internal/repository/mysql"
 	postgresRepo "github.com/bimbims125/clean-arch/internal/repository/postgresql"
+	sqliteRepo "github.com/bimbims125/clean-arch/internal/repository/sqlite"
 	"github.com/bimbims125/clean-arch/internal/rest"
 	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/internal/storage"
+	"github.com/bimbims125/clean-arch/internal/worker"
 	_ "github.com/go-sql-driver/mysql" // Import driver MySQL
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -21,8 +36,245 @@ import (
 const (
 	defaultTimeout = 30
 	defaultAddress = ":3300"
+
+	defaultTokenTTL = 24 * time.Hour
+	// minSecretLength is the minimum length of the HMAC keys signing tokens and webhooks
+	minSecretLength = 32
+	// defaultIdempotencyTTL is how long the responses of requests made with an Idempotency-Key are replayed
+	defaultIdempotencyTTL = 24 * time.Hour
+	// defaultTrashRetention is how long deleted users, categories and products can be restored
+	defaultTrashRetention = 30 * 24 * time.Hour
+	// defaultEventRetention is how long published events are kept in the outbox
+	defaultEventRetention = 7 * 24 * time.Hour
+
+	defaultUploadRoot = "./uploads"
+	uploadPathPrefix  = "/uploads/"
+
+	checkoutReservationTTL    = 15 * time.Minute
+	reservationExpiryInterval = time.Minute
+	lowStockCheckInterval     = 5 * time.Minute
+	backInStockCheckInterval  = 5 * time.Minute
+	priceScheduleInterval     = time.Minute
+	thumbnailRescanInterval   = time.Minute
+	thumbnailWorkers          = 2
+	thumbnailQueueSize        = 100
+	importWorkers             = 1
+	importQueueSize           = 10
+	idempotencyPurgeInterval  = 10 * time.Minute
+	trashPurgeInterval        = time.Hour
+	eventRelayInterval        = 5 * time.Second
 )
 
+// trashStore is implemented by the user, category and product repositories of every backend
+type trashStore interface {
+	rest.TrashService
+	worker.TrashPurger
+}
+
+// userStore is implemented by the user repository of every backend
+type userStore interface {
+	rest.UserService
+	trashStore
+}
+
+// categoryStore is implemented by the category repository of every backend
+type categoryStore interface {
+	rest.CategoryService
+	trashStore
+}
+
+// productStore is implemented by the product repository of every backend
+type productStore interface {
+	rest.ProductService
+	trashStore
+}
+
+// inventoryStore is implemented by the inventory repository of every backend
+type inventoryStore interface {
+	rest.InventoryService
+	worker.ReservationExpirer
+	worker.LowStockStore
+}
+
+// currencyStore is implemented by the currency repository of every backend
+type currencyStore interface {
+	rest.CurrencyService
+	rest.PriceBookProvider
+}
+
+// wishlistStore is implemented by the wishlist repository of every backend
+type wishlistStore interface {
+	rest.WishlistService
+	worker.BackInStockStore
+}
+
+// priceStore is implemented by the price repository of every backend
+type priceStore interface {
+	rest.PriceService
+	worker.PriceScheduler
+}
+
+// importStore is implemented by the product import repository of every backend
+type importStore interface {
+	rest.ImportService
+	worker.ProductImportStore
+}
+
+// idempotencyStore is implemented by the idempotency repository of every backend
+type idempotencyStore interface {
+	middleware.IdempotencyStore
+	worker.IdempotencyKeyPurger
+}
+
+// auditStore is implemented by the audit log repository of every backend
+type auditStore interface {
+	rest.AuditService
+	middleware.AuditStore
+}
+
+// imageStore is implemented by the product image repository of every backend
+type imageStore interface {
+	rest.ImageService
+	worker.ImageVariantStore
+}
+
+// newEventSink builds the sink of the domain events from the comma separated
+// EVENT_SINKS setting (log, webhook, nats, kafka)
+func newEventSink() event.Sink {
+	var sinks event.MultiSink
+	for _, name := range strings.Split(os.Getenv("EVENT_SINKS"), ",") {
+		switch strings.TrimSpace(name) {
+		case "", "log":
+			bus := event.NewBus()
+			bus.Subscribe(event.AllEvents, event.LogHandler)
+			sinks = append(sinks, bus)
+		case "webhook":
+			sinks = append(sinks, event.NewWebhookSink(os.Getenv("EVENT_WEBHOOK_URL"), []byte(os.Getenv("EVENT_WEBHOOK_SECRET"))))
+		case "nats":
+			sinks = append(sinks, event.NewNATSSink(os.Getenv("NATS_URL"), os.Getenv("NATS_SUBJECT_PREFIX")))
+		case "kafka":
+			sinks = append(sinks, event.NewKafkaSink(os.Getenv("KAFKA_REST_URL"), os.Getenv("KAFKA_TOPIC")))
+		default:
+			log.Fatal("unsupported event sink: ", name)
+		}
+	}
+	return sinks
+}
+
+// newNotifier builds the notifier from the comma separated NOTIFIERS setting (log, email, webhook)
+func newNotifier(outbox notification.EmailOutbox) notification.Notifier {
+	var notifiers []notification.Notifier
+	for _, name := range strings.Split(os.Getenv("NOTIFIERS"), ",") {
+		switch strings.TrimSpace(name) {
+		case "", "log":
+			notifiers = append(notifiers, notification.NewLogNotifier())
+		case "email":
+			recipients := strings.FieldsFunc(os.Getenv("NOTIFIER_EMAILS"), func(r rune) bool { return r == ',' })
+			notifiers = append(notifiers, notification.NewEmailNotifier(outbox, recipients))
+		case "webhook":
+			notifiers = append(notifiers, notification.NewWebhookNotifier(os.Getenv("NOTIFIER_WEBHOOK_URL"), []byte(os.Getenv("NOTIFIER_WEBHOOK_SECRET"))))
+		default:
+			log.Fatal("unsupported notifier: ", name)
+		}
+	}
+	return notification.NewMultiNotifier(notifiers...)
+}
+
+// newBlobStore builds the blob store from STORAGE_DRIVER (local or s3)
+func newBlobStore() storage.BlobStore {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "", "local":
+		root := os.Getenv("STORAGE_LOCAL_ROOT")
+		if root == "" {
+			root = defaultUploadRoot
+		}
+		baseURL := os.Getenv("STORAGE_BASE_URL")
+		if baseURL == "" {
+			baseURL = strings.TrimSuffix(uploadPathPrefix, "/")
+		}
+		store, err := storage.NewLocalStore(root, baseURL)
+		if err != nil {
+			log.Fatal("failed to create local storage: ", err)
+		}
+		return store
+	case "s3":
+		useSSL, _ := strconv.ParseBool(os.Getenv("S3_USE_SSL"))
+		store, err := storage.NewS3Store(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			UseSSL:    useSSL,
+			BaseURL:   os.Getenv("STORAGE_BASE_URL"),
+		})
+		if err != nil {
+			log.Fatal("failed to create S3 storage: ", err)
+		}
+		return store
+	default:
+		log.Fatal("unsupported storage driver. Please set STORAGE_DRIVER to 'local' or 's3'")
+	}
+	return nil
+}
+
+// newPaymentProvider builds the payment provider from PAYMENT_PROVIDER
+func newPaymentProvider() payment.Provider {
+	switch os.Getenv("PAYMENT_PROVIDER") {
+	case "", "fake":
+		// Anyone could sign webhooks marking orders paid with an empty or short key
+		secret := []byte(os.Getenv("PAYMENT_WEBHOOK_SECRET"))
+		if len(secret) < minSecretLength {
+			log.Fatalf("PAYMENT_WEBHOOK_SECRET must be at least %d bytes long", minSecretLength)
+		}
+		return payment.NewFakeProvider(secret)
+	default:
+		log.Fatal("unsupported payment provider. Please set PAYMENT_PROVIDER to 'fake'")
+	}
+	return nil
+}
+
+// newCharges builds the shipping and tax calculator from the rate tables of
+// SHIPPING_RATES_FILE and TAX_RATES_FILE. Without files shipping is free and
+// deliveries to Indonesia are charged PPN.
+func newCharges() domain.CartCharges {
+	tax := pricing.DefaultTaxTable()
+	if path := os.Getenv("TAX_RATES_FILE"); path != "" {
+		table, err := pricing.LoadTaxTable(path)
+		if err != nil {
+			log.Fatal("failed to load tax rates: ", err)
+		}
+		tax = table
+	}
+	shipping := pricing.FreeShippingTable()
+	if path := os.Getenv("SHIPPING_RATES_FILE"); path != "" {
+		table, err := pricing.LoadShippingTable(path)
+		if err != nil {
+			log.Fatal("failed to load shipping rates: ", err)
+		}
+		shipping = table
+	}
+	return pricing.NewCalculator(tax, shipping)
+}
+
+// loadExchangeRates saves the exchange rates of EXCHANGE_RATES_FILE, when
+// set, rates can then be maintained through the admin endpoints
+func loadExchangeRates(ctx context.Context, store rest.CurrencyService) {
+	path := os.Getenv("EXCHANGE_RATES_FILE")
+	if path == "" {
+		return
+	}
+	rates, err := pricing.LoadExchangeRates(path)
+	if err != nil {
+		log.Fatal("failed to load exchange rates: ", err)
+	}
+	for n := range rates {
+		if err := store.SaveRate(ctx, &rates[n]); err != nil {
+			log.Fatal("failed to save exchange rates: ", err)
+		}
+	}
+}
+
 func init() {
 
 	err := godotenv.Load("../.env")
@@ -46,8 +298,26 @@ func main() {
 
 	var dbConn *sql.DB
 	var err error
-	var userRepo rest.UserService // General interface for both repositories
-	var categoryRepo rest.CategoryService
+	var userRepo userStore // General interface for both repositories
+	var categoryRepo categoryStore
+	var productRepo productStore
+	var variantRepo rest.VariantService
+	var inventoryRepo inventoryStore
+	var emailOutboxRepo notification.EmailOutbox
+	var imageRepo imageStore
+	var cartRepo rest.CartService
+	var orderRepo rest.OrderService
+	var paymentRepo rest.PaymentService
+	var promotionRepo rest.PromotionService
+	var currencyRepo currencyStore
+	var reviewRepo rest.ReviewService
+	var wishlistRepo wishlistStore
+	var priceRepo priceStore
+	var importRepo importStore
+	var transactions worker.Transactor
+	var idempotencyRepo idempotencyStore
+	var auditRepo auditStore
+	var eventOutboxRepo worker.EventOutbox
 
 	// Choose database from .env setup DB_TYPE
 	switch dbType {
@@ -58,7 +328,25 @@ func main() {
 			log.Fatal("failed to open connection to Postgres: ", err)
 		}
 		userRepo = postgresRepo.NewPostgresUserRepository(dbConn)
-		// categoryRepo = postgresRepo.NewPostgresCategoryRepository(dbConn)
+		categoryRepo = postgresRepo.NewCategoryRepository(dbConn)
+		productRepo = postgresRepo.NewProductRepository(dbConn)
+		variantRepo = postgresRepo.NewVariantRepository(dbConn)
+		inventoryRepo = postgresRepo.NewInventoryRepository(dbConn)
+		emailOutboxRepo = postgresRepo.NewEmailOutboxRepository(dbConn)
+		imageRepo = postgresRepo.NewImageRepository(dbConn)
+		cartRepo = postgresRepo.NewCartRepository(dbConn)
+		orderRepo = postgresRepo.NewOrderRepository(dbConn)
+		paymentRepo = postgresRepo.NewPaymentRepository(dbConn)
+		promotionRepo = postgresRepo.NewPromotionRepository(dbConn)
+		currencyRepo = postgresRepo.NewCurrencyRepository(dbConn)
+		reviewRepo = postgresRepo.NewReviewRepository(dbConn)
+		wishlistRepo = postgresRepo.NewWishlistRepository(dbConn)
+		priceRepo = postgresRepo.NewPriceRepository(dbConn)
+		importRepo = postgresRepo.NewImportRepository(dbConn)
+		transactions = postgresRepo.NewTransactionManager(dbConn)
+		idempotencyRepo = postgresRepo.NewIdempotencyRepository(dbConn)
+		auditRepo = postgresRepo.NewAuditRepository(dbConn)
+		eventOutboxRepo = postgresRepo.NewEventOutboxRepository(dbConn)
 	case "mysql":
 		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s", dbUser, dbPass, dbHost, dbPort, dbName, val.Encode())
 		dbConn, err = sql.Open("mysql", dsn)
@@ -67,9 +355,46 @@ func main() {
 		}
 		userRepo = mysqlRepo.NewMySQLUserRepository(dbConn)
 		categoryRepo = mysqlRepo.NewMySQLCategoryRepository(dbConn)
+		productRepo = mysqlRepo.NewMySQLProductRepository(dbConn)
+		variantRepo = mysqlRepo.NewMySQLVariantRepository(dbConn)
+		inventoryRepo = mysqlRepo.NewMySQLInventoryRepository(dbConn)
+		emailOutboxRepo = mysqlRepo.NewMySQLEmailOutboxRepository(dbConn)
+		imageRepo = mysqlRepo.NewMySQLImageRepository(dbConn)
+		cartRepo = mysqlRepo.NewMySQLCartRepository(dbConn)
+		orderRepo = mysqlRepo.NewMySQLOrderRepository(dbConn)
+		paymentRepo = mysqlRepo.NewMySQLPaymentRepository(dbConn)
+		promotionRepo = mysqlRepo.NewMySQLPromotionRepository(dbConn)
+		currencyRepo = mysqlRepo.NewMySQLCurrencyRepository(dbConn)
+		reviewRepo = mysqlRepo.NewMySQLReviewRepository(dbConn)
+		wishlistRepo = mysqlRepo.NewMySQLWishlistRepository(dbConn)
+		priceRepo = mysqlRepo.NewMySQLPriceRepository(dbConn)
+		importRepo = mysqlRepo.NewMySQLImportRepository(dbConn)
+		transactions = mysqlRepo.NewMySQLTransactionManager(dbConn)
+		idempotencyRepo = mysqlRepo.NewMySQLIdempotencyRepository(dbConn)
+		auditRepo = mysqlRepo.NewMySQLAuditRepository(dbConn)
+		eventOutboxRepo = mysqlRepo.NewMySQLEventOutboxRepository(dbConn)
+	case "sqlite":
+		// DB_NAME is the path of the database file, :memory: for an in-memory database
+		dbConn, err = sqliteRepo.Open(context.Background(), dbName)
+		if err != nil {
+			log.Fatal("failed to open SQLite database: ", err)
+		}
+		userRepo = sqliteRepo.NewSQLiteUserRepository(dbConn)
+		categoryRepo = sqliteRepo.NewSQLiteCategoryRepository(dbConn)
+		productRepo = sqliteRepo.NewSQLiteProductRepository(dbConn)
+		transactions = sqliteRepo.NewSQLiteTransactionManager(dbConn)
+		idempotencyRepo = sqliteRepo.NewSQLiteIdempotencyRepository(dbConn)
+		auditRepo = sqliteRepo.NewSQLiteAuditRepository(dbConn)
+		eventOutboxRepo = sqliteRepo.NewSQLiteEventOutboxRepository(dbConn)
 	default:
-		log.Fatal("unsupported database type. Please set DB_TYPE to 'postgres' or 'mysql'")
+		log.Fatal("unsupported database type. Please set DB_TYPE to 'postgres', 'mysql' or 'sqlite'")
+	}
+	// A single instance may keep idempotency keys in memory, sparing the database
+	if os.Getenv("IDEMPOTENCY_STORE") == "memory" {
+		idempotencyRepo = memoryRepo.NewMemoryIdempotencyRepository()
 	}
+	// The SQLite backend only implements the users and the catalog
+	catalogOnly := dbType == "sqlite"
 
 	// Check DB connection
 	err = dbConn.Ping()
@@ -79,13 +404,134 @@ func main() {
 
 	defer dbConn.Close()
 
+	// Start background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go worker.RunIdempotencyKeyPurge(ctx, idempotencyRepo, idempotencyPurgeInterval)
+
+	// Deleted items can be restored until purged, products go first as they reference categories
+	trashRetention, err := time.ParseDuration(os.Getenv("TRASH_RETENTION"))
+	if err != nil || trashRetention <= 0 {
+		trashRetention = defaultTrashRetention
+	}
+	go worker.RunTrashPurge(ctx, trashRetention, trashPurgeInterval, productRepo, categoryRepo, userRepo)
+
+	// Domain events are written to the outbox with the changes and published by the relay
+	eventRetention, err := time.ParseDuration(os.Getenv("EVENT_RETENTION"))
+	if err != nil || eventRetention <= 0 {
+		eventRetention = defaultEventRetention
+	}
+	go worker.RunEventRelay(ctx, eventOutboxRepo, newEventSink(), eventRelayInterval, eventRetention)
+	if !catalogOnly {
+		loadExchangeRates(context.Background(), currencyRepo)
+		go worker.RunReservationExpiry(ctx, inventoryRepo, reservationExpiryInterval)
+		go worker.RunLowStockCheck(ctx, inventoryRepo, newNotifier(emailOutboxRepo), lowStockCheckInterval)
+		go worker.RunBackInStockCheck(ctx, wishlistRepo, newNotifier(emailOutboxRepo), backInStockCheckInterval)
+		go worker.RunPriceScheduler(ctx, priceRepo, priceScheduleInterval)
+	}
+
 	// Create a main router
 	r := mux.NewRouter()
+	r.Use(middleware.RequestIDMiddleware)
 	apiRouter := r.PathPrefix("/api/v1").Subrouter()
+	// Changes made through the API are recorded in the audit logs, in their transactions
+	apiRouter.Use(middleware.AuditMiddleware(auditRepo, transactions))
+
+	// Tokens signed with an empty or short key could be forged
+	jwtSecret := []byte(os.Getenv("JWT_SECRET"))
+	if len(jwtSecret) < minSecretLength {
+		log.Fatalf("JWT_SECRET must be at least %d bytes long", minSecretLength)
+	}
+	tokenTTL, err := time.ParseDuration(os.Getenv("JWT_TTL"))
+	if err != nil || tokenTTL <= 0 {
+		tokenTTL = defaultTokenTTL
+	}
+
+	// Retries of POST and PATCH requests made with an Idempotency-Key get the first response
+	idempotencyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || idempotencyTTL <= 0 {
+		idempotencyTTL = defaultIdempotencyTTL
+	}
+	idempotencyMaxBodySize, err := strconv.ParseInt(os.Getenv("IDEMPOTENCY_MAX_BODY_SIZE"), 10, 64)
+	if err != nil || idempotencyMaxBodySize <= 0 {
+		idempotencyMaxBodySize = middleware.DefaultIdempotencyMaxBodySize
+	}
+	apiRouter.Use(middleware.IdempotencyMiddleware(idempotencyRepo, idempotencyTTL, idempotencyMaxBodySize, jwtSecret, rest.CartCookieName))
+
+	// Staff only routes sit behind JWT authentication
+	staffRouter := apiRouter.NewRoute().Subrouter()
+	staffRouter.Use(middleware.JWTMiddleware(jwtSecret), middleware.RequireRole(domain.RoleAdmin, domain.RoleStaff))
+	adminRouter := apiRouter.NewRoute().Subrouter()
+	adminRouter.Use(middleware.JWTMiddleware(jwtSecret), middleware.RequireRole(domain.RoleAdmin))
+	charges := newCharges()
 
 	// Register user handlers to the subrouter
-	rest.NewUserHandler(apiRouter, userRepo)
-	rest.NewCategoryHandler(apiRouter, categoryRepo)
+	var carts rest.CartMerger
+	var prices rest.PriceBookProvider
+	if !catalogOnly {
+		carts, prices = cartRepo, currencyRepo
+	}
+	rest.NewUserHandler(apiRouter, adminRouter, userRepo, carts, jwtSecret, tokenTTL)
+	rest.NewCategoryHandler(apiRouter, staffRouter, categoryRepo)
+	rest.NewProductHandler(apiRouter, staffRouter, productRepo, prices, catalog.Feed{
+		Title:       os.Getenv("FEED_TITLE"),
+		Description: os.Getenv("FEED_DESCRIPTION"),
+		Link:        os.Getenv("STOREFRONT_URL"),
+	})
+	rest.NewTrashHandler(adminRouter, map[string]rest.TrashService{
+		"users":      userRepo,
+		"categories": categoryRepo,
+		"products":   productRepo,
+	})
+	rest.NewAuditHandler(adminRouter, auditRepo)
+	if !catalogOnly {
+		rest.NewPriceHandler(apiRouter, staffRouter, priceRepo)
+		rest.NewVariantHandler(apiRouter, staffRouter, variantRepo)
+
+		// Register product image handlers, serving local uploads when stored on disk
+		blobStore := newBlobStore()
+		maxImageSize, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_SIZE"), 10, 64)
+		if err != nil || maxImageSize <= 0 {
+			maxImageSize = rest.DefaultMaxImageSize
+		}
+		imageSizes, err := imaging.ParseSizes(os.Getenv("IMAGE_SIZES"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		thumbnails := worker.NewThumbnailQueue(imageRepo, blobStore, imageSizes, thumbnailQueueSize)
+		go thumbnails.Run(ctx, thumbnailWorkers, thumbnailRescanInterval)
+		rest.NewImageHandler(apiRouter, staffRouter, imageRepo, blobStore, thumbnails, maxImageSize)
+		if localStore, ok := blobStore.(*storage.LocalStore); ok {
+			r.PathPrefix(uploadPathPrefix).Handler(http.StripPrefix(uploadPathPrefix, http.FileServer(http.Dir(localStore.Root))))
+		}
+
+		// Register cart handlers, open to anonymous visitors and authenticated users
+		cartRouter := apiRouter.NewRoute().Subrouter()
+		cartRouter.Use(middleware.OptionalJWTMiddleware(jwtSecret))
+		rest.NewCartHandler(cartRouter, cartRepo, productRepo, charges)
+
+		// Register staff only handlers
+		rest.NewInventoryHandler(staffRouter, inventoryRepo)
+		rest.NewPromotionHandler(staffRouter, promotionRepo)
+		rest.NewCurrencyHandler(staffRouter, currencyRepo)
+
+		// Register product imports, large files being imported in the background
+		maxImportSize, err := strconv.ParseInt(os.Getenv("IMPORT_MAX_SIZE"), 10, 64)
+		if err != nil || maxImportSize <= 0 {
+			maxImportSize = rest.DefaultMaxImportSize
+		}
+		importer := worker.NewProductImporter(importRepo, transactions, importQueueSize)
+		importer.Run(ctx, importWorkers)
+		rest.NewImportHandler(staffRouter, importRepo, importer, maxImportSize)
+
+		// Register checkout, order, review and wishlist handlers for authenticated users
+		authRouter := apiRouter.NewRoute().Subrouter()
+		authRouter.Use(middleware.JWTMiddleware(jwtSecret))
+		rest.NewOrderHandler(authRouter, staffRouter, orderRepo, charges, checkoutReservationTTL)
+		rest.NewPaymentHandler(apiRouter, authRouter, staffRouter, paymentRepo, orderRepo, newPaymentProvider())
+		rest.NewReviewHandler(apiRouter, authRouter, staffRouter, reviewRepo)
+		rest.NewWishlistHandler(apiRouter, authRouter, wishlistRepo)
+	}
 
 	// Wrap the main router with CORS middleware
 	corsWrappedRouter := middleware.CORSMiddleware(r)
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction is what an administrative change did to its entity
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditCancel  AuditAction = "cancel"
+	AuditImport  AuditAction = "import"
+	AuditCapture AuditAction = "capture"
+	AuditRefund  AuditAction = "refund"
+)
+
+// AuditLog represent an administrative change. Entries are append only and
+// chained: the hash of an entry covers its fields and the hash of the entry
+// before it, so that editing or deleting an entry breaks the chain.
+type AuditLog struct {
+	ID int64 `json:"id"`
+	// ActorID is nil for changes made by anonymous requests
+	ActorID    *int        `json:"actor_id"`
+	ActorEmail string      `json:"actor_email"`
+	Action     AuditAction `json:"action"`
+	EntityType string      `json:"entity_type"`
+	// EntityID is empty for changes of several entities, such as imports
+	EntityID string `json:"entity_id"`
+	// Changes holds the changed fields as {"field": {"before": ..., "after": ...}}
+	Changes   json.RawMessage `json:"changes"`
+	RequestID string          `json:"request_id"`
+	IP        string          `json:"ip"`
+	CreatedAt time.Time       `json:"created_at"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+}
+
+// AuditLogFilter narrows the audit logs, zero values matching every entry
+type AuditLogFilter struct {
+	ActorID    *int
+	Action     AuditAction
+	EntityType string
+	EntityID   string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+// AuditVerification is the result of checking the hash chain of the audit logs
+type AuditVerification struct {
+	Valid   bool `json:"valid"`
+	Entries int  `json:"entries"`
+	// BrokenAt is the first entry whose hash doesn't match, nil when the
+	// chain is not valid because its last entries were removed
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+}
@@ -0,0 +1,149 @@
+package domain
+
+import "time"
+
+// CartOwner identifies a cart, either by its authenticated user or by the
+// token of an anonymous visitor
+type CartOwner struct {
+	UserID int
+	Token  string
+}
+
+// IsZero reports whether the owner identifies no cart
+func (o CartOwner) IsZero() bool {
+	return o.UserID == 0 && o.Token == ""
+}
+
+// Cart represent the shopping cart of a customer or of an anonymous visitor.
+// RejectedCoupons holds the reason of each entered coupon that does not apply.
+// Shipping and taxes are only quoted once a delivery region is known. Lines
+// are snapshotted in the catalog currency and priced in Currency, at
+// ExchangeRate, when the cart is loaded.
+type Cart struct {
+	ID              int               `json:"id,omitempty"`
+	UserID          *int              `json:"user_id,omitempty"`
+	Token           string            `json:"-"`
+	Currency        string            `json:"currency"`
+	ExchangeRate    ExchangeRate      `json:"-"`
+	Items           []CartItem        `json:"items"`
+	Coupons         []string          `json:"coupons"`
+	RejectedCoupons map[string]string `json:"rejected_coupons,omitempty"`
+	Discounts       []AppliedDiscount `json:"discounts"`
+	Region          string            `json:"region,omitempty"`
+	Taxes           []TaxLine         `json:"taxes"`
+	Subtotal        Money             `json:"subtotal"`
+	DiscountTotal   Money             `json:"discount_total"`
+	Shipping        Money             `json:"shipping"`
+	TaxTotal        Money             `json:"tax_total"`
+	Total           Money             `json:"total"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// CartItem represent a cart line. UnitPrice is the product or variant price
+// snapshotted when the line was first added.
+type CartItem struct {
+	ID         int             `json:"id"`
+	CartID     int             `json:"-"`
+	ProductID  int             `json:"product_id"`
+	VariantID  *int            `json:"variant_id,omitempty"`
+	CategoryID int             `json:"-"`
+	Shipping   ShippingDetails `json:"-"`
+	Name       string          `json:"name"`
+	SKU        string          `json:"sku,omitempty"`
+	UnitPrice  Money           `json:"unit_price"`
+	Quantity   int             `json:"quantity"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Owner returns the owner identifying the cart
+func (c Cart) Owner() CartOwner {
+	if c.UserID != nil {
+		return CartOwner{UserID: *c.UserID}
+	}
+	return CartOwner{Token: c.Token}
+}
+
+// NewCartItem builds a cart line for quantity units of a product, or of one of
+// its variants, snapshotting the price and checking the stock. Products with
+// variants can only be added through one of their variants.
+func NewCartItem(product Product, variantID *int, quantity int) (CartItem, error) {
+	if quantity <= 0 {
+		return CartItem{}, ErrBadRequest
+	}
+
+	item := CartItem{
+		ProductID: product.ID,
+		Name:      product.Name,
+		UnitPrice: product.Price,
+		Quantity:  quantity,
+	}
+	stock := product.Stock
+	if variantID != nil {
+		variant, ok := findVariant(product.Variants, *variantID)
+		if !ok {
+			return CartItem{}, ErrBadRequest
+		}
+		item.VariantID = &variant.ID
+		item.SKU = variant.SKU
+		item.UnitPrice = variant.EffectivePrice(product.Price)
+		stock = variant.Stock
+	} else if len(product.Variants) > 0 {
+		return CartItem{}, ErrBadRequest
+	}
+
+	if quantity > stock {
+		return CartItem{}, ErrInsufficientStock
+	}
+	return item, nil
+}
+
+func findVariant(variants []ProductVariant, id int) (ProductVariant, bool) {
+	for _, v := range variants {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return ProductVariant{}, false
+}
+
+// Item returns the cart line with the given id
+func (c Cart) Item(id int) (CartItem, bool) {
+	for _, item := range c.Items {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return CartItem{}, false
+}
+
+// Quantity returns the quantity of a product or variant already in the cart
+func (c Cart) Quantity(productID int, variantID *int) int {
+	for _, item := range c.Items {
+		if item.ProductID == productID && sameVariant(item.VariantID, variantID) {
+			return item.Quantity
+		}
+	}
+	return 0
+}
+
+func sameVariant(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// CalculateSubtotal returns the sum of the cart lines
+func (c Cart) CalculateSubtotal() Money {
+	subtotal := Money{Currency: c.Currency}
+	for _, item := range c.Items {
+		subtotal = subtotal.Add(item.LineTotal())
+	}
+	return subtotal
+}
+
+// LineTotal returns the unit price times the quantity of the line
+func (i CartItem) LineTotal() Money {
+	return i.UnitPrice.Mul(int64(i.Quantity))
+}
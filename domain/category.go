@@ -3,4 +3,6 @@ package domain
 type Category struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
+	// Version is incremented by every edit, it is sent in the ETag header
+	Version int `json:"-"`
 }
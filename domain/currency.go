@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+// NormalizeCurrency returns the canonical form of an ISO 4217 currency code
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ExchangeRate represent the value of one unit of the catalog currency,
+// DefaultCurrency, in another currency
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate" validate:"gt=0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CatalogRate is the identity rate of the catalog currency
+func CatalogRate() ExchangeRate {
+	return ExchangeRate{Currency: DefaultCurrency, Rate: 1}
+}
+
+// Convert returns an amount of the catalog currency in the currency of the
+// rate, rounded half away from zero. Amounts already in that currency are
+// returned as is, and the zero rate converts nothing.
+func (r ExchangeRate) Convert(m Money) Money {
+	if r.Currency == "" || m.Currency == r.Currency {
+		return m
+	}
+	if m.Currency != "" && m.Currency != DefaultCurrency {
+		panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, r.Currency))
+	}
+
+	rate, ok := new(big.Rat).SetString(strconv.FormatFloat(r.Rate, 'f', -1, 64))
+	if !ok {
+		panic(ErrInvalidAmount)
+	}
+	amount := new(big.Rat).SetInt64(m.Amount)
+	amount.Mul(amount, rate)
+	if shift := CurrencyExponent(r.Currency) - CurrencyExponent(DefaultCurrency); shift >= 0 {
+		amount.Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(shift)), nil)))
+	} else {
+		amount.Quo(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-shift)), nil)))
+	}
+	return Money{Amount: roundRat(amount), Currency: r.Currency}
+}
+
+// ProductPrice represent the price list entry of a product in a currency
+// other than the catalog one. It applies to the product and to its variants.
+type ProductPrice struct {
+	ProductID int   `json:"product_id"`
+	Price     Money `json:"price"`
+}
+
+// PriceBook resolves catalog prices in a currency, from the price lists of
+// the products or, failing that, by conversion at the exchange rate
+type PriceBook struct {
+	Rate   ExchangeRate
+	Prices map[int]Money
+}
+
+// Currency returns the currency the book prices in
+func (b PriceBook) Currency() string {
+	if b.Rate.Currency == "" {
+		return DefaultCurrency
+	}
+	return b.Rate.Currency
+}
+
+// Price returns the price of a product given its catalog price
+func (b PriceBook) Price(productID int, catalog Money) Money {
+	if price, ok := b.Prices[productID]; ok {
+		return price
+	}
+	return b.Rate.Convert(catalog)
+}
+
+// Localize prices a product and its variants in the currency of the book.
+// Variant price overrides are converted unless the product has a price list entry.
+func (b PriceBook) Localize(product *Product) {
+	listed, hasEntry := b.Prices[product.ID]
+	for n := range product.Variants {
+		variant := &product.Variants[n]
+		if variant.Price == nil {
+			continue
+		}
+		price := b.Rate.Convert(Money{Amount: variant.Price.Amount, Currency: product.Price.Currency})
+		if hasEntry {
+			price = listed
+		}
+		variant.Price = &price
+	}
+	product.Price = b.Price(product.ID, product.Price)
+}
+
+// LocalizeItems prices the lines of a cart, snapshotted in the catalog currency
+func (b PriceBook) LocalizeItems(items []CartItem) {
+	for n := range items {
+		items[n].UnitPrice = b.Price(items[n].ProductID, items[n].UnitPrice)
+	}
+}
@@ -6,4 +6,7 @@ var (
 	ErrNotFound       = errors.New("not found")
 	ErrInternalServer = errors.New("internal server error")
 	ErrBadRequest     = errors.New("bad request")
+	ErrConflict       = errors.New("conflict")
+	// ErrVersionMismatch is returned when a resource was edited since the version an edit was based on
+	ErrVersionMismatch = errors.New("version mismatch")
 )
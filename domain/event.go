@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Domain events, published to other systems through the event outbox
+const (
+	EventUserRegistered  = "user.registered"
+	EventProductCreated  = "product.created"
+	EventProductUpdated  = "product.updated"
+	EventProductDeleted  = "product.deleted"
+	EventProductRestored = "product.restored"
+	EventVariantCreated  = "variant.created"
+	EventVariantUpdated  = "variant.updated"
+	EventVariantDeleted  = "variant.deleted"
+	EventStockDepleted   = "inventory.out_of_stock"
+)
+
+// Event represent a change other systems may react to. Events are written
+// to the outbox in the transaction of the change and published at least
+// once, consumers tell redeliveries apart by ID.
+type Event struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// Key names the entity the event is about, e.g. "product:12", sinks
+	// keeping the events of a key in order where they can
+	Key        string          `json:"key"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	// Attempts counts the deliveries tried, this one included
+	Attempts int `json:"attempts"`
+}
+
+// NewEvent returns an event of eventType about the entity of key
+func NewEvent(eventType, key string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: eventType, Key: key, Payload: data, OccurredAt: time.Now()}, nil
+}
+
+// EventKey returns the key of the entity of kind, e.g. "product", and id
+func EventKey(kind string, id int) string {
+	return kind + ":" + strconv.Itoa(id)
+}
+
+// UserRegistered is the payload of user.registered events
+type UserRegistered struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+// ProductChanged is the payload of the product events
+type ProductChanged struct {
+	ProductID int `json:"product_id"`
+	// Fields lists the changed fields of product.updated events
+	Fields []string `json:"fields,omitempty"`
+}
+
+// VariantChanged is the payload of the variant events, keyed by the product
+// of the variant so they keep their order with the events of the product
+type VariantChanged struct {
+	ProductID int `json:"product_id"`
+	VariantID int `json:"variant_id"`
+	// Fields lists the changed fields of variant.updated events
+	Fields []string `json:"fields,omitempty"`
+}
+
+// StockDepleted is the payload of inventory.out_of_stock events, raised when
+// a stock movement brings the stock of a product or variant down to zero
+type StockDepleted struct {
+	ProductID int  `json:"product_id"`
+	VariantID *int `json:"variant_id,omitempty"`
+	// MovementID is the stock movement which depleted the stock
+	MovementID int `json:"movement_id"`
+}
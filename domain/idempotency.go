@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord represent a request made with an Idempotency-Key header,
+// kept along with its response to replay it to the retries of the request
+type IdempotencyRecord struct {
+	Key string
+	// Fingerprint identifies the request the key was first used with
+	Fingerprint string
+	// StatusCode is 0 while the request is in flight
+	StatusCode int
+	Header     map[string][]string
+	Body       []byte
+	// ExpiresAt is when the key may be used again. Requests in flight expire
+	// early, so that requests interrupted by a crash can be retried.
+	ExpiresAt time.Time
+}
+
+// Completed reports whether the response of the request is stored
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrFileTooLarge         = errors.New("file too large")
+)
+
+// ProductImage represent an uploaded image of a product. Images are ordered
+// by Position and exactly one image of a product is the primary image.
+type ProductImage struct {
+	ID          int       `json:"id"`
+	ProductID   int       `json:"product_id"`
+	Key         string    `json:"-"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Position    int       `json:"position"`
+	IsPrimary   bool      `json:"is_primary"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Status tracks the generation of the responsive variants
+	Status   ImageStatus    `json:"status"`
+	Variants []ImageVariant `json:"variants,omitempty"`
+	SrcSet   string         `json:"srcset,omitempty"`
+}
+
+// ImageStatus represent the processing state of an uploaded image
+type ImageStatus string
+
+const (
+	ImageStatusPending ImageStatus = "pending"
+	ImageStatusReady   ImageStatus = "ready"
+	ImageStatusFailed  ImageStatus = "failed"
+)
+
+// ImageVariant represent a resized re-encode of a product image
+type ImageVariant struct {
+	ID          int    `json:"-"`
+	ImageID     int    `json:"-"`
+	Name        string `json:"name"`
+	Key         string `json:"-"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+}
+
+// BuildSrcSet returns a srcset attribute value listing the variants by width
+func BuildSrcSet(variants []ImageVariant) string {
+	candidates := make([]string, 0, len(variants))
+	for _, v := range variants {
+		candidates = append(candidates, fmt.Sprintf("%s %dw", v.URL, v.Width))
+	}
+	return strings.Join(candidates, ", ")
+}
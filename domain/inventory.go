@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrReservationExpired = errors.New("reservation expired")
+)
+
+// StockMovementType represent the reason of a stock movement
+type StockMovementType string
+
+const (
+	StockMovementRestock    StockMovementType = "restock"
+	StockMovementSale       StockMovementType = "sale"
+	StockMovementAdjustment StockMovementType = "adjustment"
+	StockMovementReturn     StockMovementType = "return"
+)
+
+// StockMovement represent a ledger entry of a stock change. Quantity is the
+// signed change applied to the stock, StockAfter the stock once applied.
+type StockMovement struct {
+	ID         int               `json:"id"`
+	ProductID  int               `json:"product_id"`
+	VariantID  *int              `json:"variant_id,omitempty"`
+	Type       StockMovementType `json:"type" validate:"required,oneof=restock sale adjustment return"`
+	Quantity   int               `json:"quantity" validate:"required"`
+	StockAfter int               `json:"stock_after"`
+	Reference  string            `json:"reference,omitempty" validate:"max=100"`
+	Note       string            `json:"note,omitempty" validate:"max=255"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// Validate checks the quantity sign matches the movement type
+func (m StockMovement) Validate() error {
+	switch m.Type {
+	case StockMovementRestock, StockMovementReturn:
+		if m.Quantity <= 0 {
+			return ErrBadRequest
+		}
+	case StockMovementSale:
+		if m.Quantity >= 0 {
+			return ErrBadRequest
+		}
+	case StockMovementAdjustment:
+		if m.Quantity == 0 {
+			return ErrBadRequest
+		}
+	default:
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// SoldDelta returns the change a movement applies to the sold counter
+func (m StockMovement) SoldDelta() int {
+	switch m.Type {
+	case StockMovementSale, StockMovementReturn:
+		return -m.Quantity
+	default:
+		return 0
+	}
+}
+
+// ReservationStatus represent the state of a stock reservation
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationCommitted ReservationStatus = "committed"
+	ReservationReleased  ReservationStatus = "released"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// StockReservation represent stock held for a pending checkout. Active
+// reservations reduce the available stock until committed, released or expired.
+type StockReservation struct {
+	ID        int               `json:"id"`
+	ProductID int               `json:"product_id"`
+	VariantID *int              `json:"variant_id,omitempty"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status"`
+	Reference string            `json:"reference,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// LowStockAlert represent a product whose stock crossed its reorder threshold.
+// An alert stays open until the stock is replenished above the threshold, so
+// each crossing is notified only once.
+type LowStockAlert struct {
+	ID          int        `json:"id"`
+	ProductID   int        `json:"product_id"`
+	ProductName string     `json:"product_name"`
+	Stock       int        `json:"stock"`
+	Threshold   int        `json:"threshold"`
+	CreatedAt   time.Time  `json:"created_at"`
+	NotifiedAt  *time.Time `json:"notified_at,omitempty"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
@@ -0,0 +1,388 @@
+package domain
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts stored or received without one
+const DefaultCurrency = "IDR"
+
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid amount")
+)
+
+// currencyExponents lists the ISO 4217 minor unit digits of the currencies not using 2
+var currencyExponents = map[string]int{
+	"BHD": 3, "JPY": 0, "KRW": 0, "KWD": 3, "OMR": 3, "VND": 0,
+}
+
+// CurrencyExponent returns the number of minor unit digits of an ISO 4217 currency
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Money represent an exact amount in the minor units of an ISO 4217 currency,
+// e.g. cents or sen. The zero value is a zero amount without currency, it
+// takes the currency of the amounts added to it so it can start a sum.
+//
+// Arithmetic panics on mixed currencies and on overflows: amounts are
+// converted to a single currency before they are combined.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney returns an amount of minor units of currency
+func NewMoney(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: strings.ToUpper(currency)}
+}
+
+// ParseMoney parses a decimal amount in major units, e.g. "1500.50". Digits
+// beyond the minor units of the currency must be zeros.
+func ParseMoney(s, currency string) (Money, error) {
+	currency = strings.ToUpper(currency)
+	exp := CurrencyExponent(currency)
+	s = strings.TrimSpace(s)
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return Money{}, ErrInvalidAmount
+	}
+	if len(frac) > exp {
+		if strings.Trim(frac[exp:], "0") != "" {
+			return Money{}, ErrInvalidAmount
+		}
+		frac = frac[:exp]
+	}
+	frac += strings.Repeat("0", exp-len(frac))
+
+	digits := whole + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Money{}, ErrInvalidAmount
+	}
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// MoneyFromFloat converts an amount in major units, rounding half away from
+// zero to the minor units of currency. Only meant for rates and legacy inputs.
+func MoneyFromFloat(value float64, currency string) Money {
+	currency = strings.ToUpper(currency)
+	minor := math.Round(value * math.Pow10(CurrencyExponent(currency)))
+	if math.IsNaN(minor) || minor > math.MaxInt64 || minor < math.MinInt64 {
+		panic(ErrInvalidAmount)
+	}
+	return Money{Amount: int64(minor), Currency: currency}
+}
+
+// match returns the currency of a combination of m and o
+func (m Money) match(o Money) string {
+	switch {
+	case m.Currency == "":
+		return o.Currency
+	case o.Currency == "" || o.Currency == m.Currency:
+		return m.Currency
+	default:
+		panic(fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency))
+	}
+}
+
+// CurrencyCode returns the currency of m, or the default currency when unset
+func (m Money) CurrencyCode() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// SameCurrency reports whether m and o can be combined
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == "" || o.Currency == "" || m.Currency == o.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// Cmp returns -1, 0 or 1 when m is less than, equal to or greater than o
+func (m Money) Cmp(o Money) int {
+	m.match(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) Add(o Money) Money {
+	currency := m.match(o)
+	sum := m.Amount + o.Amount
+	if (o.Amount > 0 && sum < m.Amount) || (o.Amount < 0 && sum > m.Amount) {
+		panic(ErrInvalidAmount)
+	}
+	return Money{Amount: sum, Currency: currency}
+}
+
+func (m Money) Sub(o Money) Money {
+	return m.Add(o.Neg())
+}
+
+func (m Money) Neg() Money {
+	if m.Amount == math.MinInt64 {
+		panic(ErrInvalidAmount)
+	}
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Mul returns m multiplied by a quantity
+func (m Money) Mul(n int64) Money {
+	product := m.Amount * n
+	if n != 0 && (product/n != m.Amount || (n == -1 && m.Amount == math.MinInt64)) {
+		panic(ErrInvalidAmount)
+	}
+	return Money{Amount: product, Currency: m.Currency}
+}
+
+// Percent returns rate percent of m, rounded half away from zero to minor units
+func (m Money) Percent(rate float64) Money {
+	r := new(big.Rat).SetInt64(m.Amount)
+	rat, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok {
+		panic(ErrInvalidAmount)
+	}
+	r.Mul(r, rat)
+	r.Quo(r, big.NewRat(100, 1))
+	return Money{Amount: roundRat(r), Currency: m.Currency}
+}
+
+// roundRat rounds r half away from zero
+func roundRat(r *big.Rat) int64 {
+	num := new(big.Int).Abs(r.Num())
+	q, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if rem.Mul(rem, big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	if !q.IsInt64() {
+		panic(ErrInvalidAmount)
+	}
+	return q.Int64()
+}
+
+// Allocate splits m in proportion to weights without losing minor units: the
+// remainder goes one unit at a time to the largest fractional shares. Zero
+// weights get nothing, and m goes to the first share when all are zero.
+func (m Money) Allocate(weights ...int64) []Money {
+	result := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return result
+	}
+
+	total := new(big.Int)
+	for _, w := range weights {
+		if w < 0 {
+			panic(ErrInvalidAmount)
+		}
+		total.Add(total, big.NewInt(w))
+	}
+	if total.Sign() == 0 {
+		result[0] = m
+		for n := 1; n < len(result); n++ {
+			result[n] = Money{Currency: m.Currency}
+		}
+		return result
+	}
+
+	amount := big.NewInt(m.Amount)
+	remainders := make([]*big.Int, len(weights))
+	allocated := int64(0)
+	for n, w := range weights {
+		share, rem := new(big.Int).QuoRem(new(big.Int).Mul(amount, big.NewInt(w)), total, new(big.Int))
+		result[n] = Money{Amount: share.Int64(), Currency: m.Currency}
+		remainders[n] = rem.Abs(rem)
+		allocated += share.Int64()
+	}
+
+	step := int64(1)
+	if m.Amount < 0 {
+		step = -1
+	}
+	for left := m.Amount - allocated; left != 0; left -= step {
+		best := -1
+		for n := range remainders {
+			if weights[n] > 0 && (best < 0 || remainders[n].Cmp(remainders[best]) > 0) {
+				best = n
+			}
+		}
+		result[best].Amount += step
+		remainders[best] = new(big.Int)
+	}
+	return result
+}
+
+// MinMoney returns the smaller of a and b
+func MinMoney(a, b Money) Money {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+// Decimal formats m in major units, e.g. "1500.50"
+func (m Money) Decimal() string {
+	exp := CurrencyExponent(m.Currency)
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absInt64(amount), 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func absInt64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+func (m Money) String() string {
+	return strings.TrimSpace(m.Currency + " " + m.Decimal())
+}
+
+// moneyJSON is the JSON encoding of Money, amounts are decimal strings so
+// clients never parse them as floats
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	currency := m.CurrencyCode()
+	amount, err := json.Marshal(Money{Amount: m.Amount, Currency: currency}.Decimal())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(moneyJSON{Amount: amount, Currency: currency})
+}
+
+// UnmarshalJSON accepts {"amount": "1500.50", "currency": "IDR"}, the amount
+// may be a number, as well as a bare amount in the default currency
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	raw := moneyJSON{Amount: data, Currency: DefaultCurrency}
+	if len(data) > 0 && data[0] == '{' {
+		raw.Currency = ""
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		if raw.Currency == "" {
+			raw.Currency = DefaultCurrency
+		}
+	}
+
+	parsed, err := ParseMoney(strings.Trim(string(raw.Amount), `"`), raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads a DECIMAL or NUMERIC column. The currency must be known before
+// the amount is read: keep the one already set on m, typically by scanning a
+// currency column listed first, or fall back to the default currency.
+func (m *Money) Scan(src interface{}) error {
+	currency := m.CurrencyCode()
+
+	var parsed Money
+	var err error
+	switch v := src.(type) {
+	case []byte:
+		parsed, err = ParseMoney(string(v), currency)
+	case string:
+		parsed, err = ParseMoney(v, currency)
+	case int64:
+		parsed = Money{Amount: v, Currency: currency}.Mul(int64(math.Pow10(CurrencyExponent(currency))))
+	case float64:
+		parsed = MoneyFromFloat(v, currency)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores m as a decimal in major units
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
+
+// CurrencyColumn scans a currency column into each of its amounts. Listed
+// before the amount columns of a row, it lets them be read in that currency.
+type CurrencyColumn []*Money
+
+func (c CurrencyColumn) Scan(src interface{}) error {
+	var currency string
+	switch v := src.(type) {
+	case []byte:
+		currency = string(v)
+	case string:
+		currency = v
+	case nil:
+		currency = DefaultCurrency
+	default:
+		return fmt.Errorf("cannot scan %T into a currency", src)
+	}
+	for _, m := range c {
+		m.Currency = strings.ToUpper(strings.TrimSpace(currency))
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Notification events
+const (
+	EventLowStock    = "inventory.low_stock"
+	EventBackInStock = "wishlist.back_in_stock"
+)
+
+// Notification represent a message delivered to staff or customers through a notifier
+type Notification struct {
+	Event     string      `json:"event"`
+	Recipient string      `json:"recipient,omitempty"`
+	Subject   string      `json:"subject"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	// Key identifies the notification across its deliveries, such as the
+	// alert it reports, empty when it isn't retried
+	Key string `json:"key,omitempty"`
+}
+
+// OutboxEmail represent an email waiting in the outbox to be sent by a mailer
+type OutboxEmail struct {
+	ID        int        `json:"id"`
+	Recipient string     `json:"recipient"`
+	Subject   string     `json:"subject"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
@@ -0,0 +1,111 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrEmptyCart         = errors.New("cart is empty")
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
+// OrderStatus represent the lifecycle state of an order
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderRefunded  OrderStatus = "refunded"
+)
+
+// orderTransitions lists the statuses each status can move to
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderDelivered},
+	OrderDelivered: {OrderRefunded},
+}
+
+// CanTransitionTo reports whether an order can move from s to next
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// RestocksItems reports whether moving to s puts the ordered items back in stock
+func (s OrderStatus) RestocksItems() bool {
+	return s == OrderCancelled || s == OrderRefunded
+}
+
+// Order represent a checked out cart. ExchangeRate is the rate from the
+// catalog currency used to price the order, 1 for orders in the catalog currency.
+type Order struct {
+	ID            int                 `json:"id"`
+	UserID        int                 `json:"user_id"`
+	Status        OrderStatus         `json:"status"`
+	Region        string              `json:"region"`
+	ExchangeRate  float64             `json:"exchange_rate"`
+	Subtotal      Money               `json:"subtotal"`
+	DiscountTotal Money               `json:"discount_total"`
+	ShippingTotal Money               `json:"shipping"`
+	TaxTotal      Money               `json:"tax_total"`
+	Total         Money               `json:"total"`
+	Items         []OrderItem         `json:"items,omitempty"`
+	Discounts     []AppliedDiscount   `json:"discounts,omitempty"`
+	Taxes         []TaxLine           `json:"taxes,omitempty"`
+	History       []OrderStatusChange `json:"history,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+// Currency returns the currency the order was placed in
+func (o Order) Currency() string {
+	return o.Total.CurrencyCode()
+}
+
+// CalculateSubtotal returns the sum of the order lines
+func (o Order) CalculateSubtotal() Money {
+	var subtotal Money
+	for _, item := range o.Items {
+		subtotal = subtotal.Add(item.UnitPrice.Mul(int64(item.Quantity)))
+	}
+	return subtotal
+}
+
+// OrderItem represent an order line, copied from the cart line at checkout
+type OrderItem struct {
+	ID        int    `json:"id"`
+	OrderID   int    `json:"order_id"`
+	ProductID int    `json:"product_id"`
+	VariantID *int   `json:"variant_id,omitempty"`
+	Name      string `json:"name"`
+	SKU       string `json:"sku,omitempty"`
+	UnitPrice Money  `json:"unit_price"`
+	Quantity  int    `json:"quantity"`
+}
+
+// OrderStatusChange represent an entry of the status history of an order. From
+// is empty for the entry recording the creation of the order.
+type OrderStatusChange struct {
+	ID        int         `json:"id"`
+	OrderID   int         `json:"order_id"`
+	From      OrderStatus `json:"from,omitempty"`
+	To        OrderStatus `json:"to" validate:"required,oneof=pending paid shipped delivered cancelled refunded"`
+	Note      string      `json:"note,omitempty" validate:"max=255"`
+	ChangedBy *int        `json:"changed_by,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// OrderFilter narrows order listings, zero values match every order
+type OrderFilter struct {
+	UserID int
+	Status OrderStatus
+}
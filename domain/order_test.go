@@ -0,0 +1,74 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+var orderStatuses = []domain.OrderStatus{
+	domain.OrderPending, domain.OrderPaid, domain.OrderShipped,
+	domain.OrderDelivered, domain.OrderCancelled, domain.OrderRefunded,
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	allowed := map[domain.OrderStatus][]domain.OrderStatus{
+		domain.OrderPending:   {domain.OrderPaid, domain.OrderCancelled},
+		domain.OrderPaid:      {domain.OrderShipped, domain.OrderRefunded},
+		domain.OrderShipped:   {domain.OrderDelivered},
+		domain.OrderDelivered: {domain.OrderRefunded},
+	}
+
+	// Every pair not listed is forbidden, cancelled and refunded orders are final
+	for _, from := range orderStatuses {
+		for _, to := range orderStatuses {
+			want := false
+			for _, next := range allowed[from] {
+				want = want || next == to
+			}
+			if got := from.CanTransitionTo(to); got != want {
+				t.Errorf("%s to %s = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+	if domain.OrderPending.CanTransitionTo("lost") || domain.OrderStatus("lost").CanTransitionTo(domain.OrderPaid) {
+		t.Error("unknown statuses can be transitioned")
+	}
+}
+
+func TestOrderStatusRestocksItems(t *testing.T) {
+	tests := map[domain.OrderStatus]bool{
+		domain.OrderPending:   false,
+		domain.OrderPaid:      false,
+		domain.OrderShipped:   false,
+		domain.OrderDelivered: false,
+		domain.OrderCancelled: true,
+		domain.OrderRefunded:  true,
+	}
+	for _, status := range orderStatuses {
+		if got := status.RestocksItems(); got != tests[status] {
+			t.Errorf("%s restocks items = %v, want %v", status, got, tests[status])
+		}
+	}
+}
+
+// Sales made at checkout count as sold, the returns of cancelled and
+// refunded orders take them back
+func TestStockMovementSoldDelta(t *testing.T) {
+	tests := []struct {
+		movementType domain.StockMovementType
+		quantity     int
+		want         int
+	}{
+		{domain.StockMovementSale, -2, 2},
+		{domain.StockMovementReturn, 2, -2},
+		{domain.StockMovementRestock, 5, 0},
+		{domain.StockMovementAdjustment, -1, 0},
+	}
+	for _, tt := range tests {
+		movement := domain.StockMovement{Type: tt.movementType, Quantity: tt.quantity}
+		if got := movement.SoldDelta(); got != tt.want {
+			t.Errorf("%s of %d sold delta = %d, want %d", tt.movementType, tt.quantity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// PaymentStatus represent the state of a payment at its provider
+type PaymentStatus string
+
+const (
+	PaymentPending    PaymentStatus = "pending"
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentCaptured   PaymentStatus = "captured"
+	PaymentFailed     PaymentStatus = "failed"
+	PaymentRefunded   PaymentStatus = "refunded"
+)
+
+// paymentTransitions lists the statuses each status can move to, so replayed
+// or out of order provider events never move a payment backwards
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentPending:    {PaymentAuthorized, PaymentCaptured, PaymentFailed},
+	PaymentAuthorized: {PaymentCaptured, PaymentFailed},
+	PaymentFailed:     {PaymentAuthorized, PaymentCaptured},
+	PaymentCaptured:   {PaymentRefunded},
+}
+
+// CanTransitionTo reports whether a payment can move from s to next
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	for _, status := range paymentTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Payment represent a payment intent created at a payment provider for an order
+type Payment struct {
+	ID           int           `json:"id"`
+	OrderID      int           `json:"order_id"`
+	Provider     string        `json:"provider"`
+	ExternalID   string        `json:"external_id"`
+	Amount       Money         `json:"amount"`
+	Status       PaymentStatus `json:"status"`
+	ClientSecret string        `json:"client_secret,omitempty"`
+	RedirectURL  string        `json:"redirect_url,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// PaymentEventType represent the kind of a payment provider notification
+type PaymentEventType string
+
+const (
+	PaymentEventAuthorized PaymentEventType = "payment.authorized"
+	PaymentEventSucceeded  PaymentEventType = "payment.succeeded"
+	PaymentEventFailed     PaymentEventType = "payment.failed"
+	PaymentEventRefunded   PaymentEventType = "payment.refunded"
+)
+
+// PaymentEvent represent a verified notification of a payment provider. ID is
+// the provider event id used to apply each event once, it is empty for
+// events originating from this API.
+type PaymentEvent struct {
+	ID         string           `json:"id"`
+	Type       PaymentEventType `json:"type"`
+	ExternalID string           `json:"payment_id"`
+	Amount     Money            `json:"amount"`
+}
+
+// PaymentStatus returns the payment status an event moves its payment to
+func (e PaymentEvent) PaymentStatus() (PaymentStatus, bool) {
+	switch e.Type {
+	case PaymentEventAuthorized:
+		return PaymentAuthorized, true
+	case PaymentEventSucceeded:
+		return PaymentCaptured, true
+	case PaymentEventFailed:
+		return PaymentFailed, true
+	case PaymentEventRefunded:
+		return PaymentRefunded, true
+	default:
+		return "", false
+	}
+}
+
+// OrderStatus returns the order status an event moves the paid order to
+func (e PaymentEvent) OrderStatus() (OrderStatus, bool) {
+	switch e.Type {
+	case PaymentEventSucceeded:
+		return OrderPaid, true
+	case PaymentEventRefunded:
+		return OrderRefunded, true
+	default:
+		return "", false
+	}
+}
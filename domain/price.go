@@ -0,0 +1,68 @@
+package domain
+
+import "time"
+
+// PriceChange represent an entry of the price history of a product. ChangedBy
+// is the staff member who changed the price, ScheduleID the price schedule
+// which applied or reverted it. Changes of a variant price override name the
+// variant, their prices being the effective prices of the variant.
+type PriceChange struct {
+	ID         int       `json:"id"`
+	ProductID  int       `json:"product_id"`
+	VariantID  *int      `json:"variant_id,omitempty"`
+	OldPrice   Money     `json:"old_price"`
+	NewPrice   Money     `json:"new_price"`
+	ChangedBy  *int      `json:"changed_by,omitempty"`
+	ScheduleID *int      `json:"schedule_id,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PriceScheduleStatus represent the lifecycle state of a price schedule
+type PriceScheduleStatus string
+
+const (
+	PriceScheduled PriceScheduleStatus = "scheduled"
+	PriceActive    PriceScheduleStatus = "active"
+	PriceCompleted PriceScheduleStatus = "completed"
+	PriceCancelled PriceScheduleStatus = "cancelled"
+)
+
+// PriceSchedule represent a future price of a product, e.g. a flash sale. The
+// price applies at StartsAt and, when EndsAt is set, the price from before
+// the schedule is restored at EndsAt unless the price was changed meanwhile.
+type PriceSchedule struct {
+	ID            int                 `json:"id"`
+	ProductID     int                 `json:"product_id"`
+	Price         Money               `json:"price"`
+	PreviousPrice *Money              `json:"previous_price,omitempty"`
+	StartsAt      time.Time           `json:"starts_at" validate:"required"`
+	EndsAt        *time.Time          `json:"ends_at,omitempty"`
+	Status        PriceScheduleStatus `json:"status"`
+	Note          string              `json:"note,omitempty" validate:"max=255"`
+	CreatedBy     *int                `json:"created_by,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+// Validate checks the price and period of a schedule
+func (s PriceSchedule) Validate() error {
+	if s.Price.IsNegative() || s.Price.CurrencyCode() != DefaultCurrency {
+		return ErrBadRequest
+	}
+	if s.EndsAt != nil && !s.EndsAt.After(s.StartsAt) {
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// Overlaps reports whether the periods of two schedules intersect, open ended
+// schedules last forever
+func (s PriceSchedule) Overlaps(o PriceSchedule) bool {
+	if s.EndsAt != nil && !s.EndsAt.After(o.StartsAt) {
+		return false
+	}
+	if o.EndsAt != nil && !o.EndsAt.After(s.StartsAt) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "errors"
+
+var ErrShippingUnavailable = errors.New("shipping unavailable to the region")
+
+// TaxLine represent a tax charged on a cart or an order, Rate is a percentage
+type TaxLine struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Amount Money   `json:"amount"`
+}
+
+// CartCharges adds the shipping and the taxes of a delivery region to a cart
+// already priced with its discounts
+type CartCharges interface {
+	Apply(cart *Cart, region string) error
+}
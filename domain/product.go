@@ -1,12 +1,46 @@
 package domain
 
 type Product struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Description string  `json:"description,omitempty"`
-	Price       float64 `json:"price"`
-	ImageURL    string  `json:"image_url"`
-	Stock       int     `json:"stock"`
-	Sold        int     `json:"sold"`
-	Category    Category
+	ID               int    `json:"id"`
+	SKU              string `json:"sku,omitempty"`
+	Name             string `json:"name"`
+	Description      string `json:"description,omitempty"`
+	Price            Money  `json:"price"`
+	ImageURL         string `json:"image_url"`
+	Stock            int    `json:"stock"`
+	Sold             int    `json:"sold"`
+	ReorderThreshold int    `json:"reorder_threshold"`
+	ShippingDetails
+	Category Category
+	Rating   Rating           `json:"rating"`
+	Options  []ProductOption  `json:"options,omitempty"`
+	Variants []ProductVariant `json:"variants,omitempty"`
+	Images   []ProductImage   `json:"images,omitempty"`
+	// Version is incremented by every edit of the product by staff, it is
+	// sent in the ETag header. Stock and rating changes don't count as edits.
+	Version int `json:"-"`
+}
+
+// ShippingDetails represent the packed weight, in grams, and dimensions, in
+// centimeters, of a product
+type ShippingDetails struct {
+	Weight int     `json:"weight" validate:"gte=0"`
+	Length float64 `json:"length" validate:"gte=0"`
+	Width  float64 `json:"width" validate:"gte=0"`
+	Height float64 `json:"height" validate:"gte=0"`
+}
+
+// Volume returns the packed volume in cubic centimeters
+func (s ShippingDetails) Volume() float64 {
+	return s.Length * s.Width * s.Height
+}
+
+// ProductFilter narrows product listings and exports, zero fields match every
+// product. Price bounds only match products priced in their currency.
+type ProductFilter struct {
+	CategoryID int
+	Search     string
+	MinPrice   *Money
+	MaxPrice   *Money
+	InStock    bool
 }
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// ImportStatus represent the lifecycle state of an import job
+type ImportStatus string
+
+const (
+	ImportPending   ImportStatus = "pending"
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+// ProductImportRow represent a product read from a spreadsheet row, upserted
+// by SKU. Optional fields are nil when their column is missing or the cell is
+// blank, leaving the value of an existing product untouched.
+type ProductImportRow struct {
+	Row         int
+	SKU         string
+	Name        string
+	Category    string
+	Price       Money
+	Description *string
+	ImageURL    *string
+	Stock       *int
+	Weight      *int
+	Length      *float64
+	Width       *float64
+	Height      *float64
+}
+
+// ImportRowError represent a row of an import which was rejected
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportJob represent the progress and outcome of a product import. Dry runs
+// validate the file without being stored.
+type ImportJob struct {
+	ID            int              `json:"id,omitempty"`
+	Filename      string           `json:"filename"`
+	Status        ImportStatus     `json:"status"`
+	DryRun        bool             `json:"dry_run"`
+	TotalRows     int              `json:"total_rows"`
+	ProcessedRows int              `json:"processed_rows"`
+	CreatedRows   int              `json:"created_rows"`
+	UpdatedRows   int              `json:"updated_rows"`
+	FailedRows    int              `json:"failed_rows"`
+	Errors        []ImportRowError `json:"errors"`
+	Error         string           `json:"error,omitempty"`
+	CreatedBy     *int             `json:"created_by,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	FinishedAt    *time.Time       `json:"finished_at,omitempty"`
+}
+
+// MaxImportErrors caps the row errors kept on a job
+const MaxImportErrors = 1000
+
+// AddErrors records rejected rows, keeping at most MaxImportErrors of them
+func (j *ImportJob) AddErrors(errs ...ImportRowError) {
+	failed := map[int]bool{}
+	for _, e := range errs {
+		if !failed[e.Row] {
+			failed[e.Row] = true
+			j.FailedRows++
+		}
+		if len(j.Errors) < MaxImportErrors {
+			j.Errors = append(j.Errors, e)
+		}
+	}
+}
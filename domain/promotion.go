@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCouponNotApplicable = errors.New("coupon not applicable")
+	ErrPromotionInactive   = fmt.Errorf("%w: coupon is not active", ErrCouponNotApplicable)
+	ErrMinSubtotalNotMet   = fmt.Errorf("%w: cart subtotal is below the minimum", ErrCouponNotApplicable)
+	ErrUsageLimitReached   = fmt.Errorf("%w: usage limit reached", ErrCouponNotApplicable)
+	ErrNoEligibleItems     = fmt.Errorf("%w: no eligible items in the cart", ErrCouponNotApplicable)
+	ErrNotStackable        = fmt.Errorf("%w: coupon can't be combined with the other promotions", ErrCouponNotApplicable)
+)
+
+// PromotionType represent how a promotion discounts the eligible amount
+type PromotionType string
+
+const (
+	PromotionPercentage PromotionType = "percentage"
+	PromotionFixed      PromotionType = "fixed"
+)
+
+// maxPromotionAmount is the largest amount the promotion columns can store
+var maxPromotionAmount = NewMoney(999999999999999, DefaultCurrency)
+
+// Promotion represent a discount rule. Promotions without a code apply
+// automatically, the others once their coupon code is entered. Percentage
+// promotions take Percentage off the eligible amount, fixed ones take Amount,
+// an amount in the catalog currency. Amounts are converted at the exchange
+// rate of carts priced in another currency.
+// A nil CategoryID applies the promotion to every item, and zero limits are unlimited.
+type Promotion struct {
+	ID                int           `json:"id"`
+	Name              string        `json:"name" validate:"required,max=255"`
+	Code              string        `json:"code,omitempty" validate:"max=50"`
+	Type              PromotionType `json:"type" validate:"required,oneof=percentage fixed"`
+	Percentage        float64       `json:"percentage,omitempty" validate:"gte=0,lte=100"`
+	Amount            Money         `json:"amount" validate:"gte=0"`
+	MinSubtotal       Money         `json:"min_subtotal" validate:"gte=0"`
+	CategoryID        *int          `json:"category_id,omitempty"`
+	UsageLimitPerUser int           `json:"usage_limit_per_user" validate:"gte=0"`
+	Stackable         bool          `json:"stackable"`
+	Priority          int           `json:"priority"`
+	Active            bool          `json:"active"`
+	StartsAt          *time.Time    `json:"starts_at,omitempty"`
+	EndsAt            *time.Time    `json:"ends_at,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+}
+
+// Validate checks the rules the validate tags can't express. Percentages
+// must be within (0, 100] and amounts positive and storable, in the catalog
+// currency, each promotion having only the value of its type.
+func (p Promotion) Validate() error {
+	switch p.Type {
+	case PromotionPercentage:
+		if !(p.Percentage > 0 && p.Percentage <= 100) || !p.Amount.IsZero() {
+			return ErrBadRequest
+		}
+	case PromotionFixed:
+		if p.Percentage != 0 || !p.Amount.IsPositive() || p.Amount.CurrencyCode() != DefaultCurrency ||
+			p.Amount.Amount > maxPromotionAmount.Amount {
+			return ErrBadRequest
+		}
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return ErrBadRequest
+	}
+	if p.MinSubtotal.CurrencyCode() != DefaultCurrency || p.MinSubtotal.Amount > maxPromotionAmount.Amount {
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// ActiveAt reports whether the promotion is enabled and within its validity window at t
+func (p Promotion) ActiveAt(t time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && t.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !t.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// NormalizeCoupon returns the canonical form of a coupon code, codes are case insensitive
+func NormalizeCoupon(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// AppliedDiscount represent a promotion applied to a cart or an order
+type AppliedDiscount struct {
+	PromotionID int    `json:"promotion_id"`
+	Name        string `json:"name"`
+	Code        string `json:"code,omitempty"`
+	Amount      Money  `json:"amount"`
+}
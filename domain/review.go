@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// ReviewStatus represent the moderation state of a review
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// Review represent the rating and review of a product by a customer. Only
+// approved reviews are listed publicly and counted in the product rating.
+type Review struct {
+	ID               int          `json:"id"`
+	ProductID        int          `json:"product_id"`
+	UserID           int          `json:"user_id"`
+	Rating           int          `json:"rating" validate:"required,min=1,max=5"`
+	Title            string       `json:"title" validate:"max=255"`
+	Body             string       `json:"body" validate:"max=5000"`
+	VerifiedPurchase bool         `json:"verified_purchase"`
+	Status           ReviewStatus `json:"status"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// ReviewFilter narrows review listings, zero values match every review
+type ReviewFilter struct {
+	ProductID int
+	Status    ReviewStatus
+}
+
+// Rating represent the aggregated approved reviews of a product
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+// ReviewedOrderStatuses are the statuses of an order whose items count as a
+// verified purchase
+var ReviewedOrderStatuses = []OrderStatus{OrderPaid, OrderShipped, OrderDelivered}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrCategoryNotEmpty is returned when deleting a category which still has products
+	ErrCategoryNotEmpty = errors.New("category has products")
+	// ErrCategoryDeleted is returned when restoring a product of a deleted category
+	ErrCategoryDeleted = errors.New("category is deleted")
+)
+
+// TrashedItem represent a deleted user, category or product. Deleted items
+// are hidden everywhere but in orders, until restored or purged.
+type TrashedItem struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
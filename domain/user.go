@@ -2,12 +2,20 @@ package domain
 
 import "golang.org/x/crypto/bcrypt"
 
+const (
+	RoleAdmin    = "admin"
+	RoleStaff    = "staff"
+	RoleCustomer = "customer"
+)
+
 type User struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password,omitempty" validate:"required,min=8,password"`
 	Role     string `json:"role"`
+	// Version is incremented by every edit, it is sent in the ETag header
+	Version int `json:"-"`
 }
 
 func (u *User) HashPassword() error {
@@ -18,3 +26,8 @@ func (u *User) HashPassword() error {
 	u.Password = string(hashed)
 	return nil
 }
+
+// CheckPassword reports whether password matches the hashed password of the user
+func (u User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) == nil
+}
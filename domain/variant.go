@@ -0,0 +1,38 @@
+package domain
+
+// ProductOption represent an option type of a product, e.g. "Size" or "Color"
+type ProductOption struct {
+	ID        int                  `json:"id"`
+	ProductID int                  `json:"product_id"`
+	Name      string               `json:"name" validate:"required"`
+	Values    []ProductOptionValue `json:"values" validate:"required,min=1,dive"`
+}
+
+// ProductOptionValue represent a single value of an option type, e.g. "XL" or "Red"
+type ProductOptionValue struct {
+	ID       int    `json:"id"`
+	OptionID int    `json:"option_id"`
+	Option   string `json:"option,omitempty"`
+	Value    string `json:"value" validate:"required"`
+}
+
+// ProductVariant represent a sellable SKU of a product. A nil Price means the
+// variant is sold at the product price.
+type ProductVariant struct {
+	ID        int                  `json:"id"`
+	ProductID int                  `json:"product_id"`
+	SKU       string               `json:"sku" validate:"required,max=64"`
+	Price     *Money               `json:"price,omitempty" validate:"omitempty,gte=0"`
+	Stock     int                  `json:"stock" validate:"gte=0"`
+	Sold      int                  `json:"sold"`
+	Options   []ProductOptionValue `json:"options"`
+}
+
+// EffectivePrice returns the variant price override or the given product
+// price. Overrides are in the currency of their product.
+func (v ProductVariant) EffectivePrice(productPrice Money) Money {
+	if v.Price != nil {
+		return Money{Amount: v.Price.Amount, Currency: productPrice.Currency}
+	}
+	return productPrice
+}
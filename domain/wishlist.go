@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Wishlist represent a named list of products saved by a customer. A share
+// token, when set, lets anyone with the link read the wishlist.
+type Wishlist struct {
+	ID         int            `json:"id"`
+	UserID     int            `json:"user_id,omitempty"`
+	Name       string         `json:"name" validate:"required,max=100"`
+	ShareToken string         `json:"share_token,omitempty"`
+	Items      []WishlistItem `json:"items"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// WishlistItem represent a product saved in a wishlist
+type WishlistItem struct {
+	Product Product   `json:"product"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// BackInStockAlert represent a wishlisted product whose stock went up from
+// zero, to be notified to the user who saved it
+type BackInStockAlert struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Email       string     `json:"email"`
+	ProductID   int        `json:"product_id"`
+	ProductName string     `json:"product_name"`
+	CreatedAt   time.Time  `json:"created_at"`
+	NotifiedAt  *time.Time `json:"notified_at,omitempty"`
+}
@@ -0,0 +1,163 @@
+// Package audit computes the changes recorded in the audit logs and the hash
+// chain making them tamper evident. Repositories append entries with Seal,
+// which links an entry to the one before it.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// Precision is the precision of the times of the entries, which every
+// database stores without loss
+const Precision = time.Microsecond
+
+// valueField is the field holding values which aren't JSON objects
+const valueField = "value"
+
+// change is a changed field
+type change struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Diff returns the top level fields of the JSON encodings of before and after
+// which differ, as {"field": {"before": ..., "after": ...}}. A nil before
+// records a creation, a nil after a deletion. Values which aren't JSON
+// objects are compared whole under the "value" field.
+func Diff(before, after interface{}) (json.RawMessage, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]change{}
+	for name, value := range beforeFields {
+		if !bytes.Equal(value, afterFields[name]) {
+			changes[name] = change{Before: value, After: orNull(afterFields[name])}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = change{Before: json.RawMessage("null"), After: value}
+		}
+	}
+	return json.Marshal(changes)
+}
+
+// fields returns the top level fields of the JSON encoding of v
+func fields(v interface{}) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(encoded, []byte("null")) {
+		return nil, nil
+	}
+
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return map[string]json.RawMessage{valueField: encoded}, nil
+	}
+	return result, nil
+}
+
+// orNull returns value, or null for a missing field
+func orNull(value json.RawMessage) json.RawMessage {
+	if value == nil {
+		return json.RawMessage("null")
+	}
+	return value
+}
+
+// Hash returns the hash of an entry, covering its fields other than its ID
+// and hash, the hash of the entry before it included
+func Hash(entry domain.AuditLog) string {
+	// The fields are hashed in a fixed order, with the times in UTC so that
+	// the hash doesn't depend on the time zone of the database connection
+	content, _ := json.Marshal([]interface{}{
+		entry.PrevHash,
+		entry.ActorID,
+		entry.ActorEmail,
+		entry.Action,
+		entry.EntityType,
+		entry.EntityID,
+		string(entry.Changes),
+		entry.RequestID,
+		entry.IP,
+		entry.CreatedAt.UTC().Truncate(Precision).Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Seal links entry to the entry before it, whose hash is prevHash, setting
+// its time when unset
+func Seal(entry *domain.AuditLog, prevHash string) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(Precision)
+	if entry.Changes == nil {
+		entry.Changes = json.RawMessage("{}")
+	}
+	entry.PrevHash = prevHash
+	entry.Hash = Hash(*entry)
+}
+
+// Chain is implemented by the storage of the audit logs
+type Chain interface {
+	// Each calls fn with every entry, oldest first, until fn fails
+	Each(ctx context.Context, fn func(entry domain.AuditLog) error) error
+	// Head returns the hash of the last appended entry
+	Head(ctx context.Context) (string, error)
+}
+
+// errBroken stops walking a chain at its first broken entry
+type errBroken struct{}
+
+func (errBroken) Error() string { return "audit log chain is broken" }
+
+// Verify walks the audit logs, checking that every entry follows the one
+// before it and wasn't changed since appended, and that the last entries
+// weren't removed
+func Verify(ctx context.Context, chain Chain) (domain.AuditVerification, error) {
+	// The head is read first, entries appended meanwhile only lengthen the chain
+	head, err := chain.Head(ctx)
+	if err != nil {
+		return domain.AuditVerification{}, err
+	}
+
+	result := domain.AuditVerification{Valid: true}
+	prevHash := ""
+	reached := head == ""
+	err = chain.Each(ctx, func(entry domain.AuditLog) error {
+		if entry.PrevHash != prevHash || Hash(entry) != entry.Hash {
+			id := entry.ID
+			result.Valid, result.BrokenAt = false, &id
+			return errBroken{}
+		}
+		result.Entries++
+		prevHash = entry.Hash
+		reached = reached || entry.Hash == head
+		return nil
+	})
+	if _, broken := err.(errBroken); err != nil && !broken {
+		return domain.AuditVerification{}, err
+	}
+	result.Valid = result.Valid && reached
+	return result, nil
+}
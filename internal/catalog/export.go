@@ -0,0 +1,214 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// Writer writes exported products one at a time. Close writes what follows
+// the last product, it doesn't close the underlying writer.
+type Writer interface {
+	Write(product domain.Product) error
+	Close() error
+}
+
+// Feed describes the storefront linked from product feeds
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the base URL of the storefront, products being linked at Link/products/{id}
+	Link string
+}
+
+// Format represent an export format
+type Format struct {
+	ContentType string
+	Extension   string
+	NewWriter   func(w io.Writer, feed Feed) Writer
+}
+
+// Formats lists the export formats by name
+var Formats = map[string]Format{
+	"csv":    {ContentType: "text/csv; charset=utf-8", Extension: ".csv", NewWriter: newCSVWriter},
+	"ndjson": {ContentType: "application/x-ndjson", Extension: ".ndjson", NewWriter: newJSONLinesWriter},
+	"xml":    {ContentType: "application/xml; charset=utf-8", Extension: ".xml", NewWriter: newFeedWriter},
+}
+
+// csvHeader names the exported columns, the import fields among them so that
+// exports can be imported back
+var csvHeader = []string{
+	"id", FieldSKU, FieldName, FieldCategory, FieldPrice, "currency", FieldDescription, FieldImageURL,
+	FieldStock, FieldWeight, FieldLength, FieldWidth, FieldHeight,
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer, _ Feed) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(product domain.Product) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.w.Write([]string{
+		strconv.Itoa(product.ID),
+		product.SKU,
+		product.Name,
+		product.Category.Name,
+		product.Price.Decimal(),
+		product.Price.CurrencyCode(),
+		product.Description,
+		product.ImageURL,
+		strconv.Itoa(product.Stock),
+		strconv.Itoa(product.Weight),
+		strconv.FormatFloat(product.Length, 'f', -1, 64),
+		strconv.FormatFloat(product.Width, 'f', -1, 64),
+		strconv.FormatFloat(product.Height, 'f', -1, 64),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonLinesWriter writes a JSON object per line
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+func newJSONLinesWriter(w io.Writer, _ Feed) Writer {
+	return &jsonLinesWriter{enc: json.NewEncoder(w)}
+}
+
+func (j *jsonLinesWriter) Write(product domain.Product) error {
+	return j.enc.Encode(product)
+}
+
+func (j *jsonLinesWriter) Close() error {
+	return nil
+}
+
+// feedItem is a product of an RSS 2.0 feed in the Google Merchant Center format
+type feedItem struct {
+	XMLName        xml.Name `xml:"item"`
+	ID             string   `xml:"g:id"`
+	Title          string   `xml:"g:title"`
+	Description    string   `xml:"g:description"`
+	Link           string   `xml:"g:link"`
+	ImageLink      string   `xml:"g:image_link,omitempty"`
+	Availability   string   `xml:"g:availability"`
+	Price          string   `xml:"g:price"`
+	Condition      string   `xml:"g:condition"`
+	ProductType    string   `xml:"g:product_type"`
+	ShippingWeight string   `xml:"g:shipping_weight,omitempty"`
+}
+
+type feedWriter struct {
+	w      io.Writer
+	enc    *xml.Encoder
+	feed   Feed
+	opened bool
+}
+
+func newFeedWriter(w io.Writer, feed Feed) Writer {
+	return &feedWriter{w: w, enc: xml.NewEncoder(w), feed: feed}
+}
+
+// open writes the feed up to its first item
+func (f *feedWriter) open() error {
+	if f.opened {
+		return nil
+	}
+	f.opened = true
+	if _, err := io.WriteString(f.w, xml.Header); err != nil {
+		return err
+	}
+
+	rss := xml.StartElement{Name: xml.Name{Local: "rss"}, Attr: []xml.Attr{
+		{Name: xml.Name{Local: "version"}, Value: "2.0"},
+		{Name: xml.Name{Local: "xmlns:g"}, Value: "http://base.google.com/ns/1.0"},
+	}}
+	channel := xml.StartElement{Name: xml.Name{Local: "channel"}}
+	if err := f.enc.EncodeToken(rss); err != nil {
+		return err
+	}
+	if err := f.enc.EncodeToken(channel); err != nil {
+		return err
+	}
+	for _, field := range []struct{ name, value string }{
+		{"title", f.feed.Title},
+		{"link", f.feed.Link},
+		{"description", f.feed.Description},
+	} {
+		if err := f.enc.EncodeElement(field.value, xml.StartElement{Name: xml.Name{Local: field.name}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *feedWriter) Write(product domain.Product) error {
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	item := feedItem{
+		ID:           product.SKU,
+		Title:        product.Name,
+		Description:  product.Description,
+		Link:         strings.TrimSuffix(f.feed.Link, "/") + "/products/" + strconv.Itoa(product.ID),
+		ImageLink:    product.ImageURL,
+		Availability: "out_of_stock",
+		Price:        product.Price.Decimal() + " " + product.Price.CurrencyCode(),
+		Condition:    "new",
+		ProductType:  product.Category.Name,
+	}
+	if item.ID == "" {
+		item.ID = strconv.Itoa(product.ID)
+	}
+	if item.Description == "" {
+		item.Description = product.Name
+	}
+	if product.Stock > 0 {
+		item.Availability = "in_stock"
+	}
+	if product.Weight > 0 {
+		item.ShippingWeight = strconv.Itoa(product.Weight) + " g"
+	}
+	return f.enc.Encode(item)
+}
+
+func (f *feedWriter) Close() error {
+	if err := f.open(); err != nil {
+		return err
+	}
+	if err := f.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "channel"}}); err != nil {
+		return err
+	}
+	if err := f.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "rss"}}); err != nil {
+		return err
+	}
+	return f.enc.Flush()
+}
@@ -0,0 +1,252 @@
+package catalog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// Importable product fields, used as the default column names
+const (
+	FieldSKU         = "sku"
+	FieldName        = "name"
+	FieldCategory    = "category"
+	FieldPrice       = "price"
+	FieldDescription = "description"
+	FieldImageURL    = "image_url"
+	FieldStock       = "stock"
+	FieldWeight      = "weight"
+	FieldLength      = "length"
+	FieldWidth       = "width"
+	FieldHeight      = "height"
+)
+
+// Fields lists the importable fields, the first ones are required
+var Fields = []string{
+	FieldSKU, FieldName, FieldCategory, FieldPrice,
+	FieldDescription, FieldImageURL, FieldStock, FieldWeight, FieldLength, FieldWidth, FieldHeight,
+}
+
+var requiredFields = Fields[:4]
+
+// Mapping maps product fields to the header of the spreadsheet column holding
+// them. Unmapped fields are read from the column named after the field.
+type Mapping map[string]string
+
+// MappingError reports fields of a mapping which can't be resolved
+type MappingError struct {
+	Unknown []string
+	Missing []string
+}
+
+func (e *MappingError) Error() string {
+	var parts []string
+	if len(e.Unknown) > 0 {
+		parts = append(parts, "unknown fields: "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing columns: "+strings.Join(e.Missing, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// columns resolves the column index of every field found in the header row,
+// header names are matched case insensitively
+func (m Mapping) columns(header []string) (map[string]int, error) {
+	mappingErr := &MappingError{}
+	for field := range m {
+		if !isField(field) {
+			mappingErr.Unknown = append(mappingErr.Unknown, field)
+		}
+	}
+
+	index := map[string]int{}
+	for n, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := index[key]; !ok {
+			index[key] = n
+		}
+	}
+
+	columns := map[string]int{}
+	for _, field := range Fields {
+		name := field
+		if mapped, ok := m[field]; ok && strings.TrimSpace(mapped) != "" {
+			name = mapped
+		}
+		if n, ok := index[strings.ToLower(strings.TrimSpace(name))]; ok {
+			columns[field] = n
+		}
+	}
+	for _, field := range requiredFields {
+		if _, ok := columns[field]; !ok {
+			mappingErr.Missing = append(mappingErr.Missing, field)
+		}
+	}
+
+	if len(mappingErr.Unknown) > 0 || len(mappingErr.Missing) > 0 {
+		return nil, mappingErr
+	}
+	return columns, nil
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseProducts reads the products of a sheet whose first row holds the
+// column headers. Blank rows are skipped, invalid rows and rows repeating the
+// SKU of a previous row are reported as errors. Rows are numbered as in the
+// spreadsheet, the header being row 1.
+func ParseProducts(sheet [][]string, mapping Mapping) (rows []domain.ProductImportRow, errs []domain.ImportRowError, err error) {
+	if len(sheet) == 0 {
+		return nil, nil, &MappingError{Missing: requiredFields}
+	}
+	columns, err := mapping.columns(sheet[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := map[string]int{}
+	for n, cells := range sheet[1:] {
+		if isBlank(cells) {
+			continue
+		}
+		p := rowParser{number: n + 2, cells: cells, columns: columns}
+		row := p.parse()
+		if len(p.errs) == 0 {
+			if first, ok := seen[strings.ToLower(row.SKU)]; ok {
+				p.fail(FieldSKU, fmt.Sprintf("duplicates the SKU of row %d", first))
+			} else {
+				seen[strings.ToLower(row.SKU)] = row.Row
+			}
+		}
+
+		if len(p.errs) > 0 {
+			errs = append(errs, p.errs...)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, errs, nil
+}
+
+// CountRows returns the number of non blank rows below the header
+func CountRows(sheet [][]string) int {
+	count := 0
+	for n, cells := range sheet {
+		if n > 0 && !isBlank(cells) {
+			count++
+		}
+	}
+	return count
+}
+
+func isBlank(cells []string) bool {
+	for _, cell := range cells {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// rowParser reads the fields of a row, collecting the errors of every field
+type rowParser struct {
+	number  int
+	cells   []string
+	columns map[string]int
+	errs    []domain.ImportRowError
+}
+
+func (p *rowParser) fail(field, message string) {
+	p.errs = append(p.errs, domain.ImportRowError{Row: p.number, Column: field, Message: message})
+}
+
+// cell returns the trimmed value of a field, empty when the column is missing
+func (p *rowParser) cell(field string) string {
+	n, ok := p.columns[field]
+	if !ok || n >= len(p.cells) {
+		return ""
+	}
+	return strings.TrimSpace(p.cells[n])
+}
+
+func (p *rowParser) text(field string, required bool, max int) string {
+	value := p.cell(field)
+	if required && value == "" {
+		p.fail(field, "is required")
+	}
+	if len(value) > max {
+		p.fail(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+	return value
+}
+
+func (p *rowParser) optionalText(field string, max int) *string {
+	value := p.text(field, false, max)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func (p *rowParser) optionalInt(field string) *int {
+	value := p.cell(field)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		p.fail(field, "must be a whole number of at least 0")
+		return nil
+	}
+	return &n
+}
+
+func (p *rowParser) optionalFloat(field string) *float64 {
+	value := p.cell(field)
+	if value == "" {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		p.fail(field, "must be a number of at least 0")
+		return nil
+	}
+	return &f
+}
+
+func (p *rowParser) parse() domain.ProductImportRow {
+	row := domain.ProductImportRow{
+		Row:         p.number,
+		SKU:         p.text(FieldSKU, true, 100),
+		Name:        p.text(FieldName, true, 255),
+		Category:    p.text(FieldCategory, true, 255),
+		Description: p.optionalText(FieldDescription, 65535),
+		ImageURL:    p.optionalText(FieldImageURL, 255),
+		Stock:       p.optionalInt(FieldStock),
+		Weight:      p.optionalInt(FieldWeight),
+		Length:      p.optionalFloat(FieldLength),
+		Width:       p.optionalFloat(FieldWidth),
+		Height:      p.optionalFloat(FieldHeight),
+	}
+
+	price, err := domain.ParseMoney(p.cell(FieldPrice), domain.DefaultCurrency)
+	switch {
+	case p.cell(FieldPrice) == "":
+		p.fail(FieldPrice, "is required")
+	case err != nil || price.IsNegative():
+		p.fail(FieldPrice, "must be an amount of at least 0")
+	default:
+		row.Price = price
+	}
+	return row
+}
@@ -0,0 +1,67 @@
+// Package catalog reads product catalogs maintained in spreadsheets and
+// exports the catalog to files and marketplace feeds
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrUnsupportedFormat is returned for files which are neither CSV nor XLSX
+var ErrUnsupportedFormat = errors.New("unsupported spreadsheet format")
+
+// ReadSheet returns the cells of a CSV file or of the first worksheet of an
+// XLSX workbook, telling them apart by the file name and content
+func ReadSheet(r io.ReaderAt, size int64, filename string) ([][]string, error) {
+	magic := make([]byte, 4)
+	n, _ := r.ReadAt(magic, 0)
+	isZip := bytes.Equal(magic[:n], []byte("PK\x03\x04"))
+
+	switch ext := strings.ToLower(path.Ext(filename)); {
+	case ext == ".xlsx" || (ext == "" && isZip):
+		return readXLSX(r, size)
+	case ext == ".csv" || ext == ".txt" || ext == "":
+		return readCSV(io.NewSectionReader(r, 0, size))
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// readCSV reads comma or semicolon separated values, as exported by
+// spreadsheets in locales using the comma as decimal separator
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	// Blank lines are skipped by the reader, keep their place so rows are
+	// numbered as in the file
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, record)
+	}
+}
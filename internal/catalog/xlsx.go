@@ -0,0 +1,207 @@
+package catalog
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize bounds the uncompressed size of a workbook part, guarding against zip bombs
+const maxPartSize = 256 << 20
+
+var errInvalidWorkbook = errors.New("invalid xlsx workbook")
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string, plain or split in rich text runs
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX returns the cells of the first worksheet of a workbook. Only the
+// cell values are read, formulas yield their cached result.
+func readXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errInvalidWorkbook
+	}
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXML(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet xlsxWorksheet
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, errInvalidWorkbook
+	}
+	if err := decodeXML(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		// Rows and cells may be omitted when empty, place them by reference
+		index := row.Index - 1
+		if index < len(rows) {
+			index = len(rows)
+		}
+		for len(rows) < index {
+			rows = append(rows, nil)
+		}
+
+		var cells []string
+		for _, cell := range row.Cells {
+			column := len(cells)
+			if cell.Ref != "" {
+				column = columnIndex(cell.Ref)
+			}
+			if column < len(cells) {
+				return nil, errInvalidWorkbook
+			}
+			for len(cells) < column {
+				cells = append(cells, "")
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, errInvalidWorkbook
+				}
+				value = shared.Items[n].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "", "n":
+				value = formatNumber(value)
+			}
+			cells = append(cells, value)
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// firstSheetPath resolves the part holding the first sheet of the workbook
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	wf, okWorkbook := files["xl/workbook.xml"]
+	rf, okRels := files["xl/_rels/workbook.xml.rels"]
+	if !okWorkbook || !okRels {
+		return "", errInvalidWorkbook
+	}
+	if err := decodeXML(wf, &workbook); err != nil {
+		return "", err
+	}
+	if err := decodeXML(rf, &rels); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errInvalidWorkbook
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", errInvalidWorkbook
+}
+
+func decodeXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(v); err != nil {
+		return errInvalidWorkbook
+	}
+	return nil
+}
+
+// columnIndex returns the zero based column of a cell reference such as "AB12"
+func columnIndex(ref string) int {
+	column := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1
+}
+
+// formatNumber drops the binary floating point noise of numeric cells, e.g.
+// 12.300000000000001 reads as 12.3
+func formatNumber(value string) string {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,86 @@
+// Package event publishes the domain events written to the event outbox.
+// The relay worker hands every event to a Sink, which may be the in-process
+// Bus, a webhook, NATS, Kafka or several of them. Delivery is at least once,
+// subscribers and consumers dedupe redeliveries by event ID.
+package event
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// Sink represent a destination events are published to
+type Sink interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
+
+// MultiSink publishes events to several sinks
+type MultiSink []Sink
+
+// Publish publishes the event to every sink, returning the joined errors.
+// A failed event is published again to every sink, including the sinks
+// which already received it.
+func (m MultiSink) Publish(ctx context.Context, event domain.Event) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AllEvents subscribes a handler to every event type
+const AllEvents = "*"
+
+// Handler reacts to an event, an error having the event published again later
+type Handler func(ctx context.Context, event domain.Event) error
+
+// Bus dispatches events to the handlers subscribed in process
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus creates a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe calls handler for every event of eventType, or of every type
+// when AllEvents
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish calls the handlers subscribed to the event in turn, returning the
+// joined errors
+func (b *Bus) Publish(ctx context.Context, event domain.Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[event.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogHandler writes events to the application log
+func LogHandler(ctx context.Context, event domain.Event) error {
+	logrus.WithFields(logrus.Fields{
+		"event_id": event.ID,
+		"key":      event.Key,
+		"attempts": event.Attempts,
+	}).Info(event.Type, ": ", string(event.Payload))
+	return nil
+}
@@ -0,0 +1,93 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+const (
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+	kafkaAccept      = "application/vnd.kafka.v2+json"
+)
+
+// KafkaSink produces events to a Kafka topic through a Kafka REST Proxy (v2
+// API), keyed by event key so the events of an entity share a partition
+type KafkaSink struct {
+	// URL is the base URL of the REST proxy, e.g. http://localhost:8082
+	URL    string
+	Topic  string
+	Client *http.Client
+}
+
+// NewKafkaSink creates a sink producing to topic through the REST proxy at url
+func NewKafkaSink(url, topic string) *KafkaSink {
+	return &KafkaSink{
+		URL:    url,
+		Topic:  topic,
+		Client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string       `json:"key"`
+	Value domain.Event `json:"value"`
+}
+
+type kafkaProduceResponse struct {
+	Offsets []struct {
+		Partition int     `json:"partition"`
+		Offset    int64   `json:"offset"`
+		ErrorCode *int    `json:"error_code"`
+		Error     *string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (k *KafkaSink) Publish(ctx context.Context, event domain.Event) error {
+	body, err := json.Marshal(map[string][]kafkaRecord{
+		"records": {{Key: event.Key, Value: event}},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimSuffix(k.URL, "/") + "/topics/" + url.PathEscape(k.Topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaAccept)
+
+	res, err := k.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("kafka rest proxy %s responded with status %d", k.URL, res.StatusCode)
+	}
+
+	// The proxy answers 200 even when a record failed, reporting it by offset
+	var produced kafkaProduceResponse
+	if err := json.NewDecoder(res.Body).Decode(&produced); err != nil {
+		return fmt.Errorf("kafka rest proxy %s: %w", k.URL, err)
+	}
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil {
+			message := ""
+			if offset.Error != nil {
+				message = *offset.Error
+			}
+			return fmt.Errorf("kafka topic %s rejected event %d: error %d %s", k.Topic, event.ID, *offset.ErrorCode, message)
+		}
+	}
+	return nil
+}
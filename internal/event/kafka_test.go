@@ -0,0 +1,84 @@
+package event_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/event"
+)
+
+// kafkaProxy is a stand-in for a Kafka REST Proxy answering produce requests
+// with response, recording the last request
+type kafkaProxy struct {
+	status   int
+	response string
+
+	path, contentType, accept string
+	records                   []struct {
+		Key   string       `json:"key"`
+		Value domain.Event `json:"value"`
+	}
+}
+
+func (k *kafkaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.path = r.URL.EscapedPath()
+	k.contentType, k.accept = r.Header.Get("Content-Type"), r.Header.Get("Accept")
+	body, _ := io.ReadAll(r.Body)
+	var produce struct {
+		Records json.RawMessage `json:"records"`
+	}
+	if err := json.Unmarshal(body, &produce); err == nil {
+		json.Unmarshal(produce.Records, &k.records)
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.kafka.v2+json")
+	w.WriteHeader(k.status)
+	io.WriteString(w, k.response)
+}
+
+func TestKafkaSinkProducesKeyedRecords(t *testing.T) {
+	proxy := &kafkaProxy{status: http.StatusOK, response: `{"offsets":[{"partition":0,"offset":42}]}`}
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	want := testEvent(t)
+	if err := event.NewKafkaSink(server.URL+"/", "shop events").Publish(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+	if proxy.path != "/topics/shop%20events" {
+		t.Errorf("path = %q, want /topics/shop%%20events", proxy.path)
+	}
+	if proxy.contentType != "application/vnd.kafka.json.v2+json" || proxy.accept != "application/vnd.kafka.v2+json" {
+		t.Errorf("Content-Type and Accept = %q and %q, want the v2 JSON media types", proxy.contentType, proxy.accept)
+	}
+	if len(proxy.records) != 1 || proxy.records[0].Key != want.Key || proxy.records[0].Value.ID != want.ID {
+		t.Errorf("records = %+v, want event %d keyed %q", proxy.records, want.ID, want.Key)
+	}
+}
+
+func TestKafkaSinkFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     string
+	}{
+		{"error status", http.StatusInternalServerError, `{"error_code":50001}`, "status 500"},
+		{"rejected record", http.StatusOK, `{"offsets":[{"partition":null,"offset":null,"error_code":40403,"error":"topic not found"}]}`, "error 40403 topic not found"},
+		{"invalid response", http.StatusOK, `not json`, "kafka rest proxy"},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(&kafkaProxy{status: tt.status, response: tt.response})
+		err := event.NewKafkaSink(server.URL, "events").Publish(context.Background(), testEvent(t))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Publish() = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+		server.Close()
+	}
+}
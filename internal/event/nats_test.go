@@ -0,0 +1,206 @@
+package event_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/event"
+)
+
+// natsMessage is a message published to the fake NATS server
+type natsMessage struct {
+	subject string
+	data    []byte
+}
+
+// fakeNATS is a stand-in for a NATS server speaking the core text protocol
+// on a local TCP port. It records the CONNECT options and the published
+// messages, rejecting the messages of the subject reject.
+type fakeNATS struct {
+	listener net.Listener
+	reject   string
+
+	mu          sync.Mutex
+	connections int
+	options     map[string]interface{}
+	messages    []natsMessage
+}
+
+func newFakeNATS(t *testing.T, reject string) *fakeNATS {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeNATS{listener: listener, reject: reject}
+	t.Cleanup(func() { listener.Close() })
+	go f.accept()
+	return f
+}
+
+// url returns the URL of the server with the credentials of userinfo
+func (f *fakeNATS) url(userinfo string) string {
+	if userinfo != "" {
+		userinfo += "@"
+	}
+	return "nats://" + userinfo + f.listener.Addr().String()
+}
+
+func (f *fakeNATS) accept() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.connections++
+		f.mu.Unlock()
+		go f.serve(conn)
+	}
+}
+
+func (f *fakeNATS) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	io.WriteString(conn, `INFO {"server_id":"fake","max_payload":1048576}`+"\r\n")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		verb, args, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		switch verb {
+		case "CONNECT":
+			var options map[string]interface{}
+			if err := json.Unmarshal([]byte(args), &options); err != nil {
+				io.WriteString(conn, "-ERR 'Invalid Connect'\r\n")
+				return
+			}
+			f.mu.Lock()
+			f.options = options
+			f.mu.Unlock()
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "PUB":
+			fields := strings.Fields(args)
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			if fields[0] == f.reject {
+				io.WriteString(conn, "-ERR 'Permissions Violation for Publish to "+fields[0]+"'\r\n")
+				continue
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, natsMessage{subject: fields[0], data: data[:size]})
+			f.mu.Unlock()
+		default:
+			io.WriteString(conn, "-ERR 'Unknown Protocol Operation'\r\n")
+		}
+	}
+}
+
+func (f *fakeNATS) state() (connections int, options map[string]interface{}, messages []natsMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connections, f.options, append([]natsMessage(nil), f.messages...)
+}
+
+func TestNATSSinkPublishes(t *testing.T) {
+	server := newFakeNATS(t, "")
+	sink := event.NewNATSSink(server.url("app:secret"), "shop")
+	defer sink.Close()
+
+	first := testEvent(t)
+	second := first
+	second.ID, second.Type = 8, "order.created"
+	for _, e := range []domain.Event{first, second} {
+		if err := sink.Publish(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	connections, options, messages := server.state()
+	if connections != 1 {
+		t.Errorf("connections = %d, want the connection reused", connections)
+	}
+	if options["user"] != "app" || options["pass"] != "secret" || options["verbose"] != false {
+		t.Errorf("CONNECT options = %v, want user app, its password and verbose off", options)
+	}
+	if len(messages) != 2 || messages[0].subject != "shop.product.updated" || messages[1].subject != "shop.order.created" {
+		t.Fatalf("messages = %+v, want shop.product.updated then shop.order.created", messages)
+	}
+	var got domain.Event
+	if err := json.Unmarshal(messages[0].data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != first.ID || got.Key != first.Key {
+		t.Errorf("event = %+v, want %+v", got, first)
+	}
+}
+
+func TestNATSSinkAuthenticatesWithAToken(t *testing.T) {
+	server := newFakeNATS(t, "")
+	sink := event.NewNATSSink(server.url("s3cr3t"), "")
+	defer sink.Close()
+
+	if err := sink.Publish(context.Background(), testEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+	_, options, messages := server.state()
+	if options["auth_token"] != "s3cr3t" || options["user"] != nil {
+		t.Errorf("CONNECT options = %v, want the auth token only", options)
+	}
+	if len(messages) != 1 || messages[0].subject != "product.updated" {
+		t.Errorf("messages = %+v, want product.updated without a prefix", messages)
+	}
+}
+
+func TestNATSSinkReconnectsAfterAnError(t *testing.T) {
+	server := newFakeNATS(t, "shop.product.updated")
+	sink := event.NewNATSSink(server.url(""), "shop")
+	defer sink.Close()
+
+	err := sink.Publish(context.Background(), testEvent(t))
+	if err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Fatalf("Publish() = %v, want the server error", err)
+	}
+
+	accepted := testEvent(t)
+	accepted.Type = "order.created"
+	if err := sink.Publish(context.Background(), accepted); err != nil {
+		t.Fatal(err)
+	}
+	connections, _, messages := server.state()
+	if connections != 2 || len(messages) != 1 {
+		t.Errorf("connections and messages = %d and %d, want 2 and 1", connections, len(messages))
+	}
+}
+
+func TestNATSSinkFailsWithoutServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	sink := event.NewNATSSink(fmt.Sprintf("nats://%s", addr), "shop")
+	if err := sink.Publish(context.Background(), testEvent(t)); err == nil {
+		t.Error("Publish succeeded without a server")
+	}
+}
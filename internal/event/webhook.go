@@ -0,0 +1,64 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/notification"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// WebhookSink posts events as JSON to a URL
+type WebhookSink struct {
+	URL    string
+	Secret []byte
+	Client *http.Client
+}
+
+// NewWebhookSink creates a sink posting to url, signing bodies with secret
+// when set like the webhook notifier does
+func NewWebhookSink(url string, secret []byte) *WebhookSink {
+	return &WebhookSink{
+		URL:    url,
+		Secret: secret,
+		Client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+}
+
+func (wh *WebhookSink) Publish(ctx context.Context, event domain.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(wh.Secret) > 0 {
+		mac := hmac.New(sha256.New, wh.Secret)
+		mac.Write(body)
+		req.Header.Set(notification.SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := wh.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with status %d", wh.URL, res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package event_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/event"
+	"github.com/bimbims125/clean-arch/internal/notification"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func testEvent(t *testing.T) domain.Event {
+	t.Helper()
+	e, err := domain.NewEvent("product.updated", "product:12", map[string]int{"stock": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.ID = 7
+	e.OccurredAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return e
+}
+
+func TestWebhookSinkSignsTheEvent(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	want := testEvent(t)
+	if err := event.NewWebhookSink(server.URL, testSecret).Publish(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+
+	var got domain.Event
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != want.ID || got.Type != want.Type || got.Key != want.Key || string(got.Payload) != string(want.Payload) {
+		t.Errorf("event = %+v, want %+v", got, want)
+	}
+	if ct := header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	mac := hmac.New(sha256.New, testSecret)
+	mac.Write(body)
+	if signature := header.Get(notification.SignatureHeader); signature != hex.EncodeToString(mac.Sum(nil)) {
+		t.Errorf("signature = %q, want the HMAC of the body", signature)
+	}
+}
+
+func TestWebhookSinkWithoutSecretDoesNotSign(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if signature := r.Header.Get(notification.SignatureHeader); signature != "" {
+			t.Errorf("signature = %q, want none", signature)
+		}
+	}))
+	defer server.Close()
+
+	if err := event.NewWebhookSink(server.URL, nil).Publish(context.Background(), testEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookSinkFailsOnErrorStatuses(t *testing.T) {
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusServiceUnavailable} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		// Redirects aren't followed to be reported as failures
+		sink := event.NewWebhookSink(server.URL, testSecret)
+		sink.Client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		if err := sink.Publish(context.Background(), testEvent(t)); err == nil {
+			t.Errorf("status %d: Publish succeeded", status)
+		}
+		server.Close()
+	}
+}
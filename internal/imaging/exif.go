@@ -0,0 +1,190 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+)
+
+const (
+	markerSOI  = 0xD8
+	markerSOS  = 0xDA
+	markerAPP1 = 0xE1
+	// markerAPP13 carries IPTC/Photoshop metadata
+	markerAPP13 = 0xED
+
+	exifOrientationTag = 0x0112
+)
+
+var errInvalidJPEG = errors.New("invalid jpeg")
+
+// jpegSegment is a marker segment of the JPEG header, data excludes the length bytes
+type jpegSegment struct {
+	marker     byte
+	standalone bool
+	data       []byte
+}
+
+// readJPEGSegments splits the header of a JPEG into segments, returning the
+// remaining bytes starting at the start of scan marker
+func readJPEGSegments(data []byte) (segments []jpegSegment, rest []byte, err error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil, nil, errInvalidJPEG
+	}
+
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF {
+			return nil, nil, errInvalidJPEG
+		}
+		// Skip fill bytes
+		for i < len(data) && data[i] == 0xFF {
+			i++
+		}
+		if i >= len(data) {
+			return nil, nil, errInvalidJPEG
+		}
+		marker := data[i]
+		i++
+
+		// Standalone markers carry no length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			segments = append(segments, jpegSegment{marker: marker, standalone: true})
+			continue
+		}
+		if marker == markerSOS {
+			return segments, data[i-2:], nil
+		}
+		if i+2 > len(data) {
+			return nil, nil, errInvalidJPEG
+		}
+		length := int(binary.BigEndian.Uint16(data[i:]))
+		if length < 2 || i+length > len(data) {
+			return nil, nil, errInvalidJPEG
+		}
+		segments = append(segments, jpegSegment{marker: marker, data: data[i+2 : i+length]})
+		i += length
+	}
+	return nil, nil, errInvalidJPEG
+}
+
+// StripJPEGMetadata removes the EXIF, XMP and IPTC segments of a JPEG without re-encoding it
+func StripJPEGMetadata(data []byte) ([]byte, error) {
+	segments, rest, err := readJPEGSegments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	buf.Write([]byte{0xFF, markerSOI})
+	for _, s := range segments {
+		if s.marker == markerAPP1 || s.marker == markerAPP13 {
+			continue
+		}
+		buf.Write([]byte{0xFF, s.marker})
+		if !s.standalone {
+			var length [2]byte
+			binary.BigEndian.PutUint16(length[:], uint16(len(s.data)+2))
+			buf.Write(length[:])
+			buf.Write(s.data)
+		}
+	}
+	buf.Write(rest)
+	return buf.Bytes(), nil
+}
+
+// JPEGOrientation returns the EXIF orientation (1 to 8) of a JPEG, 1 when absent
+func JPEGOrientation(data []byte) int {
+	segments, _, err := readJPEGSegments(data)
+	if err != nil {
+		return 1
+	}
+	for _, s := range segments {
+		if s.marker == markerAPP1 && bytes.HasPrefix(s.data, []byte("Exif\x00\x00")) {
+			return tiffOrientation(s.data[6:])
+		}
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of a TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	// The IFD follows the 8 byte header
+	offset := int64(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > int64(len(tiff)) {
+		return 1
+	}
+	entries := int64(order.Uint16(tiff[offset:]))
+	for n := int64(0); n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > int64(len(tiff)) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyOrientation transforms an image so it displays upright given its EXIF orientation
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(nrgba, nrgba.Bounds(), src, b.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], nrgba.Pix[nrgba.PixOffset(x, y):nrgba.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,213 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/internal/imaging"
+)
+
+// ifdEntry is a SHORT entry of a TIFF image file directory
+type ifdEntry struct {
+	tag, value uint16
+}
+
+// tiff builds a TIFF structure in byte order whose first IFD, at offset,
+// declares count entries of which entries are written
+func tiff(order binary.ByteOrder, offset uint32, count uint16, entries ...ifdEntry) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, offset)
+	for buf.Len() < int(offset) && buf.Len() < 64 {
+		buf.WriteByte(0)
+	}
+	binary.Write(&buf, order, count)
+	for _, e := range entries {
+		binary.Write(&buf, order, e.tag)
+		binary.Write(&buf, order, uint16(3)) // SHORT
+		binary.Write(&buf, order, uint32(1))
+		binary.Write(&buf, order, e.value)
+		binary.Write(&buf, order, uint16(0))
+	}
+	return buf.Bytes()
+}
+
+// orientationTIFF builds a TIFF holding an orientation tag
+func orientationTIFF(order binary.ByteOrder, orientation uint16) []byte {
+	return tiff(order, 8, 1, ifdEntry{0x0112, orientation})
+}
+
+// segment encodes a JPEG marker segment
+func segment(marker byte, data []byte) []byte {
+	s := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(data)+2))
+	return append(s, data...)
+}
+
+// exifSegment encodes an APP1 segment holding an EXIF TIFF structure
+func exifSegment(tiff []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+// scan is a start of scan segment followed by entropy coded data
+var scan = []byte{0xFF, 0xDA, 0x00, 0x02, 0x12, 0x34, 0xFF, 0xD9}
+
+// jpegOf concatenates a start of image marker and parts
+func jpegOf(parts ...[]byte) []byte {
+	return bytes.Join(append([][]byte{{0xFF, 0xD8}}, parts...), nil)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", jpegOf(segment(0xE0, []byte("JFIF\x00")), scan), 1},
+		{"little endian", jpegOf(exifSegment(orientationTIFF(binary.LittleEndian, 6)), scan), 6},
+		{"big endian", jpegOf(exifSegment(orientationTIFF(binary.BigEndian, 3)), scan), 3},
+		{"after another tag", jpegOf(exifSegment(tiff(binary.BigEndian, 8, 2, ifdEntry{0x010F, 7}, ifdEntry{0x0112, 8})), scan), 8},
+		{"after other segments", jpegOf(segment(0xE0, []byte("JFIF\x00")), []byte{0xFF, 0xD0}, exifSegment(orientationTIFF(binary.LittleEndian, 5)), scan), 5},
+		{"orientation 0", jpegOf(exifSegment(orientationTIFF(binary.LittleEndian, 0)), scan), 1},
+		{"orientation 9", jpegOf(exifSegment(orientationTIFF(binary.LittleEndian, 9)), scan), 1},
+		{"unknown byte order", jpegOf(exifSegment(append([]byte("XX"), orientationTIFF(binary.BigEndian, 6)[2:]...)), scan), 1},
+		{"truncated tiff header", jpegOf(exifSegment([]byte("MM\x00\x2A\x00")), scan), 1},
+		{"ifd offset in the header", jpegOf(exifSegment(tiff(binary.BigEndian, 4, 1, ifdEntry{0x0112, 6})), scan), 1},
+		{"ifd offset past the end", jpegOf(exifSegment(tiff(binary.LittleEndian, 0xFFFF, 0)), scan), 1},
+		{"largest ifd offset", jpegOf(exifSegment(tiff(binary.LittleEndian, 0xFFFFFFFF, 0)), scan), 1},
+		{"truncated ifd entry", jpegOf(exifSegment(orientationTIFF(binary.BigEndian, 6)[:18]), scan), 1},
+		{"more entries than written", jpegOf(exifSegment(tiff(binary.LittleEndian, 8, 3, ifdEntry{0x010F, 7})), scan), 1},
+		{"truncated segment", jpegOf(exifSegment(orientationTIFF(binary.LittleEndian, 6))[:20]), 1},
+		{"not a jpeg", orientationTIFF(binary.LittleEndian, 6), 1},
+	}
+	for _, tt := range tests {
+		if got := imaging.JPEGOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: orientation = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	// Every orientation is read in both byte orders
+	for orientation := uint16(1); orientation <= 8; orientation++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			data := jpegOf(exifSegment(orientationTIFF(order, orientation)), scan)
+			if got := imaging.JPEGOrientation(data); got != int(orientation) {
+				t.Errorf("%s orientation %d read as %d", order, orientation, got)
+			}
+		}
+	}
+}
+
+func TestStripJPEGMetadata(t *testing.T) {
+	jfif := segment(0xE0, []byte("JFIF\x00"))
+	iptc := segment(0xED, []byte("Photoshop 3.0\x00"))
+	data := jpegOf(jfif, exifSegment(orientationTIFF(binary.LittleEndian, 1)), []byte{0xFF, 0xD0}, iptc, scan)
+
+	stripped, err := imaging.StripJPEGMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := jpegOf(jfif, []byte{0xFF, 0xD0}, scan); !bytes.Equal(stripped, want) {
+		t.Errorf("stripped = % X, want % X", stripped, want)
+	}
+
+	invalid := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"no start of image", append([]byte{0xFF, 0xE0}, scan...)},
+		{"segment longer than the data", jpegOf(segment(0xE0, []byte("JFIF\x00"))[:6])},
+		{"segment length below 2", jpegOf([]byte{0xFF, 0xE0, 0x00, 0x01}, scan)},
+		{"missing segment length", jpegOf([]byte{0xFF, 0xE0, 0x00})},
+		{"no start of scan", jpegOf(jfif)},
+		{"garbage between segments", jpegOf(jfif, []byte{0x00}, scan)},
+		{"fill bytes only", jpegOf([]byte{0xFF, 0xFF, 0xFF})},
+	}
+	for _, tt := range invalid {
+		if _, err := imaging.StripJPEGMetadata(tt.data); err == nil {
+			t.Errorf("%s: StripJPEGMetadata succeeded", tt.name)
+		}
+	}
+}
+
+func TestDecodeAppliesTheOrientation(t *testing.T) {
+	// A 48x32 image whose top left 16x16 block is red, the rest blue
+	src := image.NewRGBA(image.Rect(0, 0, 48, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 48; x++ {
+			c := color.RGBA{B: 255, A: 255}
+			if x < 16 && y < 16 {
+				c = color.RGBA{R: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, src, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Where the center of the red block lands in the upright image
+	tests := []struct {
+		orientation   uint16
+		width, height int
+		red           image.Point
+	}{
+		{1, 48, 32, image.Pt(8, 8)},
+		{2, 48, 32, image.Pt(39, 8)},
+		{3, 48, 32, image.Pt(39, 23)},
+		{4, 48, 32, image.Pt(8, 23)},
+		{5, 32, 48, image.Pt(8, 8)},
+		{6, 32, 48, image.Pt(23, 8)},
+		{7, 32, 48, image.Pt(23, 39)},
+		{8, 32, 48, image.Pt(8, 39)},
+	}
+	for _, tt := range tests {
+		data := append([]byte{0xFF, 0xD8}, exifSegment(orientationTIFF(binary.BigEndian, tt.orientation))...)
+		data = append(data, encoded.Bytes()[2:]...)
+
+		img, err := imaging.Decode(data)
+		if err != nil {
+			t.Fatalf("orientation %d: %v", tt.orientation, err)
+		}
+		if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Errorf("orientation %d: size = %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.width, tt.height)
+			continue
+		}
+		if r, _, b, _ := img.At(tt.red.X, tt.red.Y).RGBA(); r < b {
+			t.Errorf("orientation %d: pixel at %v isn't red", tt.orientation, tt.red)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	tests := []struct {
+		name         string
+		src          image.Rectangle
+		width        int
+		wantW, wantH int
+	}{
+		{"landscape", image.Rect(0, 0, 40, 20), 10, 10, 5},
+		{"offset bounds", image.Rect(10, 10, 50, 30), 20, 20, 10},
+		{"thin strip", image.Rect(0, 0, 400, 1), 10, 10, 1},
+		{"zero width", image.Rect(0, 0, 0, 20), 10, 0, 0},
+		{"zero height", image.Rect(0, 0, 20, 0), 10, 0, 0},
+		{"no pixels", image.Rectangle{}, 10, 0, 0},
+		{"zero target width", image.Rect(0, 0, 40, 20), 0, 0, 0},
+	}
+	for _, tt := range tests {
+		b := imaging.Resize(image.NewNRGBA(tt.src), tt.width).Bounds()
+		if b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("%s: size = %dx%d, want %dx%d", tt.name, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+		}
+	}
+}
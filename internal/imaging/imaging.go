@@ -0,0 +1,203 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"sort"
+	"strconv"
+	"strings"
+
+	// Register the decoders of the accepted upload formats
+	_ "image/gif"
+
+	_ "golang.org/x/image/webp"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	jpegQuality = 82
+	// maxPixels guards against decompression bombs
+	maxPixels = 50_000_000
+)
+
+var (
+	ErrImageTooLarge = errors.New("image dimensions too large")
+	ErrImageEmpty    = errors.New("image has no pixels")
+)
+
+// DefaultSizes are the widths generated when no sizes are configured
+var DefaultSizes = []Size{
+	{Name: "thumb", Width: 160},
+	{Name: "small", Width: 320},
+	{Name: "medium", Width: 640},
+	{Name: "large", Width: 1280},
+}
+
+// Size represent a named target width of a responsive variant
+type Size struct {
+	Name  string
+	Width int
+}
+
+// Output represent an encoded variant of an image
+type Output struct {
+	Name        string
+	Width       int
+	Height      int
+	ContentType string
+	Extension   string
+	Data        []byte
+}
+
+// ParseSizes parses a comma separated list of name:width pairs, e.g. "thumb:160,large:1280"
+func ParseSizes(s string) ([]Size, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultSizes, nil
+	}
+
+	var sizes []Size
+	for _, pair := range strings.Split(s, ",") {
+		name, width, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid image size %q", pair)
+		}
+		w, err := strconv.Atoi(width)
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("invalid image width %q", pair)
+		}
+		sizes = append(sizes, Size{Name: name, Width: w})
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i].Width < sizes[j].Width })
+	return sizes, nil
+}
+
+// Decode decodes an image, rotating JPEGs upright following their EXIF orientation
+func Decode(data []byte) (image.Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrImageEmpty
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" {
+		img = applyOrientation(img, JPEGOrientation(data))
+	}
+	return img, nil
+}
+
+// Resize scales an image to the given width, keeping its aspect ratio. Images
+// without pixels and widths below 1 give an image without pixels.
+func Resize(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	if b.Empty() || width < 1 {
+		return image.NewNRGBA(image.Rectangle{})
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+// isOpaque reports whether an image has no transparent pixels
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// Encode re-encodes an image as JPEG, or as PNG when it has transparency.
+// Re-encoding drops every metadata of the source, EXIF included.
+func Encode(img image.Image) (data []byte, contentType, extension string, err error) {
+	var buf bytes.Buffer
+	if isOpaque(img) {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+		return buf.Bytes(), "image/jpeg", ".jpg", err
+	}
+	err = png.Encode(&buf, img)
+	return buf.Bytes(), "image/png", ".png", err
+}
+
+// Process generates the variants of an image for every size narrower than
+// the image. Images narrower than every size get a single variant at their
+// own width, so every image has at least one variant.
+func Process(data []byte, sizes []Size) ([]Output, error) {
+	src, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	srcWidth := src.Bounds().Dx()
+
+	var outputs []Output
+	for _, size := range sizes {
+		if size.Width >= srcWidth {
+			continue
+		}
+		output, err := encodeOutput(Resize(src, size.Width), size.Name)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+
+	if len(outputs) == 0 && len(sizes) > 0 {
+		output, err := encodeOutput(Resize(src, srcWidth), sizes[0].Name)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func encodeOutput(img image.Image, name string) (Output, error) {
+	data, contentType, extension, err := Encode(img)
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{
+		Name:        name,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		ContentType: contentType,
+		Extension:   extension,
+		Data:        data,
+	}, nil
+}
+
+// SanitizeJPEG removes the metadata of an uploaded JPEG. Upright images are
+// stripped losslessly, rotated images are re-encoded upright since dropping
+// the EXIF orientation would display them sideways.
+func SanitizeJPEG(data []byte) ([]byte, error) {
+	if JPEGOrientation(data) == 1 {
+		return StripJPEGMetadata(data)
+	}
+
+	img, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
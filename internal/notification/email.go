@@ -0,0 +1,43 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// EmailOutbox represent the repository storing emails until a mailer sends them
+type EmailOutbox interface {
+	EnqueueEmail(ctx context.Context, email *domain.OutboxEmail) error
+}
+
+// EmailNotifier queues notifications as emails in the outbox
+type EmailNotifier struct {
+	Outbox EmailOutbox
+	// Recipients receive notifications which don't name a recipient, e.g. staff alerts
+	Recipients []string
+}
+
+// NewEmailNotifier creates a notifier queueing emails in the outbox
+func NewEmailNotifier(outbox EmailOutbox, recipients []string) *EmailNotifier {
+	return &EmailNotifier{Outbox: outbox, Recipients: recipients}
+}
+
+func (e *EmailNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	recipients := e.Recipients
+	if notification.Recipient != "" {
+		recipients = []string{notification.Recipient}
+	}
+
+	for _, recipient := range recipients {
+		email := &domain.OutboxEmail{
+			Recipient: recipient,
+			Subject:   notification.Subject,
+			Body:      notification.Message,
+		}
+		if err := e.Outbox.EnqueueEmail(ctx, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
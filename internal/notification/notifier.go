@@ -0,0 +1,86 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier represent a channel delivering notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification domain.Notification) error
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct{}
+
+// NewLogNotifier creates a notifier writing to the application log
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (l *LogNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	logrus.WithFields(logrus.Fields{
+		"event":     notification.Event,
+		"recipient": notification.Recipient,
+	}).Info(notification.Subject, ": ", notification.Message)
+	return nil
+}
+
+// MultiNotifier delivers notifications through several notifiers. A
+// notification with a key which some notifiers failed to deliver is only sent
+// through those when retried, the notifiers having delivered it being
+// remembered until they all have, or until the process stops.
+type MultiNotifier struct {
+	Notifiers []Notifier
+
+	mu sync.Mutex
+	// delivered holds the indexes of the notifiers having delivered a
+	// notification by its key
+	delivered map[string]map[int]bool
+}
+
+// NewMultiNotifier creates a notifier delivering through every notifier
+func NewMultiNotifier(notifiers ...Notifier) *MultiNotifier {
+	return &MultiNotifier{Notifiers: notifiers, delivered: map[string]map[int]bool{}}
+}
+
+// Notify delivers the notification through every notifier which didn't
+// deliver it yet, returning the joined errors
+func (m *MultiNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	m.mu.Lock()
+	skip := maps.Clone(m.delivered[notification.Key])
+	m.mu.Unlock()
+
+	var errs []error
+	delivered := make(map[int]bool, len(m.Notifiers))
+	for n, notifier := range m.Notifiers {
+		if skip[n] {
+			continue
+		}
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delivered[n] = true
+	}
+	if notification.Key == "" {
+		return errors.Join(errs...)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(errs) == 0 {
+		delete(m.delivered, notification.Key)
+		return nil
+	}
+	if m.delivered[notification.Key] == nil {
+		m.delivered[notification.Key] = map[int]bool{}
+	}
+	maps.Copy(m.delivered[notification.Key], delivered)
+	return errors.Join(errs...)
+}
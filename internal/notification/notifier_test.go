@@ -0,0 +1,69 @@
+package notification_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/notification"
+)
+
+// countingNotifier counts its deliveries, failing while failing is set
+type countingNotifier struct {
+	sent    int
+	failing bool
+}
+
+func (c *countingNotifier) Notify(ctx context.Context, n domain.Notification) error {
+	if c.failing {
+		return errors.New("unavailable")
+	}
+	c.sent++
+	return nil
+}
+
+func TestMultiNotifierRetriesFailedNotifiers(t *testing.T) {
+	ctx := context.Background()
+	email, webhook := &countingNotifier{}, &countingNotifier{failing: true}
+	notifier := notification.NewMultiNotifier(email, webhook)
+	alert := domain.Notification{Event: domain.EventLowStock, Key: "low_stock_alert:1"}
+
+	if err := notifier.Notify(ctx, alert); err == nil {
+		t.Fatal("Notify succeeded with a failing notifier")
+	}
+	// The email isn't sent again while the webhook is retried
+	if err := notifier.Notify(ctx, alert); err == nil {
+		t.Fatal("Notify succeeded with a failing notifier")
+	}
+	webhook.failing = false
+	if err := notifier.Notify(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	if email.sent != 1 || webhook.sent != 1 {
+		t.Errorf("sent %d emails and %d webhooks, want 1 each", email.sent, webhook.sent)
+	}
+
+	// Delivered notifications are forgotten, another alert of the same key is sent again
+	if err := notifier.Notify(ctx, alert); err != nil {
+		t.Fatal(err)
+	}
+	if email.sent != 2 || webhook.sent != 2 {
+		t.Errorf("sent %d emails and %d webhooks, want 2 each", email.sent, webhook.sent)
+	}
+}
+
+func TestMultiNotifierWithoutKey(t *testing.T) {
+	ctx := context.Background()
+	email, webhook := &countingNotifier{}, &countingNotifier{failing: true}
+	notifier := notification.NewMultiNotifier(email, webhook)
+
+	for range 2 {
+		if err := notifier.Notify(ctx, domain.Notification{Event: domain.EventLowStock}); err == nil {
+			t.Fatal("Notify succeeded with a failing notifier")
+		}
+	}
+	if email.sent != 2 {
+		t.Errorf("sent %d emails, want every attempt without a key", email.sent)
+	}
+}
@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+
+	// SignatureHeader carries the hex HMAC-SHA256 of the body when a secret is configured
+	SignatureHeader = "X-Signature-SHA256"
+)
+
+// WebhookNotifier posts notifications as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Secret []byte
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url, signing bodies with secret when set
+func NewWebhookNotifier(url string, secret []byte) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Secret: secret,
+		Client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+}
+
+func (wh *WebhookNotifier) Notify(ctx context.Context, notification domain.Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(wh.Secret) > 0 {
+		mac := hmac.New(sha256.New, wh.Secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	res, err := wh.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with status %d", wh.URL, res.StatusCode)
+	}
+	return nil
+}
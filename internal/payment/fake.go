@@ -0,0 +1,114 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+const (
+	// FakeSignatureHeader carries the hex HMAC-SHA256 of fake webhook payloads
+	FakeSignatureHeader = "X-Fake-Signature"
+	fakeIntentPrefix    = "fake_pi_"
+)
+
+// FakeProvider is an offline provider for development and tests. It never
+// calls out: intents get sequential ids, captures and refunds of its own
+// intents always succeed, and webhooks are signed with Secret.
+type FakeProvider struct {
+	Secret []byte
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewFakeProvider creates a fake provider signing webhooks with secret
+func NewFakeProvider(secret []byte) *FakeProvider {
+	return &FakeProvider{Secret: secret}
+}
+
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakeProvider) CreateIntent(ctx context.Context, order domain.Order) (domain.Payment, error) {
+	f.mu.Lock()
+	f.seq++
+	id := fmt.Sprintf("%s%d_%d", fakeIntentPrefix, order.ID, f.seq)
+	f.mu.Unlock()
+
+	return domain.Payment{
+		OrderID:      order.ID,
+		Provider:     f.Name(),
+		ExternalID:   id,
+		Amount:       order.Total,
+		Status:       domain.PaymentPending,
+		ClientSecret: id + "_secret",
+	}, nil
+}
+
+func (f *FakeProvider) Capture(ctx context.Context, payment domain.Payment) error {
+	if !strings.HasPrefix(payment.ExternalID, fakeIntentPrefix) {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (f *FakeProvider) Refund(ctx context.Context, payment domain.Payment, amount domain.Money) error {
+	if !strings.HasPrefix(payment.ExternalID, fakeIntentPrefix) {
+		return domain.ErrNotFound
+	}
+	if !amount.IsPositive() || !amount.SameCurrency(payment.Amount) || amount.Cmp(payment.Amount) > 0 {
+		return domain.ErrBadRequest
+	}
+	return nil
+}
+
+func (f *FakeProvider) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, f.Secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// Sign returns the signature of a webhook payload
+func (f *FakeProvider) Sign(payload []byte) string {
+	return hex.EncodeToString(f.mac(payload))
+}
+
+// SignedEvent encodes an event as a webhook payload along with its signature,
+// to simulate the provider calling the webhook
+func (f *FakeProvider) SignedEvent(event domain.PaymentEvent) (payload []byte, signature string, err error) {
+	payload, err = json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, f.Sign(payload), nil
+}
+
+func (f *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (domain.PaymentEvent, error) {
+	// Without a secret anyone could compute the signature
+	if len(f.Secret) == 0 {
+		return domain.PaymentEvent{}, domain.ErrInvalidSignature
+	}
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, f.mac(payload)) {
+		return domain.PaymentEvent{}, domain.ErrInvalidSignature
+	}
+
+	var event domain.PaymentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return domain.PaymentEvent{}, domain.ErrBadRequest
+	}
+	if _, ok := event.PaymentStatus(); !ok || event.ID == "" || event.ExternalID == "" {
+		return domain.PaymentEvent{}, domain.ErrBadRequest
+	}
+	return event, nil
+}
@@ -0,0 +1,23 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// Provider represent a payment gateway. Adapters translate the gateway API
+// and webhooks to payments and payment events so handlers stay gateway agnostic.
+type Provider interface {
+	// Name identifies the provider in stored payments and webhook routes
+	Name() string
+	// CreateIntent starts the payment of the order total
+	CreateIntent(ctx context.Context, order domain.Order) (domain.Payment, error)
+	// Capture collects an authorized payment
+	Capture(ctx context.Context, payment domain.Payment) error
+	// Refund gives amount of a captured payment back
+	Refund(ctx context.Context, payment domain.Payment, amount domain.Money) error
+	// VerifyWebhook checks the signature of a webhook request and decodes its event
+	VerifyWebhook(payload []byte, header http.Header) (domain.PaymentEvent, error)
+}
@@ -0,0 +1,36 @@
+package pricing
+
+import (
+	"fmt"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// exchangeRateFile is the layout of exchange rate files
+type exchangeRateFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// LoadExchangeRates reads the exchange rates of the catalog currency from a JSON file such as
+//
+//	{"base": "IDR", "rates": {"USD": 0.000063, "SGD": 0.000084}}
+func LoadExchangeRates(path string) ([]domain.ExchangeRate, error) {
+	var file exchangeRateFile
+	if err := readJSON(path, &file); err != nil {
+		return nil, err
+	}
+	if base := domain.NormalizeCurrency(file.Base); base != "" && base != domain.DefaultCurrency {
+		return nil, fmt.Errorf("%s: rates must be based on %s", path, domain.DefaultCurrency)
+	}
+
+	result := make([]domain.ExchangeRate, 0, len(file.Rates))
+	for code, rate := range file.Rates {
+		currency := domain.NormalizeCurrency(code)
+		if len(currency) != 3 || currency == domain.DefaultCurrency || rate <= 0 {
+			return nil, fmt.Errorf("%s: invalid exchange rate for %s", path, code)
+		}
+		result = append(result, domain.ExchangeRate{Currency: currency, Rate: rate})
+	}
+	return result, nil
+}
@@ -0,0 +1,80 @@
+// Package pricing quotes the shipping and the taxes of carts delivered to a
+// region. Calculators are plug-ins, the built-in ones are driven by rate
+// tables loaded from JSON files.
+package pricing
+
+import (
+	"strings"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// TaxCalculator computes the taxes of a delivery to region. Taxable is the
+// discounted subtotal, shipping is taxed as well when the rate says so.
+type TaxCalculator interface {
+	Taxes(region string, taxable, shipping domain.Money) ([]domain.TaxLine, error)
+}
+
+// ShippingRateProvider quotes the shipping of a parcel to region, failing with
+// domain.ErrShippingUnavailable when the region or the weight isn't served
+type ShippingRateProvider interface {
+	Rate(region string, parcel Parcel) (domain.Money, error)
+}
+
+// Parcel is the content of a shipment, Weight is in grams and Volume in cubic centimeters
+type Parcel struct {
+	Weight int
+	Volume float64
+}
+
+// NewParcel returns the parcel holding the items of a cart
+func NewParcel(items []domain.CartItem) Parcel {
+	var parcel Parcel
+	for _, item := range items {
+		parcel.Weight += item.Shipping.Weight * item.Quantity
+		parcel.Volume += item.Shipping.Volume() * float64(item.Quantity)
+	}
+	return parcel
+}
+
+// Calculator adds shipping and taxes to carts, it implements domain.CartCharges
+type Calculator struct {
+	Tax      TaxCalculator
+	Shipping ShippingRateProvider
+}
+
+// NewCalculator creates a calculator from its plug-ins
+func NewCalculator(tax TaxCalculator, shipping ShippingRateProvider) *Calculator {
+	return &Calculator{Tax: tax, Shipping: shipping}
+}
+
+// Apply quotes the shipping and the taxes of cart to region and updates its
+// total. Without a region, or items, the cart is left without either.
+func (c *Calculator) Apply(cart *domain.Cart, region string) error {
+	region = strings.ToUpper(strings.TrimSpace(region))
+	cart.Region = region
+	cart.Shipping = domain.Money{Currency: cart.Subtotal.Currency}
+	cart.Taxes = make([]domain.TaxLine, 0)
+	cart.TaxTotal = domain.Money{Currency: cart.Subtotal.Currency}
+
+	if region != "" && len(cart.Items) > 0 {
+		shipping, err := c.Shipping.Rate(region, NewParcel(cart.Items))
+		if err != nil {
+			return err
+		}
+		shipping = cart.ExchangeRate.Convert(shipping)
+		taxes, err := c.Tax.Taxes(region, cart.Subtotal.Sub(cart.DiscountTotal), shipping)
+		if err != nil {
+			return err
+		}
+
+		cart.Shipping = cart.Shipping.Add(shipping)
+		cart.Taxes = taxes
+		for _, tax := range taxes {
+			cart.TaxTotal = cart.TaxTotal.Add(tax.Amount)
+		}
+	}
+
+	cart.Total = cart.Subtotal.Sub(cart.DiscountTotal).Add(cart.Shipping).Add(cart.TaxTotal)
+	return nil
+}
@@ -0,0 +1,180 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// defaultRegion holds the rates of the regions without rates of their own
+const defaultRegion = "*"
+
+// lookup returns the entry of a region. Regions are matched from the most
+// specific code to the least, so ID-JK falls back to ID and then to "*".
+func lookup[T any](regions map[string]T, region string) (T, bool) {
+	code := strings.ToUpper(strings.TrimSpace(region))
+	for code != "" {
+		if entry, ok := regions[code]; ok {
+			return entry, true
+		}
+		n := strings.LastIndex(code, "-")
+		if n < 0 {
+			break
+		}
+		code = code[:n]
+	}
+	entry, ok := regions[defaultRegion]
+	return entry, ok
+}
+
+// TaxRate is a tax of a region, Rate is a percentage. Shipping rates also
+// apply to the shipping costs.
+type TaxRate struct {
+	Name     string  `json:"name"`
+	Rate     float64 `json:"rate"`
+	Shipping bool    `json:"shipping"`
+}
+
+// TaxTable is a TaxCalculator charging the rates of the delivery region.
+// Regions without rates are not taxed.
+type TaxTable struct {
+	Regions map[string][]TaxRate `json:"regions"`
+}
+
+// DefaultTaxTable charges the Indonesian VAT (PPN) on deliveries to Indonesia
+func DefaultTaxTable() TaxTable {
+	return TaxTable{Regions: map[string][]TaxRate{
+		"ID": {{Name: "PPN", Rate: 11, Shipping: true}},
+	}}
+}
+
+func (t TaxTable) Taxes(region string, taxable, shipping domain.Money) ([]domain.TaxLine, error) {
+	rates, _ := lookup(t.Regions, region)
+	result := make([]domain.TaxLine, 0, len(rates))
+	for _, rate := range rates {
+		base := taxable
+		if rate.Shipping {
+			base = base.Add(shipping)
+		}
+		result = append(result, domain.TaxLine{Name: rate.Name, Rate: rate.Rate, Amount: base.Percent(rate.Rate)})
+	}
+	return result, nil
+}
+
+// ShippingRate is a weight bracket of a shipping region, MaxWeight is in
+// grams and 0 means no limit. Prices are in the catalog currency.
+type ShippingRate struct {
+	MaxWeight int          `json:"max_weight"`
+	Price     domain.Money `json:"price"`
+}
+
+// ShippingTable is a ShippingRateProvider charging the weight brackets of the
+// delivery region. With a VolumetricDivisor, in cubic centimeters per
+// kilogram, bulky parcels are charged their volumetric weight.
+type ShippingTable struct {
+	VolumetricDivisor float64                   `json:"volumetric_divisor"`
+	Regions           map[string][]ShippingRate `json:"regions"`
+}
+
+// FreeShippingTable ships everything everywhere for free
+func FreeShippingTable() ShippingTable {
+	return ShippingTable{Regions: map[string][]ShippingRate{defaultRegion: {{}}}}
+}
+
+func (t ShippingTable) Rate(region string, parcel Parcel) (domain.Money, error) {
+	rates, ok := lookup(t.Regions, region)
+	if !ok {
+		return domain.Money{}, domain.ErrShippingUnavailable
+	}
+
+	weight := parcel.Weight
+	if t.VolumetricDivisor > 0 {
+		if volumetric := int(parcel.Volume / t.VolumetricDivisor * 1000); volumetric > weight {
+			weight = volumetric
+		}
+	}
+	for _, rate := range rates {
+		if rate.MaxWeight == 0 || weight <= rate.MaxWeight {
+			return rate.Price, nil
+		}
+	}
+	return domain.Money{}, domain.ErrShippingUnavailable
+}
+
+// sortBrackets orders the brackets of each region by weight, the unlimited bracket last
+func (t ShippingTable) sortBrackets() {
+	for _, rates := range t.Regions {
+		sort.SliceStable(rates, func(a, b int) bool {
+			if rates[a].MaxWeight == 0 || rates[b].MaxWeight == 0 {
+				return rates[b].MaxWeight == 0 && rates[a].MaxWeight != 0
+			}
+			return rates[a].MaxWeight < rates[b].MaxWeight
+		})
+	}
+}
+
+// normalizeRegions upper cases the region codes of a table
+func normalizeRegions[T any](regions map[string]T) map[string]T {
+	result := make(map[string]T, len(regions))
+	for code, entry := range regions {
+		result[strings.ToUpper(strings.TrimSpace(code))] = entry
+	}
+	return result
+}
+
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadTaxTable reads a tax table from a JSON file such as
+//
+//	{"regions": {"ID": [{"name": "PPN", "rate": 11, "shipping": true}]}}
+func LoadTaxTable(path string) (TaxTable, error) {
+	var table TaxTable
+	if err := readJSON(path, &table); err != nil {
+		return TaxTable{}, err
+	}
+	for code, rates := range table.Regions {
+		for _, rate := range rates {
+			if rate.Name == "" || rate.Rate < 0 {
+				return TaxTable{}, fmt.Errorf("%s: invalid tax rate for region %s", path, code)
+			}
+		}
+	}
+	table.Regions = normalizeRegions(table.Regions)
+	return table, nil
+}
+
+// LoadShippingTable reads a shipping table from a JSON file such as
+//
+//	{"volumetric_divisor": 6000, "regions": {"ID-JK": [{"max_weight": 1000, "price": 10000}, {"price": 25000}]}}
+func LoadShippingTable(path string) (ShippingTable, error) {
+	var table ShippingTable
+	if err := readJSON(path, &table); err != nil {
+		return ShippingTable{}, err
+	}
+	if table.VolumetricDivisor < 0 {
+		return ShippingTable{}, fmt.Errorf("%s: invalid volumetric divisor", path)
+	}
+	for code, rates := range table.Regions {
+		for _, rate := range rates {
+			if rate.MaxWeight < 0 || rate.Price.IsNegative() || rate.Price.CurrencyCode() != domain.DefaultCurrency {
+				return ShippingTable{}, fmt.Errorf("%s: invalid shipping rate for region %s", path, code)
+			}
+		}
+	}
+	table.Regions = normalizeRegions(table.Regions)
+	table.sortBrackets()
+	return table, nil
+}
@@ -0,0 +1,250 @@
+// Package promotion evaluates promotions and coupon codes against a cart. The
+// engine is pure: callers load the promotions, the entered codes and the usage
+// of the customer, and persist the result.
+package promotion
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// Line is a cart line as seen by the engine
+type Line struct {
+	ProductID  int
+	CategoryID int
+	Amount     domain.Money
+}
+
+// Cart is the input of an evaluation. Usage holds the number of times the
+// customer already redeemed each promotion, by promotion id. Rate converts the
+// amounts of the promotions to the currency of the lines.
+type Cart struct {
+	Lines []Line
+	Codes []string
+	Usage map[int]int
+	Rate  domain.ExchangeRate
+	Now   time.Time
+}
+
+// Result is the outcome of an evaluation. Rejected holds the reason of each
+// entered code that did not apply.
+type Result struct {
+	Discounts     []domain.AppliedDiscount
+	DiscountTotal domain.Money
+	Rejected      map[string]error
+}
+
+// NewCart builds the engine input of a cart
+func NewCart(cart domain.Cart, usage map[int]int, now time.Time) Cart {
+	lines := make([]Line, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		lines = append(lines, Line{
+			ProductID:  item.ProductID,
+			CategoryID: item.CategoryID,
+			Amount:     item.LineTotal(),
+		})
+	}
+	return Cart{Lines: lines, Codes: cart.Coupons, Usage: usage, Rate: cart.ExchangeRate, Now: now}
+}
+
+// candidate is an eligible promotion
+type candidate struct {
+	promotion domain.Promotion
+	amount    domain.Money
+}
+
+// Evaluate applies promotions to cart. Automatic promotions and the
+// promotions of entered codes are checked against their validity window,
+// minimum subtotal, usage limit and category. The eligible stackable
+// promotions are then combined by priority, each discounting what the
+// previous ones left, unless a single non stackable promotion gives a larger
+// discount on its own.
+func Evaluate(promotions []domain.Promotion, cart Cart) Result {
+	result := Result{Discounts: make([]domain.AppliedDiscount, 0), Rejected: map[string]error{}}
+
+	entered := map[string]bool{}
+	for _, code := range cart.Codes {
+		entered[domain.NormalizeCoupon(code)] = true
+	}
+	known := map[string]bool{}
+
+	var subtotal domain.Money
+	for _, line := range cart.Lines {
+		subtotal = subtotal.Add(line.Amount)
+	}
+
+	sorted := append([]domain.Promotion(nil), promotions...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		if sorted[a].Priority != sorted[b].Priority {
+			return sorted[a].Priority > sorted[b].Priority
+		}
+		return sorted[a].ID < sorted[b].ID
+	})
+
+	var stackable, exclusive []candidate
+	for _, p := range sorted {
+		if p.Code != "" {
+			if !entered[p.Code] {
+				continue
+			}
+			known[p.Code] = true
+		}
+		amount, err := discount(p, cart, subtotal, amounts(cart.Lines))
+		if err != nil {
+			if p.Code != "" {
+				result.Rejected[p.Code] = err
+			}
+			continue
+		}
+		if p.Stackable {
+			stackable = append(stackable, candidate{p, amount})
+		} else {
+			exclusive = append(exclusive, candidate{p, amount})
+		}
+	}
+	for code := range entered {
+		if !known[code] {
+			result.Rejected[code] = domain.ErrPromotionInactive
+		}
+	}
+
+	// Stackable promotions discount the remaining amounts in priority order
+	remaining := amounts(cart.Lines)
+	var stacked []candidate
+	var stackedTotal domain.Money
+	for _, c := range stackable {
+		amount, _ := discount(c.promotion, cart, subtotal, remaining)
+		if !amount.IsPositive() {
+			continue
+		}
+		take(c.promotion, cart.Lines, remaining, amount)
+		stacked = append(stacked, candidate{c.promotion, amount})
+		stackedTotal = stackedTotal.Add(amount)
+	}
+
+	var best *candidate
+	for n := range exclusive {
+		if best == nil || exclusive[n].amount.Cmp(best.amount) > 0 {
+			best = &exclusive[n]
+		}
+	}
+
+	applied := stacked
+	var skipped []candidate
+	if best != nil && best.amount.Cmp(stackedTotal) > 0 {
+		applied = []candidate{*best}
+		skipped = append(skipped, stackable...)
+		for _, c := range exclusive {
+			if c.promotion.ID != best.promotion.ID {
+				skipped = append(skipped, c)
+			}
+		}
+	} else {
+		skipped = exclusive
+		for _, c := range stackable {
+			if !contains(stacked, c.promotion.ID) {
+				skipped = append(skipped, c)
+			}
+		}
+	}
+	for _, c := range skipped {
+		if c.promotion.Code != "" {
+			result.Rejected[c.promotion.Code] = domain.ErrNotStackable
+		}
+	}
+
+	for _, c := range applied {
+		result.Discounts = append(result.Discounts, domain.AppliedDiscount{
+			PromotionID: c.promotion.ID,
+			Name:        c.promotion.Name,
+			Code:        c.promotion.Code,
+			Amount:      c.amount,
+		})
+		result.DiscountTotal = result.DiscountTotal.Add(c.amount)
+	}
+	result.DiscountTotal = domain.MinMoney(result.DiscountTotal, subtotal)
+	return result
+}
+
+// Apply sets the discounts and the total of cart from the result
+func (r Result) Apply(cart *domain.Cart) {
+	cart.Subtotal = cart.CalculateSubtotal()
+	cart.Discounts = r.Discounts
+	cart.DiscountTotal = domain.Money{Currency: cart.Subtotal.Currency}.Add(r.DiscountTotal)
+	cart.Total = cart.Subtotal.Sub(r.DiscountTotal)
+	cart.RejectedCoupons = nil
+	for code, err := range r.Rejected {
+		if cart.RejectedCoupons == nil {
+			cart.RejectedCoupons = map[string]string{}
+		}
+		cart.RejectedCoupons[code] = err.Error()
+	}
+}
+
+// discount returns the discount of p on the remaining line amounts
+func discount(p domain.Promotion, cart Cart, subtotal domain.Money, remaining []domain.Money) (domain.Money, error) {
+	if !p.ActiveAt(cart.Now) {
+		return domain.Money{}, domain.ErrPromotionInactive
+	}
+	if subtotal.Cmp(cart.Rate.Convert(p.MinSubtotal)) < 0 {
+		return domain.Money{}, domain.ErrMinSubtotalNotMet
+	}
+	if p.UsageLimitPerUser > 0 && cart.Usage[p.ID] >= p.UsageLimitPerUser {
+		return domain.Money{}, domain.ErrUsageLimitReached
+	}
+
+	var base domain.Money
+	for n, line := range cart.Lines {
+		if applies(p, line) {
+			base = base.Add(remaining[n])
+		}
+	}
+	if !base.IsPositive() {
+		return domain.Money{}, domain.ErrNoEligibleItems
+	}
+
+	switch p.Type {
+	case domain.PromotionPercentage:
+		return base.Percent(p.Percentage), nil
+	case domain.PromotionFixed:
+		return domain.MinMoney(cart.Rate.Convert(p.Amount), base), nil
+	default:
+		return domain.Money{}, domain.ErrPromotionInactive
+	}
+}
+
+// take spreads the discount amount of p over its lines, proportionally to their remaining amounts
+func take(p domain.Promotion, lines []Line, remaining []domain.Money, amount domain.Money) {
+	weights := make([]int64, len(lines))
+	for n, line := range lines {
+		if applies(p, line) {
+			weights[n] = remaining[n].Amount
+		}
+	}
+	for n, share := range amount.Allocate(weights...) {
+		remaining[n] = remaining[n].Sub(share)
+	}
+}
+
+func applies(p domain.Promotion, line Line) bool {
+	return p.CategoryID == nil || *p.CategoryID == line.CategoryID
+}
+
+func amounts(lines []Line) []domain.Money {
+	result := make([]domain.Money, len(lines))
+	for n, line := range lines {
+		result[n] = line.Amount
+	}
+	return result
+}
+
+func contains(candidates []candidate, id int) bool {
+	for _, c := range candidates {
+		if c.promotion.ID == id {
+			return true
+		}
+	}
+	return false
+}
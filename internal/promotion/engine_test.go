@@ -0,0 +1,378 @@
+package promotion_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/promotion"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func idr(minor int64) domain.Money {
+	return domain.NewMoney(minor, domain.DefaultCurrency)
+}
+
+func usd(minor int64) domain.Money {
+	return domain.NewMoney(minor, "USD")
+}
+
+func at(d time.Duration) *time.Time {
+	t := now.Add(d)
+	return &t
+}
+
+func category(id int) *int {
+	return &id
+}
+
+// percentage returns an active automatic promotion discounting value percent
+func percentage(id int, value float64) domain.Promotion {
+	return domain.Promotion{ID: id, Name: "promotion", Type: domain.PromotionPercentage, Percentage: value, Active: true}
+}
+
+// fixed returns an active automatic promotion discounting rupiah
+func fixed(id int, rupiah int64) domain.Promotion {
+	return domain.Promotion{ID: id, Name: "promotion", Type: domain.PromotionFixed, Amount: idr(rupiah * 100), Active: true}
+}
+
+func with(p domain.Promotion, edit func(*domain.Promotion)) domain.Promotion {
+	edit(&p)
+	return p
+}
+
+func coded(p domain.Promotion, code string) domain.Promotion {
+	return with(p, func(p *domain.Promotion) { p.Code = code })
+}
+
+func stackable(p domain.Promotion, priority int) domain.Promotion {
+	return with(p, func(p *domain.Promotion) { p.Stackable, p.Priority = true, priority })
+}
+
+func lines(amounts ...int64) []promotion.Line {
+	result := make([]promotion.Line, 0, len(amounts))
+	for n, amount := range amounts {
+		result = append(result, promotion.Line{ProductID: n + 1, CategoryID: 1, Amount: idr(amount)})
+	}
+	return result
+}
+
+// discount is an applied promotion by id and amount
+type discount struct {
+	id     int
+	amount domain.Money
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		promotions []domain.Promotion
+		cart       promotion.Cart
+		want       []discount
+		wantTotal  domain.Money
+		rejected   map[string]error
+	}{
+		{
+			name:       "automatic percentage",
+			promotions: []domain.Promotion{percentage(1, 10)},
+			cart:       promotion.Cart{Lines: lines(10000, 5000)},
+			want:       []discount{{1, idr(1500)}},
+			wantTotal:  idr(1500),
+		},
+		{
+			name:       "automatic fixed",
+			promotions: []domain.Promotion{fixed(1, 25)},
+			cart:       promotion.Cart{Lines: lines(10000)},
+			want:       []discount{{1, idr(2500)}},
+			wantTotal:  idr(2500),
+		},
+		{
+			name:       "disabled",
+			promotions: []domain.Promotion{with(percentage(1, 10), func(p *domain.Promotion) { p.Active = false })},
+			cart:       promotion.Cart{Lines: lines(10000)},
+		},
+		{
+			name: "expiry",
+			promotions: []domain.Promotion{
+				coded(with(percentage(1, 10), func(p *domain.Promotion) { p.EndsAt = at(0) }), "ENDED"),
+				coded(with(percentage(2, 10), func(p *domain.Promotion) { p.StartsAt = at(time.Hour) }), "LATER"),
+				with(percentage(3, 10), func(p *domain.Promotion) { p.EndsAt = at(-time.Second) }),
+				coded(with(percentage(4, 5), func(p *domain.Promotion) { p.StartsAt, p.EndsAt = at(0), at(time.Second) }), "NOW"),
+			},
+			cart:      promotion.Cart{Lines: lines(10000), Codes: []string{"ended", "later", "now"}},
+			want:      []discount{{4, idr(500)}},
+			wantTotal: idr(500),
+			rejected:  map[string]error{"ENDED": domain.ErrPromotionInactive, "LATER": domain.ErrPromotionInactive},
+		},
+		{
+			name:       "unknown and normalized codes",
+			promotions: []domain.Promotion{coded(percentage(1, 10), "SAVE10")},
+			cart:       promotion.Cart{Lines: lines(10000), Codes: []string{" save10 ", "NOPE"}},
+			want:       []discount{{1, idr(1000)}},
+			wantTotal:  idr(1000),
+			rejected:   map[string]error{"NOPE": domain.ErrPromotionInactive},
+		},
+		{
+			name:       "code not entered",
+			promotions: []domain.Promotion{coded(percentage(1, 10), "SAVE10")},
+			cart:       promotion.Cart{Lines: lines(10000)},
+		},
+		{
+			name: "minimum subtotal",
+			promotions: []domain.Promotion{
+				coded(with(percentage(1, 10), func(p *domain.Promotion) { p.MinSubtotal = idr(10001) }), "BIG"),
+				coded(with(percentage(2, 5), func(p *domain.Promotion) { p.MinSubtotal = idr(10000) }), "EXACT"),
+			},
+			cart:      promotion.Cart{Lines: lines(6000, 4000), Codes: []string{"BIG", "EXACT"}},
+			want:      []discount{{2, idr(500)}},
+			wantTotal: idr(500),
+			rejected:  map[string]error{"BIG": domain.ErrMinSubtotalNotMet},
+		},
+		{
+			name: "usage limit",
+			promotions: []domain.Promotion{
+				coded(with(percentage(1, 10), func(p *domain.Promotion) { p.UsageLimitPerUser = 2 }), "TWICE"),
+				coded(with(percentage(2, 5), func(p *domain.Promotion) { p.UsageLimitPerUser = 2 }), "AGAIN"),
+				coded(percentage(3, 1), "ALWAYS"),
+			},
+			cart: promotion.Cart{
+				Lines: lines(10000),
+				Codes: []string{"TWICE", "AGAIN", "ALWAYS"},
+				Usage: map[int]int{1: 2, 2: 1, 3: 100},
+			},
+			want:      []discount{{2, idr(500)}},
+			wantTotal: idr(500),
+			rejected:  map[string]error{"TWICE": domain.ErrUsageLimitReached, "ALWAYS": domain.ErrNotStackable},
+		},
+		{
+			name: "category",
+			promotions: []domain.Promotion{
+				with(percentage(1, 50), func(p *domain.Promotion) { p.CategoryID = category(2) }),
+				coded(with(percentage(2, 50), func(p *domain.Promotion) { p.CategoryID = category(3) }), "HATS"),
+			},
+			cart: promotion.Cart{
+				Lines: []promotion.Line{
+					{ProductID: 1, CategoryID: 1, Amount: idr(10000)},
+					{ProductID: 2, CategoryID: 2, Amount: idr(3000)},
+				},
+				Codes: []string{"HATS"},
+			},
+			want:      []discount{{1, idr(1500)}},
+			wantTotal: idr(1500),
+			rejected:  map[string]error{"HATS": domain.ErrNoEligibleItems},
+		},
+		{
+			name: "stackable promotions discount what the previous left by priority",
+			promotions: []domain.Promotion{
+				stackable(percentage(1, 10), 1),
+				stackable(fixed(2, 10), 2),
+			},
+			cart:      promotion.Cart{Lines: lines(20000)},
+			want:      []discount{{2, idr(1000)}, {1, idr(1900)}},
+			wantTotal: idr(2900),
+		},
+		{
+			name: "equal priorities stack by id",
+			promotions: []domain.Promotion{
+				stackable(fixed(2, 10), 0),
+				stackable(percentage(1, 10), 0),
+			},
+			cart:      promotion.Cart{Lines: lines(20000)},
+			want:      []discount{{1, idr(2000)}, {2, idr(1000)}},
+			wantTotal: idr(3000),
+		},
+		{
+			name: "exclusive promotion larger than the stack",
+			promotions: []domain.Promotion{
+				coded(stackable(percentage(1, 10), 1), "TEN"),
+				stackable(fixed(2, 10), 2),
+				coded(percentage(3, 20), "TWENTY"),
+			},
+			cart:      promotion.Cart{Lines: lines(20000), Codes: []string{"TEN", "TWENTY"}},
+			want:      []discount{{3, idr(4000)}},
+			wantTotal: idr(4000),
+			rejected:  map[string]error{"TEN": domain.ErrNotStackable},
+		},
+		{
+			name: "stack larger than the exclusive promotion",
+			promotions: []domain.Promotion{
+				coded(stackable(percentage(1, 10), 1), "TEN"),
+				stackable(fixed(2, 10), 2),
+				coded(percentage(3, 12), "TWELVE"),
+			},
+			cart:      promotion.Cart{Lines: lines(20000), Codes: []string{"TEN", "TWELVE"}},
+			want:      []discount{{2, idr(1000)}, {1, idr(1900)}},
+			wantTotal: idr(2900),
+			rejected:  map[string]error{"TWELVE": domain.ErrNotStackable},
+		},
+		{
+			name: "largest exclusive promotion wins",
+			promotions: []domain.Promotion{
+				coded(percentage(1, 10), "TEN"),
+				coded(fixed(2, 25), "FLAT"),
+				coded(percentage(3, 15), "FIFTEEN"),
+			},
+			cart:      promotion.Cart{Lines: lines(20000), Codes: []string{"TEN", "FLAT", "FIFTEEN"}},
+			want:      []discount{{3, idr(3000)}},
+			wantTotal: idr(3000),
+			rejected:  map[string]error{"TEN": domain.ErrNotStackable, "FLAT": domain.ErrNotStackable},
+		},
+		{
+			name:       "fixed discount capped at the eligible amount",
+			promotions: []domain.Promotion{with(fixed(1, 500), func(p *domain.Promotion) { p.CategoryID = category(2) })},
+			cart: promotion.Cart{Lines: []promotion.Line{
+				{ProductID: 1, CategoryID: 1, Amount: idr(10000)},
+				{ProductID: 2, CategoryID: 2, Amount: idr(3000)},
+			}},
+			want:      []discount{{1, idr(3000)}},
+			wantTotal: idr(3000),
+		},
+		{
+			name: "stack capped at the subtotal",
+			promotions: []domain.Promotion{
+				stackable(percentage(1, 100), 2),
+				coded(stackable(fixed(2, 10), 1), "MORE"),
+			},
+			cart:      promotion.Cart{Lines: lines(5000), Codes: []string{"MORE"}},
+			want:      []discount{{1, idr(5000)}},
+			wantTotal: idr(5000),
+			rejected:  map[string]error{"MORE": domain.ErrNotStackable},
+		},
+		{
+			name:       "percentage rounded half away from zero",
+			promotions: []domain.Promotion{percentage(1, 12.5)},
+			cart:       promotion.Cart{Lines: lines(100)},
+			want:       []discount{{1, idr(13)}},
+			wantTotal:  idr(13),
+		},
+		{
+			name: "stacked percentages rounded on the remaining lines",
+			promotions: []domain.Promotion{
+				stackable(percentage(1, 15), 2),
+				stackable(percentage(2, 15), 1),
+			},
+			cart:      promotion.Cart{Lines: lines(333, 667)},
+			want:      []discount{{1, idr(150)}, {2, idr(128)}},
+			wantTotal: idr(278),
+		},
+		{
+			name: "amounts converted at the cart rate",
+			promotions: []domain.Promotion{
+				with(fixed(1, 10000), func(p *domain.Promotion) { p.MinSubtotal = idr(5000000) }),
+			},
+			cart: promotion.Cart{
+				Lines: []promotion.Line{{ProductID: 1, CategoryID: 1, Amount: usd(500)}},
+				Rate:  domain.ExchangeRate{Currency: "USD", Rate: 0.0001},
+			},
+			want:      []discount{{1, usd(100)}},
+			wantTotal: usd(100),
+		},
+		{
+			name: "minimum subtotal converted at the cart rate",
+			promotions: []domain.Promotion{
+				coded(with(fixed(1, 10000), func(p *domain.Promotion) { p.MinSubtotal = idr(10000000) }), "BIG"),
+			},
+			cart: promotion.Cart{
+				Lines: []promotion.Line{{ProductID: 1, CategoryID: 1, Amount: usd(500)}},
+				Codes: []string{"BIG"},
+				Rate:  domain.ExchangeRate{Currency: "USD", Rate: 0.0001},
+			},
+			rejected: map[string]error{"BIG": domain.ErrMinSubtotalNotMet},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cart.Now = now
+			result := promotion.Evaluate(tt.promotions, tt.cart)
+
+			got := make([]discount, 0, len(result.Discounts))
+			for _, d := range result.Discounts {
+				got = append(got, discount{d.PromotionID, d.Amount})
+			}
+			if want := append([]discount{}, tt.want...); !reflect.DeepEqual(got, want) {
+				t.Errorf("discounts = %v, want %v", got, want)
+			}
+			if result.DiscountTotal.Cmp(tt.wantTotal) != 0 {
+				t.Errorf("discount total = %v, want %v", result.DiscountTotal, tt.wantTotal)
+			}
+			if len(result.Rejected) != len(tt.rejected) {
+				t.Errorf("rejected = %v, want %v", result.Rejected, tt.rejected)
+			}
+			for code, want := range tt.rejected {
+				if err := result.Rejected[code]; !errors.Is(err, want) {
+					t.Errorf("code %s rejected with %v, want %v", code, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateDoesNotReorderPromotions(t *testing.T) {
+	promotions := []domain.Promotion{stackable(percentage(1, 10), 1), stackable(percentage(2, 10), 2)}
+	promotion.Evaluate(promotions, promotion.Cart{Lines: lines(1000), Now: now})
+	if promotions[0].ID != 1 || promotions[1].ID != 2 {
+		t.Errorf("promotions reordered: %v", promotions)
+	}
+}
+
+func TestResultApply(t *testing.T) {
+	cart := domain.Cart{
+		Currency: domain.DefaultCurrency,
+		Items: []domain.CartItem{
+			{ProductID: 1, CategoryID: 1, UnitPrice: idr(2500), Quantity: 2},
+			{ProductID: 2, CategoryID: 2, UnitPrice: idr(5000), Quantity: 1},
+		},
+		Coupons:         []string{"TEN", "NOPE"},
+		RejectedCoupons: map[string]string{"OLD": "stale"},
+	}
+	promotions := []domain.Promotion{coded(percentage(1, 10), "TEN")}
+
+	promotion.Evaluate(promotions, promotion.NewCart(cart, nil, now)).Apply(&cart)
+	if cart.Subtotal != idr(10000) || cart.DiscountTotal != idr(1000) || cart.Total != idr(9000) {
+		t.Errorf("subtotal, discount and total = %v, %v, %v, want 10000, 1000 and 9000", cart.Subtotal, cart.DiscountTotal, cart.Total)
+	}
+	if len(cart.Discounts) != 1 || cart.Discounts[0].Code != "TEN" {
+		t.Errorf("discounts = %+v, want TEN", cart.Discounts)
+	}
+	want := map[string]string{"NOPE": domain.ErrPromotionInactive.Error()}
+	if !reflect.DeepEqual(cart.RejectedCoupons, want) {
+		t.Errorf("rejected coupons = %v, want %v", cart.RejectedCoupons, want)
+	}
+
+	// A cart without discounts still has a total in its currency
+	cart.Coupons = nil
+	promotion.Evaluate(nil, promotion.NewCart(cart, nil, now)).Apply(&cart)
+	if cart.DiscountTotal != idr(0) || cart.Total != idr(10000) || cart.RejectedCoupons != nil {
+		t.Errorf("cart = %+v, want no discount", cart)
+	}
+}
+
+func TestPromotionValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion domain.Promotion
+		valid     bool
+	}{
+		{"percentage", percentage(1, 12.5), true},
+		{"whole percentage", percentage(1, 100), true},
+		{"zero percentage", percentage(1, 0), false},
+		{"percentage above 100", percentage(1, 100.01), false},
+		{"percentage with an amount", with(percentage(1, 10), func(p *domain.Promotion) { p.Amount = idr(100) }), false},
+		{"fixed", fixed(1, 2500), true},
+		{"zero amount", fixed(1, 0), false},
+		{"negative amount", fixed(1, -1), false},
+		{"largest amount", with(fixed(1, 0), func(p *domain.Promotion) { p.Amount = idr(999999999999999) }), true},
+		{"amount above the column", with(fixed(1, 0), func(p *domain.Promotion) { p.Amount = idr(1000000000000000) }), false},
+		{"amount in another currency", with(fixed(1, 0), func(p *domain.Promotion) { p.Amount = usd(100) }), false},
+		{"fixed with a percentage", with(fixed(1, 2500), func(p *domain.Promotion) { p.Percentage = 10 }), false},
+		{"minimum above the column", with(fixed(1, 2500), func(p *domain.Promotion) { p.MinSubtotal = idr(1000000000000000) }), false},
+	}
+	for _, tt := range tests {
+		if err := tt.promotion.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// CategoryRepository doesn't know the products of its categories, unlike the
+// SQL repositories it deletes categories holding products
+type CategoryRepository struct {
+	mu         sync.RWMutex
+	categories []domain.Category
+	deleted    trash
+	nextID     int
+}
+
+func NewMemoryCategoryRepository() *CategoryRepository {
+	return &CategoryRepository{deleted: trash{}, nextID: 1}
+}
+
+func (m *CategoryRepository) Fetch(ctx context.Context) (result []domain.Category, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result = make([]domain.Category, 0, len(m.categories))
+	for _, c := range m.categories {
+		if _, ok := m.deleted[c.ID]; !ok {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (m *CategoryRepository) GetByID(ctx context.Context, id string) (result domain.Category, err error) {
+	categoryID, err := strconv.Atoi(id)
+	if err != nil {
+		return domain.Category{}, domain.ErrNotFound
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, c := range m.categories {
+		if _, deleted := m.deleted[c.ID]; c.ID == categoryID && !deleted {
+			return c, nil
+		}
+	}
+	return domain.Category{}, domain.ErrNotFound
+}
+
+// Create stores a category, under its id when set
+func (m *CategoryRepository) Create(ctx context.Context, category domain.Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if category.ID == 0 {
+		category.ID = m.nextID
+	}
+	for _, c := range m.categories {
+		if c.ID == category.ID {
+			return domain.ErrConflict
+		}
+	}
+	if category.ID >= m.nextID {
+		m.nextID = category.ID + 1
+	}
+	category.Version = 1
+	m.categories = append(m.categories, category)
+	return nil
+}
+
+// Delete moves a category to the trash, failing with
+// domain.ErrVersionMismatch unless it is at version, 0 matching any version
+func (m *CategoryRepository) Delete(ctx context.Context, id, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for n := range m.categories {
+		if _, deleted := m.deleted[id]; m.categories[n].ID != id || deleted {
+			continue
+		}
+		if version != 0 && m.categories[n].Version != version {
+			return domain.ErrVersionMismatch
+		}
+		m.deleted[id] = time.Now()
+		m.categories[n].Version++
+		return nil
+	}
+	return domain.ErrNotFound
+}
+
+// FetchTrashed returns a page of the deleted categories
+func (m *CategoryRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items = make([]domain.TrashedItem, 0, len(m.deleted))
+	for _, c := range m.categories {
+		if deletedAt, ok := m.deleted[c.ID]; ok {
+			items = append(items, domain.TrashedItem{ID: c.ID, Name: c.Name, DeletedAt: deletedAt})
+		}
+	}
+	total, items = m.deleted.page(items, offset, limit)
+	return total, items, nil
+}
+
+// GetTrashed returns a deleted category
+func (m *CategoryRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deletedAt, ok := m.deleted[id]
+	if !ok {
+		return domain.TrashedItem{}, domain.ErrNotFound
+	}
+	for _, c := range m.categories {
+		if c.ID == id {
+			return domain.TrashedItem{ID: c.ID, Name: c.Name, DeletedAt: deletedAt}, nil
+		}
+	}
+	return domain.TrashedItem{}, domain.ErrNotFound
+}
+
+// Restore restores a deleted category
+func (m *CategoryRepository) Restore(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.deleted[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(m.deleted, id)
+	for n := range m.categories {
+		if m.categories[n].ID == id {
+			m.categories[n].Version++
+		}
+	}
+	return nil
+}
+
+// Purge permanently deletes the categories deleted before
+func (m *CategoryRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.categories[:0]
+	for _, c := range m.categories {
+		if m.deleted.expired(c.ID, before) {
+			delete(m.deleted, c.ID)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	purged := int64(len(m.categories) - len(kept))
+	m.categories = kept
+	return purged, nil
+}
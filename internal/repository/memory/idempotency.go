@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// IdempotencyRepository keeps idempotency records in memory, it only suits
+// a single instance of the API
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+func NewMemoryIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{records: make(map[string]domain.IdempotencyRecord)}
+}
+
+// Claim stores a request in flight, unless an unexpired record is stored
+// under its key, which is returned instead
+func (m *IdempotencyRepository) Claim(ctx context.Context, record domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.records[record.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, nil
+	}
+	m.records[record.Key] = record
+	return nil, nil
+}
+
+// Complete stores the response of a claimed request
+func (m *IdempotencyRepository) Complete(ctx context.Context, record domain.IdempotencyRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.records[record.Key] = record
+	return nil
+}
+
+// Renew extends the lock of a request in flight until expiresAt
+func (m *IdempotencyRepository) Renew(ctx context.Context, key string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, ok := m.records[key]; ok && !record.Completed() {
+		record.ExpiresAt = expiresAt
+		m.records[key] = record
+	}
+	return nil
+}
+
+func (m *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, key)
+	return nil
+}
+
+// DeleteExpired deletes the records expired at now, returning how many were deleted
+func (m *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, record := range m.records {
+		if !record.ExpiresAt.After(now) {
+			delete(m.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
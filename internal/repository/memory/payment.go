@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// paymentEventKey identifies a provider event
+type paymentEventKey struct {
+	provider, id string
+}
+
+// PaymentRepository doesn't know the orders of its payments, unlike the SQL
+// repositories it applies provider events to payments only
+type PaymentRepository struct {
+	mu       sync.Mutex
+	payments []domain.Payment
+	events   map[paymentEventKey]struct{}
+	nextID   int
+}
+
+func NewMemoryPaymentRepository() *PaymentRepository {
+	return &PaymentRepository{events: map[paymentEventKey]struct{}{}, nextID: 1}
+}
+
+func (m *PaymentRepository) FetchByOrder(ctx context.Context, orderID int) (result []domain.Payment, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result = make([]domain.Payment, 0)
+	for _, pm := range m.payments {
+		if pm.OrderID == orderID {
+			result = append(result, pm)
+		}
+	}
+	return result, nil
+}
+
+func (m *PaymentRepository) GetByID(ctx context.Context, id int) (domain.Payment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pm := range m.payments {
+		if pm.ID == id {
+			return pm, nil
+		}
+	}
+	return domain.Payment{}, domain.ErrNotFound
+}
+
+func (m *PaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, pm := range m.payments {
+		if pm.Provider == payment.Provider && pm.ExternalID == payment.ExternalID {
+			return domain.ErrConflict
+		}
+	}
+	payment.ID = m.nextID
+	m.nextID++
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = payment.CreatedAt
+	m.payments = append(m.payments, *payment)
+	return nil
+}
+
+// ApplyEvent updates the payment of a provider event. Events with an id are
+// applied once, replays are ignored, as are events that would move the
+// payment backwards.
+func (m *PaymentRepository) ApplyEvent(ctx context.Context, provider string, event domain.PaymentEvent) error {
+	status, ok := event.PaymentStatus()
+	if !ok {
+		return domain.ErrBadRequest
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := paymentEventKey{provider: provider, id: event.ID}
+	if _, seen := m.events[key]; event.ID != "" && seen {
+		return nil
+	}
+
+	for n, pm := range m.payments {
+		if pm.Provider != provider || pm.ExternalID != event.ExternalID {
+			continue
+		}
+		if event.ID != "" {
+			m.events[key] = struct{}{}
+		}
+		if pm.Status.CanTransitionTo(status) {
+			m.payments[n].Status = status
+			m.payments[n].UpdatedAt = time.Now()
+		}
+		return nil
+	}
+	return domain.ErrNotFound
+}
@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+type ProductRepository struct {
+	mu       sync.RWMutex
+	products []domain.Product
+	deleted  trash
+	nextID   int
+}
+
+func NewMemoryProductRepository() *ProductRepository {
+	return &ProductRepository{deleted: trash{}, nextID: 1}
+}
+
+// Add stores a product, under its id when set, and returns it. Products are
+// created by imports with the SQL repositories, Add seeds the catalog of tests.
+func (m *ProductRepository) Add(product domain.Product) (domain.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if product.ID == 0 {
+		product.ID = m.nextID
+	}
+	for _, p := range m.products {
+		if p.ID == product.ID || (product.SKU != "" && p.SKU == product.SKU) {
+			return domain.Product{}, domain.ErrConflict
+		}
+	}
+	if product.ID >= m.nextID {
+		m.nextID = product.ID + 1
+	}
+	if product.Version == 0 {
+		product.Version = 1
+	}
+	m.products = append(m.products, cloneProduct(product))
+	return cloneProduct(product), nil
+}
+
+// cloneProduct deeply copies the slices and pointers of a product, so callers
+// can't modify the stored product
+func cloneProduct(product domain.Product) domain.Product {
+	product.Options = cloneSlice(product.Options)
+	for n := range product.Options {
+		product.Options[n].Values = cloneSlice(product.Options[n].Values)
+	}
+	product.Variants = cloneSlice(product.Variants)
+	for n := range product.Variants {
+		variant := &product.Variants[n]
+		if variant.Price != nil {
+			price := *variant.Price
+			variant.Price = &price
+		}
+		variant.Options = cloneSlice(variant.Options)
+	}
+	product.Images = cloneSlice(product.Images)
+	for n := range product.Images {
+		product.Images[n].Variants = cloneSlice(product.Images[n].Variants)
+	}
+	return product
+}
+
+// cloneSlice copies a slice, keeping nil slices nil
+func cloneSlice[T any](s []T) []T {
+	if s == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(s)), s...)
+}
+
+// listed returns a product as listed, without its description, options,
+// variants and images
+func listed(product domain.Product) domain.Product {
+	product.Description = ""
+	product.Options, product.Variants, product.Images = nil, nil, nil
+	return product
+}
+
+// matches reports whether a product matches filter
+func matches(product domain.Product, filter domain.ProductFilter) bool {
+	if filter.CategoryID != 0 && product.Category.ID != filter.CategoryID {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(product.Name), search) && !strings.Contains(strings.ToLower(product.SKU), search) {
+			return false
+		}
+	}
+	if filter.MinPrice != nil && (product.Price.CurrencyCode() != filter.MinPrice.CurrencyCode() || product.Price.Amount < filter.MinPrice.Amount) {
+		return false
+	}
+	if filter.MaxPrice != nil && (product.Price.CurrencyCode() != filter.MaxPrice.CurrencyCode() || product.Price.Amount > filter.MaxPrice.Amount) {
+		return false
+	}
+	return !filter.InStock || product.Stock > 0
+}
+
+// filtered returns the products matching filter
+func (m *ProductRepository) filtered(filter domain.ProductFilter) []domain.Product {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := make([]domain.Product, 0)
+	for _, p := range m.products {
+		if _, deleted := m.deleted[p.ID]; !deleted && matches(p, filter) {
+			result = append(result, cloneProduct(p))
+		}
+	}
+	return result
+}
+
+func (m *ProductRepository) Fetch(ctx context.Context) (result []domain.Product, err error) {
+	result = m.filtered(domain.ProductFilter{})
+	for n := range result {
+		result[n] = listed(result[n])
+	}
+	return result, nil
+}
+
+func (m *ProductRepository) FetchPaginated(ctx context.Context, filter domain.ProductFilter, offset, limit int) (total int, products []domain.Product, err error) {
+	all := m.filtered(filter)
+	products = make([]domain.Product, 0)
+	for n := offset; n < len(all) && n < offset+limit; n++ {
+		products = append(products, listed(all[n]))
+	}
+	return len(all), products, nil
+}
+
+// Export calls fn with every product matching filter, with its description
+func (m *ProductRepository) Export(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error {
+	for _, p := range m.filtered(filter) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		description := p.Description
+		p = listed(p)
+		p.Description = description
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByID returns a product with its description, options, variants and images
+func (m *ProductRepository) GetByID(ctx context.Context, id int) (result domain.Product, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, p := range m.products {
+		if _, deleted := m.deleted[p.ID]; p.ID == id && !deleted {
+			return cloneProduct(p), nil
+		}
+	}
+	return domain.Product{}, domain.ErrNotFound
+}
+
+// find returns the index of a product which isn't deleted, failing with
+// domain.ErrVersionMismatch unless it is at version, 0 matching any version
+func (m *ProductRepository) find(productID, version int) (int, error) {
+	for n := range m.products {
+		if _, deleted := m.deleted[productID]; m.products[n].ID != productID || deleted {
+			continue
+		}
+		if version != 0 && m.products[n].Version != version {
+			return 0, domain.ErrVersionMismatch
+		}
+		return n, nil
+	}
+	return 0, domain.ErrNotFound
+}
+
+func (m *ProductRepository) UpdateShippingDetails(ctx context.Context, productID, version int, details domain.ShippingDetails) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, err := m.find(productID, version)
+	if err != nil {
+		return err
+	}
+	m.products[n].ShippingDetails = details
+	m.products[n].Version++
+	return nil
+}
+
+// Delete moves a product to the trash
+func (m *ProductRepository) Delete(ctx context.Context, productID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, err := m.find(productID, version)
+	if err != nil {
+		return err
+	}
+	m.deleted[productID] = time.Now()
+	m.products[n].Version++
+	return nil
+}
+
+// FetchTrashed returns a page of the deleted products
+func (m *ProductRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items = make([]domain.TrashedItem, 0, len(m.deleted))
+	for _, p := range m.products {
+		if deletedAt, ok := m.deleted[p.ID]; ok {
+			items = append(items, domain.TrashedItem{ID: p.ID, Name: p.Name, DeletedAt: deletedAt})
+		}
+	}
+	total, items = m.deleted.page(items, offset, limit)
+	return total, items, nil
+}
+
+// GetTrashed returns a deleted product
+func (m *ProductRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deletedAt, ok := m.deleted[id]
+	if !ok {
+		return domain.TrashedItem{}, domain.ErrNotFound
+	}
+	for _, p := range m.products {
+		if p.ID == id {
+			return domain.TrashedItem{ID: p.ID, Name: p.Name, DeletedAt: deletedAt}, nil
+		}
+	}
+	return domain.TrashedItem{}, domain.ErrNotFound
+}
+
+// Restore restores a deleted product
+func (m *ProductRepository) Restore(ctx context.Context, productID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.deleted[productID]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(m.deleted, productID)
+	for n := range m.products {
+		if m.products[n].ID == productID {
+			m.products[n].Version++
+		}
+	}
+	return nil
+}
+
+// Purge permanently deletes the products deleted before
+func (m *ProductRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.products[:0]
+	for _, p := range m.products {
+		if m.deleted.expired(p.ID, before) {
+			delete(m.deleted, p.ID)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	purged := int64(len(m.products) - len(kept))
+	m.products = kept
+	return purged, nil
+}
@@ -0,0 +1,56 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/memory"
+)
+
+func TestProductRepositoryCopiesProducts(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMemoryProductRepository()
+	price := domain.Money{Amount: 1000, Currency: domain.DefaultCurrency}
+	added, err := repo.Add(domain.Product{
+		Name:        "Shoe",
+		Description: "Light",
+		Options:     []domain.ProductOption{{Name: "Size", Values: []domain.ProductOptionValue{{Value: "42"}}}},
+		Variants:    []domain.ProductVariant{{SKU: "SHOE-42", Price: &price, Options: []domain.ProductOptionValue{{Value: "42"}}}},
+		Images:      []domain.ProductImage{{Variants: []domain.ImageVariant{{Width: 320}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Neither the added product, its seed nor a fetched product share memory with the stored product
+	price.Amount = 1
+	added.Options[0].Values[0].Value = "43"
+	fetched, err := repo.GetByID(ctx, added.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched.Variants[0].Price.Amount = 2
+	fetched.Variants[0].Options[0].Value = "44"
+	fetched.Images[0].Variants[0].Width = 640
+
+	product, err := repo.GetByID(ctx, added.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Description != "Light" {
+		t.Errorf("description = %q, want Light", product.Description)
+	}
+	if got := product.Options[0].Values[0].Value; got != "42" {
+		t.Errorf("option value = %q, want 42", got)
+	}
+	if got := product.Variants[0].Price.Amount; got != 1000 {
+		t.Errorf("variant price = %d, want 1000", got)
+	}
+	if got := product.Variants[0].Options[0].Value; got != "42" {
+		t.Errorf("variant option = %q, want 42", got)
+	}
+	if got := product.Images[0].Variants[0].Width; got != 320 {
+		t.Errorf("image variant width = %d, want 320", got)
+	}
+}
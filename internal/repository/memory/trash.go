@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// trash holds the deletion times of the deleted items of a repository by id.
+// There are no orders in memory, purges never keep items.
+type trash map[int]time.Time
+
+// page returns a page of deleted items, last deleted first
+func (t trash) page(items []domain.TrashedItem, offset, limit int) (total int, result []domain.TrashedItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	result = make([]domain.TrashedItem, 0)
+	for n := offset; n < len(items) && n < offset+limit; n++ {
+		result = append(result, items[n])
+	}
+	return len(items), result
+}
+
+// expired reports whether the item id was deleted before
+func (t trash) expired(id int, before time.Time) bool {
+	deletedAt, ok := t[id]
+	return ok && deletedAt.Before(before)
+}
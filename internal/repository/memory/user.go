@@ -0,0 +1,186 @@
+// Package memory implements repositories holding their data in memory, safe
+// for concurrent use, to test handlers without a database. They return the
+// same errors as the SQL repositories.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+type UserRepository struct {
+	mu      sync.RWMutex
+	users   []domain.User
+	deleted trash
+	nextID  int
+}
+
+func NewMemoryUserRepository() *UserRepository {
+	return &UserRepository{deleted: trash{}, nextID: 1}
+}
+
+func (m *UserRepository) Fetch(ctx context.Context) (result []domain.User, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result = make([]domain.User, 0, len(m.users))
+	for _, u := range m.users {
+		if _, ok := m.deleted[u.ID]; ok {
+			continue
+		}
+		u.Password = ""
+		result = append(result, u)
+	}
+	return result, nil
+}
+
+func (m *UserRepository) Create(ctx context.Context, user domain.User) error {
+	if err := user.HashPassword(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.find(user.Email); ok {
+		return domain.ErrConflict
+	}
+	user.ID = m.nextID
+	user.Version = 1
+	m.nextID++
+	m.users = append(m.users, user)
+	return nil
+}
+
+// find returns the user with the given email along with its hashed password,
+// deleted users keeping their email
+func (m *UserRepository) find(email string) (domain.User, bool) {
+	for _, u := range m.users {
+		if u.Email == email {
+			return u, true
+		}
+	}
+	return domain.User{}, false
+}
+
+// GetByID returns the user of id
+func (m *UserRepository) GetByID(ctx context.Context, id int) (result domain.User, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, u := range m.users {
+		if _, deleted := m.deleted[u.ID]; u.ID == id && !deleted {
+			u.Password = ""
+			return u, nil
+		}
+	}
+	return domain.User{}, domain.ErrNotFound
+}
+
+func (m *UserRepository) GetByEmail(ctx context.Context, email string) (result domain.User, err error) {
+	result, err = m.GetCredentials(ctx, email)
+	result.Password = ""
+	return result, err
+}
+
+// GetCredentials returns the user with the given email along with its hashed
+// password. Deleted users aren't found, so they can't log in.
+func (m *UserRepository) GetCredentials(ctx context.Context, email string) (result domain.User, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result, ok := m.find(email)
+	if _, deleted := m.deleted[result.ID]; !ok || deleted {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return result, nil
+}
+
+// Delete moves a user to the trash, failing with domain.ErrVersionMismatch
+// unless it is at version, 0 matching any version
+func (m *UserRepository) Delete(ctx context.Context, id, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for n := range m.users {
+		if _, deleted := m.deleted[id]; m.users[n].ID != id || deleted {
+			continue
+		}
+		if version != 0 && m.users[n].Version != version {
+			return domain.ErrVersionMismatch
+		}
+		m.deleted[id] = time.Now()
+		m.users[n].Version++
+		return nil
+	}
+	return domain.ErrNotFound
+}
+
+// FetchTrashed returns a page of the deleted users
+func (m *UserRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items = make([]domain.TrashedItem, 0, len(m.deleted))
+	for _, u := range m.users {
+		if deletedAt, ok := m.deleted[u.ID]; ok {
+			items = append(items, domain.TrashedItem{ID: u.ID, Name: u.Name, DeletedAt: deletedAt})
+		}
+	}
+	total, items = m.deleted.page(items, offset, limit)
+	return total, items, nil
+}
+
+// GetTrashed returns a deleted user
+func (m *UserRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	deletedAt, ok := m.deleted[id]
+	if !ok {
+		return domain.TrashedItem{}, domain.ErrNotFound
+	}
+	for _, u := range m.users {
+		if u.ID == id {
+			return domain.TrashedItem{ID: u.ID, Name: u.Name, DeletedAt: deletedAt}, nil
+		}
+	}
+	return domain.TrashedItem{}, domain.ErrNotFound
+}
+
+// Restore restores a deleted user
+func (m *UserRepository) Restore(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.deleted[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(m.deleted, id)
+	for n := range m.users {
+		if m.users[n].ID == id {
+			m.users[n].Version++
+		}
+	}
+	return nil
+}
+
+// Purge permanently deletes the users deleted before
+func (m *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	kept := m.users[:0]
+	for _, u := range m.users {
+		if m.deleted.expired(u.ID, before) {
+			delete(m.deleted, u.ID)
+			continue
+		}
+		kept = append(kept, u)
+	}
+	purged := int64(len(m.users) - len(kept))
+	m.users = kept
+	return purged, nil
+}
@@ -0,0 +1,178 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/audit"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLAuditRepository(conn *sql.DB) *AuditRepository {
+	return &AuditRepository{conn}
+}
+
+const auditLogColumns = `id, actor_id, actor_email, action, entity_type, entity_id, changes, request_id, ip, created_at, prev_hash, hash`
+
+// scanAuditLog scans a row of auditLogColumns
+func scanAuditLog(rows *sql.Rows) (entry domain.AuditLog, err error) {
+	var actorID sql.NullInt64
+	var changes string
+	err = rows.Scan(&entry.ID, &actorID, &entry.ActorEmail, &entry.Action, &entry.EntityType, &entry.EntityID,
+		&changes, &entry.RequestID, &entry.IP, &entry.CreatedAt, &entry.PrevHash, &entry.Hash)
+	if err != nil {
+		return domain.AuditLog{}, err
+	}
+	if actorID.Valid {
+		id := int(actorID.Int64)
+		entry.ActorID = &id
+	}
+	entry.Changes = json.RawMessage(changes)
+	return entry, nil
+}
+
+// Append appends an entry to the audit logs, chained to the last entry
+func (m *AuditRepository) Append(ctx context.Context, entry *domain.AuditLog) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// The head is locked so that concurrent entries are chained one after the other
+	var head string
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit_log_head WHERE id = 1 FOR UPDATE`).Scan(&head)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	audit.Seal(entry, head)
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO audit_logs (actor_id, actor_email, action, entity_type, entity_id, changes, request_id, ip, created_at, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ActorID, entry.ActorEmail, entry.Action, entry.EntityType, entry.EntityID, string(entry.Changes),
+		entry.RequestID, entry.IP, entry.CreatedAt, entry.PrevHash, entry.Hash)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	entry.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE audit_log_head SET hash = ? WHERE id = 1`, entry.Hash)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return nil
+}
+
+// Fetch returns a page of the audit logs matching filter, last appended first
+func (m *AuditRepository) Fetch(ctx context.Context, filter domain.AuditLogFilter, offset, limit int) (total int, result []domain.AuditLog, err error) {
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, condition)
+	}
+	if filter.ActorID != nil {
+		add("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = ?", filter.Action)
+	}
+	if filter.EntityType != "" {
+		add("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		add("entity_id = ?", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		add("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		add("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < ?", *filter.To)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	conn := transaction.Conn(ctx, m.Conn)
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_logs WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	args = append(args, limit, offset)
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM audit_logs
+		WHERE %s
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`, auditLogColumns, where), args...)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.AuditLog, 0)
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			logrus.Error(err)
+			return 0, nil, err
+		}
+		result = append(result, entry)
+	}
+	return total, result, rows.Err()
+}
+
+// Each calls fn with every entry of the audit logs, oldest first, until fn fails
+func (m *AuditRepository) Each(ctx context.Context, fn func(entry domain.AuditLog) error) error {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, `SELECT `+auditLogColumns+` FROM audit_logs ORDER BY id`)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry, err := scanAuditLog(rows)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Head returns the hash of the last appended entry
+func (m *AuditRepository) Head(ctx context.Context) (head string, err error) {
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT hash FROM audit_log_head WHERE id = 1`).Scan(&head)
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+	return head, nil
+}
@@ -0,0 +1,450 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/promotion"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type CartRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLCartRepository(conn *sql.DB) *CartRepository {
+	return &CartRepository{conn}
+}
+
+// ownerCondition returns the condition selecting the cart of owner
+func ownerCondition(owner domain.CartOwner) (string, interface{}) {
+	if owner.UserID != 0 {
+		return "user_id = ?", owner.UserID
+	}
+	return "token = ? AND user_id IS NULL", owner.Token
+}
+
+func (m *CartRepository) fetchItems(ctx context.Context, q querier, cartID int) (result []domain.CartItem, err error) {
+	query := `SELECT ci.id, ci.cart_id, ci.product_id, ci.variant_id, p.category_id, p.weight, p.length, p.width, p.height, ci.name, ci.sku, p.currency, ci.unit_price, ci.quantity, ci.created_at
+						FROM cart_items ci
+						JOIN products p ON ci.product_id = p.id
+						WHERE ci.cart_id = ? AND p.deleted_at IS NULL
+						ORDER BY ci.id ASC`
+	rows, err := q.QueryContext(ctx, query, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.CartItem, 0)
+	for rows.Next() {
+		i := domain.CartItem{}
+		var variantID sql.NullInt64
+		err := rows.Scan(&i.ID, &i.CartID, &i.ProductID, &variantID, &i.CategoryID, &i.Shipping.Weight, &i.Shipping.Length, &i.Shipping.Width, &i.Shipping.Height, &i.Name, &i.SKU, &i.UnitPrice.Currency, &i.UnitPrice, &i.Quantity, &i.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			i.VariantID = &id
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+func (m *CartRepository) fetchCoupons(ctx context.Context, q querier, cartID int) (result []string, err error) {
+	rows, err := q.QueryContext(ctx, `SELECT code FROM cart_coupons WHERE cart_id = ? ORDER BY created_at ASC, code ASC`, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]string, 0)
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, code)
+	}
+	return result, rows.Err()
+}
+
+// price loads the items and coupons of cart, prices the items in the cart
+// currency and applies the active promotions. Usage limits are checked against
+// the redemptions of the cart user, anonymous carts are priced as if the
+// visitor never redeemed any.
+func (m *CartRepository) price(ctx context.Context, q querier, cart *domain.Cart) (err error) {
+	cart.Items, err = m.fetchItems(ctx, q, cart.ID)
+	if err != nil {
+		return err
+	}
+	cart.Coupons, err = m.fetchCoupons(ctx, q, cart.ID)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]int, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	book, err := NewMySQLCurrencyRepository(m.Conn).priceBook(ctx, q, cart.Currency, productIDs)
+	if err != nil {
+		return err
+	}
+	book.LocalizeItems(cart.Items)
+	cart.Currency = book.Currency()
+	cart.ExchangeRate = book.Rate
+
+	now := time.Now()
+	promotions := NewMySQLPromotionRepository(m.Conn)
+	active, err := promotions.fetchActive(ctx, q, now)
+	if err != nil {
+		return err
+	}
+	usage := map[int]int{}
+	if cart.UserID != nil {
+		usage, err = promotions.usage(ctx, q, *cart.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	promotion.Evaluate(active, promotion.NewCart(*cart, usage, now)).Apply(cart)
+	return nil
+}
+
+// GetCart returns the cart of owner with its items
+func (m *CartRepository) GetCart(ctx context.Context, owner domain.CartOwner) (result domain.Cart, err error) {
+	condition, arg := ownerCondition(owner)
+	var userID sql.NullInt64
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx,
+		`SELECT id, user_id, COALESCE(token, ''), currency, created_at, updated_at FROM carts WHERE `+condition, arg).
+		Scan(&result.ID, &userID, &result.Token, &result.Currency, &result.CreatedAt, &result.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Cart{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Cart{}, err
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		result.UserID = &id
+	}
+
+	if err := m.price(ctx, transaction.Conn(ctx, m.Conn), &result); err != nil {
+		return domain.Cart{}, err
+	}
+	return result, nil
+}
+
+// GetOrCreateCart returns the cart of owner, creating an empty cart when it has none
+func (m *CartRepository) GetOrCreateCart(ctx context.Context, owner domain.CartOwner) (domain.Cart, error) {
+	var userID, token interface{}
+	if owner.UserID != 0 {
+		userID = owner.UserID
+	} else {
+		token = owner.Token
+	}
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `INSERT INTO carts (user_id, token) VALUES (?, ?) ON DUPLICATE KEY UPDATE id = id`, userID, token)
+	if err != nil {
+		logrus.Error(err)
+		return domain.Cart{}, err
+	}
+	return m.GetCart(ctx, owner)
+}
+
+// touch records the modification of a cart
+func (m *CartRepository) touch(ctx context.Context, tx *transaction.Tx, cartID int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE carts SET updated_at = ? WHERE id = ?`, time.Now(), cartID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// SaveItem adds a line to a cart or sets the quantity of the existing line of
+// the same product and variant, keeping its price snapshot
+func (m *CartRepository) SaveItem(ctx context.Context, cartID int, item *domain.CartItem) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	item.CartID = cartID
+	item.CreatedAt = time.Now()
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO cart_items (cart_id, product_id, variant_id, name, sku, unit_price, quantity, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), quantity = VALUES(quantity)`,
+		cartID, item.ProductID, item.VariantID, item.Name, item.SKU, item.UnitPrice, item.Quantity, item.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	item.ID = int(id)
+
+	err = tx.QueryRowContext(ctx, `SELECT unit_price, created_at FROM cart_items WHERE id = ?`, item.ID).
+		Scan(&item.UnitPrice, &item.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return m.touch(ctx, tx, cartID)
+}
+
+func (m *CartRepository) UpdateItemQuantity(ctx context.Context, cartID, itemID, quantity int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// RowsAffected is 0 when the quantity is unchanged, check the line exists first
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM cart_items WHERE id = ? AND cart_id = ? FOR UPDATE`, itemID, cartID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE cart_items SET quantity = ? WHERE id = ?`, quantity, itemID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return m.touch(ctx, tx, cartID)
+}
+
+func (m *CartRepository) DeleteItem(ctx context.Context, cartID, itemID int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE id = ? AND cart_id = ?`, itemID, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return m.touch(ctx, tx, cartID)
+}
+
+// SetCurrency switches the currency a cart is priced in, the currency needs an exchange rate
+func (m *CartRepository) SetCurrency(ctx context.Context, cartID int, currency string) error {
+	rate, err := NewMySQLCurrencyRepository(m.Conn).rate(ctx, transaction.Conn(ctx, m.Conn), currency)
+	if err != nil {
+		return err
+	}
+
+	// MySQL reports zero affected rows when nothing changed, so check existence first
+	var exists int
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT 1 FROM carts WHERE id = ?`, cartID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = transaction.Conn(ctx, m.Conn).ExecContext(ctx, `UPDATE carts SET currency = ?, updated_at = ? WHERE id = ?`, rate.Currency, time.Now(), cartID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// AddCoupon enters a coupon code on a cart, the code must belong to a promotion
+func (m *CartRepository) AddCoupon(ctx context.Context, cartID int, code string) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM promotions WHERE code = ?`, code).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO cart_coupons (cart_id, code, created_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE code = code`,
+		cartID, code, time.Now())
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return m.touch(ctx, tx, cartID)
+}
+
+func (m *CartRepository) RemoveCoupon(ctx context.Context, cartID int, code string) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM cart_coupons WHERE cart_id = ? AND code = ?`, cartID, code)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return m.touch(ctx, tx, cartID)
+}
+
+// MergeCarts moves the anonymous cart of token into the cart of userID. The
+// anonymous cart becomes the user cart when the user has none, otherwise the
+// quantities of matching lines are added up, capped to the available stock,
+// and the entered coupons are carried over.
+func (m *CartRepository) MergeCarts(ctx context.Context, token string, userID int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var anonymousID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM carts WHERE token = ? AND user_id IS NULL FOR UPDATE`, token).Scan(&anonymousID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	var cartID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM carts WHERE user_id = ? FOR UPDATE`, userID).Scan(&cartID)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.ExecContext(ctx, `UPDATE carts SET user_id = ?, token = NULL, updated_at = ? WHERE id = ?`,
+			userID, time.Now(), anonymousID)
+		if err != nil {
+			logrus.Error(err)
+		}
+		return err
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO cart_items (cart_id, product_id, variant_id, name, sku, unit_price, quantity, created_at)
+		SELECT ?, a.product_id, a.variant_id, a.name, a.sku, a.unit_price, a.quantity, a.created_at FROM cart_items a WHERE a.cart_id = ?
+		ON DUPLICATE KEY UPDATE quantity = cart_items.quantity + VALUES(quantity)`,
+		cartID, anonymousID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO cart_coupons (cart_id, code, created_at)
+		SELECT ?, a.code, a.created_at FROM cart_coupons a WHERE a.cart_id = ?
+		ON DUPLICATE KEY UPDATE code = cart_coupons.code`,
+		cartID, anonymousID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// Out of stock lines are kept as is, checkout rejects them
+	_, err = tx.ExecContext(ctx,
+		`UPDATE cart_items i
+		JOIN products p ON i.product_id = p.id
+		LEFT JOIN product_variants v ON i.variant_id = v.id
+		SET i.quantity = COALESCE(v.stock, p.stock)
+		WHERE i.cart_id = ? AND i.quantity > COALESCE(v.stock, p.stock) AND COALESCE(v.stock, p.stock) > 0`, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM carts WHERE id = ?`, anonymousID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return m.touch(ctx, tx, cartID)
+}
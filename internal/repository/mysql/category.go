@@ -3,8 +3,11 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
 	"github.com/sirupsen/logrus"
 )
 
@@ -17,7 +20,7 @@ func NewMySQLCategoryRepository(conn *sql.DB) *CategoryRepository {
 }
 
 func (m *CategoryRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Category, err error) {
-	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +35,7 @@ func (m *CategoryRepository) fetch(ctx context.Context, query string, args ...in
 	result = make([]domain.Category, 0)
 	for rows.Next() {
 		c := domain.Category{}
-		err := rows.Scan(&c.ID, &c.Name)
+		err := rows.Scan(&c.ID, &c.Name, &c.Version)
 		if err != nil {
 			logrus.Error(err)
 			return nil, err
@@ -43,7 +46,7 @@ func (m *CategoryRepository) fetch(ctx context.Context, query string, args ...in
 }
 
 func (m *CategoryRepository) Fetch(ctx context.Context) (result []domain.Category, err error) {
-	query := "SELECT id, name FROM categories"
+	query := "SELECT id, name, version FROM categories WHERE deleted_at IS NULL"
 	res, err := m.fetch(ctx, query)
 	if err != nil {
 		logrus.Error(err)
@@ -53,7 +56,7 @@ func (m *CategoryRepository) Fetch(ctx context.Context) (result []domain.Categor
 }
 
 func (m *CategoryRepository) GetByID(ctx context.Context, id string) (result domain.Category, err error) {
-	query := "SELECT id, name FROM categories WHERE id = ?"
+	query := "SELECT id, name, version FROM categories WHERE id = ? AND deleted_at IS NULL"
 	res, err := m.fetch(ctx, query, id)
 	if err != nil {
 		logrus.Error(err)
@@ -70,9 +73,70 @@ func (m *CategoryRepository) Create(ctx context.Context, category domain.Categor
 	query := `INSERT INTO categories (id, name) VALUES (?, ?)`
 
 	var createdCategory domain.Category
-	err := m.Conn.QueryRowContext(ctx, query, category.ID, category.Name).Scan(&createdCategory.ID, &createdCategory.Name)
+	err := transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, query, category.ID, category.Name).Scan(&createdCategory.ID, &createdCategory.Name)
 	if err != nil {
 		logrus.Error(err)
 	}
 	return nil
 }
+
+// Delete moves a category to the trash, failing with
+// domain.ErrCategoryNotEmpty while it has products and with
+// domain.ErrVersionMismatch unless it is at version, 0 matching any version
+func (m *CategoryRepository) Delete(ctx context.Context, id, version int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkRowVersion(ctx, tx, "categories", id, version); err != nil {
+		return err
+	}
+
+	// Lock the category so none of its products is restored meanwhile
+	var hasProducts bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products p WHERE p.category_id = c.id AND p.deleted_at IS NULL)
+		FROM categories c
+		WHERE c.id = ? AND c.deleted_at IS NULL
+		FOR UPDATE`, id).Scan(&hasProducts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if hasProducts {
+		return domain.ErrCategoryNotEmpty
+	}
+	return moveToTrash(ctx, tx, "categories", id)
+}
+
+// FetchTrashed returns a page of the deleted categories
+func (m *CategoryRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	return fetchTrash(ctx, transaction.Conn(ctx, m.Conn), "categories", offset, limit)
+}
+
+// GetTrashed returns a deleted category
+func (m *CategoryRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	return getTrashed(ctx, transaction.Conn(ctx, m.Conn), "categories", id)
+}
+
+// Restore restores a deleted category, its products staying deleted
+func (m *CategoryRepository) Restore(ctx context.Context, id int) error {
+	return restoreFromTrash(ctx, transaction.Conn(ctx, m.Conn), "categories", id)
+}
+
+// Purge permanently deletes the categories deleted before, except those
+// holding products, deleted or not
+func (m *CategoryRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purgeTrash(ctx, transaction.Conn(ctx, m.Conn), "categories",
+		`SELECT 1 FROM products p WHERE p.category_id = t.id`, before)
+}
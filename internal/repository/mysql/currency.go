@@ -0,0 +1,213 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type CurrencyRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLCurrencyRepository(conn *sql.DB) *CurrencyRepository {
+	return &CurrencyRepository{conn}
+}
+
+func (m *CurrencyRepository) FetchRates(ctx context.Context) (result []domain.ExchangeRate, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, `SELECT currency, rate, updated_at FROM exchange_rates ORDER BY currency ASC`)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.ExchangeRate, 0)
+	for rows.Next() {
+		r := domain.ExchangeRate{}
+		if err := rows.Scan(&r.Currency, &r.Rate, &r.UpdatedAt); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// rate returns the exchange rate of currency, the identity rate for the catalog currency
+func (m *CurrencyRepository) rate(ctx context.Context, q querier, currency string) (domain.ExchangeRate, error) {
+	currency = domain.NormalizeCurrency(currency)
+	if currency == "" || currency == domain.DefaultCurrency {
+		return domain.CatalogRate(), nil
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT currency, rate, updated_at FROM exchange_rates WHERE currency = ?`, currency)
+	if err != nil {
+		logrus.Error(err)
+		return domain.ExchangeRate{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return domain.ExchangeRate{}, err
+		}
+		return domain.ExchangeRate{}, domain.ErrUnsupportedCurrency
+	}
+	r := domain.ExchangeRate{}
+	if err := rows.Scan(&r.Currency, &r.Rate, &r.UpdatedAt); err != nil {
+		logrus.Error(err)
+		return domain.ExchangeRate{}, err
+	}
+	return r, nil
+}
+
+// SaveRate creates or replaces the exchange rate of a currency
+func (m *CurrencyRepository) SaveRate(ctx context.Context, rate *domain.ExchangeRate) error {
+	rate.Currency = domain.NormalizeCurrency(rate.Currency)
+	if rate.Currency == domain.DefaultCurrency {
+		return domain.ErrBadRequest
+	}
+	rate.UpdatedAt = time.Now()
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO exchange_rates (currency, rate, updated_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE rate = VALUES(rate), updated_at = VALUES(updated_at)`,
+		rate.Currency, rate.Rate, rate.UpdatedAt)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (m *CurrencyRepository) DeleteRate(ctx context.Context, currency string) error {
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `DELETE FROM exchange_rates WHERE currency = ?`, domain.NormalizeCurrency(currency))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// FetchProductPrices returns the price list entries of a product
+func (m *CurrencyRepository) FetchProductPrices(ctx context.Context, productID int) (result []domain.ProductPrice, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT product_id, currency, price FROM product_prices WHERE product_id = ? ORDER BY currency ASC`, productID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.ProductPrice, 0)
+	for rows.Next() {
+		pp := domain.ProductPrice{}
+		if err := rows.Scan(&pp.ProductID, &pp.Price.Currency, &pp.Price); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, pp)
+	}
+	return result, rows.Err()
+}
+
+// SetProductPrice creates or replaces the price list entry of a product in
+// the currency of the price, which needs an exchange rate
+func (m *CurrencyRepository) SetProductPrice(ctx context.Context, price domain.ProductPrice) error {
+	if price.Price.Currency == domain.DefaultCurrency {
+		return domain.ErrBadRequest
+	}
+	if _, err := m.rate(ctx, transaction.Conn(ctx, m.Conn), price.Price.Currency); err != nil {
+		return err
+	}
+
+	var exists int
+	err := transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT 1 FROM products WHERE id = ? AND deleted_at IS NULL`, price.ProductID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO product_prices (product_id, currency, price) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE price = VALUES(price)`,
+		price.ProductID, price.Price.Currency, price.Price)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (m *CurrencyRepository) DeleteProductPrice(ctx context.Context, productID int, currency string) error {
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `DELETE FROM product_prices WHERE product_id = ? AND currency = ?`,
+		productID, domain.NormalizeCurrency(currency))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// priceBook loads the exchange rate of currency and the price list entries of the given products
+func (m *CurrencyRepository) priceBook(ctx context.Context, q querier, currency string, productIDs []int) (domain.PriceBook, error) {
+	rate, err := m.rate(ctx, q, currency)
+	if err != nil {
+		return domain.PriceBook{}, err
+	}
+	book := domain.PriceBook{Rate: rate, Prices: map[int]domain.Money{}}
+	if rate.Currency == domain.DefaultCurrency || len(productIDs) == 0 {
+		return book, nil
+	}
+
+	args := []interface{}{rate.Currency}
+	for _, id := range productIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(productIDs)), ", ")
+	rows, err := q.QueryContext(ctx,
+		`SELECT product_id, currency, price FROM product_prices WHERE currency = ? AND product_id IN (`+placeholders+`)`,
+		args...)
+	if err != nil {
+		logrus.Error(err)
+		return domain.PriceBook{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID int
+		var price domain.Money
+		if err := rows.Scan(&productID, &price.Currency, &price); err != nil {
+			logrus.Error(err)
+			return domain.PriceBook{}, err
+		}
+		book.Prices[productID] = price
+	}
+	return book, rows.Err()
+}
+
+// PriceBook returns the book pricing the given products in currency
+func (m *CurrencyRepository) PriceBook(ctx context.Context, currency string, productIDs []int) (domain.PriceBook, error) {
+	return m.priceBook(ctx, transaction.Conn(ctx, m.Conn), currency, productIDs)
+}
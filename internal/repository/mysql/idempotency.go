@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type IdempotencyRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLIdempotencyRepository(conn *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{conn}
+}
+
+func (m *IdempotencyRepository) get(ctx context.Context, key string) (*domain.IdempotencyRecord, error) {
+	record := domain.IdempotencyRecord{Key: key}
+	var headers string
+	err := transaction.Conn(ctx, m.Conn).QueryRowContext(ctx,
+		`SELECT fingerprint, COALESCE(status_code, 0), COALESCE(headers, ''), body, expires_at
+		FROM idempotency_keys WHERE idempotency_key = ?`, key).
+		Scan(&record.Fingerprint, &record.StatusCode, &headers, &record.Body, &record.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	if headers != "" {
+		if err := json.Unmarshal([]byte(headers), &record.Header); err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
+
+// Claim stores a request in flight, unless an unexpired record is stored
+// under its key, which is returned instead
+func (m *IdempotencyRepository) Claim(ctx context.Context, record domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	conn := transaction.Conn(ctx, m.Conn)
+	for {
+		_, err := conn.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE idempotency_key = ? AND expires_at <= ?`, record.Key, time.Now())
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+
+		res, err := conn.ExecContext(ctx, `INSERT INTO idempotency_keys (idempotency_key, fingerprint, expires_at) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE idempotency_key = idempotency_key`,
+			record.Key, record.Fingerprint, record.ExpiresAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		claimed, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if claimed > 0 {
+			return nil, nil
+		}
+
+		// The existing record may have been released meanwhile, claim the key again
+		existing, err := m.get(ctx, record.Key)
+		if !errors.Is(err, domain.ErrNotFound) {
+			return existing, err
+		}
+	}
+}
+
+// Complete stores the response of a claimed request
+func (m *IdempotencyRepository) Complete(ctx context.Context, record domain.IdempotencyRecord) error {
+	headers, err := json.Marshal(record.Header)
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`UPDATE idempotency_keys SET status_code = ?, headers = ?, body = ?, expires_at = ? WHERE idempotency_key = ?`,
+		record.StatusCode, string(headers), record.Body, record.ExpiresAt, record.Key)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// Renew extends the lock of a request in flight until expiresAt
+func (m *IdempotencyRepository) Renew(ctx context.Context, key string, expiresAt time.Time) error {
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`UPDATE idempotency_keys SET expires_at = ? WHERE idempotency_key = ? AND status_code IS NULL`, expiresAt, key)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (m *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE idempotency_key = ?`, key)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// DeleteExpired deletes the records expired at now, returning how many were deleted
+func (m *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, now)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
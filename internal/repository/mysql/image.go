@@ -0,0 +1,375 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type ImageRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLImageRepository(conn *sql.DB) *ImageRepository {
+	return &ImageRepository{conn}
+}
+
+func (m *ImageRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.ProductImage, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.ProductImage, 0)
+	for rows.Next() {
+		i := domain.ProductImage{}
+		err := rows.Scan(&i.ID, &i.ProductID, &i.Key, &i.URL, &i.ContentType, &i.Size, &i.Position, &i.IsPrimary, &i.CreatedAt, &i.Status)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+func (m *ImageRepository) FetchByProduct(ctx context.Context, productID int) (result []domain.ProductImage, err error) {
+	query := `SELECT id, product_id, storage_key, url, content_type, size, position, is_primary, created_at, status
+						FROM product_images
+						WHERE product_id = ?
+						ORDER BY position ASC, id ASC`
+	res, err := m.fetch(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.attachVariants(ctx, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *ImageRepository) GetByID(ctx context.Context, productID, id int) (result domain.ProductImage, err error) {
+	query := `SELECT id, product_id, storage_key, url, content_type, size, position, is_primary, created_at, status
+						FROM product_images
+						WHERE id = ? AND product_id = ?`
+	res, err := m.fetch(ctx, query, id, productID)
+	if err != nil {
+		return domain.ProductImage{}, err
+	}
+	if len(res) == 0 {
+		return domain.ProductImage{}, domain.ErrNotFound
+	}
+	if err := m.attachVariants(ctx, res); err != nil {
+		return domain.ProductImage{}, err
+	}
+	return res[0], nil
+}
+
+// attachVariants loads the responsive variants of the given images and builds their srcset
+func (m *ImageRepository) attachVariants(ctx context.Context, images []domain.ProductImage) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(images))
+	for i, image := range images {
+		index[image.ID] = i
+	}
+
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT v.id, v.image_id, v.name, v.storage_key, v.url, v.content_type, v.width, v.height, v.size
+		FROM product_image_variants v
+		JOIN product_images i ON v.image_id = i.id
+		WHERE i.product_id = ?
+		ORDER BY v.width ASC`, images[0].ProductID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		v := domain.ImageVariant{}
+		if err := rows.Scan(&v.ID, &v.ImageID, &v.Name, &v.Key, &v.URL, &v.ContentType, &v.Width, &v.Height, &v.Size); err != nil {
+			logrus.Error(err)
+			return err
+		}
+		if i, ok := index[v.ImageID]; ok {
+			images[i].Variants = append(images[i].Variants, v)
+		}
+	}
+	for i := range images {
+		images[i].SrcSet = domain.BuildSrcSet(images[i].Variants)
+	}
+	return rows.Err()
+}
+
+// FetchPendingImages returns up to limit images waiting for their variants
+func (m *ImageRepository) FetchPendingImages(ctx context.Context, limit int) (result []domain.ProductImage, err error) {
+	query := `SELECT id, product_id, storage_key, url, content_type, size, position, is_primary, created_at, status
+						FROM product_images
+						WHERE status = ?
+						ORDER BY id ASC
+						LIMIT ?`
+	return m.fetch(ctx, query, domain.ImageStatusPending, limit)
+}
+
+// SaveVariants replaces the variants of an image and marks it ready
+func (m *ImageRepository) SaveVariants(ctx context.Context, imageID int, variants []domain.ImageVariant) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM product_image_variants WHERE image_id = ?`, imageID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	for i := range variants {
+		v := &variants[i]
+		v.ImageID = imageID
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO product_image_variants (image_id, name, storage_key, url, content_type, width, height, size)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			imageID, v.Name, v.Key, v.URL, v.ContentType, v.Width, v.Height, v.Size)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		v.ID = int(id)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE product_images SET status = ? WHERE id = ?`, domain.ImageStatusReady, imageID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (m *ImageRepository) SetStatus(ctx context.Context, imageID int, status domain.ImageStatus) error {
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `UPDATE product_images SET status = ? WHERE id = ?`, status, imageID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// Create appends an image to the product images, the first image becomes the primary image
+func (m *ImageRepository) Create(ctx context.Context, image *domain.ProductImage) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// Lock the product so concurrent uploads get distinct positions
+	var productID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, image.ProductID).Scan(&productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(MAX(position), 0) + 1 FROM product_images WHERE product_id = ?`,
+		image.ProductID).Scan(&count, &image.Position)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	image.IsPrimary = count == 0
+	image.CreatedAt = time.Now()
+	image.Status = domain.ImageStatusPending
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO product_images (product_id, storage_key, url, content_type, size, position, is_primary, created_at, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		image.ProductID, image.Key, image.URL, image.ContentType, image.Size, image.Position, image.IsPrimary, image.CreatedAt, image.Status)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	image.ID = int(id)
+
+	if image.IsPrimary {
+		return m.syncProductImageURL(ctx, tx, image.ProductID)
+	}
+	return nil
+}
+
+// syncProductImageURL copies the primary image URL to products.image_url
+func (m *ImageRepository) syncProductImageURL(ctx context.Context, tx *transaction.Tx, productID int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE products SET image_url = COALESCE(
+			(SELECT url FROM product_images WHERE product_id = ? AND is_primary), '')
+		WHERE id = ?`, productID, productID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// Reorder sets the image positions following ids, which must list every image of the product
+func (m *ImageRepository) Reorder(ctx context.Context, productID int, ids []int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var count int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM product_images WHERE product_id = ? FOR UPDATE`, productID).Scan(&count)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if count != len(ids) {
+		return domain.ErrBadRequest
+	}
+
+	for position, id := range ids {
+		var exists int
+		err = tx.QueryRowContext(ctx, `SELECT 1 FROM product_images WHERE id = ? AND product_id = ?`, id, productID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrBadRequest
+		}
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE product_images SET position = ? WHERE id = ?`, position+1, id)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ImageRepository) SetPrimary(ctx context.Context, productID, id int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT 1 FROM product_images WHERE id = ? AND product_id = ? FOR UPDATE`, id, productID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// Unset first, the unique key allows a single primary image per product
+	_, err = tx.ExecContext(ctx, `UPDATE product_images SET is_primary = FALSE WHERE product_id = ? AND is_primary`, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE product_images SET is_primary = TRUE WHERE id = ?`, id)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return m.syncProductImageURL(ctx, tx, productID)
+}
+
+// Delete removes an image, promoting the first remaining image when the primary image is removed
+func (m *ImageRepository) Delete(ctx context.Context, productID, id int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var wasPrimary bool
+	err = tx.QueryRowContext(ctx, `SELECT is_primary FROM product_images WHERE id = ? AND product_id = ? FOR UPDATE`,
+		id, productID).Scan(&wasPrimary)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM product_images WHERE id = ?`, id)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if !wasPrimary {
+		return nil
+	}
+
+	var nextID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM product_images WHERE product_id = ? ORDER BY position ASC, id ASC LIMIT 1`,
+		productID).Scan(&nextID)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, `UPDATE product_images SET is_primary = TRUE WHERE id = ?`, nextID)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		logrus.Error(err)
+		return err
+	}
+	return m.syncProductImageURL(ctx, tx, productID)
+}
@@ -0,0 +1,367 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type InventoryRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLInventoryRepository(conn *sql.DB) *InventoryRepository {
+	return &InventoryRepository{conn}
+}
+
+func (m *InventoryRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.StockMovement, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.StockMovement, 0)
+	for rows.Next() {
+		s := domain.StockMovement{}
+		var variantID sql.NullInt64
+		err := rows.Scan(&s.ID, &s.ProductID, &variantID, &s.Type, &s.Quantity, &s.StockAfter, &s.Reference, &s.Note, &s.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			s.VariantID = &id
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func (m *InventoryRepository) FetchMovements(ctx context.Context, productID, offset, limit int) (total int, result []domain.StockMovement, err error) {
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT COUNT(*) FROM stock_movements WHERE product_id = ?`, productID).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	query := `SELECT id, product_id, variant_id, type, quantity, stock_after, reference, note, created_at
+						FROM stock_movements
+						WHERE product_id = ?
+						ORDER BY id DESC
+						LIMIT ? OFFSET ?`
+	result, err = m.fetch(ctx, query, productID, limit, offset)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, result, nil
+}
+
+// lockStock locks the stock row of a product or one of its variants and
+// returns the stock. Products in the trash are found, so orders cancelled
+// after their products were deleted still give the stock back.
+func (m *InventoryRepository) lockStock(ctx context.Context, tx *transaction.Tx, productID int, variantID *int) (stock int, err error) {
+	if variantID != nil {
+		err = tx.QueryRowContext(ctx, `SELECT stock FROM product_variants WHERE id = ? AND product_id = ? FOR UPDATE`,
+			*variantID, productID).Scan(&stock)
+	} else {
+		err = tx.QueryRowContext(ctx, `SELECT stock FROM products WHERE id = ? FOR UPDATE`, productID).Scan(&stock)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return stock, err
+}
+
+// lockStockOrder reports whether the stock row of a product or variant is
+// locked before the other's, so concurrent transactions locking several rows
+// can't deadlock
+func lockStockOrder(productA int, variantA *int, productB int, variantB *int) bool {
+	if productA != productB {
+		return productA < productB
+	}
+	return variantA != nil && (variantB == nil || *variantA < *variantB)
+}
+
+// available locks the stock row of a product or one of its variants and
+// returns the stock not held by the active reservations of other references
+func (m *InventoryRepository) available(ctx context.Context, tx *transaction.Tx, productID int, variantID *int, reference string, now time.Time) (int, error) {
+	stock, err := m.lockStock(ctx, tx, productID, variantID)
+	if err != nil {
+		return 0, err
+	}
+
+	var reserved int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(quantity), 0) FROM stock_reservations
+		WHERE product_id = ? AND variant_id <=> ? AND status = ? AND expires_at > ? AND reference <> ?`,
+		productID, variantID, domain.ReservationActive, now, reference).Scan(&reserved)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return stock - reserved, nil
+}
+
+// reserve holds stock for a pending checkout. The stock row is locked so
+// concurrent reservations can't both take the last units.
+func (m *InventoryRepository) reserve(ctx context.Context, tx *transaction.Tx, reservation *domain.StockReservation, now time.Time) error {
+	if reservation.Quantity <= 0 {
+		return domain.ErrBadRequest
+	}
+
+	available, err := m.available(ctx, tx, reservation.ProductID, reservation.VariantID, reservation.Reference, now)
+	if err != nil {
+		return err
+	}
+	if available < reservation.Quantity {
+		return domain.ErrInsufficientStock
+	}
+
+	reservation.Status = domain.ReservationActive
+	reservation.CreatedAt = now
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO stock_reservations (product_id, variant_id, quantity, status, reference, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		reservation.ProductID, reservation.VariantID, reservation.Quantity, reservation.Status,
+		reservation.Reference, reservation.ExpiresAt, reservation.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	reservation.ID = int(id)
+	return nil
+}
+
+// settleReservations moves the active reservations of a reference to status
+func (m *InventoryRepository) settleReservations(ctx context.Context, tx *transaction.Tx, reference string, status domain.ReservationStatus) error {
+	_, err := tx.ExecContext(ctx, `UPDATE stock_reservations SET status = ? WHERE reference = ? AND status = ?`,
+		status, reference, domain.ReservationActive)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// ExpireReservations marks active reservations past their expiry as expired
+func (m *InventoryRepository) ExpireReservations(ctx context.Context, now time.Time) (int64, error) {
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `UPDATE stock_reservations SET status = ? WHERE status = ? AND expires_at <= ?`,
+		domain.ReservationExpired, domain.ReservationActive, now)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// applyMovement locks the stock row, rejects changes that would make the
+// stock negative, rolls variant movements up to their product, then appends
+// the movement to the ledger
+func (m *InventoryRepository) applyMovement(ctx context.Context, tx *transaction.Tx, movement *domain.StockMovement) error {
+	stock, err := m.lockStock(ctx, tx, movement.ProductID, movement.VariantID)
+	if err != nil {
+		return err
+	}
+	if stock+movement.Quantity < 0 {
+		return domain.ErrInsufficientStock
+	}
+
+	if movement.VariantID != nil {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE product_variants SET stock = stock + ?, sold = GREATEST(sold + ?, 0) WHERE id = ? AND product_id = ?`,
+			movement.Quantity, movement.SoldDelta(), *movement.VariantID, movement.ProductID)
+	} else {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE products SET stock = stock + ?, sold = GREATEST(sold + ?, 0) WHERE id = ?`,
+			movement.Quantity, movement.SoldDelta(), movement.ProductID)
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if movement.VariantID != nil {
+		// The stock and sales of a product count its variants'. Stock
+		// recorded before its variants may not, hence the clamp.
+		_, err = tx.ExecContext(ctx,
+			`UPDATE products SET stock = GREATEST(stock + ?, 0), sold = GREATEST(sold + ?, 0) WHERE id = ?`,
+			movement.Quantity, movement.SoldDelta(), movement.ProductID)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	}
+	movement.StockAfter = stock + movement.Quantity
+
+	movement.CreatedAt = time.Now()
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO stock_movements (product_id, variant_id, type, quantity, stock_after, reference, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		movement.ProductID, movement.VariantID, movement.Type, movement.Quantity, movement.StockAfter,
+		movement.Reference, movement.Note, movement.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	movement.ID = int(id)
+	return recordStockDepletion(ctx, tx, movement)
+}
+
+// RecordMovement records a stock movement made by staff, deleted products
+// not being found
+func (m *InventoryRepository) RecordMovement(ctx context.Context, movement *domain.StockMovement) (err error) {
+	if err := movement.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, movement.ProductID, 0); err != nil {
+		return err
+	}
+	return m.applyMovement(ctx, tx, movement)
+}
+
+// FetchLowStock returns the products at or below their reorder threshold
+func (m *InventoryRepository) FetchLowStock(ctx context.Context) (result []domain.Product, err error) {
+	query := `SELECT p.id, COALESCE(p.sku, ''), p.name, p.currency, p.price, p.image_url, p.stock, p.sold, p.reorder_threshold, p.weight, p.length, p.width, p.height, p.category_id, c.name as category_name, p.rating_average, p.rating_count
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE p.reorder_threshold > 0 AND p.stock <= p.reorder_threshold AND p.deleted_at IS NULL
+						ORDER BY p.stock ASC, p.id ASC`
+	return NewMySQLProductRepository(m.Conn).fetch(ctx, query)
+}
+
+// SetReorderThreshold sets the reorder threshold of a product, returning the previous one
+func (m *InventoryRepository) SetReorderThreshold(ctx context.Context, productID, version, threshold int) (previous int, err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, productID, version); err != nil {
+		return 0, err
+	}
+	err = tx.QueryRowContext(ctx, `SELECT reorder_threshold FROM products WHERE id = ?`, productID).Scan(&previous)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE products SET reorder_threshold = ?, version = version + 1 WHERE id = ?`, threshold, productID)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return previous, recordProductEvent(ctx, tx, domain.EventProductUpdated, productID, "reorder_threshold")
+}
+
+// SyncLowStockAlerts resolves the alerts of replenished products and opens an
+// alert for every product which crossed its threshold without an open alert
+func (m *InventoryRepository) SyncLowStockAlerts(ctx context.Context, now time.Time) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE low_stock_alerts a
+		JOIN products p ON a.product_id = p.id
+		SET a.resolved_at = ?
+		WHERE a.resolved_at IS NULL AND (p.reorder_threshold = 0 OR p.stock > p.reorder_threshold)`, now)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// The unique key on open alerts keeps concurrent checkers from duplicating alerts
+	_, err = tx.ExecContext(ctx,
+		`INSERT IGNORE INTO low_stock_alerts (product_id, stock, threshold, created_at)
+		SELECT p.id, p.stock, p.reorder_threshold, ?
+		FROM products p
+		WHERE p.reorder_threshold > 0 AND p.stock <= p.reorder_threshold AND p.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM low_stock_alerts a WHERE a.product_id = p.id AND a.resolved_at IS NULL)`, now)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// FetchPendingAlerts returns the open alerts which haven't been notified yet
+func (m *InventoryRepository) FetchPendingAlerts(ctx context.Context) (result []domain.LowStockAlert, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT a.id, a.product_id, p.name, a.stock, a.threshold, a.created_at
+		FROM low_stock_alerts a
+		JOIN products p ON a.product_id = p.id
+		WHERE a.resolved_at IS NULL AND a.notified_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY a.id ASC`)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.LowStockAlert, 0)
+	for rows.Next() {
+		a := domain.LowStockAlert{}
+		if err := rows.Scan(&a.ID, &a.ProductID, &a.ProductName, &a.Stock, &a.Threshold, &a.CreatedAt); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+func (m *InventoryRepository) MarkAlertNotified(ctx context.Context, id int, now time.Time) error {
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `UPDATE low_stock_alerts SET notified_at = ? WHERE id = ?`, now, id)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type EmailOutboxRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLEmailOutboxRepository(conn *sql.DB) *EmailOutboxRepository {
+	return &EmailOutboxRepository{conn}
+}
+
+func (m *EmailOutboxRepository) EnqueueEmail(ctx context.Context, email *domain.OutboxEmail) error {
+	email.CreatedAt = time.Now()
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO email_outbox (recipient, subject, body, created_at) VALUES (?, ?, ?, ?)`,
+		email.Recipient, email.Subject, email.Body, email.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	email.ID = int(id)
+	return nil
+}
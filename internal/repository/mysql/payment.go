@@ -0,0 +1,171 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type PaymentRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLPaymentRepository(conn *sql.DB) *PaymentRepository {
+	return &PaymentRepository{conn}
+}
+
+func (m *PaymentRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Payment, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Payment, 0)
+	for rows.Next() {
+		pm := domain.Payment{}
+		err := rows.Scan(&pm.ID, &pm.OrderID, &pm.Provider, &pm.ExternalID, &pm.Amount.Currency, &pm.Amount, &pm.Status,
+			&pm.ClientSecret, &pm.RedirectURL, &pm.CreatedAt, &pm.UpdatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, pm)
+	}
+	return result, nil
+}
+
+func (m *PaymentRepository) FetchByOrder(ctx context.Context, orderID int) (result []domain.Payment, err error) {
+	query := `SELECT id, order_id, provider, external_id, currency, amount, status, client_secret, redirect_url, created_at, updated_at
+						FROM payments
+						WHERE order_id = ?
+						ORDER BY id ASC`
+	return m.fetch(ctx, query, orderID)
+}
+
+func (m *PaymentRepository) GetByID(ctx context.Context, id int) (result domain.Payment, err error) {
+	query := `SELECT id, order_id, provider, external_id, currency, amount, status, client_secret, redirect_url, created_at, updated_at
+						FROM payments
+						WHERE id = ?`
+	res, err := m.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+	if len(res) == 0 {
+		return domain.Payment{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+func (m *PaymentRepository) Create(ctx context.Context, payment *domain.Payment) error {
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = payment.CreatedAt
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO payments (order_id, provider, external_id, currency, amount, status, client_secret, redirect_url, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		payment.OrderID, payment.Provider, payment.ExternalID, payment.Amount.CurrencyCode(), payment.Amount, payment.Status,
+		payment.ClientSecret, payment.RedirectURL, payment.CreatedAt, payment.UpdatedAt)
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	payment.ID = int(id)
+	return nil
+}
+
+// ApplyEvent updates the payment of a provider event and moves its order
+// along. Events with an id are applied once, replays are ignored, as are
+// events that would move the payment or the order backwards.
+func (m *PaymentRepository) ApplyEvent(ctx context.Context, provider string, event domain.PaymentEvent) (err error) {
+	status, ok := event.PaymentStatus()
+	if !ok {
+		return domain.ErrBadRequest
+	}
+
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if event.ID != "" {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO payment_events (provider, event_id, type, external_id, created_at)
+			VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = id`,
+			provider, event.ID, event.Type, event.ExternalID, time.Now())
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return nil
+		}
+	}
+
+	var paymentID, orderID int
+	var current domain.PaymentStatus
+	err = tx.QueryRowContext(ctx, `SELECT id, order_id, status FROM payments WHERE provider = ? AND external_id = ? FOR UPDATE`,
+		provider, event.ExternalID).Scan(&paymentID, &orderID, &current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if !current.CanTransitionTo(status) {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE payments SET status = ?, updated_at = ? WHERE id = ?`, status, time.Now(), paymentID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	orderStatus, ok := event.OrderStatus()
+	if !ok {
+		return nil
+	}
+	var currentOrder domain.OrderStatus
+	err = tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = ? FOR UPDATE`, orderID).Scan(&currentOrder)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if !currentOrder.CanTransitionTo(orderStatus) {
+		return nil
+	}
+
+	change := domain.OrderStatusChange{To: orderStatus, Note: fmt.Sprintf("%s payment %s", provider, event.ExternalID)}
+	return NewMySQLOrderRepository(m.Conn).applyStatusChange(ctx, tx, orderID, &change)
+}
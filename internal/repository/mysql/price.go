@@ -0,0 +1,472 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type PriceRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLPriceRepository(conn *sql.DB) *PriceRepository {
+	return &PriceRepository{conn}
+}
+
+// setPrice changes the price of a product and records the change in its
+// price history, filling the old price of change
+func (m *PriceRepository) setPrice(ctx context.Context, tx *transaction.Tx, change *domain.PriceChange) error {
+	err := tx.QueryRowContext(ctx, `SELECT currency, price FROM products WHERE id = ? FOR UPDATE`, change.ProductID).
+		Scan(&change.OldPrice.Currency, &change.OldPrice)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if !change.NewPrice.SameCurrency(change.OldPrice) {
+		return domain.ErrCurrencyMismatch
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET price = ?, version = version + 1 WHERE id = ?`, change.NewPrice, change.ProductID); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if err := m.insertPriceChange(ctx, tx, change); err != nil {
+		return err
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductUpdated, change.ProductID, "price")
+}
+
+// insertPriceChange appends a change of the price of a product to its price history
+func (m *PriceRepository) insertPriceChange(ctx context.Context, tx *transaction.Tx, change *domain.PriceChange) error {
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO price_changes (product_id, currency, old_price, new_price, changed_by, schedule_id, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		change.ProductID, change.OldPrice.CurrencyCode(), change.OldPrice, change.NewPrice, change.ChangedBy,
+		change.ScheduleID, change.Note, change.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	change.ID = int(id)
+	return nil
+}
+
+// setVariantPrice changes the price override of the variant of change, nil
+// for the price of its product, and records the change of the effective
+// price of the variant in the price history, filling the prices of change
+func (m *PriceRepository) setVariantPrice(ctx context.Context, tx *transaction.Tx, change *domain.PriceChange, override *domain.Money) error {
+	var productPrice domain.Money
+	var current sql.Null[domain.Money]
+	err := tx.QueryRowContext(ctx,
+		`SELECT p.currency, p.price, v.price
+		FROM product_variants v
+		JOIN products p ON v.product_id = p.id
+		WHERE v.id = ? AND v.product_id = ?
+		FOR UPDATE`,
+		*change.VariantID, change.ProductID).Scan(domain.CurrencyColumn{&productPrice, &current.V}, &productPrice, &current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if override != nil && !override.SameCurrency(productPrice) {
+		return domain.ErrCurrencyMismatch
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE product_variants SET price = ? WHERE id = ?`, override, *change.VariantID); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	change.OldPrice = productPrice
+	if current.Valid {
+		change.OldPrice = current.V
+	}
+	change.NewPrice = productPrice
+	if override != nil {
+		change.NewPrice = *override
+		change.NewPrice.Currency = productPrice.Currency
+	}
+	if change.NewPrice.Cmp(change.OldPrice) == 0 {
+		return nil
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO price_changes (product_id, variant_id, currency, old_price, new_price, changed_by, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		change.ProductID, change.VariantID, change.OldPrice.CurrencyCode(), change.OldPrice, change.NewPrice, change.ChangedBy,
+		change.Note, change.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	change.ID = int(id)
+	return nil
+}
+
+// UpdatePrice changes the price of a product at version, 0 matching any
+// version, recording who changed it
+func (m *PriceRepository) UpdatePrice(ctx context.Context, change *domain.PriceChange, version int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, change.ProductID, version); err != nil {
+		return err
+	}
+	change.CreatedAt = time.Now()
+	return m.setPrice(ctx, tx, change)
+}
+
+// FetchHistory returns a page of the price changes of a product, latest first
+func (m *PriceRepository) FetchHistory(ctx context.Context, productID, offset, limit int) (total int, result []domain.PriceChange, err error) {
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT COUNT(*) FROM price_changes WHERE product_id = ?`, productID).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT id, product_id, variant_id, currency, old_price, new_price, changed_by, schedule_id, note, created_at
+		FROM price_changes
+		WHERE product_id = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?`, productID, limit, offset)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.PriceChange, 0)
+	for rows.Next() {
+		c := domain.PriceChange{}
+		var variantID, changedBy, scheduleID sql.NullInt64
+		err := rows.Scan(&c.ID, &c.ProductID, &variantID, domain.CurrencyColumn{&c.OldPrice, &c.NewPrice}, &c.OldPrice, &c.NewPrice,
+			&changedBy, &scheduleID, &c.Note, &c.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return 0, nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			c.VariantID = &id
+		}
+		if changedBy.Valid {
+			id := int(changedBy.Int64)
+			c.ChangedBy = &id
+		}
+		if scheduleID.Valid {
+			id := int(scheduleID.Int64)
+			c.ScheduleID = &id
+		}
+		result = append(result, c)
+	}
+	return total, result, rows.Err()
+}
+
+func (m *PriceRepository) fetchSchedules(ctx context.Context, q querier, query string, args ...interface{}) (result []domain.PriceSchedule, err error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.PriceSchedule, 0)
+	for rows.Next() {
+		s := domain.PriceSchedule{}
+		var previous sql.Null[domain.Money]
+		var endsAt sql.NullTime
+		var createdBy sql.NullInt64
+		err := rows.Scan(&s.ID, &s.ProductID, domain.CurrencyColumn{&s.Price, &previous.V}, &s.Price, &previous,
+			&s.StartsAt, &endsAt, &s.Status, &s.Note, &createdBy, &s.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if previous.Valid {
+			s.PreviousPrice = &previous.V
+		}
+		if endsAt.Valid {
+			s.EndsAt = &endsAt.Time
+		}
+		if createdBy.Valid {
+			id := int(createdBy.Int64)
+			s.CreatedBy = &id
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+const priceScheduleColumns = `id, product_id, currency, price, previous_price, starts_at, ends_at, status, note, created_by, created_at`
+
+// FetchSchedules returns the price schedules of a product by start time
+func (m *PriceRepository) FetchSchedules(ctx context.Context, productID int) ([]domain.PriceSchedule, error) {
+	return m.fetchSchedules(ctx, transaction.Conn(ctx, m.Conn), `SELECT `+priceScheduleColumns+`
+		FROM price_schedules WHERE product_id = ? ORDER BY starts_at ASC, id ASC`, productID)
+}
+
+// CreateSchedule schedules a price change of a product, conflicting with the
+// pending schedules of the product whose period overlaps
+func (m *PriceRepository) CreateSchedule(ctx context.Context, schedule *domain.PriceSchedule) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// Locking the product serializes the schedules of a product
+	var currency string
+	err = tx.QueryRowContext(ctx, `SELECT currency FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, schedule.ProductID).Scan(&currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if schedule.Price.CurrencyCode() != currency {
+		return domain.ErrCurrencyMismatch
+	}
+
+	pending, err := m.fetchSchedules(ctx, tx, `SELECT `+priceScheduleColumns+`
+		FROM price_schedules WHERE product_id = ? AND status IN (?, ?)`,
+		schedule.ProductID, domain.PriceScheduled, domain.PriceActive)
+	if err != nil {
+		return err
+	}
+	for _, s := range pending {
+		if s.Overlaps(*schedule) {
+			return domain.ErrConflict
+		}
+	}
+
+	schedule.Status = domain.PriceScheduled
+	schedule.CreatedAt = time.Now()
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO price_schedules (product_id, currency, price, starts_at, ends_at, status, note, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		schedule.ProductID, currency, schedule.Price, schedule.StartsAt, schedule.EndsAt, schedule.Status,
+		schedule.Note, schedule.CreatedBy, schedule.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	schedule.ID = int(id)
+	return nil
+}
+
+// lockSchedule returns the first schedule matching where locked for update,
+// skipping the schedules locked by other transactions, nil when none is left
+func (m *PriceRepository) lockSchedule(ctx context.Context, tx *transaction.Tx, where string, args ...interface{}) (*domain.PriceSchedule, error) {
+	res, err := m.fetchSchedules(ctx, tx, `SELECT `+priceScheduleColumns+`
+		FROM price_schedules WHERE `+where+` FOR UPDATE SKIP LOCKED`, args...)
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+	return &res[0], nil
+}
+
+// apply sets the price of a due schedule, open ended schedules complete at once
+func (m *PriceRepository) apply(ctx context.Context, tx *transaction.Tx, schedule *domain.PriceSchedule, now time.Time) error {
+	change := domain.PriceChange{
+		ProductID:  schedule.ProductID,
+		NewPrice:   schedule.Price,
+		ScheduleID: &schedule.ID,
+		Note:       schedule.Note,
+		CreatedAt:  now,
+	}
+	if err := m.setPrice(ctx, tx, &change); err != nil {
+		return err
+	}
+
+	schedule.PreviousPrice = &change.OldPrice
+	schedule.Status = domain.PriceActive
+	if schedule.EndsAt == nil {
+		schedule.Status = domain.PriceCompleted
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE price_schedules SET status = ?, previous_price = ? WHERE id = ?`,
+		schedule.Status, change.OldPrice, schedule.ID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// revert restores the price from before an active schedule and sets its
+// final status. A price changed since the schedule applied is kept, the
+// price history recording that the schedule left it alone.
+func (m *PriceRepository) revert(ctx context.Context, tx *transaction.Tx, schedule *domain.PriceSchedule, status domain.PriceScheduleStatus, now time.Time) error {
+	change := domain.PriceChange{
+		ProductID:  schedule.ProductID,
+		NewPrice:   *schedule.PreviousPrice,
+		ScheduleID: &schedule.ID,
+		Note:       "price schedule " + string(status),
+		CreatedAt:  now,
+	}
+
+	var current domain.Money
+	err := tx.QueryRowContext(ctx, `SELECT currency, price FROM products WHERE id = ? FOR UPDATE`, schedule.ProductID).
+		Scan(&current.Currency, &current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if current.SameCurrency(schedule.Price) && current.Cmp(schedule.Price) == 0 {
+		err = m.setPrice(ctx, tx, &change)
+	} else {
+		change.OldPrice, change.NewPrice = current, current
+		change.Note += ", price kept as it changed since the schedule applied"
+		err = m.insertPriceChange(ctx, tx, &change)
+	}
+	if err != nil {
+		return err
+	}
+
+	schedule.Status = status
+	_, err = tx.ExecContext(ctx, `UPDATE price_schedules SET status = ? WHERE id = ?`, schedule.Status, schedule.ID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// CancelSchedule cancels a pending schedule of a product, an active schedule
+// restores the price from before it unless the price changed since
+func (m *PriceRepository) CancelSchedule(ctx context.Context, productID, id int) (schedule domain.PriceSchedule, err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return domain.PriceSchedule{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := m.fetchSchedules(ctx, tx, `SELECT `+priceScheduleColumns+`
+		FROM price_schedules WHERE id = ? AND product_id = ? FOR UPDATE`, id, productID)
+	if err != nil {
+		return domain.PriceSchedule{}, err
+	}
+	if len(res) == 0 {
+		return domain.PriceSchedule{}, domain.ErrNotFound
+	}
+	schedule = res[0]
+
+	switch schedule.Status {
+	case domain.PriceScheduled:
+		schedule.Status = domain.PriceCancelled
+		_, err = tx.ExecContext(ctx, `UPDATE price_schedules SET status = ? WHERE id = ?`, schedule.Status, schedule.ID)
+		if err != nil {
+			logrus.Error(err)
+		}
+	case domain.PriceActive:
+		err = m.revert(ctx, tx, &schedule, domain.PriceCancelled, time.Now())
+	default:
+		err = domain.ErrInvalidTransition
+	}
+	if err != nil {
+		return domain.PriceSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// ApplyPriceSchedules reverts the active schedules which ended and applies
+// the schedules which started by now, one schedule per transaction. Rows
+// locked by another instance are skipped, so several instances can run it.
+func (m *PriceRepository) ApplyPriceSchedules(ctx context.Context, now time.Time) (applied, reverted int, err error) {
+	revert := func(tx *transaction.Tx, schedule *domain.PriceSchedule) error {
+		return m.revert(ctx, tx, schedule, domain.PriceCompleted, now)
+	}
+	apply := func(tx *transaction.Tx, schedule *domain.PriceSchedule) error {
+		return m.apply(ctx, tx, schedule, now)
+	}
+
+	reverted, err = m.drain(ctx, `status = ? AND ends_at <= ? ORDER BY ends_at ASC LIMIT 1`, domain.PriceActive, now, revert)
+	if err != nil {
+		return 0, reverted, err
+	}
+	applied, err = m.drain(ctx, `status = ? AND starts_at <= ? ORDER BY starts_at ASC LIMIT 1`, domain.PriceScheduled, now, apply)
+	return applied, reverted, err
+}
+
+// drain runs fn on every schedule matching where, returning how many it processed
+func (m *PriceRepository) drain(ctx context.Context, where string, status domain.PriceScheduleStatus, now time.Time, fn func(*transaction.Tx, *domain.PriceSchedule) error) (n int, err error) {
+	for {
+		done, err := m.step(ctx, where, status, now, fn)
+		if err != nil || done {
+			return n, err
+		}
+		n++
+	}
+}
+
+// step runs fn on the next schedule matching where in its own transaction,
+// reporting done when no schedule is left
+func (m *PriceRepository) step(ctx context.Context, where string, status domain.PriceScheduleStatus, now time.Time, fn func(*transaction.Tx, *domain.PriceSchedule) error) (done bool, err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil || done {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	schedule, err := m.lockSchedule(ctx, tx, where, status, now)
+	if err != nil {
+		return false, err
+	}
+	if schedule == nil {
+		return true, nil
+	}
+	return false, fn(tx, schedule)
+}
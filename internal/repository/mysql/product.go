@@ -0,0 +1,339 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type ProductRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLProductRepository(conn *sql.DB) *ProductRepository {
+	return &ProductRepository{conn}
+}
+
+func (m *ProductRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Product, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Product, 0)
+	for rows.Next() {
+		p := domain.Product{}
+		// Scan the currency before the price, amounts are read in the currency already set
+		err := rows.Scan(&p.ID, &p.SKU, &p.Name, &p.Price.Currency, &p.Price, &p.ImageURL, &p.Stock, &p.Sold, &p.ReorderThreshold, &p.Weight, &p.Length, &p.Width, &p.Height, &p.Category.ID, &p.Category.Name, &p.Rating.Average, &p.Rating.Count)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func (m *ProductRepository) Fetch(ctx context.Context) (result []domain.Product, err error) {
+	query := `SELECT p.id, COALESCE(p.sku, ''), p.name, p.currency, p.price, p.image_url, p.stock, p.sold, p.reorder_threshold, p.weight, p.length, p.width, p.height, p.category_id, c.name as category_name, p.rating_average, p.rating_count
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE p.deleted_at IS NULL
+						ORDER BY p.id ASC`
+
+	res, err := m.fetch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// productConditions returns the WHERE clause on products p matching filter.
+// Deleted products never match.
+func productConditions(filter domain.ProductFilter) (string, []interface{}) {
+	conditions := []string{"p.deleted_at IS NULL"}
+	args := []interface{}{}
+	if filter.CategoryID != 0 {
+		conditions = append(conditions, "p.category_id = ?")
+		args = append(args, filter.CategoryID)
+	}
+	if filter.Search != "" {
+		// The default collation compares case insensitively
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		conditions = append(conditions, "(p.name LIKE ? OR p.sku LIKE ?)")
+		args = append(args, pattern, pattern)
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "p.currency = ? AND p.price >= ?")
+		args = append(args, filter.MinPrice.CurrencyCode(), *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "p.currency = ? AND p.price <= ?")
+		args = append(args, filter.MaxPrice.CurrencyCode(), *filter.MaxPrice)
+	}
+	if filter.InStock {
+		conditions = append(conditions, "p.stock > 0")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (m *ProductRepository) FetchPaginated(ctx context.Context, filter domain.ProductFilter, offset, limit int) (total int, products []domain.Product, err error) {
+	where, args := productConditions(filter)
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, "SELECT COUNT(*) FROM products p WHERE "+where, args...).Scan(&total)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT
+			p.id,
+			COALESCE(p.sku, ''),
+			p.name,
+			p.currency,
+			p.price,
+			p.category_id,
+			p.stock,
+			p.sold,
+			p.reorder_threshold,
+			p.weight,
+			p.length,
+			p.width,
+			p.height,
+			p.image_url,
+			c.name as category_name,
+			p.rating_average,
+			p.rating_count
+			FROM
+					products p
+			JOIN
+					categories c
+			ON
+					p.category_id = c.id
+			WHERE
+					`+where+`
+			ORDER BY
+					p.id ASC
+			LIMIT
+					?
+			OFFSET
+					?;`, append(args, limit, offset)...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product domain.Product
+		if err := rows.Scan(&product.ID, &product.SKU, &product.Name, &product.Price.Currency, &product.Price, &product.Category.ID, &product.Stock, &product.Sold, &product.ReorderThreshold, &product.Weight, &product.Length, &product.Width, &product.Height, &product.ImageURL, &product.Category.Name, &product.Rating.Average, &product.Rating.Count); err != nil {
+			return 0, nil, err
+		}
+		products = append(products, product)
+	}
+
+	return total, products, nil
+}
+
+// Export calls fn with every product matching filter, with its description,
+// reading them one row at a time instead of loading the catalog in memory
+func (m *ProductRepository) Export(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error {
+	where, args := productConditions(filter)
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT p.id, COALESCE(p.sku, ''), p.name, COALESCE(p.description, ''), p.currency, p.price, p.image_url, p.stock, p.sold, p.reorder_threshold, p.weight, p.length, p.width, p.height, p.category_id, c.name as category_name, p.rating_average, p.rating_count
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE `+where+`
+						ORDER BY p.id ASC`, args...)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product := domain.Product{}
+		err := rows.Scan(&product.ID, &product.SKU, &product.Name, &product.Description, &product.Price.Currency, &product.Price, &product.ImageURL, &product.Stock, &product.Sold, &product.ReorderThreshold,
+			&product.Weight, &product.Length, &product.Width, &product.Height, &product.Category.ID, &product.Category.Name, &product.Rating.Average, &product.Rating.Count)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (m *ProductRepository) GetByID(ctx context.Context, id int) (result domain.Product, err error) {
+	query := `SELECT p.id, COALESCE(p.sku, ''), p.name, p.currency, p.price, p.image_url, p.stock, p.sold, p.reorder_threshold, p.weight, p.length, p.width, p.height, p.category_id, c.name as category_name, p.rating_average, p.rating_count
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE p.id = ? AND p.deleted_at IS NULL`
+	// Read the version first, an edit made meanwhile then fails the
+	// conditional requests based on the product returned
+	var version int
+	var description string
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT version, COALESCE(description, '') FROM products WHERE id = ? AND deleted_at IS NULL`, id).
+		Scan(&version, &description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Product{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Product{}, err
+	}
+
+	res, err := m.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if len(res) == 0 {
+		return domain.Product{}, domain.ErrNotFound
+	}
+	res[0].Version = version
+	res[0].Description = description
+
+	// Load the option types, variants and images of the product
+	product := res[0]
+	variants := NewMySQLVariantRepository(m.Conn)
+	product.Options, err = variants.FetchOptions(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	product.Variants, err = variants.FetchByProduct(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	product.Images, err = NewMySQLImageRepository(m.Conn).FetchByProduct(ctx, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+
+	return product, nil
+}
+
+// checkVersion locks a product until the end of tx, failing with
+// domain.ErrVersionMismatch unless it is at version, 0 matching any version.
+// Deleted products aren't found.
+func checkVersion(ctx context.Context, tx *transaction.Tx, productID, version int) error {
+	return checkRowVersion(ctx, tx, "products", productID, version)
+}
+
+func (m *ProductRepository) UpdateShippingDetails(ctx context.Context, productID, version int, details domain.ShippingDetails) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, productID, version); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE products SET weight = ?, length = ?, width = ?, height = ?, version = version + 1 WHERE id = ?`,
+		details.Weight, details.Length, details.Width, details.Height, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductUpdated, productID, "weight", "length", "width", "height")
+}
+
+// Delete moves a product to the trash. It stays in the orders and carts
+// holding it, carts hiding it.
+func (m *ProductRepository) Delete(ctx context.Context, productID, version int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, productID, version); err != nil {
+		return err
+	}
+	if err = moveToTrash(ctx, tx, "products", productID); err != nil {
+		return err
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductDeleted, productID)
+}
+
+// FetchTrashed returns a page of the deleted products
+func (m *ProductRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	return fetchTrash(ctx, transaction.Conn(ctx, m.Conn), "products", offset, limit)
+}
+
+// GetTrashed returns a deleted product
+func (m *ProductRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	return getTrashed(ctx, transaction.Conn(ctx, m.Conn), "products", id)
+}
+
+// Restore restores a deleted product, failing with domain.ErrCategoryDeleted
+// while its category is deleted
+func (m *ProductRepository) Restore(ctx context.Context, productID int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// Lock the category so it isn't deleted meanwhile
+	var categoryDeleted bool
+	err = tx.QueryRowContext(ctx, `SELECT c.deleted_at IS NOT NULL
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.id = ? AND p.deleted_at IS NOT NULL
+		FOR SHARE`, productID).Scan(&categoryDeleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if categoryDeleted {
+		return domain.ErrCategoryDeleted
+	}
+	if err = restoreFromTrash(ctx, tx, "products", productID); err != nil {
+		return err
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductRestored, productID)
+}
+
+// Purge permanently deletes the products deleted before, except those ordered
+func (m *ProductRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purgeTrash(ctx, transaction.Conn(ctx, m.Conn), "products",
+		`SELECT 1 FROM order_items oi WHERE oi.product_id = t.id`, before)
+}
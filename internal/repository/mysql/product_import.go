@@ -0,0 +1,252 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type ImportRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLImportRepository(conn *sql.DB) *ImportRepository {
+	return &ImportRepository{conn}
+}
+
+func (m *ImportRepository) CreateImportJob(ctx context.Context, job *domain.ImportJob) error {
+	job.CreatedAt = time.Now()
+	if job.Errors == nil {
+		job.Errors = []domain.ImportRowError{}
+	}
+	errs, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO import_jobs (filename, status, total_rows, failed_rows, errors, error, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.Filename, job.Status, job.TotalRows, job.FailedRows, string(errs), job.Error, job.CreatedBy, job.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	job.ID = int(id)
+	return nil
+}
+
+func (m *ImportRepository) GetImportJob(ctx context.Context, id int) (job domain.ImportJob, err error) {
+	var errs string
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx,
+		`SELECT id, filename, status, total_rows, processed_rows, created_rows, updated_rows, failed_rows, errors, error, created_by, created_at, finished_at
+		FROM import_jobs WHERE id = ?`, id).
+		Scan(&job.ID, &job.Filename, &job.Status, &job.TotalRows, &job.ProcessedRows, &job.CreatedRows, &job.UpdatedRows,
+			&job.FailedRows, &errs, &job.Error, &job.CreatedBy, &job.CreatedAt, &job.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ImportJob{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.ImportJob{}, err
+	}
+	if err := json.Unmarshal([]byte(errs), &job.Errors); err != nil {
+		return domain.ImportJob{}, err
+	}
+	return job, nil
+}
+
+// UpdateImportJob saves the progress of a job
+func (m *ImportRepository) UpdateImportJob(ctx context.Context, job *domain.ImportJob) error {
+	errs, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`UPDATE import_jobs SET status = ?, processed_rows = ?, created_rows = ?, updated_rows = ?, failed_rows = ?,
+		errors = ?, error = ?, finished_at = ?
+		WHERE id = ?`,
+		job.Status, job.ProcessedRows, job.CreatedRows, job.UpdatedRows, job.FailedRows, string(errs), job.Error, job.FinishedAt, job.ID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// ImportProducts upserts a chunk of imported rows by SKU in a single
+// transaction, creating the categories missing by name. Rows whose price is
+// in another currency than the existing product are rejected.
+func (m *ImportRepository) ImportProducts(ctx context.Context, job *domain.ImportJob, rows []domain.ProductImportRow) (created, updated int, rowErrs []domain.ImportRowError, err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	categories := map[string]int{}
+	for _, row := range rows {
+		categoryID, err := m.category(ctx, tx, categories, row.Category)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		isNew, err := m.upsert(ctx, tx, job, row, categoryID)
+		switch {
+		case errors.Is(err, domain.ErrCurrencyMismatch):
+			rowErrs = append(rowErrs, domain.ImportRowError{Row: row.Row, Column: "price", Message: "must be in the currency of the product"})
+		case err != nil:
+			return 0, 0, nil, err
+		case isNew:
+			created++
+		default:
+			updated++
+		}
+	}
+	return created, updated, rowErrs, nil
+}
+
+// category returns the id of the category named name, ignoring case, creating
+// it when missing. Ids are cached in categories along a chunk.
+func (m *ImportRepository) category(ctx context.Context, tx *transaction.Tx, categories map[string]int, name string) (int, error) {
+	key := strings.ToLower(name)
+	if id, ok := categories[key]; ok {
+		return id, nil
+	}
+
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM categories WHERE LOWER(name) = ? AND deleted_at IS NULL ORDER BY id ASC LIMIT 1`, key).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		id, err = m.createCategory(ctx, tx, name)
+	}
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	categories[key] = id
+	return id, nil
+}
+
+func (m *ImportRepository) createCategory(ctx context.Context, tx *transaction.Tx, name string) (int, error) {
+	res, err := tx.ExecContext(ctx, `INSERT INTO categories (name) VALUES (?)`, name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// upsert creates the product of a row or updates the product with its SKU,
+// restoring it when deleted. Price and stock changes go through the price
+// history and the stock ledger.
+func (m *ImportRepository) upsert(ctx context.Context, tx *transaction.Tx, job *domain.ImportJob, row domain.ProductImportRow, categoryID int) (created bool, err error) {
+	var id, stock int
+	var price domain.Money
+	err = tx.QueryRowContext(ctx, `SELECT id, stock, currency, price FROM products WHERE sku = ? FOR UPDATE`, row.SKU).
+		Scan(&id, &stock, &price.Currency, &price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, m.insert(ctx, tx, job, row, categoryID)
+	}
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	if !row.Price.SameCurrency(price) {
+		return false, domain.ErrCurrencyMismatch
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE products SET name = ?, category_id = ?, description = COALESCE(?, description), image_url = COALESCE(?, image_url),
+		weight = COALESCE(?, weight), length = COALESCE(?, length), width = COALESCE(?, width), height = COALESCE(?, height), version = version + 1,
+		deleted_at = NULL
+		WHERE id = ?`,
+		row.Name, categoryID, row.Description, row.ImageURL, row.Weight, row.Length, row.Width, row.Height, id)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	err = recordProductEvent(ctx, tx, domain.EventProductUpdated, id,
+		"name", "category_id", "description", "image_url", "weight", "length", "width", "height")
+	if err != nil {
+		return false, err
+	}
+
+	if row.Price.Cmp(price) != 0 {
+		change := &domain.PriceChange{
+			ProductID: id,
+			NewPrice:  row.Price,
+			ChangedBy: job.CreatedBy,
+			Note:      fmt.Sprintf("product import %d", job.ID),
+			CreatedAt: time.Now(),
+		}
+		if err := NewMySQLPriceRepository(m.Conn).setPrice(ctx, tx, change); err != nil {
+			return false, err
+		}
+	}
+	if row.Stock != nil && *row.Stock != stock {
+		return false, m.moveStock(ctx, tx, job, id, domain.StockMovementAdjustment, *row.Stock-stock)
+	}
+	return false, nil
+}
+
+func (m *ImportRepository) insert(ctx context.Context, tx *transaction.Tx, job *domain.ImportJob, row domain.ProductImportRow, categoryID int) error {
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO products (sku, name, category_id, currency, price, description, image_url, weight, length, width, height)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		row.SKU, row.Name, categoryID, row.Price.CurrencyCode(), row.Price, row.Description, valueOrZero(row.ImageURL),
+		valueOrZero(row.Weight), valueOrZero(row.Length), valueOrZero(row.Width), valueOrZero(row.Height))
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := recordProductEvent(ctx, tx, domain.EventProductCreated, int(id)); err != nil {
+		return err
+	}
+
+	// Products start out of stock, the initial stock is recorded as a restock
+	if row.Stock != nil && *row.Stock > 0 {
+		return m.moveStock(ctx, tx, job, int(id), domain.StockMovementRestock, *row.Stock)
+	}
+	return nil
+}
+
+func (m *ImportRepository) moveStock(ctx context.Context, tx *transaction.Tx, job *domain.ImportJob, productID int, movementType domain.StockMovementType, quantity int) error {
+	return NewMySQLInventoryRepository(m.Conn).applyMovement(ctx, tx, &domain.StockMovement{
+		ProductID: productID,
+		Type:      movementType,
+		Quantity:  quantity,
+		Reference: fmt.Sprintf("import-%d", job.ID),
+		Note:      "product import",
+	})
+}
+
+// valueOrZero returns the value pointed by v, the zero value when nil
+func valueOrZero[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type PromotionRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLPromotionRepository(conn *sql.DB) *PromotionRepository {
+	return &PromotionRepository{conn}
+}
+
+const promotionColumns = `id, name, COALESCE(code, ''), type, percentage, amount, min_subtotal, category_id, usage_limit_per_user,
+	stackable, priority, active, starts_at, ends_at, created_at`
+
+func (m *PromotionRepository) fetch(ctx context.Context, q querier, query string, args ...interface{}) (result []domain.Promotion, err error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Promotion, 0)
+	for rows.Next() {
+		pr := domain.Promotion{}
+		var categoryID sql.NullInt64
+		var startsAt, endsAt sql.NullTime
+		err := rows.Scan(&pr.ID, &pr.Name, &pr.Code, &pr.Type, &pr.Percentage, &pr.Amount, &pr.MinSubtotal, &categoryID, &pr.UsageLimitPerUser,
+			&pr.Stackable, &pr.Priority, &pr.Active, &startsAt, &endsAt, &pr.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if categoryID.Valid {
+			id := int(categoryID.Int64)
+			pr.CategoryID = &id
+		}
+		if startsAt.Valid {
+			pr.StartsAt = &startsAt.Time
+		}
+		if endsAt.Valid {
+			pr.EndsAt = &endsAt.Time
+		}
+		result = append(result, pr)
+	}
+	return result, rows.Err()
+}
+
+// fetchActive returns the promotions enabled and within their validity window at now
+func (m *PromotionRepository) fetchActive(ctx context.Context, q querier, now time.Time) ([]domain.Promotion, error) {
+	query := `SELECT ` + promotionColumns + `
+						FROM promotions
+						WHERE active AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)`
+	return m.fetch(ctx, q, query, now, now)
+}
+
+func (m *PromotionRepository) Fetch(ctx context.Context) (result []domain.Promotion, err error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions ORDER BY id ASC`
+	return m.fetch(ctx, transaction.Conn(ctx, m.Conn), query)
+}
+
+func (m *PromotionRepository) GetByID(ctx context.Context, id int) (result domain.Promotion, err error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = ?`
+	res, err := m.fetch(ctx, transaction.Conn(ctx, m.Conn), query, id)
+	if err != nil {
+		return domain.Promotion{}, err
+	}
+	if len(res) == 0 {
+		return domain.Promotion{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+// nullableCode stores automatic promotions with a NULL code, codes are unique
+func nullableCode(code string) interface{} {
+	if code == "" {
+		return nil
+	}
+	return code
+}
+
+func (m *PromotionRepository) Create(ctx context.Context, promotion *domain.Promotion) error {
+	promotion.CreatedAt = time.Now()
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO promotions (name, code, type, percentage, amount, min_subtotal, category_id, usage_limit_per_user,
+			stackable, priority, active, starts_at, ends_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		promotion.Name, nullableCode(promotion.Code), promotion.Type, promotion.Percentage, promotion.Amount, promotion.MinSubtotal,
+		promotion.CategoryID, promotion.UsageLimitPerUser, promotion.Stackable, promotion.Priority, promotion.Active,
+		promotion.StartsAt, promotion.EndsAt, promotion.CreatedAt)
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	promotion.ID = int(id)
+	return nil
+}
+
+func (m *PromotionRepository) Update(ctx context.Context, promotion *domain.Promotion) error {
+	// MySQL reports zero affected rows when nothing changed, so check existence first
+	err := transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT created_at FROM promotions WHERE id = ?`, promotion.ID).Scan(&promotion.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`UPDATE promotions SET name = ?, code = ?, type = ?, percentage = ?, amount = ?, min_subtotal = ?, category_id = ?,
+			usage_limit_per_user = ?, stackable = ?, priority = ?, active = ?, starts_at = ?, ends_at = ?
+		WHERE id = ?`,
+		promotion.Name, nullableCode(promotion.Code), promotion.Type, promotion.Percentage, promotion.Amount, promotion.MinSubtotal,
+		promotion.CategoryID, promotion.UsageLimitPerUser, promotion.Stackable, promotion.Priority, promotion.Active,
+		promotion.StartsAt, promotion.EndsAt, promotion.ID)
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (m *PromotionRepository) Delete(ctx context.Context, id int) error {
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `DELETE FROM promotions WHERE id = ?`, id)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// usage returns the number of redemptions of each promotion by a user
+func (m *PromotionRepository) usage(ctx context.Context, q querier, userID int) (result map[int]int, err error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT promotion_id, COUNT(*) FROM promotion_redemptions WHERE user_id = ? GROUP BY promotion_id`, userID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = map[int]int{}
+	for rows.Next() {
+		var promotionID, count int
+		if err := rows.Scan(&promotionID, &count); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result[promotionID] = count
+	}
+	return result, rows.Err()
+}
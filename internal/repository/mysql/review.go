@@ -0,0 +1,187 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type ReviewRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLReviewRepository(conn *sql.DB) *ReviewRepository {
+	return &ReviewRepository{conn}
+}
+
+func (m *ReviewRepository) fetch(ctx context.Context, q querier, query string, args ...interface{}) (result []domain.Review, err error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.Review, 0)
+	for rows.Next() {
+		r := domain.Review{}
+		err := rows.Scan(&r.ID, &r.ProductID, &r.UserID, &r.Rating, &r.Title, &r.Body, &r.VerifiedPurchase, &r.Status, &r.CreatedAt, &r.UpdatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func (m *ReviewRepository) Fetch(ctx context.Context, filter domain.ReviewFilter, offset, limit int) (total int, result []domain.Review, err error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	if filter.ProductID != 0 {
+		conditions = append(conditions, "product_id = ?")
+		args = append(args, filter.ProductID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT COUNT(*) FROM reviews WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	query := `SELECT id, product_id, user_id, rating, title, body, verified_purchase, status, created_at, updated_at
+						FROM reviews
+						WHERE ` + where + `
+						ORDER BY id DESC
+						LIMIT ? OFFSET ?`
+	result, err = m.fetch(ctx, transaction.Conn(ctx, m.Conn), query, append(args, limit, offset)...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, result, nil
+}
+
+func (m *ReviewRepository) getByID(ctx context.Context, q querier, id int, lock string) (domain.Review, error) {
+	res, err := m.fetch(ctx, q, `SELECT id, product_id, user_id, rating, title, body, verified_purchase, status, created_at, updated_at
+						FROM reviews WHERE id = ?`+lock, id)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if len(res) == 0 {
+		return domain.Review{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+func (m *ReviewRepository) GetByID(ctx context.Context, id int) (domain.Review, error) {
+	return m.getByID(ctx, transaction.Conn(ctx, m.Conn), id, "")
+}
+
+// Create stores a pending review, flagged as a verified purchase when the
+// user has a paid order of the product. A user reviews a product once.
+func (m *ReviewRepository) Create(ctx context.Context, review *domain.Review) error {
+	var exists int
+	err := transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT 1 FROM products WHERE id = ? AND deleted_at IS NULL`, review.ProductID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	args := []interface{}{review.UserID, review.ProductID}
+	for _, status := range domain.ReviewedOrderStatuses {
+		args = append(args, status)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(domain.ReviewedOrderStatuses)), ", ")
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.user_id = ? AND oi.product_id = ? AND o.status IN (`+placeholders+`)
+		)`, args...).Scan(&review.VerifiedPurchase)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	review.Status = domain.ReviewPending
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO reviews (product_id, user_id, rating, title, body, verified_purchase, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		review.ProductID, review.UserID, review.Rating, review.Title, review.Body, review.VerifiedPurchase,
+		review.Status, review.CreatedAt, review.UpdatedAt)
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	review.ID = int(id)
+	return nil
+}
+
+// UpdateStatus moderates a review and refreshes the rating of its product
+func (m *ReviewRepository) UpdateStatus(ctx context.Context, id int, status domain.ReviewStatus) (review domain.Review, err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// MySQL reports zero affected rows when nothing changed, so load the review first
+	review, err = m.getByID(ctx, tx, id, " FOR UPDATE")
+	if err != nil {
+		return domain.Review{}, err
+	}
+	review.Status = status
+	review.UpdatedAt = time.Now()
+	_, err = tx.ExecContext(ctx, `UPDATE reviews SET status = ?, updated_at = ? WHERE id = ?`, review.Status, review.UpdatedAt, id)
+	if err != nil {
+		logrus.Error(err)
+		return domain.Review{}, err
+	}
+
+	if err = m.refreshRating(ctx, tx, review.ProductID); err != nil {
+		return domain.Review{}, err
+	}
+	return review, nil
+}
+
+// refreshRating recomputes the rating of a product from its approved reviews
+func (m *ReviewRepository) refreshRating(ctx context.Context, tx *transaction.Tx, productID int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE products SET
+			rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE product_id = ? AND status = ?), 0),
+			rating_count = (SELECT COUNT(*) FROM reviews WHERE product_id = ? AND status = ?)
+		WHERE id = ?`, productID, domain.ReviewApproved, productID, domain.ReviewApproved, productID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
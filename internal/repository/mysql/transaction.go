@@ -0,0 +1,22 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/go-sql-driver/mysql"
+)
+
+// deadlock is the MySQL error number of transactions rolled back to resolve a deadlock
+const deadlock = 1213
+
+func isDeadlock(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == deadlock
+}
+
+// NewMySQLTransactionManager creates a transaction manager retrying deadlocks
+func NewMySQLTransactionManager(conn *sql.DB) *transaction.Manager {
+	return transaction.NewManager(conn, isDeadlock)
+}
@@ -3,8 +3,11 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,7 +21,7 @@ func NewMySQLUserRepository(conn *sql.DB) *UserRepository {
 }
 
 func (m *UserRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.User, err error) {
-	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -33,7 +36,7 @@ func (m *UserRepository) fetch(ctx context.Context, query string, args ...interf
 	result = make([]domain.User, 0)
 	for rows.Next() {
 		u := domain.User{}
-		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role)
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Version)
 		if err != nil {
 			logrus.Error(err)
 			return nil, err
@@ -44,7 +47,7 @@ func (m *UserRepository) fetch(ctx context.Context, query string, args ...interf
 }
 
 func (m *UserRepository) Fetch(ctx context.Context) (result []domain.User, err error) {
-	query := "SELECT id, name, email, role FROM users"
+	query := "SELECT id, name, email, role, version FROM users WHERE deleted_at IS NULL"
 	res, err := m.fetch(ctx, query)
 	if err != nil {
 		return nil, err
@@ -52,26 +55,63 @@ func (m *UserRepository) Fetch(ctx context.Context) (result []domain.User, err e
 	return res, nil
 }
 
-func (m *UserRepository) Create(ctx context.Context, user domain.User) error {
+// Create registers a user, raising a user.registered event
+func (m *UserRepository) Create(ctx context.Context, user domain.User) (err error) {
 	query := `
 		INSERT INTO users (name, email, password, role)
 		VALUES (?, ?, ?, ?)
 	`
-	var createdUser domain.User
 	if err := user.HashPassword(); err != nil {
 		return err
 	}
 
-	err := m.Conn.QueryRowContext(ctx, query, user.Name, user.Email, user.Password, user.Role).
-		Scan(&createdUser.ID, &createdUser.Name, &createdUser.Email, &createdUser.Role)
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, query, user.Name, user.Email, user.Password, user.Role)
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
 	if err != nil {
 		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
 	}
-	return nil
+	user.ID = int(id)
+	return recordEvent(ctx, tx, domain.EventUserRegistered, domain.EventKey("user", user.ID), domain.UserRegistered{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+		Role:   user.Role,
+	})
+}
+
+// GetByID returns the user of id
+func (m *UserRepository) GetByID(ctx context.Context, id int) (result domain.User, err error) {
+	res, err := m.fetch(ctx, `SELECT id, name, email, role, version FROM users WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if len(res) == 0 {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return res[0], nil
 }
 
 func (m *UserRepository) GetByEmail(ctx context.Context, email string) (result domain.User, err error) {
-	query := `SELECT id, name, email, role FROM users WHERE email = ?`
+	query := `SELECT id, name, email, role, version FROM users WHERE email = ? AND deleted_at IS NULL`
 	res, err := m.fetch(ctx, query, email)
 	if err != nil {
 		return domain.User{}, err
@@ -81,3 +121,61 @@ func (m *UserRepository) GetByEmail(ctx context.Context, email string) (result d
 	}
 	return res[0], nil
 }
+
+// GetCredentials returns the user with the given email along with its hashed
+// password. Deleted users aren't found, so they can't log in.
+func (m *UserRepository) GetCredentials(ctx context.Context, email string) (result domain.User, err error) {
+	query := `SELECT id, name, email, role, password FROM users WHERE email = ? AND deleted_at IS NULL`
+	err = transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, query, email).Scan(&result.ID, &result.Name, &result.Email, &result.Role, &result.Password)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.User{}, err
+	}
+	return result, nil
+}
+
+// Delete moves a user to the trash, its orders are kept. It fails with
+// domain.ErrVersionMismatch unless the user is at version, 0 matching any
+// version.
+func (m *UserRepository) Delete(ctx context.Context, id, version int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkRowVersion(ctx, tx, "users", id, version); err != nil {
+		return err
+	}
+	return moveToTrash(ctx, tx, "users", id)
+}
+
+// FetchTrashed returns a page of the deleted users
+func (m *UserRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	return fetchTrash(ctx, transaction.Conn(ctx, m.Conn), "users", offset, limit)
+}
+
+// GetTrashed returns a deleted user
+func (m *UserRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	return getTrashed(ctx, transaction.Conn(ctx, m.Conn), "users", id)
+}
+
+// Restore restores a deleted user
+func (m *UserRepository) Restore(ctx context.Context, id int) error {
+	return restoreFromTrash(ctx, transaction.Conn(ctx, m.Conn), "users", id)
+}
+
+// Purge permanently deletes the users deleted before, except those with orders
+func (m *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purgeTrash(ctx, transaction.Conn(ctx, m.Conn), "users",
+		`SELECT 1 FROM orders o WHERE o.user_id = t.id`, before)
+}
@@ -0,0 +1,437 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/go-sql-driver/mysql"
+	"github.com/sirupsen/logrus"
+)
+
+// duplicateEntry is the MySQL error number for unique key violations
+const duplicateEntry = 1062
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntry
+}
+
+type VariantRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLVariantRepository(conn *sql.DB) *VariantRepository {
+	return &VariantRepository{conn}
+}
+
+func (m *VariantRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.ProductVariant, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.ProductVariant, 0)
+	for rows.Next() {
+		v := domain.ProductVariant{}
+		// The price override is in the currency of the product, scanned first
+		var price sql.Null[domain.Money]
+		err := rows.Scan(&v.ID, &v.ProductID, &v.SKU, &price.V.Currency, &price, &v.Stock, &v.Sold)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if price.Valid {
+			v.Price = &price.V
+		}
+		v.Options = make([]domain.ProductOptionValue, 0)
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+// attachOptionValues loads the option values of the given variants of a product
+func (m *VariantRepository) attachOptionValues(ctx context.Context, productID int, variants []domain.ProductVariant) error {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	query := `SELECT vov.variant_id, ov.id, ov.option_id, o.name, ov.value
+						FROM product_variant_option_values vov
+						JOIN product_option_values ov ON vov.option_value_id = ov.id
+						JOIN product_options o ON ov.option_id = o.id
+						WHERE o.product_id = ?
+						ORDER BY o.id ASC, ov.id ASC`
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	defer rows.Close()
+
+	index := make(map[int]int, len(variants))
+	for i, v := range variants {
+		index[v.ID] = i
+	}
+	for rows.Next() {
+		var variantID int
+		ov := domain.ProductOptionValue{}
+		if err := rows.Scan(&variantID, &ov.ID, &ov.OptionID, &ov.Option, &ov.Value); err != nil {
+			logrus.Error(err)
+			return err
+		}
+		if i, ok := index[variantID]; ok {
+			variants[i].Options = append(variants[i].Options, ov)
+		}
+	}
+	return rows.Err()
+}
+
+func (m *VariantRepository) FetchOptions(ctx context.Context, productID int) (result []domain.ProductOption, err error) {
+	query := `SELECT o.id, o.product_id, o.name, ov.id, ov.value
+						FROM product_options o
+						LEFT JOIN product_option_values ov ON ov.option_id = o.id
+						WHERE o.product_id = ?
+						ORDER BY o.id ASC, ov.id ASC`
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, productID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.ProductOption, 0)
+	for rows.Next() {
+		o := domain.ProductOption{}
+		var valueID sql.NullInt64
+		var value sql.NullString
+		if err := rows.Scan(&o.ID, &o.ProductID, &o.Name, &valueID, &value); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if len(result) == 0 || result[len(result)-1].ID != o.ID {
+			o.Values = make([]domain.ProductOptionValue, 0)
+			result = append(result, o)
+		}
+		if valueID.Valid {
+			last := &result[len(result)-1]
+			last.Values = append(last.Values, domain.ProductOptionValue{
+				ID:       int(valueID.Int64),
+				OptionID: o.ID,
+				Option:   o.Name,
+				Value:    value.String,
+			})
+		}
+	}
+	return result, rows.Err()
+}
+
+func (m *VariantRepository) CreateOption(ctx context.Context, option *domain.ProductOption) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, option.ProductID, 0); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO product_options (product_id, name) VALUES (?, ?)`, option.ProductID, option.Name)
+	if err != nil {
+		logrus.Error(err)
+		if isDuplicateEntry(err) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	option.ID = int(id)
+
+	for i := range option.Values {
+		value := &option.Values[i]
+		value.OptionID = option.ID
+		value.Option = option.Name
+		res, err = tx.ExecContext(ctx, `INSERT INTO product_option_values (option_id, value) VALUES (?, ?)`, option.ID, value.Value)
+		if err != nil {
+			logrus.Error(err)
+			if isDuplicateEntry(err) {
+				return domain.ErrConflict
+			}
+			return err
+		}
+		id, err = res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		value.ID = int(id)
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductUpdated, option.ProductID, "options")
+}
+
+func (m *VariantRepository) DeleteOption(ctx context.Context, productID, id int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM product_options WHERE id = ? AND product_id = ?`, id, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductUpdated, productID, "options")
+}
+
+func (m *VariantRepository) FetchByProduct(ctx context.Context, productID int) (result []domain.ProductVariant, err error) {
+	query := `SELECT v.id, v.product_id, v.sku, p.currency, v.price, v.stock, v.sold
+						FROM product_variants v
+						JOIN products p ON v.product_id = p.id
+						WHERE v.product_id = ? AND p.deleted_at IS NULL
+						ORDER BY v.id ASC`
+	res, err := m.fetch(ctx, query, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.attachOptionValues(ctx, productID, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *VariantRepository) GetByID(ctx context.Context, productID, id int) (result domain.ProductVariant, err error) {
+	query := `SELECT v.id, v.product_id, v.sku, p.currency, v.price, v.stock, v.sold
+						FROM product_variants v
+						JOIN products p ON v.product_id = p.id
+						WHERE v.id = ? AND v.product_id = ? AND p.deleted_at IS NULL`
+	res, err := m.fetch(ctx, query, id, productID)
+	if err != nil {
+		return domain.ProductVariant{}, err
+	}
+	if len(res) == 0 {
+		return domain.ProductVariant{}, domain.ErrNotFound
+	}
+	if err := m.attachOptionValues(ctx, productID, res); err != nil {
+		return domain.ProductVariant{}, err
+	}
+	return res[0], nil
+}
+
+// linkOptionValues links option values to a variant, only accepting values of the variant's product
+func (m *VariantRepository) linkOptionValues(ctx context.Context, tx *transaction.Tx, variant *domain.ProductVariant) error {
+	for _, value := range variant.Options {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO product_variant_option_values (variant_id, option_value_id)
+			SELECT ?, ov.id
+			FROM product_option_values ov
+			JOIN product_options o ON ov.option_id = o.id
+			WHERE ov.id = ? AND o.product_id = ?`,
+			variant.ID, value.ID, variant.ProductID)
+		if err != nil {
+			logrus.Error(err)
+			if isDuplicateEntry(err) {
+				return domain.ErrConflict
+			}
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return domain.ErrBadRequest
+		}
+	}
+	return nil
+}
+
+// Create creates a variant of a product, its initial stock being recorded in
+// the stock ledger as a restock
+func (m *VariantRepository) Create(ctx context.Context, variant *domain.ProductVariant) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var currency string
+	err = tx.QueryRowContext(ctx, `SELECT currency FROM products WHERE id = ? AND deleted_at IS NULL FOR UPDATE`, variant.ProductID).Scan(&currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if variant.Price != nil && !variant.Price.SameCurrency(domain.Money{Currency: currency}) {
+		return domain.ErrCurrencyMismatch
+	}
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO product_variants (product_id, sku, price, stock, sold) VALUES (?, ?, ?, 0, 0)`,
+		variant.ProductID, variant.SKU, variant.Price)
+	if err != nil {
+		logrus.Error(err)
+		if isDuplicateEntry(err) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	variant.ID = int(id)
+	variant.Sold = 0
+
+	if err = m.linkOptionValues(ctx, tx, variant); err != nil {
+		return err
+	}
+	if err = recordVariantEvent(ctx, tx, domain.EventVariantCreated, variant.ProductID, variant.ID); err != nil {
+		return err
+	}
+	if variant.Stock > 0 {
+		return m.moveStock(ctx, tx, variant, domain.StockMovementRestock, variant.Stock)
+	}
+	return nil
+}
+
+// moveStock records a stock movement of a variant created by staff
+func (m *VariantRepository) moveStock(ctx context.Context, tx *transaction.Tx, variant *domain.ProductVariant, movementType domain.StockMovementType, quantity int) error {
+	return NewMySQLInventoryRepository(m.Conn).applyMovement(ctx, tx, &domain.StockMovement{
+		ProductID: variant.ProductID,
+		VariantID: &variant.ID,
+		Type:      movementType,
+		Quantity:  quantity,
+		Note:      "variant update",
+	})
+}
+
+// Update updates a variant changed by a staff member, price changes going
+// through the price history. Its stock is left as is, stock only changes
+// through stock movements so concurrent sales aren't overwritten.
+func (m *VariantRepository) Update(ctx context.Context, variant *domain.ProductVariant, changedBy *int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, variant.ProductID, 0); err != nil {
+		return err
+	}
+	// MySQL reports zero affected rows when nothing changed, so the variant is looked up first
+	if _, err = NewMySQLInventoryRepository(m.Conn).lockStock(ctx, tx, variant.ProductID, &variant.ID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE product_variants SET sku = ? WHERE id = ?`, variant.SKU, variant.ID)
+	if err != nil {
+		logrus.Error(err)
+		if isDuplicateEntry(err) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+
+	change := &domain.PriceChange{
+		ProductID: variant.ProductID,
+		VariantID: &variant.ID,
+		ChangedBy: changedBy,
+		Note:      "variant update",
+		CreatedAt: time.Now(),
+	}
+	if err = NewMySQLPriceRepository(m.Conn).setVariantPrice(ctx, tx, change, variant.Price); err != nil {
+		return err
+	}
+
+	fields := []string{"sku", "price"}
+	if variant.Options != nil {
+		_, err = tx.ExecContext(ctx, `DELETE FROM product_variant_option_values WHERE variant_id = ?`, variant.ID)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+		if err = m.linkOptionValues(ctx, tx, variant); err != nil {
+			return err
+		}
+		fields = append(fields, "options")
+	}
+	return recordVariantEvent(ctx, tx, domain.EventVariantUpdated, variant.ProductID, variant.ID, fields...)
+}
+
+func (m *VariantRepository) Delete(ctx context.Context, productID, id int) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	stock, err := NewMySQLInventoryRepository(m.Conn).lockStock(ctx, tx, productID, &id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM product_variants WHERE id = ? AND product_id = ?`, id, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// The stock of the product no longer counts the variant's
+	_, err = tx.ExecContext(ctx, `UPDATE products SET stock = GREATEST(stock - ?, 0) WHERE id = ?`, stock, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return recordVariantEvent(ctx, tx, domain.EventVariantDeleted, productID, id)
+}
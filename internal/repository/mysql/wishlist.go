@@ -0,0 +1,319 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type WishlistRepository struct {
+	Conn *sql.DB
+}
+
+func NewMySQLWishlistRepository(conn *sql.DB) *WishlistRepository {
+	return &WishlistRepository{conn}
+}
+
+func (m *WishlistRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Wishlist, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.Wishlist, 0)
+	for rows.Next() {
+		w := domain.Wishlist{}
+		var shareToken sql.NullString
+		if err := rows.Scan(&w.ID, &w.UserID, &w.Name, &shareToken, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		w.ShareToken = shareToken.String
+		result = append(result, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for n := range result {
+		result[n].Items, err = m.fetchItems(ctx, result[n].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (m *WishlistRepository) fetchItems(ctx context.Context, wishlistID int) (result []domain.WishlistItem, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT p.id, COALESCE(p.sku, ''), p.name, p.currency, p.price, p.image_url, p.stock, p.sold, p.reorder_threshold, p.weight, p.length, p.width, p.height, p.category_id, c.name, p.rating_average, p.rating_count, wi.created_at
+		FROM wishlist_items wi
+		JOIN products p ON wi.product_id = p.id
+		JOIN categories c ON p.category_id = c.id
+		WHERE wi.wishlist_id = ? AND p.deleted_at IS NULL
+		ORDER BY wi.created_at ASC, p.id ASC`, wishlistID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.WishlistItem, 0)
+	for rows.Next() {
+		i := domain.WishlistItem{}
+		product := &i.Product
+		// Scan the currency before the price, amounts are read in the currency already set
+		err := rows.Scan(&product.ID, &product.SKU, &product.Name, &product.Price.Currency, &product.Price, &product.ImageURL, &product.Stock, &product.Sold, &product.ReorderThreshold,
+			&product.Weight, &product.Length, &product.Width, &product.Height, &product.Category.ID, &product.Category.Name, &product.Rating.Average, &product.Rating.Count, &i.AddedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, i)
+	}
+	return result, rows.Err()
+}
+
+func (m *WishlistRepository) Fetch(ctx context.Context, userID int) ([]domain.Wishlist, error) {
+	return m.fetch(ctx, `SELECT id, user_id, name, share_token, created_at, updated_at
+						FROM wishlists WHERE user_id = ? ORDER BY id ASC`, userID)
+}
+
+func (m *WishlistRepository) GetByID(ctx context.Context, userID, id int) (domain.Wishlist, error) {
+	res, err := m.fetch(ctx, `SELECT id, user_id, name, share_token, created_at, updated_at
+						FROM wishlists WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+	if len(res) == 0 {
+		return domain.Wishlist{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+// GetByShareToken returns the wishlist shared under token
+func (m *WishlistRepository) GetByShareToken(ctx context.Context, token string) (domain.Wishlist, error) {
+	res, err := m.fetch(ctx, `SELECT id, user_id, name, share_token, created_at, updated_at
+						FROM wishlists WHERE share_token = ?`, token)
+	if err != nil {
+		return domain.Wishlist{}, err
+	}
+	if len(res) == 0 {
+		return domain.Wishlist{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+func (m *WishlistRepository) Create(ctx context.Context, wishlist *domain.Wishlist) error {
+	wishlist.CreatedAt = time.Now()
+	wishlist.UpdatedAt = wishlist.CreatedAt
+	wishlist.Items = []domain.WishlistItem{}
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO wishlists (user_id, name, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		wishlist.UserID, wishlist.Name, wishlist.CreatedAt, wishlist.UpdatedAt)
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	wishlist.ID = int(id)
+	return nil
+}
+
+// exists reports whether the wishlist belongs to the user
+func (m *WishlistRepository) exists(ctx context.Context, userID, id int) error {
+	var exists int
+	err := transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT 1 FROM wishlists WHERE id = ? AND user_id = ?`, id, userID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// Rename changes the name of a wishlist of the user
+func (m *WishlistRepository) Rename(ctx context.Context, userID, id int, name string) error {
+	// MySQL reports zero affected rows when nothing changed, so check existence first
+	if err := m.exists(ctx, userID, id); err != nil {
+		return err
+	}
+
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `UPDATE wishlists SET name = ?, updated_at = ? WHERE id = ?`, name, time.Now(), id)
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (m *WishlistRepository) Delete(ctx context.Context, userID, id int) error {
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `DELETE FROM wishlists WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// SetShareToken shares a wishlist of the user under token, an empty token stops sharing it
+func (m *WishlistRepository) SetShareToken(ctx context.Context, userID, id int, token string) error {
+	// MySQL reports zero affected rows when nothing changed, so check existence first
+	if err := m.exists(ctx, userID, id); err != nil {
+		return err
+	}
+
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `UPDATE wishlists SET share_token = ?, updated_at = ? WHERE id = ?`,
+		sql.NullString{String: token, Valid: token != ""}, time.Now(), id)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// AddItem saves a product in a wishlist of the user, remembering whether it
+// is out of stock so that restocking it is notified
+func (m *WishlistRepository) AddItem(ctx context.Context, userID, wishlistID, productID int) error {
+	if err := m.exists(ctx, userID, wishlistID); err != nil {
+		return err
+	}
+
+	var stock int
+	err := transaction.Conn(ctx, m.Conn).QueryRowContext(ctx, `SELECT stock FROM products WHERE id = ? AND deleted_at IS NULL`, productID).Scan(&stock)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`INSERT INTO wishlist_items (wishlist_id, product_id, out_of_stock, created_at) VALUES (?, ?, ?, ?)`,
+		wishlistID, productID, stock <= 0, time.Now())
+	if isDuplicateEntry(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (m *WishlistRepository) RemoveItem(ctx context.Context, userID, wishlistID, productID int) error {
+	res, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx,
+		`DELETE wi FROM wishlist_items wi
+		JOIN wishlists w ON wi.wishlist_id = w.id
+		WHERE w.id = ? AND w.user_id = ? AND wi.product_id = ?`,
+		wishlistID, userID, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// SyncBackInStockAlerts opens an alert for every user who wishlisted a product
+// whose stock went up from zero since the last check, then records the stock
+// state of every wishlisted product for the next check
+func (m *WishlistRepository) SyncBackInStockAlerts(ctx context.Context, now time.Time) (err error) {
+	tx, err := transaction.Begin(ctx, m.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// The unique key on pending alerts keeps concurrent checkers from duplicating alerts
+	_, err = tx.ExecContext(ctx,
+		`INSERT IGNORE INTO back_in_stock_alerts (user_id, product_id, created_at)
+		SELECT DISTINCT w.user_id, wi.product_id, ?
+		FROM wishlist_items wi
+		JOIN wishlists w ON wi.wishlist_id = w.id
+		JOIN products p ON wi.product_id = p.id
+		WHERE wi.out_of_stock AND p.stock > 0 AND p.deleted_at IS NULL`, now)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE wishlist_items wi
+		JOIN products p ON wi.product_id = p.id
+		SET wi.out_of_stock = p.stock <= 0
+		WHERE wi.out_of_stock <> (p.stock <= 0)`)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// FetchPendingStockAlerts returns the back-in-stock alerts which haven't been notified yet
+func (m *WishlistRepository) FetchPendingStockAlerts(ctx context.Context) (result []domain.BackInStockAlert, err error) {
+	rows, err := transaction.Conn(ctx, m.Conn).QueryContext(ctx,
+		`SELECT a.id, a.user_id, u.email, a.product_id, p.name, a.created_at
+		FROM back_in_stock_alerts a
+		JOIN users u ON a.user_id = u.id
+		JOIN products p ON a.product_id = p.id
+		WHERE a.notified_at IS NULL AND u.deleted_at IS NULL AND p.deleted_at IS NULL
+		ORDER BY a.id ASC`)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.BackInStockAlert, 0)
+	for rows.Next() {
+		a := domain.BackInStockAlert{}
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Email, &a.ProductID, &a.ProductName, &a.CreatedAt); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+func (m *WishlistRepository) MarkStockAlertNotified(ctx context.Context, id int, now time.Time) error {
+	_, err := transaction.Conn(ctx, m.Conn).ExecContext(ctx, `UPDATE back_in_stock_alerts SET notified_at = ? WHERE id = ?`, now, id)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
@@ -0,0 +1,442 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/promotion"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type CartRepository struct {
+	Conn *sql.DB
+}
+
+func NewCartRepository(conn *sql.DB) *CartRepository {
+	return &CartRepository{conn}
+}
+
+// ownerCondition returns the condition selecting the cart of owner
+func ownerCondition(owner domain.CartOwner) (string, interface{}) {
+	if owner.UserID != 0 {
+		return "user_id = $1", owner.UserID
+	}
+	return "token = $1 AND user_id IS NULL", owner.Token
+}
+
+func (p *CartRepository) fetchItems(ctx context.Context, q querier, cartID int) (result []domain.CartItem, err error) {
+	query := `SELECT ci.id, ci.cart_id, ci.product_id, ci.variant_id, p.category_id, p.weight, p.length, p.width, p.height, ci.name, ci.sku, p.currency, ci.unit_price, ci.quantity, ci.created_at
+						FROM cart_items ci
+						JOIN products p ON ci.product_id = p.id
+						WHERE ci.cart_id = $1 AND p.deleted_at IS NULL
+						ORDER BY ci.id ASC`
+	rows, err := q.QueryContext(ctx, query, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.CartItem, 0)
+	for rows.Next() {
+		i := domain.CartItem{}
+		var variantID sql.NullInt64
+		err := rows.Scan(&i.ID, &i.CartID, &i.ProductID, &variantID, &i.CategoryID, &i.Shipping.Weight, &i.Shipping.Length, &i.Shipping.Width, &i.Shipping.Height, &i.Name, &i.SKU, &i.UnitPrice.Currency, &i.UnitPrice, &i.Quantity, &i.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			i.VariantID = &id
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+func (p *CartRepository) fetchCoupons(ctx context.Context, q querier, cartID int) (result []string, err error) {
+	rows, err := q.QueryContext(ctx, `SELECT code FROM cart_coupons WHERE cart_id = $1 ORDER BY created_at ASC, code ASC`, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]string, 0)
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, code)
+	}
+	return result, rows.Err()
+}
+
+// price loads the items and coupons of cart, prices the items in the cart
+// currency and applies the active promotions. Usage limits are checked against
+// the redemptions of the cart user, anonymous carts are priced as if the
+// visitor never redeemed any.
+func (p *CartRepository) price(ctx context.Context, q querier, cart *domain.Cart) (err error) {
+	cart.Items, err = p.fetchItems(ctx, q, cart.ID)
+	if err != nil {
+		return err
+	}
+	cart.Coupons, err = p.fetchCoupons(ctx, q, cart.ID)
+	if err != nil {
+		return err
+	}
+
+	productIDs := make([]int, 0, len(cart.Items))
+	for _, item := range cart.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	book, err := NewCurrencyRepository(p.Conn).priceBook(ctx, q, cart.Currency, productIDs)
+	if err != nil {
+		return err
+	}
+	book.LocalizeItems(cart.Items)
+	cart.Currency = book.Currency()
+	cart.ExchangeRate = book.Rate
+
+	now := time.Now()
+	promotions := NewPromotionRepository(p.Conn)
+	active, err := promotions.fetchActive(ctx, q, now)
+	if err != nil {
+		return err
+	}
+	usage := map[int]int{}
+	if cart.UserID != nil {
+		usage, err = promotions.usage(ctx, q, *cart.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	promotion.Evaluate(active, promotion.NewCart(*cart, usage, now)).Apply(cart)
+	return nil
+}
+
+// GetCart returns the cart of owner with its items
+func (p *CartRepository) GetCart(ctx context.Context, owner domain.CartOwner) (result domain.Cart, err error) {
+	condition, arg := ownerCondition(owner)
+	var userID sql.NullInt64
+	err = transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`SELECT id, user_id, COALESCE(token, ''), currency, created_at, updated_at FROM carts WHERE `+condition, arg).
+		Scan(&result.ID, &userID, &result.Token, &result.Currency, &result.CreatedAt, &result.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Cart{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Cart{}, err
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		result.UserID = &id
+	}
+
+	if err := p.price(ctx, transaction.Conn(ctx, p.Conn), &result); err != nil {
+		return domain.Cart{}, err
+	}
+	return result, nil
+}
+
+// GetOrCreateCart returns the cart of owner, creating an empty cart when it has none
+func (p *CartRepository) GetOrCreateCart(ctx context.Context, owner domain.CartOwner) (domain.Cart, error) {
+	var userID, token interface{}
+	if owner.UserID != 0 {
+		userID = owner.UserID
+	} else {
+		token = owner.Token
+	}
+	_, err := transaction.Conn(ctx, p.Conn).ExecContext(ctx, `INSERT INTO carts (user_id, token) VALUES ($1, $2) ON CONFLICT DO NOTHING`, userID, token)
+	if err != nil {
+		logrus.Error(err)
+		return domain.Cart{}, err
+	}
+	return p.GetCart(ctx, owner)
+}
+
+// touch records the modification of a cart
+func (p *CartRepository) touch(ctx context.Context, tx *transaction.Tx, cartID int) error {
+	_, err := tx.ExecContext(ctx, `UPDATE carts SET updated_at = $1 WHERE id = $2`, time.Now(), cartID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// SaveItem adds a line to a cart or sets the quantity of the existing line of
+// the same product and variant, keeping its price snapshot
+func (p *CartRepository) SaveItem(ctx context.Context, cartID int, item *domain.CartItem) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	item.CartID = cartID
+	item.CreatedAt = time.Now()
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO cart_items (cart_id, product_id, variant_id, name, sku, unit_price, quantity, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (cart_id, product_id, COALESCE(variant_id, 0)) DO UPDATE SET quantity = EXCLUDED.quantity
+		RETURNING id, unit_price, created_at`,
+		cartID, item.ProductID, item.VariantID, item.Name, item.SKU, item.UnitPrice, item.Quantity, item.CreatedAt).
+		Scan(&item.ID, &item.UnitPrice, &item.CreatedAt)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return p.touch(ctx, tx, cartID)
+}
+
+func (p *CartRepository) UpdateItemQuantity(ctx context.Context, cartID, itemID, quantity int) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `UPDATE cart_items SET quantity = $1 WHERE id = $2 AND cart_id = $3`, quantity, itemID, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return p.touch(ctx, tx, cartID)
+}
+
+func (p *CartRepository) DeleteItem(ctx context.Context, cartID, itemID int) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM cart_items WHERE id = $1 AND cart_id = $2`, itemID, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return p.touch(ctx, tx, cartID)
+}
+
+// SetCurrency switches the currency a cart is priced in, the currency needs an exchange rate
+func (p *CartRepository) SetCurrency(ctx context.Context, cartID int, currency string) error {
+	rate, err := NewCurrencyRepository(p.Conn).rate(ctx, transaction.Conn(ctx, p.Conn), currency)
+	if err != nil {
+		return err
+	}
+	res, err := transaction.Conn(ctx, p.Conn).ExecContext(ctx, `UPDATE carts SET currency = $1, updated_at = $2 WHERE id = $3`, rate.Currency, time.Now(), cartID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// AddCoupon enters a coupon code on a cart, the code must belong to a promotion
+func (p *CartRepository) AddCoupon(ctx context.Context, cartID int, code string) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO cart_coupons (cart_id, code, created_at)
+		SELECT $1, code, $3 FROM promotions WHERE code = $2
+		ON CONFLICT (cart_id, code) DO NOTHING`,
+		cartID, code, time.Now())
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM promotions WHERE code = $1)`, code).Scan(&exists)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+		if !exists {
+			return domain.ErrNotFound
+		}
+	}
+	return p.touch(ctx, tx, cartID)
+}
+
+func (p *CartRepository) RemoveCoupon(ctx context.Context, cartID int, code string) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM cart_coupons WHERE cart_id = $1 AND code = $2`, cartID, code)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return p.touch(ctx, tx, cartID)
+}
+
+// MergeCarts moves the anonymous cart of token into the cart of userID. The
+// anonymous cart becomes the user cart when the user has none, otherwise the
+// quantities of matching lines are added up, capped to the available stock,
+// and the entered coupons are carried over.
+func (p *CartRepository) MergeCarts(ctx context.Context, token string, userID int) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var anonymousID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM carts WHERE token = $1 AND user_id IS NULL FOR UPDATE`, token).Scan(&anonymousID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	var cartID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM carts WHERE user_id = $1 FOR UPDATE`, userID).Scan(&cartID)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = tx.ExecContext(ctx, `UPDATE carts SET user_id = $1, token = NULL, updated_at = $2 WHERE id = $3`,
+			userID, time.Now(), anonymousID)
+		if err != nil {
+			logrus.Error(err)
+		}
+		return err
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO cart_items (cart_id, product_id, variant_id, name, sku, unit_price, quantity, created_at)
+		SELECT $1, product_id, variant_id, name, sku, unit_price, quantity, created_at FROM cart_items WHERE cart_id = $2
+		ON CONFLICT (cart_id, product_id, COALESCE(variant_id, 0)) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`,
+		cartID, anonymousID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO cart_coupons (cart_id, code, created_at)
+		SELECT $1, code, created_at FROM cart_coupons WHERE cart_id = $2
+		ON CONFLICT (cart_id, code) DO NOTHING`,
+		cartID, anonymousID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	// Out of stock lines are kept as is, checkout rejects them
+	_, err = tx.ExecContext(ctx,
+		`UPDATE cart_items i SET quantity = s.stock
+		FROM (
+			SELECT ci.id, COALESCE(v.stock, p.stock) AS stock
+			FROM cart_items ci
+			JOIN products p ON ci.product_id = p.id
+			LEFT JOIN product_variants v ON ci.variant_id = v.id
+			WHERE ci.cart_id = $1
+		) s
+		WHERE i.id = s.id AND i.quantity > s.stock AND s.stock > 0`, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM carts WHERE id = $1`, anonymousID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return p.touch(ctx, tx, cartID)
+}
@@ -3,8 +3,11 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
 	"github.com/sirupsen/logrus"
 )
 
@@ -17,7 +20,7 @@ func NewCategoryRepository(conn *sql.DB) *CategoryRepository {
 }
 
 func (p *CategoryRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Category, err error) {
-	rows, err := p.Conn.QueryContext(ctx, query, args...)
+	rows, err := transaction.Conn(ctx, p.Conn).QueryContext(ctx, query, args...)
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -32,7 +35,7 @@ func (p *CategoryRepository) fetch(ctx context.Context, query string, args ...in
 	result = make([]domain.Category, 0)
 	for rows.Next() {
 		c := domain.Category{}
-		err := rows.Scan(&c.ID, &c.Name)
+		err := rows.Scan(&c.ID, &c.Name, &c.Version)
 		if err != nil {
 			logrus.Error(err)
 			return nil, err
@@ -43,7 +46,7 @@ func (p *CategoryRepository) fetch(ctx context.Context, query string, args ...in
 }
 
 func (p *CategoryRepository) Fetch(ctx context.Context) (result []domain.Category, err error) {
-	query := `SELECT id, name FROM categories ORDER BY id ASC`
+	query := `SELECT id, name, version FROM categories WHERE deleted_at IS NULL ORDER BY id ASC`
 
 	res, err := p.fetch(ctx, query)
 	if err != nil {
@@ -51,3 +54,83 @@ func (p *CategoryRepository) Fetch(ctx context.Context) (result []domain.Categor
 	}
 	return res, nil
 }
+
+func (p *CategoryRepository) GetByID(ctx context.Context, id string) (result domain.Category, err error) {
+	res, err := p.fetch(ctx, `SELECT id, name, version FROM categories WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return domain.Category{}, err
+	}
+	if len(res) == 0 {
+		return domain.Category{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+func (p *CategoryRepository) Create(ctx context.Context, category domain.Category) error {
+	_, err := transaction.Conn(ctx, p.Conn).ExecContext(ctx, `INSERT INTO categories (name) VALUES ($1)`, category.Name)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// Delete moves a category to the trash, failing with
+// domain.ErrCategoryNotEmpty while it has products and with
+// domain.ErrVersionMismatch unless it is at version, 0 matching any version
+func (p *CategoryRepository) Delete(ctx context.Context, id, version int) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkRowVersion(ctx, tx, "categories", id, version); err != nil {
+		return err
+	}
+
+	// Lock the category so none of its products is restored meanwhile
+	var hasProducts bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products p WHERE p.category_id = c.id AND p.deleted_at IS NULL)
+		FROM categories c
+		WHERE c.id = $1 AND c.deleted_at IS NULL
+		FOR UPDATE OF c`, id).Scan(&hasProducts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if hasProducts {
+		return domain.ErrCategoryNotEmpty
+	}
+	return moveToTrash(ctx, tx, "categories", id)
+}
+
+// FetchTrashed returns a page of the deleted categories
+func (p *CategoryRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	return fetchTrash(ctx, transaction.Conn(ctx, p.Conn), "categories", offset, limit)
+}
+
+// GetTrashed returns a deleted category
+func (p *CategoryRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	return getTrashed(ctx, transaction.Conn(ctx, p.Conn), "categories", id)
+}
+
+// Restore restores a deleted category, its products staying deleted
+func (p *CategoryRepository) Restore(ctx context.Context, id int) error {
+	return restoreFromTrash(ctx, transaction.Conn(ctx, p.Conn), "categories", id)
+}
+
+// Purge permanently deletes the categories deleted before, except those
+// holding products, deleted or not
+func (p *CategoryRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purgeTrash(ctx, transaction.Conn(ctx, p.Conn), "categories",
+		`SELECT 1 FROM products p WHERE p.category_id = t.id`, before)
+}
@@ -0,0 +1,133 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+// recordEvent writes an event to the outbox on conn, which must run the
+// transaction of the change the event is about
+func recordEvent(ctx context.Context, conn transaction.Executor, eventType, key string, payload interface{}) error {
+	event, err := domain.NewEvent(eventType, key, payload)
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `INSERT INTO event_outbox (event_type, event_key, payload, occurred_at, available_at)
+		VALUES ($1, $2, $3, $4, $4)`, event.Type, event.Key, string(event.Payload), event.OccurredAt)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// recordProductEvent writes a product event to the outbox on conn, fields
+// naming the changed fields of product.updated events
+func recordProductEvent(ctx context.Context, conn transaction.Executor, eventType string, productID int, fields ...string) error {
+	return recordEvent(ctx, conn, eventType, domain.EventKey("product", productID), domain.ProductChanged{ProductID: productID, Fields: fields})
+}
+
+// recordVariantEvent writes a variant event to the outbox on conn, fields
+// naming the changed fields of variant.updated events
+func recordVariantEvent(ctx context.Context, conn transaction.Executor, eventType string, productID, variantID int, fields ...string) error {
+	return recordEvent(ctx, conn, eventType, domain.EventKey("product", productID), domain.VariantChanged{
+		ProductID: productID,
+		VariantID: variantID,
+		Fields:    fields,
+	})
+}
+
+// recordStockDepletion writes an inventory.out_of_stock event to the outbox
+// on conn when movement took the last units of its product or variant
+func recordStockDepletion(ctx context.Context, conn transaction.Executor, movement *domain.StockMovement) error {
+	if movement.Quantity >= 0 || movement.StockAfter != 0 {
+		return nil
+	}
+	return recordEvent(ctx, conn, domain.EventStockDepleted, domain.EventKey("product", movement.ProductID), domain.StockDepleted{
+		ProductID:  movement.ProductID,
+		VariantID:  movement.VariantID,
+		MovementID: movement.ID,
+	})
+}
+
+type EventOutboxRepository struct {
+	Conn *sql.DB
+}
+
+func NewEventOutboxRepository(conn *sql.DB) *EventOutboxRepository {
+	return &EventOutboxRepository{conn}
+}
+
+// ClaimEvents claims up to limit events due for publication, oldest first,
+// hiding them from other relays until lease is over
+func (p *EventOutboxRepository) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]domain.Event, error) {
+	now := time.Now()
+	rows, err := transaction.Conn(ctx, p.Conn).QueryContext(ctx, `UPDATE event_outbox SET attempts = attempts + 1, available_at = $1
+		WHERE id IN (
+			SELECT id FROM event_outbox
+			WHERE published_at IS NULL AND available_at <= $2
+			ORDER BY id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, event_key, payload, occurred_at, attempts`, now.Add(lease), now, limit)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]domain.Event, 0)
+	for rows.Next() {
+		var event domain.Event
+		var payload string
+		if err := rows.Scan(&event.ID, &event.Type, &event.Key, &payload, &event.OccurredAt, &event.Attempts); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// MarkEventPublished records that a claimed event was published
+func (p *EventOutboxRepository) MarkEventPublished(ctx context.Context, id int64) error {
+	_, err := transaction.Conn(ctx, p.Conn).ExecContext(ctx, `UPDATE event_outbox SET published_at = $1, last_error = '' WHERE id = $2`,
+		time.Now(), id)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// RetryEvent releases a claimed event which failed to publish, to be claimed
+// again from retryAt
+func (p *EventOutboxRepository) RetryEvent(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	_, err := transaction.Conn(ctx, p.Conn).ExecContext(ctx, `UPDATE event_outbox SET available_at = $1, last_error = $2 WHERE id = $3`,
+		retryAt, reason, id)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// PurgeEvents deletes the events published before
+func (p *EventOutboxRepository) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := transaction.Conn(ctx, p.Conn).ExecContext(ctx, `DELETE FROM event_outbox WHERE published_at < $1`, before)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
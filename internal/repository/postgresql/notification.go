@@ -0,0 +1,30 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type EmailOutboxRepository struct {
+	Conn *sql.DB
+}
+
+func NewEmailOutboxRepository(conn *sql.DB) *EmailOutboxRepository {
+	return &EmailOutboxRepository{conn}
+}
+
+func (p *EmailOutboxRepository) EnqueueEmail(ctx context.Context, email *domain.OutboxEmail) error {
+	email.CreatedAt = time.Now()
+	err := transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`INSERT INTO email_outbox (recipient, subject, body, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		email.Recipient, email.Subject, email.Body, email.CreatedAt).Scan(&email.ID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
@@ -0,0 +1,597 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type OrderRepository struct {
+	Conn *sql.DB
+}
+
+func NewOrderRepository(conn *sql.DB) *OrderRepository {
+	return &OrderRepository{conn}
+}
+
+// querier is implemented by both *sql.DB and *transaction.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// orderReference returns the stock movement reference of an order
+func orderReference(orderID int) string {
+	return fmt.Sprintf("order:%d", orderID)
+}
+
+// cartReference returns the reference of the stock reserved for a cart
+func cartReference(cartID int) string {
+	return fmt.Sprintf("cart:%d", cartID)
+}
+
+func (p *OrderRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Order, err error) {
+	rows, err := transaction.Conn(ctx, p.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Order, 0)
+	for rows.Next() {
+		o := domain.Order{}
+		amounts := domain.CurrencyColumn{&o.Subtotal, &o.DiscountTotal, &o.ShippingTotal, &o.TaxTotal, &o.Total}
+		err := rows.Scan(&o.ID, &o.UserID, &o.Status, &o.Region, &o.ExchangeRate, amounts, &o.Subtotal, &o.DiscountTotal, &o.ShippingTotal, &o.TaxTotal, &o.Total,
+			&o.CreatedAt, &o.UpdatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, o)
+	}
+	return result, nil
+}
+
+func (p *OrderRepository) fetchItems(ctx context.Context, q querier, orderID int) (result []domain.OrderItem, err error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT oi.id, oi.order_id, oi.product_id, oi.variant_id, oi.name, oi.sku, o.currency, oi.unit_price, oi.quantity
+		FROM order_items oi
+		JOIN orders o ON oi.order_id = o.id
+		WHERE oi.order_id = $1
+		ORDER BY oi.id ASC`, orderID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.OrderItem, 0)
+	for rows.Next() {
+		i := domain.OrderItem{}
+		var variantID sql.NullInt64
+		if err := rows.Scan(&i.ID, &i.OrderID, &i.ProductID, &variantID, &i.Name, &i.SKU, &i.UnitPrice.Currency, &i.UnitPrice, &i.Quantity); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if variantID.Valid {
+			id := int(variantID.Int64)
+			i.VariantID = &id
+		}
+		result = append(result, i)
+	}
+	return result, rows.Err()
+}
+
+func (p *OrderRepository) fetchHistory(ctx context.Context, orderID int) (result []domain.OrderStatusChange, err error) {
+	rows, err := transaction.Conn(ctx, p.Conn).QueryContext(ctx,
+		`SELECT id, order_id, COALESCE(from_status, ''), to_status, note, changed_by, created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY id ASC`, orderID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.OrderStatusChange, 0)
+	for rows.Next() {
+		c := domain.OrderStatusChange{}
+		var changedBy sql.NullInt64
+		if err := rows.Scan(&c.ID, &c.OrderID, &c.From, &c.To, &c.Note, &changedBy, &c.CreatedAt); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if changedBy.Valid {
+			id := int(changedBy.Int64)
+			c.ChangedBy = &id
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+func (p *OrderRepository) fetchDiscounts(ctx context.Context, orderID int) (result []domain.AppliedDiscount, err error) {
+	rows, err := transaction.Conn(ctx, p.Conn).QueryContext(ctx,
+		`SELECT d.promotion_id, d.name, d.code, o.currency, d.amount
+		FROM order_discounts d
+		JOIN orders o ON d.order_id = o.id
+		WHERE d.order_id = $1
+		ORDER BY d.id ASC`, orderID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.AppliedDiscount, 0)
+	for rows.Next() {
+		d := domain.AppliedDiscount{}
+		var promotionID sql.NullInt64
+		if err := rows.Scan(&promotionID, &d.Name, &d.Code, &d.Amount.Currency, &d.Amount); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		d.PromotionID = int(promotionID.Int64)
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+func (p *OrderRepository) fetchTaxes(ctx context.Context, orderID int) (result []domain.TaxLine, err error) {
+	rows, err := transaction.Conn(ctx, p.Conn).QueryContext(ctx,
+		`SELECT t.name, t.rate, o.currency, t.amount
+		FROM order_taxes t
+		JOIN orders o ON t.order_id = o.id
+		WHERE t.order_id = $1
+		ORDER BY t.id ASC`, orderID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.TaxLine, 0)
+	for rows.Next() {
+		t := domain.TaxLine{}
+		if err := rows.Scan(&t.Name, &t.Rate, &t.Amount.Currency, &t.Amount); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// Fetch returns a page of orders matching filter, newest first, without their items
+func (p *OrderRepository) Fetch(ctx context.Context, filter domain.OrderFilter, offset, limit int) (total int, result []domain.Order, err error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	err = transaction.Conn(ctx, p.Conn).QueryRowContext(ctx, `SELECT COUNT(*) FROM orders WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`SELECT id, user_id, status, region, exchange_rate, currency, subtotal, discount_total, shipping_total, tax_total, total, created_at, updated_at
+						FROM orders
+						WHERE %s
+						ORDER BY id DESC
+						LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+	result, err = p.fetch(ctx, query, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, result, nil
+}
+
+// GetByID returns an order with its items, discounts, taxes and status history
+func (p *OrderRepository) GetByID(ctx context.Context, id int) (result domain.Order, err error) {
+	query := `SELECT id, user_id, status, region, exchange_rate, currency, subtotal, discount_total, shipping_total, tax_total, total, created_at, updated_at FROM orders WHERE id = $1`
+	res, err := p.fetch(ctx, query, id)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	if len(res) == 0 {
+		return domain.Order{}, domain.ErrNotFound
+	}
+
+	order := res[0]
+	order.Items, err = p.fetchItems(ctx, transaction.Conn(ctx, p.Conn), id)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	order.Discounts, err = p.fetchDiscounts(ctx, id)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	order.Taxes, err = p.fetchTaxes(ctx, id)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	order.History, err = p.fetchHistory(ctx, id)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	return order, nil
+}
+
+// Checkout converts the cart of a user into a pending order in a single
+// transaction, applying its promotions and the shipping and taxes of region,
+// taking the ordered quantities out of stock and emptying the cart. The
+// stock reserved for the cart is sold, the stock reserved for other carts
+// can't be. Coupons that no longer apply are dropped.
+func (p *OrderRepository) Checkout(ctx context.Context, userID int, region string, charges domain.CartCharges) (order domain.Order, err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return domain.Order{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var cartID int
+	var currency string
+	err = tx.QueryRowContext(ctx, `SELECT id, currency FROM carts WHERE user_id = $1 FOR UPDATE`, userID).Scan(&cartID, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Order{}, domain.ErrEmptyCart
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Order{}, err
+	}
+
+	cart := domain.Cart{ID: cartID, UserID: &userID, Currency: currency}
+	if err = NewCartRepository(p.Conn).price(ctx, tx, &cart); err != nil {
+		return domain.Order{}, err
+	}
+	if err = charges.Apply(&cart, region); err != nil {
+		return domain.Order{}, err
+	}
+	for _, item := range cart.Items {
+		order.Items = append(order.Items, domain.OrderItem{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Name:      item.Name,
+			SKU:       item.SKU,
+			UnitPrice: item.UnitPrice,
+			Quantity:  item.Quantity,
+		})
+	}
+	if len(order.Items) == 0 {
+		return domain.Order{}, domain.ErrEmptyCart
+	}
+
+	sort.Slice(order.Items, func(a, b int) bool {
+		ia, ib := order.Items[a], order.Items[b]
+		return lockStockOrder(ia.ProductID, ia.VariantID, ib.ProductID, ib.VariantID)
+	})
+
+	order.UserID = userID
+	order.Status = domain.OrderPending
+	order.Subtotal = order.CalculateSubtotal()
+	order.Region = cart.Region
+	order.ExchangeRate = cart.ExchangeRate.Rate
+	order.DiscountTotal = cart.DiscountTotal
+	order.ShippingTotal = cart.Shipping
+	order.TaxTotal = cart.TaxTotal
+	order.Total = cart.Total
+	order.Discounts = cart.Discounts
+	order.Taxes = cart.Taxes
+	order.CreatedAt = time.Now()
+	order.UpdatedAt = order.CreatedAt
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO orders (user_id, status, region, currency, exchange_rate, subtotal, discount_total, shipping_total, tax_total, total, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		order.UserID, order.Status, order.Region, order.Currency(), order.ExchangeRate, order.Subtotal, order.DiscountTotal, order.ShippingTotal, order.TaxTotal,
+		order.Total, order.CreatedAt, order.UpdatedAt).Scan(&order.ID)
+	if err != nil {
+		logrus.Error(err)
+		return domain.Order{}, err
+	}
+
+	for _, tax := range order.Taxes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO order_taxes (order_id, name, rate, amount) VALUES ($1, $2, $3, $4)`,
+			order.ID, tax.Name, tax.Rate, tax.Amount)
+		if err != nil {
+			logrus.Error(err)
+			return domain.Order{}, err
+		}
+	}
+
+	// Redemptions count towards the per user usage limits
+	for _, discount := range order.Discounts {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO order_discounts (order_id, promotion_id, name, code, amount) VALUES ($1, $2, $3, $4, $5)`,
+			order.ID, discount.PromotionID, discount.Name, discount.Code, discount.Amount)
+		if err != nil {
+			logrus.Error(err)
+			return domain.Order{}, err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO promotion_redemptions (promotion_id, user_id, order_id, created_at) VALUES ($1, $2, $3, $4)`,
+			discount.PromotionID, userID, order.ID, order.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return domain.Order{}, err
+		}
+	}
+
+	// The stock reserved for the cart is sold, other carts' reservations are
+	// left alone
+	inventory := NewInventoryRepository(p.Conn)
+	reference := cartReference(cartID)
+	for n := range order.Items {
+		item := &order.Items[n]
+		var available int
+		available, err = inventory.available(ctx, tx, item.ProductID, item.VariantID, reference, order.CreatedAt)
+		if err != nil {
+			return domain.Order{}, err
+		}
+		if available < item.Quantity {
+			return domain.Order{}, p.shortage(ctx, tx, reference, item.ProductID, item.VariantID)
+		}
+
+		item.OrderID = order.ID
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO order_items (order_id, product_id, variant_id, name, sku, unit_price, quantity)
+			VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+			order.ID, item.ProductID, item.VariantID, item.Name, item.SKU, item.UnitPrice, item.Quantity).Scan(&item.ID)
+		if err != nil {
+			logrus.Error(err)
+			return domain.Order{}, err
+		}
+
+		err = inventory.applyMovement(ctx, tx, &domain.StockMovement{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Type:      domain.StockMovementSale,
+			Quantity:  -item.Quantity,
+			Reference: orderReference(order.ID),
+		})
+		if err != nil {
+			return domain.Order{}, err
+		}
+	}
+
+	if err = inventory.settleReservations(ctx, tx, reference, domain.ReservationCommitted); err != nil {
+		return domain.Order{}, err
+	}
+
+	change := domain.OrderStatusChange{To: domain.OrderPending, ChangedBy: &userID}
+	if err = p.insertStatusChange(ctx, tx, order.ID, &change); err != nil {
+		return domain.Order{}, err
+	}
+	order.History = []domain.OrderStatusChange{change}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM carts WHERE id = $1`, cartID)
+	if err != nil {
+		logrus.Error(err)
+		return domain.Order{}, err
+	}
+	return order, nil
+}
+
+// shortage returns the error of a cart line short of stock: the reservation
+// of the cart lapsed, or no stock was reserved
+func (p *OrderRepository) shortage(ctx context.Context, tx *transaction.Tx, reference string, productID int, variantID *int) error {
+	var lapsed bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM stock_reservations
+		WHERE reference = $1 AND product_id = $2 AND variant_id IS NOT DISTINCT FROM $3 AND status IN ($4, $5))`,
+		reference, productID, variantID, domain.ReservationActive, domain.ReservationExpired).Scan(&lapsed)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if lapsed {
+		return domain.ErrReservationExpired
+	}
+	return domain.ErrInsufficientStock
+}
+
+// Reserve holds the stock of the cart of a user for ttl, so other customers
+// can't buy it while the user checks out. Reserving again replaces the
+// reservations of the cart, checkout sells them.
+func (p *OrderRepository) Reserve(ctx context.Context, userID int, ttl time.Duration) (result []domain.StockReservation, err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var cartID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM carts WHERE user_id = $1 FOR UPDATE`, userID).Scan(&cartID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrEmptyCart
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	items, err := NewCartRepository(p.Conn).fetchItems(ctx, tx, cartID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, domain.ErrEmptyCart
+	}
+	sort.Slice(items, func(a, b int) bool {
+		return lockStockOrder(items[a].ProductID, items[a].VariantID, items[b].ProductID, items[b].VariantID)
+	})
+
+	inventory := NewInventoryRepository(p.Conn)
+	reference := cartReference(cartID)
+	if err = inventory.settleReservations(ctx, tx, reference, domain.ReservationReleased); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result = make([]domain.StockReservation, 0, len(items))
+	for _, item := range items {
+		reservation := domain.StockReservation{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			Reference: reference,
+			ExpiresAt: now.Add(ttl),
+		}
+		if err = inventory.reserve(ctx, tx, &reservation, now); err != nil {
+			return nil, err
+		}
+		result = append(result, reservation)
+	}
+	return result, nil
+}
+
+// ReleaseReservations gives the stock reserved for the cart of a user back
+func (p *OrderRepository) ReleaseReservations(ctx context.Context, userID int) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var cartID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM carts WHERE user_id = $1`, userID).Scan(&cartID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return NewInventoryRepository(p.Conn).settleReservations(ctx, tx, cartReference(cartID), domain.ReservationReleased)
+}
+
+func (p *OrderRepository) insertStatusChange(ctx context.Context, tx *transaction.Tx, orderID int, change *domain.OrderStatusChange) error {
+	var from interface{}
+	if change.From != "" {
+		from = change.From
+	}
+	change.OrderID = orderID
+	change.CreatedAt = time.Now()
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO order_status_history (order_id, from_status, to_status, note, changed_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		orderID, from, change.To, change.Note, change.ChangedBy, change.CreatedAt).Scan(&change.ID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// UpdateStatus moves an order to change.To when the transition is allowed and
+// records it in the history. Cancelled and refunded orders are put back in
+// stock, cancelled orders no longer count towards promotion usage limits.
+func (p *OrderRepository) UpdateStatus(ctx context.Context, id int, change *domain.OrderStatusChange) (err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return p.applyStatusChange(ctx, tx, id, change)
+}
+
+// applyStatusChange updates the order status within tx, see UpdateStatus
+func (p *OrderRepository) applyStatusChange(ctx context.Context, tx *transaction.Tx, id int, change *domain.OrderStatusChange) error {
+	err := tx.QueryRowContext(ctx, `SELECT status FROM orders WHERE id = $1 FOR UPDATE`, id).Scan(&change.From)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if !change.From.CanTransitionTo(change.To) {
+		return domain.ErrInvalidTransition
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3`, change.To, time.Now(), id)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if err := p.insertStatusChange(ctx, tx, id, change); err != nil {
+		return err
+	}
+
+	// Cancelled orders give their coupons back
+	if change.To == domain.OrderCancelled {
+		_, err = tx.ExecContext(ctx, `DELETE FROM promotion_redemptions WHERE order_id = $1`, id)
+		if err != nil {
+			logrus.Error(err)
+			return err
+		}
+	}
+	if !change.To.RestocksItems() {
+		return nil
+	}
+
+	items, err := p.fetchItems(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	inventory := NewInventoryRepository(p.Conn)
+	for _, item := range items {
+		err := inventory.applyMovement(ctx, tx, &domain.StockMovement{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Type:      domain.StockMovementReturn,
+			Quantity:  item.Quantity,
+			Reference: orderReference(id),
+			Note:      string(change.To),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
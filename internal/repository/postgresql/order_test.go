@@ -0,0 +1,148 @@
+package postgresql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/pricing"
+	"github.com/bimbims125/clean-arch/internal/repository/postgresql"
+	_ "github.com/lib/pq"
+)
+
+// openTestDB connects to the migrated database of POSTGRES_TEST_DSN, skipping
+// the test without one. Tests seed rows of their own and leave them behind.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// insert runs an INSERT returning the id of the new row
+func insert(t *testing.T, db *sql.DB, query string, args ...interface{}) int {
+	t.Helper()
+	var id int
+	if err := db.QueryRow(query+` RETURNING id`, args...).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// stock returns the stock and sales of a product, or of one of its variants
+func stock(t *testing.T, db *sql.DB, table string, id int) (stock, sold int) {
+	t.Helper()
+	err := db.QueryRow(`SELECT stock, sold FROM `+table+` WHERE id = $1`, id).Scan(&stock, &sold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stock, sold
+}
+
+// shopper seeds a customer and a product of stock units, returning their ids
+func shopper(t *testing.T, db *sql.DB, stock int) (userID, productID int) {
+	t.Helper()
+	seed := time.Now().UnixNano()
+	userID = insert(t, db, `INSERT INTO users (name, email, password) VALUES ($1, $2, 'x')`,
+		"shopper", fmt.Sprintf("shopper-%d@example.com", seed))
+	categoryID := insert(t, db, `INSERT INTO categories (name) VALUES ('shoes')`)
+	productID = insert(t, db, `INSERT INTO products (name, price, stock, category_id) VALUES ('shoe', 100000, $1, $2)`,
+		stock, categoryID)
+	return userID, productID
+}
+
+// addToCart puts a line in the cart of a user
+func addToCart(t *testing.T, db *sql.DB, userID, productID int, variantID *int, quantity int) int {
+	t.Helper()
+	carts := postgresql.NewCartRepository(db)
+	cart, err := carts.GetOrCreateCart(context.Background(), domain.CartOwner{UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := domain.CartItem{ProductID: productID, VariantID: variantID, Name: "shoe", UnitPrice: domain.NewMoney(10000000, domain.DefaultCurrency), Quantity: quantity}
+	if err := carts.SaveItem(context.Background(), cart.ID, &item); err != nil {
+		t.Fatal(err)
+	}
+	return cart.ID
+}
+
+func charges() domain.CartCharges {
+	return pricing.NewCalculator(pricing.DefaultTaxTable(), pricing.FreeShippingTable())
+}
+
+func TestCheckoutShortOfStockKeepsTheCart(t *testing.T) {
+	db := openTestDB(t)
+	userID, productID := shopper(t, db, 1)
+	cartID := addToCart(t, db, userID, productID, nil, 2)
+
+	_, err := postgresql.NewOrderRepository(db).Checkout(context.Background(), userID, "ID", charges())
+	if !errors.Is(err, domain.ErrInsufficientStock) {
+		t.Fatalf("Checkout() = %v, want %v", err, domain.ErrInsufficientStock)
+	}
+
+	var items, orders int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM cart_items WHERE cart_id = $1`, cartID).Scan(&items); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM orders WHERE user_id = $1`, userID).Scan(&orders); err != nil {
+		t.Fatal(err)
+	}
+	if items != 1 || orders != 0 {
+		t.Errorf("cart items and orders = %d and %d, want 1 and 0", items, orders)
+	}
+	if got, sold := stock(t, db, "products", productID); got != 1 || sold != 0 {
+		t.Errorf("stock and sold = %d and %d, want 1 and 0", got, sold)
+	}
+}
+
+func TestCancelRestocksTheItems(t *testing.T) {
+	db := openTestDB(t)
+	userID, productID := shopper(t, db, 3)
+	variantID := insert(t, db, `INSERT INTO product_variants (product_id, sku, stock) VALUES ($1, $2, 3)`,
+		productID, fmt.Sprintf("SHOE-%d-42", productID))
+	addToCart(t, db, userID, productID, &variantID, 2)
+
+	orders := postgresql.NewOrderRepository(db)
+	order, err := orders.Checkout(context.Background(), userID, "ID", charges())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, sold := stock(t, db, "product_variants", variantID); got != 1 || sold != 2 {
+		t.Fatalf("variant stock and sold after checkout = %d and %d, want 1 and 2", got, sold)
+	}
+	if got, sold := stock(t, db, "products", productID); got != 1 || sold != 2 {
+		t.Fatalf("product stock and sold after checkout = %d and %d, want 1 and 2", got, sold)
+	}
+
+	change := domain.OrderStatusChange{To: domain.OrderCancelled}
+	if err := orders.UpdateStatus(context.Background(), order.ID, &change); err != nil {
+		t.Fatal(err)
+	}
+	if got, sold := stock(t, db, "product_variants", variantID); got != 3 || sold != 0 {
+		t.Errorf("variant stock and sold after cancelling = %d and %d, want 3 and 0", got, sold)
+	}
+	if got, sold := stock(t, db, "products", productID); got != 3 || sold != 0 {
+		t.Errorf("product stock and sold after cancelling = %d and %d, want 3 and 0", got, sold)
+	}
+
+	// Cancelled orders are final, their items are put back once
+	change = domain.OrderStatusChange{To: domain.OrderRefunded}
+	if err := orders.UpdateStatus(context.Background(), order.ID, &change); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("UpdateStatus(refunded) = %v, want %v", err, domain.ErrInvalidTransition)
+	}
+}
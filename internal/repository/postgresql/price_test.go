@@ -0,0 +1,109 @@
+package postgresql_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/postgresql"
+)
+
+func idr(minor int64) domain.Money {
+	return domain.NewMoney(minor, domain.DefaultCurrency)
+}
+
+// price returns the price of a product
+func price(t *testing.T, db *sql.DB, productID int) domain.Money {
+	t.Helper()
+	var price domain.Money
+	if err := db.QueryRow(`SELECT currency, price FROM products WHERE id = $1`, productID).Scan(domain.CurrencyColumn{&price}, &price); err != nil {
+		t.Fatal(err)
+	}
+	return price
+}
+
+// scheduleSale applies a sale at 80000 until an hour after now to a product
+// priced 100000
+func scheduleSale(t *testing.T, db *sql.DB, productID int, now time.Time) (*postgresql.PriceRepository, domain.PriceSchedule) {
+	t.Helper()
+	prices := postgresql.NewPriceRepository(db)
+	ends := now.Add(time.Hour)
+	schedule := domain.PriceSchedule{ProductID: productID, Price: idr(8000000), StartsAt: now.Add(-time.Hour), EndsAt: &ends}
+	if err := prices.CreateSchedule(context.Background(), &schedule); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := prices.ApplyPriceSchedules(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+	if got := price(t, db, productID); got != idr(8000000) {
+		t.Fatalf("price during the sale = %v, want 80000", got)
+	}
+	return prices, schedule
+}
+
+// lastChange returns the latest entry of the price history of a product
+func lastChange(t *testing.T, prices *postgresql.PriceRepository, productID int) domain.PriceChange {
+	t.Helper()
+	_, history, err := prices.FetchHistory(context.Background(), productID, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) == 0 {
+		t.Fatal("empty price history")
+	}
+	return history[0]
+}
+
+func TestEndedScheduleRestoresThePrice(t *testing.T) {
+	db := openTestDB(t)
+	_, productID := shopper(t, db, 0)
+	now := time.Now()
+	prices, schedule := scheduleSale(t, db, productID, now)
+
+	if _, _, err := prices.ApplyPriceSchedules(context.Background(), now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := price(t, db, productID); got != idr(10000000) {
+		t.Errorf("price after the sale = %v, want 100000", got)
+	}
+	change := lastChange(t, prices, productID)
+	if change.ScheduleID == nil || *change.ScheduleID != schedule.ID || change.NewPrice != idr(10000000) {
+		t.Errorf("last change = %+v, want the schedule restoring 100000", change)
+	}
+}
+
+func TestEndedScheduleKeepsAPriceChangedMeanwhile(t *testing.T) {
+	db := openTestDB(t)
+	_, productID := shopper(t, db, 0)
+	now := time.Now()
+	prices, schedule := scheduleSale(t, db, productID, now)
+
+	// Staff reprices the product during the sale
+	update := domain.PriceChange{ProductID: productID, NewPrice: idr(9000000)}
+	if err := prices.UpdatePrice(context.Background(), &update, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := prices.ApplyPriceSchedules(context.Background(), now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got := price(t, db, productID); got != idr(9000000) {
+		t.Errorf("price after the sale = %v, want the staff price 90000", got)
+	}
+
+	schedules, err := prices.FetchSchedules(context.Background(), productID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 1 || schedules[0].Status != domain.PriceCompleted {
+		t.Errorf("schedules = %+v, want the sale completed", schedules)
+	}
+	change := lastChange(t, prices, productID)
+	if change.ScheduleID == nil || *change.ScheduleID != schedule.ID || change.OldPrice != change.NewPrice ||
+		!strings.Contains(change.Note, "price kept") {
+		t.Errorf("last change = %+v, want the schedule noting the price was kept", change)
+	}
+}
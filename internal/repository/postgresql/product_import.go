@@ -0,0 +1,234 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type ImportRepository struct {
+	Conn *sql.DB
+}
+
+func NewImportRepository(conn *sql.DB) *ImportRepository {
+	return &ImportRepository{conn}
+}
+
+func (p *ImportRepository) CreateImportJob(ctx context.Context, job *domain.ImportJob) error {
+	job.CreatedAt = time.Now()
+	if job.Errors == nil {
+		job.Errors = []domain.ImportRowError{}
+	}
+	errs, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	err = transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`INSERT INTO import_jobs (filename, status, total_rows, failed_rows, errors, error, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		job.Filename, job.Status, job.TotalRows, job.FailedRows, string(errs), job.Error, job.CreatedBy, job.CreatedAt).Scan(&job.ID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (p *ImportRepository) GetImportJob(ctx context.Context, id int) (job domain.ImportJob, err error) {
+	var errs string
+	err = transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`SELECT id, filename, status, total_rows, processed_rows, created_rows, updated_rows, failed_rows, errors, error, created_by, created_at, finished_at
+		FROM import_jobs WHERE id = $1`, id).
+		Scan(&job.ID, &job.Filename, &job.Status, &job.TotalRows, &job.ProcessedRows, &job.CreatedRows, &job.UpdatedRows,
+			&job.FailedRows, &errs, &job.Error, &job.CreatedBy, &job.CreatedAt, &job.FinishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ImportJob{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.ImportJob{}, err
+	}
+	if err := json.Unmarshal([]byte(errs), &job.Errors); err != nil {
+		return domain.ImportJob{}, err
+	}
+	return job, nil
+}
+
+// UpdateImportJob saves the progress of a job
+func (p *ImportRepository) UpdateImportJob(ctx context.Context, job *domain.ImportJob) error {
+	errs, err := json.Marshal(job.Errors)
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.Conn(ctx, p.Conn).ExecContext(ctx,
+		`UPDATE import_jobs SET status = $1, processed_rows = $2, created_rows = $3, updated_rows = $4, failed_rows = $5,
+		errors = $6, error = $7, finished_at = $8
+		WHERE id = $9`,
+		job.Status, job.ProcessedRows, job.CreatedRows, job.UpdatedRows, job.FailedRows, string(errs), job.Error, job.FinishedAt, job.ID)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// ImportProducts upserts a chunk of imported rows by SKU in a single
+// transaction, creating the categories missing by name. Rows whose price is
+// in another currency than the existing product are rejected.
+func (p *ImportRepository) ImportProducts(ctx context.Context, job *domain.ImportJob, rows []domain.ProductImportRow) (created, updated int, rowErrs []domain.ImportRowError, err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	categories := map[string]int{}
+	for _, row := range rows {
+		categoryID, err := p.category(ctx, tx, categories, row.Category)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		isNew, err := p.upsert(ctx, tx, job, row, categoryID)
+		switch {
+		case errors.Is(err, domain.ErrCurrencyMismatch):
+			rowErrs = append(rowErrs, domain.ImportRowError{Row: row.Row, Column: "price", Message: "must be in the currency of the product"})
+		case err != nil:
+			return 0, 0, nil, err
+		case isNew:
+			created++
+		default:
+			updated++
+		}
+	}
+	return created, updated, rowErrs, nil
+}
+
+// category returns the id of the category named name, ignoring case, creating
+// it when missing. Ids are cached in categories along a chunk.
+func (p *ImportRepository) category(ctx context.Context, tx *transaction.Tx, categories map[string]int, name string) (int, error) {
+	key := strings.ToLower(name)
+	if id, ok := categories[key]; ok {
+		return id, nil
+	}
+
+	var id int
+	err := tx.QueryRowContext(ctx, `SELECT id FROM categories WHERE LOWER(name) = $1 AND deleted_at IS NULL ORDER BY id ASC LIMIT 1`, key).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRowContext(ctx, `INSERT INTO categories (name) VALUES ($1) RETURNING id`, name).Scan(&id)
+	}
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	categories[key] = id
+	return id, nil
+}
+
+// upsert creates the product of a row or updates the product with its SKU,
+// restoring it when deleted. Price and stock changes go through the price
+// history and the stock ledger.
+func (p *ImportRepository) upsert(ctx context.Context, tx *transaction.Tx, job *domain.ImportJob, row domain.ProductImportRow, categoryID int) (created bool, err error) {
+	var id, stock int
+	var price domain.Money
+	err = tx.QueryRowContext(ctx, `SELECT id, stock, currency, price FROM products WHERE sku = $1 FOR UPDATE`, row.SKU).
+		Scan(&id, &stock, &price.Currency, &price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true, p.insert(ctx, tx, job, row, categoryID)
+	}
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	if !row.Price.SameCurrency(price) {
+		return false, domain.ErrCurrencyMismatch
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE products SET name = $1, category_id = $2, description = COALESCE($3, description), image_url = COALESCE($4, image_url),
+		weight = COALESCE($5, weight), length = COALESCE($6, length), width = COALESCE($7, width), height = COALESCE($8, height), version = version + 1,
+		deleted_at = NULL
+		WHERE id = $9`,
+		row.Name, categoryID, row.Description, row.ImageURL, row.Weight, row.Length, row.Width, row.Height, id)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	err = recordProductEvent(ctx, tx, domain.EventProductUpdated, id,
+		"name", "category_id", "description", "image_url", "weight", "length", "width", "height")
+	if err != nil {
+		return false, err
+	}
+
+	if row.Price.Cmp(price) != 0 {
+		change := &domain.PriceChange{
+			ProductID: id,
+			NewPrice:  row.Price,
+			ChangedBy: job.CreatedBy,
+			Note:      fmt.Sprintf("product import %d", job.ID),
+			CreatedAt: time.Now(),
+		}
+		if err := NewPriceRepository(p.Conn).setPrice(ctx, tx, change); err != nil {
+			return false, err
+		}
+	}
+	if row.Stock != nil && *row.Stock != stock {
+		return false, p.moveStock(ctx, tx, job, id, domain.StockMovementAdjustment, *row.Stock-stock)
+	}
+	return false, nil
+}
+
+func (p *ImportRepository) insert(ctx context.Context, tx *transaction.Tx, job *domain.ImportJob, row domain.ProductImportRow, categoryID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO products (sku, name, category_id, currency, price, description, image_url, weight, length, width, height)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
+		row.SKU, row.Name, categoryID, row.Price.CurrencyCode(), row.Price, row.Description, valueOrZero(row.ImageURL),
+		valueOrZero(row.Weight), valueOrZero(row.Length), valueOrZero(row.Width), valueOrZero(row.Height)).Scan(&id)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if err := recordProductEvent(ctx, tx, domain.EventProductCreated, id); err != nil {
+		return err
+	}
+
+	// Products start out of stock, the initial stock is recorded as a restock
+	if row.Stock != nil && *row.Stock > 0 {
+		return p.moveStock(ctx, tx, job, id, domain.StockMovementRestock, *row.Stock)
+	}
+	return nil
+}
+
+func (p *ImportRepository) moveStock(ctx context.Context, tx *transaction.Tx, job *domain.ImportJob, productID int, movementType domain.StockMovementType, quantity int) error {
+	return NewInventoryRepository(p.Conn).applyMovement(ctx, tx, &domain.StockMovement{
+		ProductID: productID,
+		Type:      movementType,
+		Quantity:  quantity,
+		Reference: fmt.Sprintf("import-%d", job.ID),
+		Note:      "product import",
+	})
+}
+
+// valueOrZero returns the value pointed by v, the zero value when nil
+func valueOrZero[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
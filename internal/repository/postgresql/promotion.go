@@ -0,0 +1,172 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type PromotionRepository struct {
+	Conn *sql.DB
+}
+
+func NewPromotionRepository(conn *sql.DB) *PromotionRepository {
+	return &PromotionRepository{conn}
+}
+
+const promotionColumns = `id, name, COALESCE(code, ''), type, percentage, amount, min_subtotal, category_id, usage_limit_per_user,
+	stackable, priority, active, starts_at, ends_at, created_at`
+
+func (p *PromotionRepository) fetch(ctx context.Context, q querier, query string, args ...interface{}) (result []domain.Promotion, err error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer func() {
+		errRow := rows.Close()
+		if errRow != nil {
+			logrus.Error(errRow)
+		}
+	}()
+
+	result = make([]domain.Promotion, 0)
+	for rows.Next() {
+		pr := domain.Promotion{}
+		var categoryID sql.NullInt64
+		var startsAt, endsAt sql.NullTime
+		err := rows.Scan(&pr.ID, &pr.Name, &pr.Code, &pr.Type, &pr.Percentage, &pr.Amount, &pr.MinSubtotal, &categoryID, &pr.UsageLimitPerUser,
+			&pr.Stackable, &pr.Priority, &pr.Active, &startsAt, &endsAt, &pr.CreatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		if categoryID.Valid {
+			id := int(categoryID.Int64)
+			pr.CategoryID = &id
+		}
+		if startsAt.Valid {
+			pr.StartsAt = &startsAt.Time
+		}
+		if endsAt.Valid {
+			pr.EndsAt = &endsAt.Time
+		}
+		result = append(result, pr)
+	}
+	return result, rows.Err()
+}
+
+// fetchActive returns the promotions enabled and within their validity window at now
+func (p *PromotionRepository) fetchActive(ctx context.Context, q querier, now time.Time) ([]domain.Promotion, error) {
+	query := `SELECT ` + promotionColumns + `
+						FROM promotions
+						WHERE active AND (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR ends_at > $1)`
+	return p.fetch(ctx, q, query, now)
+}
+
+func (p *PromotionRepository) Fetch(ctx context.Context) (result []domain.Promotion, err error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions ORDER BY id ASC`
+	return p.fetch(ctx, transaction.Conn(ctx, p.Conn), query)
+}
+
+func (p *PromotionRepository) GetByID(ctx context.Context, id int) (result domain.Promotion, err error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1`
+	res, err := p.fetch(ctx, transaction.Conn(ctx, p.Conn), query, id)
+	if err != nil {
+		return domain.Promotion{}, err
+	}
+	if len(res) == 0 {
+		return domain.Promotion{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+// nullableCode stores automatic promotions with a NULL code, codes are unique
+func nullableCode(code string) interface{} {
+	if code == "" {
+		return nil
+	}
+	return code
+}
+
+func (p *PromotionRepository) Create(ctx context.Context, promotion *domain.Promotion) error {
+	promotion.CreatedAt = time.Now()
+	err := transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`INSERT INTO promotions (name, code, type, percentage, amount, min_subtotal, category_id, usage_limit_per_user,
+			stackable, priority, active, starts_at, ends_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`,
+		promotion.Name, nullableCode(promotion.Code), promotion.Type, promotion.Percentage, promotion.Amount, promotion.MinSubtotal,
+		promotion.CategoryID, promotion.UsageLimitPerUser, promotion.Stackable, promotion.Priority, promotion.Active,
+		promotion.StartsAt, promotion.EndsAt, promotion.CreatedAt).Scan(&promotion.ID)
+	if isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (p *PromotionRepository) Update(ctx context.Context, promotion *domain.Promotion) error {
+	err := transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`UPDATE promotions SET name = $1, code = $2, type = $3, percentage = $4, amount = $5, min_subtotal = $6,
+			category_id = $7, usage_limit_per_user = $8, stackable = $9, priority = $10, active = $11, starts_at = $12,
+			ends_at = $13
+		WHERE id = $14 RETURNING created_at`,
+		promotion.Name, nullableCode(promotion.Code), promotion.Type, promotion.Percentage, promotion.Amount, promotion.MinSubtotal,
+		promotion.CategoryID, promotion.UsageLimitPerUser, promotion.Stackable, promotion.Priority, promotion.Active,
+		promotion.StartsAt, promotion.EndsAt, promotion.ID).Scan(&promotion.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+func (p *PromotionRepository) Delete(ctx context.Context, id int) error {
+	res, err := transaction.Conn(ctx, p.Conn).ExecContext(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// usage returns the number of redemptions of each promotion by a user
+func (p *PromotionRepository) usage(ctx context.Context, q querier, userID int) (result map[int]int, err error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT promotion_id, COUNT(*) FROM promotion_redemptions WHERE user_id = $1 GROUP BY promotion_id`, userID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = map[int]int{}
+	for rows.Next() {
+		var promotionID, count int
+		if err := rows.Scan(&promotionID, &count); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result[promotionID] = count
+	}
+	return result, rows.Err()
+}
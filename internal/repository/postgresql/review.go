@@ -0,0 +1,179 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+type ReviewRepository struct {
+	Conn *sql.DB
+}
+
+func NewReviewRepository(conn *sql.DB) *ReviewRepository {
+	return &ReviewRepository{conn}
+}
+
+func (p *ReviewRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Review, err error) {
+	rows, err := transaction.Conn(ctx, p.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.Review, 0)
+	for rows.Next() {
+		r := domain.Review{}
+		err := rows.Scan(&r.ID, &r.ProductID, &r.UserID, &r.Rating, &r.Title, &r.Body, &r.VerifiedPurchase, &r.Status, &r.CreatedAt, &r.UpdatedAt)
+		if err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+func (p *ReviewRepository) Fetch(ctx context.Context, filter domain.ReviewFilter, offset, limit int) (total int, result []domain.Review, err error) {
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	if filter.ProductID != 0 {
+		args = append(args, filter.ProductID)
+		conditions = append(conditions, fmt.Sprintf("product_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	err = transaction.Conn(ctx, p.Conn).QueryRowContext(ctx, `SELECT COUNT(*) FROM reviews WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	args = append(args, limit, offset)
+	query := fmt.Sprintf(`SELECT id, product_id, user_id, rating, title, body, verified_purchase, status, created_at, updated_at
+						FROM reviews
+						WHERE %s
+						ORDER BY id DESC
+						LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+	result, err = p.fetch(ctx, query, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, result, nil
+}
+
+func (p *ReviewRepository) GetByID(ctx context.Context, id int) (domain.Review, error) {
+	res, err := p.fetch(ctx, `SELECT id, product_id, user_id, rating, title, body, verified_purchase, status, created_at, updated_at
+						FROM reviews WHERE id = $1`, id)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if len(res) == 0 {
+		return domain.Review{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+// Create stores a pending review, flagged as a verified purchase when the
+// user has a paid order of the product. A user reviews a product once.
+func (p *ReviewRepository) Create(ctx context.Context, review *domain.Review) error {
+	var exists bool
+	err := transaction.Conn(ctx, p.Conn).QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL)`, review.ProductID).Scan(&exists)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if !exists {
+		return domain.ErrNotFound
+	}
+
+	statuses := make([]string, len(domain.ReviewedOrderStatuses))
+	for n, status := range domain.ReviewedOrderStatuses {
+		statuses[n] = string(status)
+	}
+	err = transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			WHERE o.user_id = $1 AND oi.product_id = $2 AND o.status = ANY($3)
+		)`, review.UserID, review.ProductID, pq.Array(statuses)).Scan(&review.VerifiedPurchase)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	review.Status = domain.ReviewPending
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+	err = transaction.Conn(ctx, p.Conn).QueryRowContext(ctx,
+		`INSERT INTO reviews (product_id, user_id, rating, title, body, verified_purchase, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		review.ProductID, review.UserID, review.Rating, review.Title, review.Body, review.VerifiedPurchase,
+		review.Status, review.CreatedAt, review.UpdatedAt).Scan(&review.ID)
+	if isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// UpdateStatus moderates a review and refreshes the rating of its product
+func (p *ReviewRepository) UpdateStatus(ctx context.Context, id int, status domain.ReviewStatus) (review domain.Review, err error) {
+	tx, err := transaction.Begin(ctx, p.Conn)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	err = tx.QueryRowContext(ctx,
+		`UPDATE reviews SET status = $1, updated_at = $2 WHERE id = $3
+		RETURNING id, product_id, user_id, rating, title, body, verified_purchase, status, created_at, updated_at`,
+		status, time.Now(), id).Scan(&review.ID, &review.ProductID, &review.UserID, &review.Rating, &review.Title,
+		&review.Body, &review.VerifiedPurchase, &review.Status, &review.CreatedAt, &review.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Review{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.Review{}, err
+	}
+
+	if err = p.refreshRating(ctx, tx, review.ProductID); err != nil {
+		return domain.Review{}, err
+	}
+	return review, nil
+}
+
+// refreshRating recomputes the rating of a product from its approved reviews
+func (p *ReviewRepository) refreshRating(ctx context.Context, tx *transaction.Tx, productID int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE products SET
+			rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE product_id = $1 AND status = $2), 0),
+			rating_count = (SELECT COUNT(*) FROM reviews WHERE product_id = $1 AND status = $2)
+		WHERE id = $1`, productID, domain.ReviewApproved)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
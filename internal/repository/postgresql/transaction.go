@@ -0,0 +1,26 @@
+package postgresql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/lib/pq"
+)
+
+// Postgres error codes of the transactions which may succeed when run again
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected)
+}
+
+// NewTransactionManager creates a transaction manager retrying serialization
+// failures and deadlocks
+func NewTransactionManager(conn *sql.DB) *transaction.Manager {
+	return transaction.NewManager(conn, isRetryable)
+}
@@ -0,0 +1,118 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+// The users, categories and products are soft deleted, the helpers below
+// work on any table with id, name, version and deleted_at columns
+
+// moveToTrash deletes a row of table, keeping it until restored or purged
+func moveToTrash(ctx context.Context, conn transaction.Executor, table string, id int) error {
+	res, err := conn.ExecContext(ctx, `UPDATE `+table+` SET deleted_at = $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL`,
+		time.Now(), id)
+	return changedOne(res, err)
+}
+
+// checkRowVersion locks a row of table until the end of tx, failing with
+// domain.ErrVersionMismatch unless it is at version, 0 matching any version.
+// Deleted rows aren't found.
+func checkRowVersion(ctx context.Context, tx *transaction.Tx, table string, id, version int) error {
+	var current int
+	err := tx.QueryRowContext(ctx, `SELECT version FROM `+table+` WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if version != 0 && current != version {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+// restoreFromTrash restores a deleted row of table
+func restoreFromTrash(ctx context.Context, conn transaction.Executor, table string, id int) error {
+	res, err := conn.ExecContext(ctx, `UPDATE `+table+` SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	return changedOne(res, err)
+}
+
+// changedOne fails with domain.ErrNotFound when a statement changed no row
+func changedOne(res sql.Result, err error) error {
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// fetchTrash returns a page of the deleted rows of table, last deleted first
+func fetchTrash(ctx context.Context, conn transaction.Executor, table string, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	err = conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table+` WHERE deleted_at IS NOT NULL`).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT id, name, deleted_at FROM `+table+`
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	items = make([]domain.TrashedItem, 0)
+	for rows.Next() {
+		var item domain.TrashedItem
+		if err := rows.Scan(&item.ID, &item.Name, &item.DeletedAt); err != nil {
+			logrus.Error(err)
+			return 0, nil, err
+		}
+		items = append(items, item)
+	}
+	return total, items, rows.Err()
+}
+
+// getTrashed returns a deleted row of table
+func getTrashed(ctx context.Context, conn transaction.Executor, table string, id int) (item domain.TrashedItem, err error) {
+	err = conn.QueryRowContext(ctx, `SELECT id, name, deleted_at FROM `+table+` WHERE id = $1 AND deleted_at IS NOT NULL`, id).
+		Scan(&item.ID, &item.Name, &item.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.TrashedItem{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.TrashedItem{}, err
+	}
+	return item, nil
+}
+
+// purgeTrash permanently deletes the rows t of table deleted before, except
+// those for which referenced, a query on t, returns rows
+func purgeTrash(ctx context.Context, conn transaction.Executor, table, referenced string, before time.Time) (int64, error) {
+	res, err := conn.ExecContext(ctx, `DELETE FROM `+table+` t WHERE t.deleted_at < $1 AND NOT EXISTS (`+referenced+`)`, before)
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
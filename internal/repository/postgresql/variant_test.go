@@ -0,0 +1,42 @@
+package postgresql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/postgresql"
+)
+
+func TestVariantUpdateKeepsTheStock(t *testing.T) {
+	db := openTestDB(t)
+	userID, productID := shopper(t, db, 3)
+	variants := postgresql.NewVariantRepository(db)
+	variant := domain.ProductVariant{ProductID: productID, SKU: fmt.Sprintf("SHOE-%d-42", productID), Stock: 3}
+	if err := variants.Create(context.Background(), &variant); err != nil {
+		t.Fatal(err)
+	}
+
+	// Staff edits the variant as read before a sale
+	stale := variant
+	addToCart(t, db, userID, productID, &variant.ID, 1)
+	if _, err := postgresql.NewOrderRepository(db).Checkout(context.Background(), userID, "ID", charges()); err != nil {
+		t.Fatal(err)
+	}
+	stale.SKU += "-B"
+	if err := variants.Update(context.Background(), &stale, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, sold := stock(t, db, "product_variants", variant.ID); got != 2 || sold != 1 {
+		t.Errorf("stock and sold = %d and %d, want the sale kept at 2 and 1", got, sold)
+	}
+	updated, err := variants.GetByID(context.Background(), productID, variant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.SKU != stale.SKU {
+		t.Errorf("sku = %q, want %q", updated.SKU, stale.SKU)
+	}
+}
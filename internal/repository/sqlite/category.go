@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type CategoryRepository struct {
+	Conn *sql.DB
+}
+
+func NewSQLiteCategoryRepository(conn *sql.DB) *CategoryRepository {
+	return &CategoryRepository{conn}
+}
+
+func (s *CategoryRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Category, err error) {
+	rows, err := transaction.Conn(ctx, s.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.Category, 0)
+	for rows.Next() {
+		c := domain.Category{}
+		if err := rows.Scan(&c.ID, &c.Name, &c.Version); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
+
+func (s *CategoryRepository) Fetch(ctx context.Context) (result []domain.Category, err error) {
+	return s.fetch(ctx, `SELECT id, name, version FROM categories WHERE deleted_at IS NULL ORDER BY id ASC`)
+}
+
+func (s *CategoryRepository) GetByID(ctx context.Context, id string) (result domain.Category, err error) {
+	res, err := s.fetch(ctx, `SELECT id, name, version FROM categories WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return domain.Category{}, err
+	}
+	if len(res) == 0 {
+		return domain.Category{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+// Create stores a category, under its id when set
+func (s *CategoryRepository) Create(ctx context.Context, category domain.Category) error {
+	id := sql.NullInt64{Int64: int64(category.ID), Valid: category.ID != 0}
+	_, err := transaction.Conn(ctx, s.Conn).ExecContext(ctx, `INSERT INTO categories (id, name) VALUES (?, ?)`, id, category.Name)
+	if isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// Delete moves a category to the trash, failing with
+// domain.ErrCategoryNotEmpty while it has products and with
+// domain.ErrVersionMismatch unless it is at version, 0 matching any version
+func (s *CategoryRepository) Delete(ctx context.Context, id, version int) (err error) {
+	tx, err := transaction.Begin(ctx, s.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkRowVersion(ctx, tx, "categories", id, version); err != nil {
+		return err
+	}
+
+	var hasProducts bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM products p WHERE p.category_id = c.id AND p.deleted_at IS NULL)
+		FROM categories c
+		WHERE c.id = ? AND c.deleted_at IS NULL`, id).Scan(&hasProducts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if hasProducts {
+		return domain.ErrCategoryNotEmpty
+	}
+	return moveToTrash(ctx, tx, "categories", id)
+}
+
+// FetchTrashed returns a page of the deleted categories
+func (s *CategoryRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	return fetchTrash(ctx, transaction.Conn(ctx, s.Conn), "categories", offset, limit)
+}
+
+// GetTrashed returns a deleted category
+func (s *CategoryRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	return getTrashed(ctx, transaction.Conn(ctx, s.Conn), "categories", id)
+}
+
+// Restore restores a deleted category, its products staying deleted
+func (s *CategoryRepository) Restore(ctx context.Context, id int) error {
+	return restoreFromTrash(ctx, transaction.Conn(ctx, s.Conn), "categories", id)
+}
+
+// Purge permanently deletes the categories deleted before, except those
+// holding products, deleted or not
+func (s *CategoryRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purgeTrash(ctx, transaction.Conn(ctx, s.Conn), "categories",
+		`SELECT 1 FROM products p WHERE p.category_id = t.id`, before)
+}
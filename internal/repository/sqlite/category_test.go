@@ -0,0 +1,117 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/sqlite"
+)
+
+func TestCategoryRepository(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteCategoryRepository(db)
+
+	if err := repo.Create(ctx, domain.Category{Name: "Shoes"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, domain.Category{ID: 10, Name: "Bags"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, domain.Category{ID: 10, Name: "Hats"}); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("creating a taken id: error = %v, want %v", err, domain.ErrConflict)
+	}
+
+	categories, err := repo.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []domain.Category{{ID: 1, Name: "Shoes", Version: 1}, {ID: 10, Name: "Bags", Version: 1}}
+	if len(categories) != 2 || categories[0] != want[0] || categories[1] != want[1] {
+		t.Errorf("categories = %+v, want %+v", categories, want)
+	}
+
+	category, err := repo.GetByID(ctx, "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if category != want[1] {
+		t.Errorf("category = %+v, want %+v", category, want[1])
+	}
+	if _, err := repo.GetByID(ctx, "2"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetByID of a missing category: error = %v, want %v", err, domain.ErrNotFound)
+	}
+}
+
+func TestCategoryRepositoryTrash(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteCategoryRepository(db)
+	products := sqlite.NewSQLiteProductRepository(db)
+	seedCatalog(t, db)
+
+	// Categories are deleted once their products are
+	if err := repo.Delete(ctx, 1, 0); !errors.Is(err, domain.ErrCategoryNotEmpty) {
+		t.Errorf("deleting a category holding products: error = %v, want %v", err, domain.ErrCategoryNotEmpty)
+	}
+	if err := products.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 1, 2); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("delete of another version: error = %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if err := repo.Delete(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 1, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("deleting a deleted category: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := repo.GetByID(ctx, "1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetByID of a deleted category: error = %v, want %v", err, domain.ErrNotFound)
+	}
+
+	total, items, err := repo.FetchTrashed(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(items) != 1 || items[0].ID != 1 || items[0].Name != "Shoes" {
+		t.Errorf("trash = %d %+v, want Shoes", total, items)
+	}
+
+	// Restoring a category leaves its products deleted
+	if err := repo.Restore(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	category, err := repo.GetByID(ctx, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if category.Version != 3 {
+		t.Errorf("version = %d, want 3 after a delete and a restore", category.Version)
+	}
+	if _, err := products.GetByID(ctx, 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetByID of the product of a restored category: error = %v, want %v", err, domain.ErrNotFound)
+	}
+
+	// Categories holding products, deleted or not, aren't purged
+	exec(t, db, `INSERT INTO categories (name) VALUES ('Empty')`)
+	if err := repo.Delete(ctx, 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := repo.Purge(ctx, time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want the empty category", n, err)
+	}
+	total, items, err = repo.FetchTrashed(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || items[0].ID != 1 {
+		t.Errorf("trash = %d %+v, want Shoes", total, items)
+	}
+}
@@ -0,0 +1,110 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+// recordEvent writes an event to the outbox on conn, which must run the
+// transaction of the change the event is about
+func recordEvent(ctx context.Context, conn transaction.Executor, eventType, key string, payload interface{}) error {
+	event, err := domain.NewEvent(eventType, key, payload)
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `INSERT INTO event_outbox (event_type, event_key, payload, occurred_at, available_at)
+		VALUES (?, ?, ?, ?, ?)`, event.Type, event.Key, string(event.Payload), event.OccurredAt.UTC(), event.OccurredAt.UTC())
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// recordProductEvent writes a product event to the outbox on conn, fields
+// naming the changed fields of product.updated events
+func recordProductEvent(ctx context.Context, conn transaction.Executor, eventType string, productID int, fields ...string) error {
+	return recordEvent(ctx, conn, eventType, domain.EventKey("product", productID), domain.ProductChanged{ProductID: productID, Fields: fields})
+}
+
+// EventOutboxRepository stores times in UTC, SQLite comparing them as text
+type EventOutboxRepository struct {
+	Conn *sql.DB
+}
+
+func NewSQLiteEventOutboxRepository(conn *sql.DB) *EventOutboxRepository {
+	return &EventOutboxRepository{conn}
+}
+
+// ClaimEvents claims up to limit events due for publication, oldest first,
+// hiding them from other relays until lease is over
+func (s *EventOutboxRepository) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]domain.Event, error) {
+	now := time.Now().UTC()
+	rows, err := transaction.Conn(ctx, s.Conn).QueryContext(ctx, `UPDATE event_outbox SET attempts = attempts + 1, available_at = ?
+		WHERE id IN (
+			SELECT id FROM event_outbox
+			WHERE published_at IS NULL AND available_at <= ?
+			ORDER BY id
+			LIMIT ?
+		)
+		RETURNING id, event_type, event_key, payload, occurred_at, attempts`, now.Add(lease), now, limit)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]domain.Event, 0)
+	for rows.Next() {
+		var event domain.Event
+		var payload string
+		if err := rows.Scan(&event.ID, &event.Type, &event.Key, &payload, &event.OccurredAt, &event.Attempts); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		event.Payload = json.RawMessage(payload)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// MarkEventPublished records that a claimed event was published
+func (s *EventOutboxRepository) MarkEventPublished(ctx context.Context, id int64) error {
+	_, err := transaction.Conn(ctx, s.Conn).ExecContext(ctx, `UPDATE event_outbox SET published_at = ?, last_error = '' WHERE id = ?`,
+		time.Now().UTC(), id)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// RetryEvent releases a claimed event which failed to publish, to be claimed
+// again from retryAt
+func (s *EventOutboxRepository) RetryEvent(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	_, err := transaction.Conn(ctx, s.Conn).ExecContext(ctx, `UPDATE event_outbox SET available_at = ?, last_error = ? WHERE id = ?`,
+		retryAt.UTC(), reason, id)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return err
+}
+
+// PurgeEvents deletes the events published before
+func (s *EventOutboxRepository) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := transaction.Conn(ctx, s.Conn).ExecContext(ctx, `DELETE FROM event_outbox WHERE published_at < ?`, before.UTC())
+	if err != nil {
+		logrus.Error(err)
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,278 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+// productColumns lists the columns scanned by scanProduct
+const productColumns = `p.id, COALESCE(p.sku, ''), p.name, p.currency, p.price, p.image_url, p.stock, p.sold, p.reorder_threshold,
+	p.weight, p.length, p.width, p.height, p.category_id, c.name, p.rating_average, p.rating_count`
+
+type ProductRepository struct {
+	Conn *sql.DB
+}
+
+func NewSQLiteProductRepository(conn *sql.DB) *ProductRepository {
+	return &ProductRepository{conn}
+}
+
+// scanProduct reads the productColumns of a row, followed by extra columns
+func scanProduct(rows *sql.Rows, product *domain.Product, extra ...interface{}) error {
+	// Scan the currency before the price, amounts are read in the currency already set
+	dest := []interface{}{&product.ID, &product.SKU, &product.Name, &product.Price.Currency, &product.Price, &product.ImageURL,
+		&product.Stock, &product.Sold, &product.ReorderThreshold, &product.Weight, &product.Length, &product.Width, &product.Height,
+		&product.Category.ID, &product.Category.Name, &product.Rating.Average, &product.Rating.Count}
+	return rows.Scan(append(dest, extra...)...)
+}
+
+// each calls fn with every product returned by query
+func (s *ProductRepository) each(ctx context.Context, fn func(*sql.Rows) error, query string, args ...interface{}) error {
+	rows, err := transaction.Conn(ctx, s.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *ProductRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.Product, err error) {
+	result = make([]domain.Product, 0)
+	err = s.each(ctx, func(rows *sql.Rows) error {
+		product := domain.Product{}
+		if err := scanProduct(rows, &product); err != nil {
+			logrus.Error(err)
+			return err
+		}
+		result = append(result, product)
+		return nil
+	}, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// productConditions returns the WHERE clause on products p matching filter.
+// Deleted products never match.
+func productConditions(filter domain.ProductFilter) (string, []interface{}) {
+	conditions := []string{"p.deleted_at IS NULL"}
+	args := []interface{}{}
+	if filter.CategoryID != 0 {
+		conditions = append(conditions, "p.category_id = ?")
+		args = append(args, filter.CategoryID)
+	}
+	if filter.Search != "" {
+		// LIKE compares ASCII letters case insensitively
+		pattern := "%" + likeEscaper.Replace(filter.Search) + "%"
+		conditions = append(conditions, `(p.name LIKE ? ESCAPE '\' OR p.sku LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+	if filter.MinPrice != nil {
+		conditions = append(conditions, "p.currency = ? AND p.price >= ?")
+		args = append(args, filter.MinPrice.CurrencyCode(), *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		conditions = append(conditions, "p.currency = ? AND p.price <= ?")
+		args = append(args, filter.MaxPrice.CurrencyCode(), *filter.MaxPrice)
+	}
+	if filter.InStock {
+		conditions = append(conditions, "p.stock > 0")
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *ProductRepository) Fetch(ctx context.Context) (result []domain.Product, err error) {
+	return s.fetch(ctx, `SELECT `+productColumns+`
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE p.deleted_at IS NULL
+						ORDER BY p.id ASC`)
+}
+
+func (s *ProductRepository) FetchPaginated(ctx context.Context, filter domain.ProductFilter, offset, limit int) (total int, products []domain.Product, err error) {
+	where, args := productConditions(filter)
+	err = transaction.Conn(ctx, s.Conn).QueryRowContext(ctx, `SELECT COUNT(*) FROM products p WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		logrus.Error(err)
+		return 0, nil, err
+	}
+
+	products, err = s.fetch(ctx, `SELECT `+productColumns+`
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE `+where+`
+						ORDER BY p.id ASC
+						LIMIT ? OFFSET ?`, append(args, limit, offset)...)
+	if err != nil {
+		return 0, nil, err
+	}
+	return total, products, nil
+}
+
+// Export calls fn with every product matching filter, with its description,
+// reading them one row at a time instead of loading the catalog in memory
+func (s *ProductRepository) Export(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error {
+	where, args := productConditions(filter)
+	return s.each(ctx, func(rows *sql.Rows) error {
+		product := domain.Product{}
+		if err := scanProduct(rows, &product, &product.Description); err != nil {
+			logrus.Error(err)
+			return err
+		}
+		return fn(product)
+	}, `SELECT `+productColumns+`, COALESCE(p.description, '')
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE `+where+`
+						ORDER BY p.id ASC`, args...)
+}
+
+// GetByID returns a product. Variants and images aren't implemented by the
+// SQLite backend, products are returned without them.
+func (s *ProductRepository) GetByID(ctx context.Context, id int) (result domain.Product, err error) {
+	found := false
+	err = s.each(ctx, func(rows *sql.Rows) error {
+		found = true
+		if err := scanProduct(rows, &result, &result.Version, &result.Description); err != nil {
+			logrus.Error(err)
+			return err
+		}
+		return nil
+	}, `SELECT `+productColumns+`, p.version, COALESCE(p.description, '')
+						FROM products p
+						JOIN categories c ON p.category_id = c.id
+						WHERE p.id = ? AND p.deleted_at IS NULL`, id)
+	if err != nil {
+		return domain.Product{}, err
+	}
+	if !found {
+		return domain.Product{}, domain.ErrNotFound
+	}
+	return result, nil
+}
+
+// checkVersion fails with domain.ErrVersionMismatch unless a product is at
+// version, 0 matching any version. SQLite fails the writes of a transaction
+// which read rows changed since, so the check holds until tx ends. Deleted
+// products aren't found.
+func checkVersion(ctx context.Context, tx *transaction.Tx, productID, version int) error {
+	return checkRowVersion(ctx, tx, "products", productID, version)
+}
+
+func (s *ProductRepository) UpdateShippingDetails(ctx context.Context, productID, version int, details domain.ShippingDetails) (err error) {
+	tx, err := transaction.Begin(ctx, s.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, productID, version); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE products SET weight = ?, length = ?, width = ?, height = ?, version = version + 1 WHERE id = ?`,
+		details.Weight, details.Length, details.Width, details.Height, productID)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductUpdated, productID, "weight", "length", "width", "height")
+}
+
+// Delete moves a product to the trash
+func (s *ProductRepository) Delete(ctx context.Context, productID, version int) (err error) {
+	tx, err := transaction.Begin(ctx, s.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkVersion(ctx, tx, productID, version); err != nil {
+		return err
+	}
+	if err = moveToTrash(ctx, tx, "products", productID); err != nil {
+		return err
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductDeleted, productID)
+}
+
+// FetchTrashed returns a page of the deleted products
+func (s *ProductRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	return fetchTrash(ctx, transaction.Conn(ctx, s.Conn), "products", offset, limit)
+}
+
+// GetTrashed returns a deleted product
+func (s *ProductRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	return getTrashed(ctx, transaction.Conn(ctx, s.Conn), "products", id)
+}
+
+// Restore restores a deleted product, failing with domain.ErrCategoryDeleted
+// while its category is deleted
+func (s *ProductRepository) Restore(ctx context.Context, productID int) (err error) {
+	tx, err := transaction.Begin(ctx, s.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var categoryDeleted bool
+	err = tx.QueryRowContext(ctx, `SELECT c.deleted_at IS NOT NULL
+		FROM products p
+		JOIN categories c ON p.category_id = c.id
+		WHERE p.id = ? AND p.deleted_at IS NOT NULL`, productID).Scan(&categoryDeleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	if categoryDeleted {
+		return domain.ErrCategoryDeleted
+	}
+	if err = restoreFromTrash(ctx, tx, "products", productID); err != nil {
+		return err
+	}
+	return recordProductEvent(ctx, tx, domain.EventProductRestored, productID)
+}
+
+// Purge permanently deletes the products deleted before, except those ordered
+func (s *ProductRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purgeTrash(ctx, transaction.Conn(ctx, s.Conn), "products",
+		`SELECT 1 FROM order_items oi WHERE oi.product_id = t.id`, before)
+}
@@ -0,0 +1,260 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/sqlite"
+)
+
+func rupiah(amount int64) domain.Money {
+	return domain.NewMoney(amount*100, domain.DefaultCurrency)
+}
+
+// seedCatalog adds the Shoes and Accessories categories and a shoe, a bag
+// out of stock and a hat. Products are created by imports, which the SQLite
+// backend doesn't implement.
+func seedCatalog(t *testing.T, db *sql.DB) {
+	t.Helper()
+	exec(t, db, `INSERT INTO categories (id, name) VALUES (1, 'Shoes'), (2, 'Accessories')`)
+	exec(t, db, `INSERT INTO products (id, sku, name, description, price, stock, category_id) VALUES
+		(1, 'SHOE-1', 'Running Shoe', 'Light and fast', ?, 5, 1),
+		(2, 'BAG-1', 'Leather Bag', NULL, ?, 0, 2),
+		(3, 'HAT-1', '100% Sun_Hat', 'Wide', ?, 10, 2)`,
+		rupiah(150000), rupiah(300000), rupiah(50000))
+}
+
+func productSKUs(products []domain.Product) string {
+	skus := make([]string, 0, len(products))
+	for _, p := range products {
+		skus = append(skus, p.SKU)
+	}
+	return strings.Join(skus, ",")
+}
+
+func TestProductRepositoryFetch(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteProductRepository(db)
+	seedCatalog(t, db)
+
+	products, err := repo.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := productSKUs(products); got != "SHOE-1,BAG-1,HAT-1" {
+		t.Fatalf("products = %s, want SHOE-1,BAG-1,HAT-1", got)
+	}
+	shoe := products[0]
+	if shoe.Price != rupiah(150000) || shoe.Stock != 5 || shoe.Category != (domain.Category{ID: 1, Name: "Shoes"}) {
+		t.Errorf("product = %+v, want the shoe", shoe)
+	}
+
+	low, high := rupiah(100000), rupiah(200000)
+	tests := []struct {
+		name   string
+		filter domain.ProductFilter
+		want   string
+	}{
+		{"all", domain.ProductFilter{}, "SHOE-1,BAG-1,HAT-1"},
+		{"category", domain.ProductFilter{CategoryID: 2}, "BAG-1,HAT-1"},
+		{"name", domain.ProductFilter{Search: "shoe"}, "SHOE-1"},
+		{"sku", domain.ProductFilter{Search: "bag-"}, "BAG-1"},
+		{"escaped wildcards", domain.ProductFilter{Search: "0% Sun_"}, "HAT-1"},
+		{"literal wildcards", domain.ProductFilter{Search: "g_1"}, ""},
+		{"in stock", domain.ProductFilter{InStock: true}, "SHOE-1,HAT-1"},
+		{"min price", domain.ProductFilter{MinPrice: &low}, "SHOE-1,BAG-1"},
+		{"price range", domain.ProductFilter{MinPrice: &low, MaxPrice: &high}, "SHOE-1"},
+		{"other currency", domain.ProductFilter{MinPrice: &domain.Money{Amount: 1, Currency: "USD"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, products, err := repo.FetchPaginated(ctx, tt.filter, 0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := productSKUs(products); got != tt.want {
+				t.Errorf("products = %s, want %s", got, tt.want)
+			}
+			if total != len(products) {
+				t.Errorf("total = %d, want %d", total, len(products))
+			}
+		})
+	}
+
+	total, products, err := repo.FetchPaginated(ctx, domain.ProductFilter{}, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || productSKUs(products) != "BAG-1" {
+		t.Errorf("second page = %d %s, want BAG-1 of 3", total, productSKUs(products))
+	}
+}
+
+func TestProductRepositoryExport(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteProductRepository(db)
+	seedCatalog(t, db)
+
+	var exported []domain.Product
+	err := repo.Export(ctx, domain.ProductFilter{CategoryID: 2}, func(product domain.Product) error {
+		exported = append(exported, product)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if productSKUs(exported) != "BAG-1,HAT-1" || exported[0].Description != "" || exported[1].Description != "Wide" {
+		t.Errorf("exported = %+v, want the bag and the hat with their descriptions", exported)
+	}
+
+	stop := errors.New("stop")
+	err = repo.Export(ctx, domain.ProductFilter{}, func(product domain.Product) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Export error = %v, want the error of fn", err)
+	}
+}
+
+func TestProductRepositoryGetByID(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteProductRepository(db)
+	seedCatalog(t, db)
+
+	product, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.SKU != "SHOE-1" || product.Description != "Light and fast" || product.Version != 1 || product.Price != rupiah(150000) {
+		t.Errorf("product = %+v, want the shoe at version 1 with its description", product)
+	}
+	if _, err := repo.GetByID(ctx, 4); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetByID of a missing product: error = %v, want %v", err, domain.ErrNotFound)
+	}
+}
+
+func TestProductRepositoryUpdateShippingDetails(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteProductRepository(db)
+	seedCatalog(t, db)
+	details := domain.ShippingDetails{Weight: 800, Length: 30, Width: 20, Height: 12.5}
+
+	if err := repo.UpdateShippingDetails(ctx, 1, 2, details); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("update of another version: error = %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if err := repo.UpdateShippingDetails(ctx, 4, 0, details); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("update of a missing product: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	expectEvents(t, db)
+
+	if err := repo.UpdateShippingDetails(ctx, 1, 1, details); err != nil {
+		t.Fatal(err)
+	}
+	product, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.ShippingDetails != details || product.Version != 2 {
+		t.Errorf("product = %+v, want the shipping details at version 2", product)
+	}
+	expectEvents(t, db, domain.EventProductUpdated)
+
+	// Unconditional updates match any version
+	if err := repo.UpdateShippingDetails(ctx, 1, 0, domain.ShippingDetails{Weight: 900}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProductRepositoryTrash(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteProductRepository(db)
+	categories := sqlite.NewSQLiteCategoryRepository(db)
+	seedCatalog(t, db)
+
+	if err := repo.Delete(ctx, 1, 2); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("delete of another version: error = %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if err := repo.Delete(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 1, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("delete of a deleted product: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := repo.GetByID(ctx, 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetByID of a deleted product: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if err := repo.UpdateShippingDetails(ctx, 1, 0, domain.ShippingDetails{}); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("update of a deleted product: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	products, err := repo.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := productSKUs(products); got != "BAG-1,HAT-1" {
+		t.Errorf("products = %s, want BAG-1,HAT-1", got)
+	}
+
+	total, items, err := repo.FetchTrashed(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(items) != 1 || items[0].ID != 1 || items[0].Name != "Running Shoe" {
+		t.Errorf("trash = %d %+v, want the shoe", total, items)
+	}
+
+	// Products are restored once their category is
+	if err := categories.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(ctx, 1); !errors.Is(err, domain.ErrCategoryDeleted) {
+		t.Errorf("restoring the product of a deleted category: error = %v, want %v", err, domain.ErrCategoryDeleted)
+	}
+	if err := categories.Restore(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(ctx, 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("restoring a product which isn't deleted: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	product, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Version != 3 {
+		t.Errorf("version = %d, want 3 after a delete and a restore", product.Version)
+	}
+	expectEvents(t, db, domain.EventProductDeleted, domain.EventProductRestored)
+
+	// Ordered products aren't purged
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, `INSERT INTO users (name, email, password, role) VALUES ('Alice', 'alice@example.com', 'hash', 'customer')`)
+	exec(t, db, `INSERT INTO orders (user_id, subtotal, total) VALUES (1, 100, 100)`)
+	exec(t, db, `INSERT INTO order_items (order_id, product_id, name, unit_price, quantity) VALUES (1, 2, 'Leather Bag', 100, 1)`)
+	if n, err := repo.Purge(ctx, time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want the shoe", n, err)
+	}
+	total, items, err = repo.FetchTrashed(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || items[0].ID != 2 {
+		t.Errorf("trash = %d %+v, want the bag", total, items)
+	}
+}
@@ -0,0 +1,114 @@
+// Package sqlite implements repositories on an embedded SQLite database, so
+// the API runs from a single file during local development and tests. The
+// users and the catalog are implemented.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bimbims125/clean-arch/migrations"
+	sqlite3 "modernc.org/sqlite"
+	sqlite3lib "modernc.org/sqlite/lib"
+)
+
+// MemoryPath opens a private in-memory database, lost when closed
+const MemoryPath = ":memory:"
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code() == sqlite3lib.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3lib.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// Open opens the database file at path, creating it when missing, and applies
+// the pending migrations
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	if path != MemoryPath {
+		query.Add("_pragma", "journal_mode(WAL)")
+	}
+	query.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if path == MemoryPath {
+		// Every connection would open its own empty database
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := Migrate(ctx, db, migrations.SQLite()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate applies the migrations of fsys which weren't applied yet, in the
+// order of their file names, recording them in schema_migrations
+func Migrate(ctx context.Context, db *sql.DB, fsys fs.FS) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := migrate(ctx, db, fsys, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrate applies a migration unless it was applied already
+func migrate(ctx context.Context, db *sql.DB, fsys fs.FS, name string) (err error) {
+	version := strings.TrimSuffix(name, ".sql")
+	var applied int
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, version).Scan(&applied)
+	if err != nil || applied > 0 {
+		return err
+	}
+
+	script, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+		return fmt.Errorf("migration %s: %w", version, err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, version)
+	return err
+}
@@ -0,0 +1,116 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"reflect"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/sqlite"
+	"github.com/bimbims125/clean-arch/migrations"
+)
+
+// openTestDB opens a private in-memory database with the migrations applied
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite.Open(context.Background(), sqlite.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// exec runs statements seeding a test database
+func exec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventTypes returns the types of the events in the outbox, oldest first
+func eventTypes(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT event_type FROM event_outbox ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	types := []string{}
+	for rows.Next() {
+		var eventType string
+		if err := rows.Scan(&eventType); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, eventType)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return types
+}
+
+func expectEvents(t *testing.T, db *sql.DB, want ...string) {
+	t.Helper()
+	if want == nil {
+		want = []string{}
+	}
+	if got := eventTypes(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+
+	names, err := fs.Glob(migrations.SQLite(), "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(names) {
+		t.Errorf("applied %d migrations, want %d", applied, len(names))
+	}
+
+	// Applied migrations are skipped
+	if err := sqlite.Migrate(ctx, db, migrations.SQLite()); err != nil {
+		t.Fatal(err)
+	}
+	var again int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&again); err != nil {
+		t.Fatal(err)
+	}
+	if again != applied {
+		t.Errorf("applied %d migrations after migrating again, want %d", again, applied)
+	}
+
+	// Foreign keys are enforced
+	_, err = db.Exec(`INSERT INTO products (name, category_id) VALUES ('Orphan', 42)`)
+	if err == nil {
+		t.Error("inserted a product of a missing category")
+	}
+}
+
+func TestMemoryDatabasesArePrivate(t *testing.T) {
+	ctx := context.Background()
+	first, second := openTestDB(t), openTestDB(t)
+
+	if err := sqlite.NewSQLiteCategoryRepository(first).Create(ctx, domain.Category{Name: "Shoes"}); err != nil {
+		t.Fatal(err)
+	}
+	categories, err := sqlite.NewSQLiteCategoryRepository(second).Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 0 {
+		t.Errorf("categories = %+v, want none", categories)
+	}
+}
@@ -0,0 +1,14 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+)
+
+// NewSQLiteTransactionManager creates a transaction manager. SQLite runs
+// writes one at a time, waiting for the busy timeout, so failures aren't
+// retried.
+func NewSQLiteTransactionManager(conn *sql.DB) *transaction.Manager {
+	return transaction.NewManager(conn, nil)
+}
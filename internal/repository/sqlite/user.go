@@ -0,0 +1,164 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/transaction"
+	"github.com/sirupsen/logrus"
+)
+
+type UserRepository struct {
+	Conn *sql.DB
+}
+
+func NewSQLiteUserRepository(conn *sql.DB) *UserRepository {
+	return &UserRepository{conn}
+}
+
+func (s *UserRepository) fetch(ctx context.Context, query string, args ...interface{}) (result []domain.User, err error) {
+	rows, err := transaction.Conn(ctx, s.Conn).QueryContext(ctx, query, args...)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	result = make([]domain.User, 0)
+	for rows.Next() {
+		u := domain.User{}
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Version); err != nil {
+			logrus.Error(err)
+			return nil, err
+		}
+		result = append(result, u)
+	}
+	return result, rows.Err()
+}
+
+func (s *UserRepository) Fetch(ctx context.Context) (result []domain.User, err error) {
+	return s.fetch(ctx, `SELECT id, name, email, role, version FROM users WHERE deleted_at IS NULL ORDER BY id ASC`)
+}
+
+// Create registers a user, raising a user.registered event
+func (s *UserRepository) Create(ctx context.Context, user domain.User) (err error) {
+	if err := user.HashPassword(); err != nil {
+		return err
+	}
+
+	tx, err := transaction.Begin(ctx, s.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO users (name, email, password, role) VALUES (?, ?, ?, ?)`,
+		user.Name, user.Email, user.Password, user.Role)
+	if isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	user.ID = int(id)
+	return recordEvent(ctx, tx, domain.EventUserRegistered, domain.EventKey("user", user.ID), domain.UserRegistered{
+		UserID: user.ID,
+		Name:   user.Name,
+		Email:  user.Email,
+		Role:   user.Role,
+	})
+}
+
+// GetByID returns the user of id
+func (s *UserRepository) GetByID(ctx context.Context, id int) (result domain.User, err error) {
+	res, err := s.fetch(ctx, `SELECT id, name, email, role, version FROM users WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if len(res) == 0 {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+func (s *UserRepository) GetByEmail(ctx context.Context, email string) (result domain.User, err error) {
+	res, err := s.fetch(ctx, `SELECT id, name, email, role, version FROM users WHERE email = ? AND deleted_at IS NULL`, email)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if len(res) == 0 {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return res[0], nil
+}
+
+// GetCredentials returns the user with the given email along with its hashed
+// password. Deleted users aren't found, so they can't log in.
+func (s *UserRepository) GetCredentials(ctx context.Context, email string) (result domain.User, err error) {
+	err = transaction.Conn(ctx, s.Conn).QueryRowContext(ctx, `SELECT id, name, email, role, password FROM users WHERE email = ? AND deleted_at IS NULL`, email).
+		Scan(&result.ID, &result.Name, &result.Email, &result.Role, &result.Password)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.User{}, err
+	}
+	return result, nil
+}
+
+// Delete moves a user to the trash, failing with domain.ErrVersionMismatch
+// unless it is at version, 0 matching any version
+func (s *UserRepository) Delete(ctx context.Context, id, version int) (err error) {
+	tx, err := transaction.Begin(ctx, s.Conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if err = checkRowVersion(ctx, tx, "users", id, version); err != nil {
+		return err
+	}
+	return moveToTrash(ctx, tx, "users", id)
+}
+
+// FetchTrashed returns a page of the deleted users
+func (s *UserRepository) FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error) {
+	return fetchTrash(ctx, transaction.Conn(ctx, s.Conn), "users", offset, limit)
+}
+
+// GetTrashed returns a deleted user
+func (s *UserRepository) GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error) {
+	return getTrashed(ctx, transaction.Conn(ctx, s.Conn), "users", id)
+}
+
+// Restore restores a deleted user
+func (s *UserRepository) Restore(ctx context.Context, id int) error {
+	return restoreFromTrash(ctx, transaction.Conn(ctx, s.Conn), "users", id)
+}
+
+// Purge permanently deletes the users deleted before, except those with orders
+func (s *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return purgeTrash(ctx, transaction.Conn(ctx, s.Conn), "users",
+		`SELECT 1 FROM orders o WHERE o.user_id = t.id`, before)
+}
@@ -0,0 +1,138 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/repository/sqlite"
+)
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteUserRepository(db)
+
+	alice := domain.User{Name: "Alice", Email: "alice@example.com", Password: "Passw0rd!", Role: domain.RoleCustomer}
+	if err := repo.Create(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, domain.User{Name: "Bob", Email: "bob@example.com", Password: "Passw0rd!", Role: domain.RoleStaff}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, alice); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("creating a taken email: error = %v, want %v", err, domain.ErrConflict)
+	}
+	// The failed signup raised no event
+	expectEvents(t, db, domain.EventUserRegistered, domain.EventUserRegistered)
+
+	users, err := repo.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Email != "alice@example.com" || users[1].Role != domain.RoleStaff {
+		t.Errorf("users = %+v, want alice and bob", users)
+	}
+
+	user, err := repo.GetByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != users[0].ID || user.Name != "Alice" || user.Password != "" {
+		t.Errorf("user = %+v, want alice without her password", user)
+	}
+	if _, err := repo.GetByEmail(ctx, "carol@example.com"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetByEmail of an unknown email: error = %v, want %v", err, domain.ErrNotFound)
+	}
+
+	credentials, err := repo.GetCredentials(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.Password == alice.Password || !credentials.CheckPassword(alice.Password) {
+		t.Error("the stored password isn't a hash of the password")
+	}
+}
+
+func TestUserRepositoryTrash(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := sqlite.NewSQLiteUserRepository(db)
+
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := repo.Create(ctx, domain.User{Name: email, Email: email, Password: "Passw0rd!", Role: domain.RoleCustomer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.Delete(ctx, 1, 2); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Errorf("delete of another version: error = %v, want %v", err, domain.ErrVersionMismatch)
+	}
+	if err := repo.Delete(ctx, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 1, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("deleting a deleted user: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if err := repo.Delete(ctx, 3, 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("deleting a missing user: error = %v, want %v", err, domain.ErrNotFound)
+	}
+
+	// Deleted users can't log in and keep their email
+	if _, err := repo.GetCredentials(ctx, "alice@example.com"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetCredentials of a deleted user: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	err := repo.Create(ctx, domain.User{Name: "Alice", Email: "alice@example.com", Password: "Passw0rd!", Role: domain.RoleCustomer})
+	if !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("creating the email of a deleted user: error = %v, want %v", err, domain.ErrConflict)
+	}
+	users, err := repo.Fetch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].ID != 2 {
+		t.Errorf("users = %+v, want bob", users)
+	}
+
+	total, items, err := repo.FetchTrashed(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(items) != 1 || items[0].ID != 1 || items[0].Name != "alice@example.com" || items[0].DeletedAt.IsZero() {
+		t.Errorf("trash = %d %+v, want alice", total, items)
+	}
+
+	if err := repo.Restore(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Restore(ctx, 1); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("restoring a user which isn't deleted: error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := repo.GetCredentials(ctx, "alice@example.com"); err != nil {
+		t.Errorf("GetCredentials of a restored user: %v", err)
+	}
+
+	// Users are purged once deleted before the given time, unless they ordered
+	if err := repo.Delete(ctx, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, `INSERT INTO orders (user_id, subtotal, total) VALUES (2, 100, 100)`)
+	if n, err := repo.Purge(ctx, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("Purge before an hour ago = %d, %v, want none", n, err)
+	}
+	if n, err := repo.Purge(ctx, time.Now().Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("Purge = %d, %v, want alice", n, err)
+	}
+	total, items, err = repo.FetchTrashed(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || items[0].ID != 2 {
+		t.Errorf("trash = %d %+v, want bob", total, items)
+	}
+}
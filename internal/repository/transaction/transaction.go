@@ -0,0 +1,189 @@
+// Package transaction carries database transactions in contexts, so that the
+// operations of several repositories given the same context commit or roll
+// back together. Repositories run their statements on Conn and begin their
+// own transactions with Begin, which turn into savepoints of the transaction
+// carried by the context, if any.
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAttempts is the number of times a transaction is run before its
+// retryable failure is returned
+const DefaultAttempts = 3
+
+// Executor runs statements, implemented by *sql.DB, *sql.Tx and *Tx
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type contextKey struct{}
+
+// state is a transaction carried by a context, shared with the savepoints
+// nested in it. Like *sql.Tx, it isn't safe for concurrent use.
+type state struct {
+	db         *sql.DB
+	tx         *sql.Tx
+	savepoints int
+}
+
+// current returns the transaction on db carried by ctx, or nil
+func current(ctx context.Context, db *sql.DB) *state {
+	s, _ := ctx.Value(contextKey{}).(*state)
+	if s == nil || s.db != db {
+		return nil
+	}
+	return s
+}
+
+// Conn returns the transaction on db carried by ctx, or db when there is none
+func Conn(ctx context.Context, db *sql.DB) Executor {
+	if s := current(ctx, db); s != nil {
+		return s.tx
+	}
+	return db
+}
+
+// Tx is a transaction, or a savepoint when begun in a transaction
+type Tx struct {
+	tx        *sql.Tx
+	savepoint string
+	done      bool
+}
+
+// Begin begins a transaction on db, or a savepoint of the transaction on db
+// carried by ctx
+func Begin(ctx context.Context, db *sql.DB) (*Tx, error) {
+	return begin(ctx, db, nil)
+}
+
+func begin(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*Tx, error) {
+	if s := current(ctx, db); s != nil {
+		s.savepoints++
+		name := "sp_" + strconv.Itoa(s.savepoints)
+		if _, err := s.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, err
+		}
+		return &Tx{tx: s.tx, savepoint: name}, nil
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx}, nil
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+// Commit commits the transaction, or releases the savepoint
+func (t *Tx) Commit() error {
+	if t.savepoint == "" {
+		return t.tx.Commit()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.tx.Exec("RELEASE SAVEPOINT " + t.savepoint)
+	return err
+}
+
+// Rollback rolls back the transaction, or the statements run since the savepoint
+func (t *Tx) Rollback() error {
+	if t.savepoint == "" {
+		return t.tx.Rollback()
+	}
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	_, err := t.tx.Exec("ROLLBACK TO SAVEPOINT " + t.savepoint)
+	return err
+}
+
+// Manager runs functions in transactions carried by their context
+type Manager struct {
+	DB *sql.DB
+	// Options are used when beginning a transaction, not a savepoint
+	Options *sql.TxOptions
+	// Retryable reports whether a transaction which failed with err may
+	// succeed when run again, nil when failures are never retried
+	Retryable func(err error) bool
+	Attempts  int
+}
+
+// NewManager creates a transaction manager retrying the failures reported
+// as retryable up to DefaultAttempts times
+func NewManager(db *sql.DB, retryable func(err error) bool) *Manager {
+	return &Manager{DB: db, Retryable: retryable, Attempts: DefaultAttempts}
+}
+
+// Do calls fn with a context carrying a transaction, committed when fn
+// returns nil and rolled back otherwise. When ctx already carries a
+// transaction fn runs in a savepoint of it, and its failures are retried
+// with the outermost transaction. fn may be called several times, it must
+// not have side effects outside of the database.
+func (m *Manager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if current(ctx, m.DB) != nil {
+		return m.run(ctx, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.run(ctx, fn)
+		if err == nil || attempt >= m.Attempts || m.Retryable == nil || !m.Retryable(err) {
+			return err
+		}
+		logrus.Warnf("retrying transaction after attempt %d: %v", attempt, err)
+
+		// Back off with jitter so that the conflicting transactions don't collide again
+		delay := time.Duration(attempt*attempt)*10*time.Millisecond + time.Duration(rand.Int63n(int64(10*time.Millisecond)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (m *Manager) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	tx, err := begin(ctx, m.DB, m.Options)
+	if err != nil {
+		return err
+	}
+	if tx.savepoint == "" {
+		ctx = context.WithValue(ctx, contextKey{}, &state{db: m.DB, tx: tx.tx})
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(ctx)
+}
@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/audit"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// AuditService represent the usecases of the audit logs
+type AuditService interface {
+	Fetch(ctx context.Context, filter domain.AuditLogFilter, offset, limit int) (total int, result []domain.AuditLog, err error)
+	audit.Chain
+}
+
+// AuditHandler represent the http handler for the audit logs
+type AuditHandler struct {
+	Service AuditService
+}
+
+// NewAuditHandler initializes the audit log HTTP handler on an admin only router
+func NewAuditHandler(admin *mux.Router, service AuditService) {
+	handler := &AuditHandler{Service: service}
+
+	admin.HandleFunc("/audit-logs", handler.Fetch).Methods("GET")
+	admin.HandleFunc("/audit-logs/verify", handler.Verify).Methods("GET")
+}
+
+// Fetch handles HTTP GET /audit-logs, last change first, optionally filtered
+// by actor_id, action, entity_type, entity_id, request_id and the from and
+// to RFC 3339 times
+func (a *AuditHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.AuditLogFilter{
+		Action:     domain.AuditAction(query.Get("action")),
+		EntityType: query.Get("entity_type"),
+		EntityID:   query.Get("entity_id"),
+		RequestID:  query.Get("request_id"),
+	}
+	if value := query.Get("actor_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "invalid actor_id")
+			return
+		}
+		filter.ActorID = &id
+	}
+	for name, bound := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, "invalid "+name)
+				return
+			}
+			*bound = &t
+		}
+	}
+	page, perPage, offset := pagination(r)
+
+	total, logs, err := a.Service.Fetch(r.Context(), filter, offset, perPage)
+	if err != nil {
+		respondWithServiceError(w, err, "audit log")
+		return
+	}
+
+	response := map[string]interface{}{
+		"metadata":   paginationMetadata(page, perPage, len(logs), total),
+		"audit_logs": logs,
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: response})
+}
+
+// Verify handles HTTP GET /audit-logs/verify, checking that no entry was
+// changed or removed since appended
+func (a *AuditHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	result, err := audit.Verify(r.Context(), a.Service)
+	if err != nil {
+		respondWithServiceError(w, err, "audit log")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: result})
+}
+
+// recordChange records in the audit logs the change of the entity of id
+// made by a request, an id of 0 standing for several entities. Handlers
+// call it in the change of middleware.Audited, failing with the change.
+func recordChange(r *http.Request, action domain.AuditAction, entityType string, id interface{}, before, after interface{}) error {
+	entityID := fmt.Sprint(id)
+	if id == 0 {
+		entityID = ""
+	}
+	return middleware.RecordChange(r, action, entityType, entityID, before, after)
+}
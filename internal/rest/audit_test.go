@@ -0,0 +1,111 @@
+package rest_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	sqliteRepo "github.com/bimbims125/clean-arch/internal/repository/sqlite"
+	"github.com/bimbims125/clean-arch/internal/rest"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/gorilla/mux"
+)
+
+// auditedServer routes requests to the user and trash handlers backed by an
+// in-memory SQLite database, recording their changes in its audit logs
+type auditedServer struct {
+	testServer
+	db    *sql.DB
+	users *sqliteRepo.UserRepository
+	audit *sqliteRepo.AuditRepository
+}
+
+func newAuditedServer(t *testing.T) *auditedServer {
+	t.Helper()
+	db, err := sqliteRepo.Open(context.Background(), sqliteRepo.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	s := &auditedServer{
+		testServer: testServer{router: mux.NewRouter()},
+		db:         db,
+		users:      sqliteRepo.NewSQLiteUserRepository(db),
+		audit:      sqliteRepo.NewSQLiteAuditRepository(db),
+	}
+
+	s.router.Use(middleware.AuditMiddleware(s.audit, sqliteRepo.NewSQLiteTransactionManager(db)))
+	admin := s.router.NewRoute().Subrouter()
+	admin.Use(middleware.JWTMiddleware(testSecret), middleware.RequireRole(domain.RoleAdmin))
+	rest.NewUserHandler(s.router, admin, s.users, nil, testSecret, time.Hour)
+	rest.NewTrashHandler(admin, map[string]rest.TrashService{"users": s.users})
+
+	for _, email := range []string{"admin@example.com", "alice@example.com"} {
+		if err := s.users.Create(context.Background(), domain.User{Name: email, Email: email, Password: "Passw0rd!", Role: domain.RoleCustomer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// changes returns the changes recorded by the only entry about an entity
+func (s *auditedServer) changes(t *testing.T, action domain.AuditAction, entityType, entityID string) map[string]struct{ Before, After json.RawMessage } {
+	t.Helper()
+	filter := domain.AuditLogFilter{Action: action, EntityType: entityType, EntityID: entityID}
+	total, logs, err := s.audit.Fetch(context.Background(), filter, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Fatalf("%d %s entries about %s %s, want 1", total, action, entityType, entityID)
+	}
+	if logs[0].ActorID == nil || *logs[0].ActorID != 1 {
+		t.Errorf("actor = %v, want the admin", logs[0].ActorID)
+	}
+
+	var changes map[string]struct{ Before, After json.RawMessage }
+	if err := json.Unmarshal(logs[0].Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+	return changes
+}
+
+func TestAuditRecordsStates(t *testing.T) {
+	s := newAuditedServer(t)
+	admin := token(t, 1, domain.RoleAdmin)
+
+	expectStatus(t, s.do(http.MethodDelete, "/users/2", "", admin, "If-Match", "*"), http.StatusOK)
+	changes := s.changes(t, domain.AuditDelete, "user", "2")
+	if email := changes["email"]; string(email.Before) != `"alice@example.com"` || string(email.After) != "null" {
+		t.Errorf("email change = %s to %s, want the deleted user", email.Before, email.After)
+	}
+
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/users/2/restore", "", admin), http.StatusOK)
+	changes = s.changes(t, domain.AuditRestore, "users", "2")
+	if deletedAt := changes["deleted_at"]; len(changes) != 1 || string(deletedAt.Before) == "null" || string(deletedAt.After) != "null" {
+		t.Errorf("changes = %v, want deleted_at cleared", changes)
+	}
+}
+
+func TestAuditFailureRollsBackChange(t *testing.T) {
+	s := newAuditedServer(t)
+	admin := token(t, 1, domain.RoleAdmin)
+
+	// Entries can't be chained without the head of the audit logs
+	if _, err := s.db.Exec(`DROP TABLE audit_log_head`); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(t, s.do(http.MethodDelete, "/users/2", "", admin, "If-Match", "*"), http.StatusInternalServerError)
+
+	if _, err := s.users.GetByID(context.Background(), 2); err != nil {
+		t.Errorf("GetByID of the user whose deletion failed: %v", err)
+	}
+	if _, err := s.users.GetTrashed(context.Background(), 2); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetTrashed of the user whose deletion failed: error = %v, want %v", err, domain.ErrNotFound)
+	}
+}
@@ -0,0 +1,345 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// CartCookieName is the cookie holding the cart token of anonymous visitors
+	CartCookieName   = "cart_token"
+	cartCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+// CartService represent the shopping cart usecases
+type CartService interface {
+	GetCart(ctx context.Context, owner domain.CartOwner) (domain.Cart, error)
+	GetOrCreateCart(ctx context.Context, owner domain.CartOwner) (domain.Cart, error)
+	SaveItem(ctx context.Context, cartID int, item *domain.CartItem) error
+	UpdateItemQuantity(ctx context.Context, cartID, itemID, quantity int) error
+	DeleteItem(ctx context.Context, cartID, itemID int) error
+	AddCoupon(ctx context.Context, cartID int, code string) error
+	RemoveCoupon(ctx context.Context, cartID int, code string) error
+	SetCurrency(ctx context.Context, cartID int, currency string) error
+	MergeCarts(ctx context.Context, token string, userID int) error
+}
+
+// CartHandler represent the http handler for shopping carts
+type CartHandler struct {
+	Service  CartService
+	Products ProductService
+	Charges  domain.CartCharges
+}
+
+// addCartItemRequest represent the payload of POST /cart/items
+type addCartItemRequest struct {
+	ProductID int  `json:"product_id" validate:"required"`
+	VariantID *int `json:"variant_id"`
+	Quantity  int  `json:"quantity" validate:"required,min=1"`
+}
+
+// updateCartItemRequest represent the payload of PATCH /cart/items/{itemID}
+type updateCartItemRequest struct {
+	Quantity int `json:"quantity" validate:"required,min=1"`
+}
+
+// addCouponRequest represent the payload of POST /cart/coupons
+type addCouponRequest struct {
+	Code string `json:"code" validate:"required,max=50"`
+}
+
+// setCartCurrencyRequest represent the payload of PUT /cart/currency
+type setCartCurrencyRequest struct {
+	Currency string `json:"currency" validate:"required,len=3,alpha"`
+}
+
+// NewCartHandler initializes the shopping cart HTTP handler. The router is
+// expected to identify authenticated users with middleware.OptionalJWTMiddleware.
+// Carts are quoted shipping and taxes when requested with a region query parameter.
+func NewCartHandler(r *mux.Router, service CartService, products ProductService, charges domain.CartCharges) {
+	handler := &CartHandler{Service: service, Products: products, Charges: charges}
+
+	r.HandleFunc("/cart", handler.Get).Methods("GET")
+	r.HandleFunc("/cart/items", handler.AddItem).Methods("POST")
+	r.HandleFunc("/cart/items/{itemID}", handler.UpdateItem).Methods("PATCH")
+	r.HandleFunc("/cart/items/{itemID}", handler.DeleteItem).Methods("DELETE")
+	r.HandleFunc("/cart/coupons", handler.AddCoupon).Methods("POST")
+	r.HandleFunc("/cart/coupons/{code}", handler.RemoveCoupon).Methods("DELETE")
+	r.HandleFunc("/cart/currency", handler.SetCurrency).Methods("PUT")
+}
+
+// cartOwner identifies the cart of a request by its authenticated user or its cart cookie
+func cartOwner(r *http.Request) domain.CartOwner {
+	if user, ok := middleware.UserFromContext(r.Context()); ok {
+		return domain.CartOwner{UserID: user.ID}
+	}
+	if cookie, err := r.Cookie(CartCookieName); err == nil {
+		return domain.CartOwner{Token: cookie.Value}
+	}
+	return domain.CartOwner{}
+}
+
+// setCartCookie stores the cart token of an anonymous visitor, an empty token clears it
+func setCartCookie(w http.ResponseWriter, r *http.Request, token string) {
+	maxAge := cartCookieMaxAge
+	if token == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     CartCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// resolveCart returns the cart of the request. When create is set a missing
+// cart is created, anonymous visitors then get a new token so that carts are
+// never created for tokens not issued by the server.
+func (c *CartHandler) resolveCart(w http.ResponseWriter, r *http.Request, create bool) (domain.Cart, error) {
+	owner := cartOwner(r)
+	if !owner.IsZero() {
+		cart, err := c.Service.GetCart(r.Context(), owner)
+		if !errors.Is(err, domain.ErrNotFound) || !create {
+			return cart, err
+		}
+	} else if !create {
+		return domain.Cart{}, domain.ErrNotFound
+	}
+
+	if owner.UserID == 0 {
+		token, err := randomToken()
+		if err != nil {
+			return domain.Cart{}, err
+		}
+		owner.Token = token
+		setCartCookie(w, r, token)
+	}
+	return c.Service.GetOrCreateCart(r.Context(), owner)
+}
+
+// respondWithCart reloads the cart and responds with it
+func (c *CartHandler) respondWithCart(w http.ResponseWriter, r *http.Request, code int, cart domain.Cart) {
+	cart, err := c.Service.GetCart(r.Context(), cart.Owner())
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	c.respondWithQuote(w, r, code, cart)
+}
+
+// respondWithQuote adds the shipping and taxes of the requested region to the cart and responds with it
+func (c *CartHandler) respondWithQuote(w http.ResponseWriter, r *http.Request, code int, cart domain.Cart) {
+	if err := c.Charges.Apply(&cart, r.URL.Query().Get("region")); err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	utils.RespondWithJSON(w, code, utils.ResponseData{Data: cart})
+}
+
+// Get handles HTTP GET /cart
+func (c *CartHandler) Get(w http.ResponseWriter, r *http.Request) {
+	cart, err := c.resolveCart(w, r, false)
+	if errors.Is(err, domain.ErrNotFound) {
+		cart = domain.Cart{
+			Currency:  domain.DefaultCurrency,
+			Items:     make([]domain.CartItem, 0),
+			Coupons:   make([]string, 0),
+			Discounts: make([]domain.AppliedDiscount, 0),
+		}
+	} else if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	c.respondWithQuote(w, r, http.StatusOK, cart)
+}
+
+// AddItem handles HTTP POST /cart/items, adding to the quantity of a product already in the cart
+func (c *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	var req addCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	product, err := c.Products.GetByID(r.Context(), req.ProductID)
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	cart, err := c.resolveCart(w, r, true)
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+
+	quantity := cart.Quantity(req.ProductID, req.VariantID) + req.Quantity
+	item, err := domain.NewCartItem(product, req.VariantID, quantity)
+	if err != nil {
+		respondWithServiceError(w, err, "cart item")
+		return
+	}
+	// Cart lines are snapshotted in the catalog currency
+	if item.UnitPrice.CurrencyCode() != domain.DefaultCurrency {
+		respondWithServiceError(w, domain.ErrCurrencyMismatch, "cart item")
+		return
+	}
+	if err := c.Service.SaveItem(r.Context(), cart.ID, &item); err != nil {
+		respondWithServiceError(w, err, "cart item")
+		return
+	}
+	c.respondWithCart(w, r, http.StatusCreated, cart)
+}
+
+// UpdateItem handles HTTP PATCH /cart/items/{itemID}
+func (c *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	itemID, ok := pathInt(w, r, "itemID")
+	if !ok {
+		return
+	}
+
+	var req updateCartItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	cart, err := c.resolveCart(w, r, false)
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	item, ok := cart.Item(itemID)
+	if !ok {
+		respondWithServiceError(w, domain.ErrNotFound, "cart item")
+		return
+	}
+
+	// Check the new quantity against the current stock
+	product, err := c.Products.GetByID(r.Context(), item.ProductID)
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	if _, err := domain.NewCartItem(product, item.VariantID, req.Quantity); err != nil {
+		respondWithServiceError(w, err, "cart item")
+		return
+	}
+
+	if err := c.Service.UpdateItemQuantity(r.Context(), cart.ID, itemID, req.Quantity); err != nil {
+		respondWithServiceError(w, err, "cart item")
+		return
+	}
+	c.respondWithCart(w, r, http.StatusOK, cart)
+}
+
+// DeleteItem handles HTTP DELETE /cart/items/{itemID}
+func (c *CartHandler) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	itemID, ok := pathInt(w, r, "itemID")
+	if !ok {
+		return
+	}
+
+	cart, err := c.resolveCart(w, r, false)
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	if err := c.Service.DeleteItem(r.Context(), cart.ID, itemID); err != nil {
+		respondWithServiceError(w, err, "cart item")
+		return
+	}
+	c.respondWithCart(w, r, http.StatusOK, cart)
+}
+
+// AddCoupon handles HTTP POST /cart/coupons. Coupons that don't apply to the
+// cart as it is are rejected with the reason.
+func (c *CartHandler) AddCoupon(w http.ResponseWriter, r *http.Request) {
+	var req addCouponRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+	code := domain.NormalizeCoupon(req.Code)
+
+	cart, err := c.resolveCart(w, r, true)
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	if err := c.Service.AddCoupon(r.Context(), cart.ID, code); err != nil {
+		respondWithServiceError(w, err, "coupon")
+		return
+	}
+
+	cart, err = c.Service.GetCart(r.Context(), cart.Owner())
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	if reason, rejected := cart.RejectedCoupons[code]; rejected {
+		if err := c.Service.RemoveCoupon(r.Context(), cart.ID, code); err != nil {
+			respondWithServiceError(w, err, "coupon")
+			return
+		}
+		utils.RespondWithError(w, http.StatusUnprocessableEntity, reason)
+		return
+	}
+	c.respondWithQuote(w, r, http.StatusCreated, cart)
+}
+
+// RemoveCoupon handles HTTP DELETE /cart/coupons/{code}
+func (c *CartHandler) RemoveCoupon(w http.ResponseWriter, r *http.Request) {
+	cart, err := c.resolveCart(w, r, false)
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	if err := c.Service.RemoveCoupon(r.Context(), cart.ID, domain.NormalizeCoupon(mux.Vars(r)["code"])); err != nil {
+		respondWithServiceError(w, err, "coupon")
+		return
+	}
+	c.respondWithCart(w, r, http.StatusOK, cart)
+}
+
+// SetCurrency handles HTTP PUT /cart/currency, switching the currency the cart is priced in
+func (c *CartHandler) SetCurrency(w http.ResponseWriter, r *http.Request) {
+	var req setCartCurrencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	cart, err := c.resolveCart(w, r, true)
+	if err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	if err := c.Service.SetCurrency(r.Context(), cart.ID, req.Currency); err != nil {
+		respondWithServiceError(w, err, "cart")
+		return
+	}
+	c.respondWithCart(w, r, http.StatusOK, cart)
+}
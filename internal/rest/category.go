@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
 	"github.com/bimbims125/clean-arch/utils"
 	"github.com/gorilla/mux"
 )
@@ -14,18 +16,25 @@ type CategoryService interface {
 	Fetch(ctx context.Context) (result []domain.Category, err error)
 	GetByID(ctx context.Context, id string) (result domain.Category, err error)
 	Create(ctx context.Context, category domain.Category) (err error)
+	// Delete moves a category to the trash, failing with
+	// domain.ErrCategoryNotEmpty while it has products and with
+	// domain.ErrVersionMismatch unless it is at version
+	Delete(ctx context.Context, id, version int) error
 }
 
 type CategoryHandler struct {
 	Service CategoryService
 }
 
-func NewCategoryHandler(r *mux.Router, service CategoryService) {
+// NewCategoryHandler initializes the category HTTP handler. Catalog routes
+// are registered on r and staff routes on staff.
+func NewCategoryHandler(r, staff *mux.Router, service CategoryService) {
 	handler := &CategoryHandler{Service: service}
 
 	r.HandleFunc("/categories", handler.Fetch).Methods("GET")
 	r.HandleFunc("/categories/{id}", handler.GetByID).Methods("GET")
 	r.HandleFunc("/categories", handler.Create).Methods("POST")
+	staff.HandleFunc("/categories/{id}", handler.Delete).Methods("DELETE")
 }
 
 func (c *CategoryHandler) Fetch(w http.ResponseWriter, r *http.Request) {
@@ -42,9 +51,7 @@ func (c *CategoryHandler) Fetch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Respond with the fetched categories in JSON format
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(utils.ResponseData{Data: categories})
+	respondWithETag(w, r, 0, utils.ResponseData{Data: categories})
 }
 
 func (c *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -57,15 +64,11 @@ func (c *CategoryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	// Get the category by ID using the servuce'
 	category, err := c.Service.GetByID(ctx, id)
 	if err != nil {
-		// Respond with an error if fetching fails
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(utils.ResponseError{Message: err.Error()})
+		respondWithServiceError(w, err, "category")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(utils.ResponseData{Data: category})
+	respondWithETag(w, r, category.Version, utils.ResponseData{Data: category})
 }
 
 func (c *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -80,14 +83,50 @@ func (c *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = c.Service.Create(r.Context(), category)
+	err = middleware.Audited(r, func(r *http.Request) error {
+		if err := c.Service.Create(r.Context(), category); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCreate, "category", category.ID, nil, category)
+	})
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(utils.ResponseError{Message: err.Error()})
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(utils.ResponseSuccess{Message: "Category created successfully"})
 }
+
+// Delete handles HTTP DELETE /categories/{id}, moving the category to the
+// trash. Categories are deleted once their products are. The request must be
+// conditional on the version of the category.
+func (c *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	version, ok := requireIfMatchVersion(w, r, "category")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		category, err := c.Service.GetByID(r.Context(), strconv.Itoa(id))
+		if err != nil {
+			return err
+		}
+		if err := c.Service.Delete(r.Context(), id, version); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "category", id, category, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "category")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Category deleted successfully")
+}
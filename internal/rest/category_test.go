@@ -0,0 +1,75 @@
+package rest_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+func TestCategoryCreateAndFetch(t *testing.T) {
+	s := newTestServer(t)
+
+	expectStatus(t, s.do(http.MethodPost, "/categories", `{"name":`, ""), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodPost, "/categories", `{"name":"Shoes"}`, ""), http.StatusCreated)
+	expectStatus(t, s.do(http.MethodPost, "/categories", `{"name":"Bags"}`, ""), http.StatusCreated)
+
+	rec := s.do(http.MethodGet, "/categories", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	var categories []domain.Category
+	decodeData(t, rec, &categories)
+	if len(categories) != 2 || categories[0].Name != "Shoes" || categories[1].Name != "Bags" {
+		t.Fatalf("categories = %+v, want Shoes and Bags", categories)
+	}
+
+	expectStatus(t, s.do(http.MethodGet, "/categories", "", "", "If-None-Match", etag), http.StatusNotModified)
+	expectStatus(t, s.do(http.MethodPost, "/categories", `{"name":"Hats"}`, ""), http.StatusCreated)
+	expectStatus(t, s.do(http.MethodGet, "/categories", "", "", "If-None-Match", etag), http.StatusOK)
+}
+
+func TestCategoryGetByID(t *testing.T) {
+	s := newTestServer(t)
+	expectStatus(t, s.do(http.MethodPost, "/categories", `{"name":"Shoes"}`, ""), http.StatusCreated)
+
+	rec := s.do(http.MethodGet, "/categories/1", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	if etag := rec.Header().Get("ETag"); !strings.HasPrefix(etag, `"1-`) {
+		t.Errorf("ETag = %s, want a tag of version 1", etag)
+	}
+	var category domain.Category
+	decodeData(t, rec, &category)
+	if category.ID != 1 || category.Name != "Shoes" {
+		t.Errorf("category = %+v, want Shoes", category)
+	}
+
+	expectStatus(t, s.do(http.MethodGet, "/categories/2", "", ""), http.StatusNotFound)
+	expectStatus(t, s.do(http.MethodGet, "/categories/x", "", ""), http.StatusNotFound)
+}
+
+func TestCategoryDelete(t *testing.T) {
+	s := newTestServer(t)
+	expectStatus(t, s.do(http.MethodPost, "/categories", `{"name":"Shoes"}`, ""), http.StatusCreated)
+
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", ""), http.StatusUnauthorized)
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", token(t, 1, domain.RoleCustomer)), http.StatusForbidden)
+
+	staff := token(t, 1, domain.RoleStaff)
+	expectStatus(t, s.do(http.MethodDelete, "/categories/x", "", staff, "If-Match", "*"), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", staff), http.StatusPreconditionRequired)
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", staff, "If-Match", `"7-abc"`), http.StatusPreconditionFailed)
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", staff, "If-Match", `W/"1-abc"`), http.StatusPreconditionFailed)
+
+	etag := s.do(http.MethodGet, "/categories/1", "", "").Header().Get("ETag")
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", staff, "If-Match", etag), http.StatusOK)
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", staff, "If-Match", "*"), http.StatusNotFound)
+	expectStatus(t, s.do(http.MethodGet, "/categories/1", "", ""), http.StatusNotFound)
+
+	var categories []domain.Category
+	rec := s.do(http.MethodGet, "/categories", "", "")
+	decodeData(t, rec, &categories)
+	if len(categories) != 0 {
+		t.Errorf("categories = %+v, want none", categories)
+	}
+}
@@ -0,0 +1,258 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// CurrencyService represent the exchange rate and price list usecases
+type CurrencyService interface {
+	FetchRates(ctx context.Context) (result []domain.ExchangeRate, err error)
+	SaveRate(ctx context.Context, rate *domain.ExchangeRate) error
+	DeleteRate(ctx context.Context, currency string) error
+	FetchProductPrices(ctx context.Context, productID int) (result []domain.ProductPrice, err error)
+	SetProductPrice(ctx context.Context, price domain.ProductPrice) error
+	DeleteProductPrice(ctx context.Context, productID int, currency string) error
+}
+
+// CurrencyHandler represent the http handler for exchange rates and price lists
+type CurrencyHandler struct {
+	Service CurrencyService
+}
+
+type exchangeRateRequest struct {
+	Rate float64 `json:"rate" validate:"gt=0"`
+}
+
+// productPriceRequest holds a decimal amount in the currency of the path
+type productPriceRequest struct {
+	Amount string `json:"amount" validate:"required"`
+}
+
+// NewCurrencyHandler initializes the currency HTTP handler, the router is expected to be staff only
+func NewCurrencyHandler(r *mux.Router, service CurrencyService) {
+	handler := &CurrencyHandler{Service: service}
+
+	r.HandleFunc("/admin/exchange-rates", handler.FetchRates).Methods("GET")
+	r.HandleFunc("/admin/exchange-rates/{currency}", handler.SaveRate).Methods("PUT")
+	r.HandleFunc("/admin/exchange-rates/{currency}", handler.DeleteRate).Methods("DELETE")
+	r.HandleFunc("/products/{id}/prices", handler.FetchProductPrices).Methods("GET")
+	r.HandleFunc("/products/{id}/prices/{currency}", handler.SetProductPrice).Methods("PUT")
+	r.HandleFunc("/products/{id}/prices/{currency}", handler.DeleteProductPrice).Methods("DELETE")
+}
+
+// pathCurrency reads a currency code path parameter, responding with 400 when it is malformed
+func pathCurrency(w http.ResponseWriter, r *http.Request) (string, bool) {
+	currency := domain.NormalizeCurrency(mux.Vars(r)["currency"])
+	if validate.Var(currency, "len=3,alpha") != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "invalid currency")
+		return "", false
+	}
+	return currency, true
+}
+
+// savedRate returns the exchange rate of currency, nil when there is none
+func (c *CurrencyHandler) savedRate(ctx context.Context, currency string) (*domain.ExchangeRate, error) {
+	rates, err := c.Service.FetchRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for n := range rates {
+		if rates[n].Currency == currency {
+			return &rates[n], nil
+		}
+	}
+	return nil, nil
+}
+
+// savedProductPrice returns the price of a product in currency, nil when
+// there is none
+func (c *CurrencyHandler) savedProductPrice(ctx context.Context, productID int, currency string) (*domain.ProductPrice, error) {
+	prices, err := c.Service.FetchProductPrices(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for n := range prices {
+		if prices[n].Price.CurrencyCode() == currency {
+			return &prices[n], nil
+		}
+	}
+	return nil, nil
+}
+
+// requestCurrency returns the currency asked for by the currency query
+// parameter or the Accept-Currency header, empty for the catalog currency
+func requestCurrency(r *http.Request) string {
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		return domain.NormalizeCurrency(currency)
+	}
+	return domain.NormalizeCurrency(r.Header.Get("Accept-Currency"))
+}
+
+// FetchRates handles HTTP GET /admin/exchange-rates
+func (c *CurrencyHandler) FetchRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := c.Service.FetchRates(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "exchange rate")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: map[string]interface{}{
+		"base":  domain.DefaultCurrency,
+		"rates": rates,
+	}})
+}
+
+// SaveRate handles HTTP PUT /admin/exchange-rates/{currency}
+func (c *CurrencyHandler) SaveRate(w http.ResponseWriter, r *http.Request) {
+	currency, ok := pathCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	var req exchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	rate := domain.ExchangeRate{Currency: currency, Rate: req.Rate}
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := c.savedRate(r.Context(), currency)
+		if err != nil {
+			return err
+		}
+		if err := c.Service.SaveRate(r.Context(), &rate); err != nil {
+			return err
+		}
+		action := domain.AuditUpdate
+		if before == nil {
+			action = domain.AuditCreate
+		}
+		return recordChange(r, action, "exchange_rate", currency, before, rate)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "exchange rate")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: rate})
+}
+
+// DeleteRate handles HTTP DELETE /admin/exchange-rates/{currency}
+func (c *CurrencyHandler) DeleteRate(w http.ResponseWriter, r *http.Request) {
+	currency, ok := pathCurrency(w, r)
+	if !ok {
+		return
+	}
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := c.savedRate(r.Context(), currency)
+		if err != nil {
+			return err
+		}
+		if err := c.Service.DeleteRate(r.Context(), currency); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "exchange_rate", currency, before, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "exchange rate")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Exchange rate deleted successfully")
+}
+
+// FetchProductPrices handles HTTP GET /products/{id}/prices
+func (c *CurrencyHandler) FetchProductPrices(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	prices, err := c.Service.FetchProductPrices(r.Context(), productID)
+	if err != nil {
+		respondWithServiceError(w, err, "product price")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: prices})
+}
+
+// SetProductPrice handles HTTP PUT /products/{id}/prices/{currency}
+func (c *CurrencyHandler) SetProductPrice(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	currency, ok := pathCurrency(w, r)
+	if !ok {
+		return
+	}
+
+	var req productPriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+	amount, err := domain.ParseMoney(req.Amount, currency)
+	if err != nil || amount.IsNegative() {
+		utils.RespondWithError(w, http.StatusBadRequest, "invalid amount")
+		return
+	}
+
+	price := domain.ProductPrice{ProductID: productID, Price: amount}
+	err = middleware.Audited(r, func(r *http.Request) error {
+		before, err := c.savedProductPrice(r.Context(), productID, currency)
+		if err != nil {
+			return err
+		}
+		if err := c.Service.SetProductPrice(r.Context(), price); err != nil {
+			return err
+		}
+		action := domain.AuditUpdate
+		if before == nil {
+			action = domain.AuditCreate
+		}
+		return recordChange(r, action, "product_price", productID, before, price)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: price})
+}
+
+// DeleteProductPrice handles HTTP DELETE /products/{id}/prices/{currency}
+func (c *CurrencyHandler) DeleteProductPrice(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	currency, ok := pathCurrency(w, r)
+	if !ok {
+		return
+	}
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := c.savedProductPrice(r.Context(), productID, currency)
+		if err != nil {
+			return err
+		}
+		if err := c.Service.DeleteProductPrice(r.Context(), productID, currency); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "product_price", productID, before, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "product price")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Product price deleted successfully")
+}
@@ -0,0 +1,99 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// respondWithETag responds with payload tagged with an ETag made of the
+// version of the resource, when it has one, and of a hash of the response,
+// so that the tag changes along with any part of the response. Requests
+// listing the tag in If-None-Match are answered with 304 Not Modified.
+func respondWithETag(w http.ResponseWriter, r *http.Request, version int, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logrus.Error(err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	tag := hex.EncodeToString(sum[:8])
+	if version > 0 {
+		tag = strconv.Itoa(version) + "-" + tag
+	}
+	tag = `"` + tag + `"`
+	w.Header().Set("ETag", tag)
+
+	for _, candidate := range entityTags(r.Header.Get("If-None-Match")) {
+		// If-None-Match uses the weak comparison
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(append(body, '\n'))
+}
+
+// entityTags splits the list of entity tags of an If-Match or If-None-Match header
+func entityTags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// ifMatchVersion reads the version of the resource an edit is based on from
+// the If-Match header, 0 when the edit is unconditional. It responds with 412
+// when the header holds no tag of a version, and with 400 when it holds tags
+// of several versions.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request, resource string) (int, bool) {
+	version := 0
+	for _, tag := range entityTags(r.Header.Get("If-Match")) {
+		if tag == "*" {
+			return 0, true
+		}
+
+		// If-Match uses the strong comparison, weak tags never match
+		value, _, _ := strings.Cut(strings.Trim(tag, `"`), "-")
+		n, err := strconv.Atoi(value)
+		if !strings.HasPrefix(tag, `"`) || err != nil || n <= 0 {
+			continue
+		}
+		if version != 0 && n != version {
+			utils.RespondWithError(w, http.StatusBadRequest, "If-Match lists several versions")
+			return 0, false
+		}
+		version = n
+	}
+
+	if version == 0 && r.Header.Get("If-Match") != "" {
+		utils.RespondWithError(w, http.StatusPreconditionFailed, resource+" was modified")
+		return 0, false
+	}
+	return version, true
+}
+
+// requireIfMatchVersion reads the version of the resource a deletion is based
+// on like ifMatchVersion, responding with 428 when the request has no If-Match
+// header. "*" deletes any version.
+func requireIfMatchVersion(w http.ResponseWriter, r *http.Request, resource string) (int, bool) {
+	if r.Header.Get("If-Match") == "" {
+		utils.RespondWithError(w, http.StatusPreconditionRequired, "If-Match is required to delete a "+resource)
+		return 0, false
+	}
+	return ifMatchVersion(w, r, resource)
+}
@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/validation"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// pathInt reads an integer path parameter, responding with 400 when it is malformed
+func pathInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	value, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return value, true
+}
+
+// respondWithServiceError maps domain errors to HTTP status codes
+func respondWithServiceError(w http.ResponseWriter, err error, resource string) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		utils.RespondWithError(w, http.StatusNotFound, resource+" not found")
+	case errors.Is(err, domain.ErrConflict):
+		utils.RespondWithError(w, http.StatusConflict, resource+" already exists")
+	case errors.Is(err, domain.ErrVersionMismatch):
+		utils.RespondWithError(w, http.StatusPreconditionFailed, resource+" was modified")
+	case errors.Is(err, domain.ErrBadRequest):
+		utils.RespondWithError(w, http.StatusBadRequest, "invalid "+resource)
+	case errors.Is(err, domain.ErrInsufficientStock):
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrReservationExpired):
+		utils.RespondWithError(w, http.StatusGone, err.Error())
+	case errors.Is(err, domain.ErrUnsupportedMediaType):
+		utils.RespondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, domain.ErrEmptyCart):
+		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, domain.ErrInvalidTransition), errors.Is(err, domain.ErrCategoryNotEmpty),
+		errors.Is(err, domain.ErrCategoryDeleted):
+		utils.RespondWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, domain.ErrInvalidSignature):
+		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, domain.ErrFileTooLarge):
+		utils.RespondWithError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, domain.ErrShippingUnavailable), errors.Is(err, domain.ErrCouponNotApplicable),
+		errors.Is(err, domain.ErrCurrencyMismatch), errors.Is(err, domain.ErrUnsupportedCurrency):
+		utils.RespondWithError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		utils.RespondWithError(w, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// respondWithValidationError validates a payload, responding with 400 when it is invalid
+func respondWithValidationError(w http.ResponseWriter, payload interface{}) bool {
+	if err := validate.Struct(payload); err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, map[string]interface{}{"errors": validation.FormatValidationError(err)})
+		return true
+	}
+	return false
+}
+
+// maxPerPage is the largest page size of paginated listings
+const maxPerPage = 100
+
+// pagination reads the page and per_page query parameters, falling back to
+// defaults. Pages hold at most maxPerPage items.
+func pagination(r *http.Request) (page, perPage, offset int) {
+	page = 1
+	perPage = 10
+
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if p, err := strconv.Atoi(r.URL.Query().Get("per_page")); err == nil && p > 0 {
+		perPage = min(p, maxPerPage)
+	}
+	return page, perPage, (page - 1) * perPage
+}
+
+// paginationMetadata builds the metadata returned along paginated listings
+func paginationMetadata(page, perPage, count, total int) map[string]interface{} {
+	return map[string]interface{}{
+		"page":        page,
+		"per_page":    perPage,
+		"sub_total":   count,
+		"total":       total,
+		"total_pages": (total + perPage - 1) / perPage,
+	}
+}
+
+// randomToken returns a random hex string, used for file names and cart tokens
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
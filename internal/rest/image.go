@@ -0,0 +1,320 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/internal/storage"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxImageSize is the default size limit of an uploaded image
+	DefaultMaxImageSize = 5 << 20
+	maxImagesPerUpload  = 10
+)
+
+// allowedImageTypes lists the accepted image content types, sniffed from the file content
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// ImageService represent the product image usecases
+type ImageService interface {
+	FetchByProduct(ctx context.Context, productID int) (result []domain.ProductImage, err error)
+	GetByID(ctx context.Context, productID, id int) (result domain.ProductImage, err error)
+	Create(ctx context.Context, image *domain.ProductImage) error
+	Reorder(ctx context.Context, productID int, ids []int) error
+	SetPrimary(ctx context.Context, productID, id int) error
+	Delete(ctx context.Context, productID, id int) error
+}
+
+// ImageProcessor represent the background generation of responsive image variants
+type ImageProcessor interface {
+	Enqueue(image domain.ProductImage) bool
+}
+
+// ImageHandler represent the http handler for product images
+type ImageHandler struct {
+	Service   ImageService
+	Store     storage.BlobStore
+	Processor ImageProcessor
+	MaxSize   int64
+}
+
+// reorderImagesRequest represent the payload of PUT /products/{id}/images/order
+type reorderImagesRequest struct {
+	ImageIDs []int `json:"image_ids" validate:"required,min=1"`
+}
+
+// NewImageHandler initializes the product image HTTP handler. Catalog routes
+// are registered on r and staff routes on staff.
+func NewImageHandler(r, staff *mux.Router, service ImageService, store storage.BlobStore, processor ImageProcessor, maxSize int64) {
+	handler := &ImageHandler{Service: service, Store: store, Processor: processor, MaxSize: maxSize}
+
+	r.HandleFunc("/products/{id}/images", handler.Fetch).Methods("GET")
+	staff.HandleFunc("/products/{id}/images", handler.Upload).Methods("POST")
+	staff.HandleFunc("/products/{id}/images/order", handler.Reorder).Methods("PUT")
+	staff.HandleFunc("/products/{id}/images/{imageID}/primary", handler.SetPrimary).Methods("PUT")
+	staff.HandleFunc("/products/{id}/images/{imageID}", handler.Delete).Methods("DELETE")
+}
+
+// Fetch handles HTTP GET /products/{id}/images
+func (i *ImageHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	images, err := i.Service.FetchByProduct(r.Context(), productID)
+	if err != nil {
+		respondWithServiceError(w, err, "image")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: images})
+}
+
+// Upload handles HTTP POST /products/{id}/images with one or more multipart "images" files
+func (i *ImageHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImagesPerUpload*i.MaxSize+1<<20)
+	if err := r.ParseMultipartForm(i.MaxSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithServiceError(w, domain.ErrFileTooLarge, "image")
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid multipart payload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["images"]
+	if len(files) == 0 || len(files) > maxImagesPerUpload {
+		utils.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Between 1 and %d images are required", maxImagesPerUpload))
+		return
+	}
+
+	// Validate every file before storing any of them
+	types := make([]*mimetype.MIME, len(files))
+	for n, file := range files {
+		mime, err := i.sniff(file)
+		if err != nil {
+			respondWithServiceError(w, err, "image")
+			return
+		}
+		types[n] = mime
+	}
+
+	images := make([]domain.ProductImage, 0, len(files))
+	for n, file := range files {
+		image, err := i.store(r, productID, file, types[n])
+		if err != nil {
+			respondWithServiceError(w, err, "image")
+			return
+		}
+		images = append(images, image)
+
+		// Variants are generated in the background, the image stays pending until then
+		i.Processor.Enqueue(image)
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: images})
+}
+
+// sniff checks the size and detects the content type of an uploaded file
+func (i *ImageHandler) sniff(file *multipart.FileHeader) (*mimetype.MIME, error) {
+	if file.Size > i.MaxSize {
+		return nil, domain.ErrFileTooLarge
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mime, err := mimetype.DetectReader(f)
+	if err != nil {
+		return nil, err
+	}
+	if !allowedImageTypes[mime.String()] {
+		return nil, domain.ErrUnsupportedMediaType
+	}
+	return mime, nil
+}
+
+// store writes an uploaded file to the blob store and records it as a product image
+func (i *ImageHandler) store(r *http.Request, productID int, file *multipart.FileHeader, mime *mimetype.MIME) (domain.ProductImage, error) {
+	f, err := file.Open()
+	if err != nil {
+		return domain.ProductImage{}, err
+	}
+	defer f.Close()
+
+	name, err := randomToken()
+	if err != nil {
+		return domain.ProductImage{}, err
+	}
+	key := fmt.Sprintf("products/%d/%s%s", productID, name, mime.Extension())
+	if err := i.Store.Put(r.Context(), key, io.LimitReader(f, i.MaxSize), file.Size, mime.String()); err != nil {
+		logrus.Error(err)
+		return domain.ProductImage{}, err
+	}
+
+	image := domain.ProductImage{
+		ProductID:   productID,
+		Key:         key,
+		URL:         i.Store.URL(key),
+		ContentType: mime.String(),
+		Size:        file.Size,
+	}
+	err = middleware.Audited(r, func(r *http.Request) error {
+		if err := i.Service.Create(r.Context(), &image); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCreate, "product_image", image.ID, nil, image)
+	})
+	if err != nil {
+		if errDelete := i.Store.Delete(r.Context(), key); errDelete != nil {
+			logrus.Error(errDelete)
+		}
+		return domain.ProductImage{}, err
+	}
+	return image, nil
+}
+
+// imageOrder is the order of the images of a product recorded in the audit logs
+func imageOrder(images []domain.ProductImage) map[string][]int {
+	ids := make([]int, 0, len(images))
+	for _, image := range images {
+		ids = append(ids, image.ID)
+	}
+	return map[string][]int{"image_ids": ids}
+}
+
+// Reorder handles HTTP PUT /products/{id}/images/order
+func (i *ImageHandler) Reorder(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req reorderImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	var images []domain.ProductImage
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := i.Service.FetchByProduct(r.Context(), productID)
+		if err != nil {
+			return err
+		}
+		if err := i.Service.Reorder(r.Context(), productID, req.ImageIDs); err != nil {
+			return err
+		}
+		images, err = i.Service.FetchByProduct(r.Context(), productID)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "product", productID, imageOrder(before), imageOrder(images))
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "image order")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: images})
+}
+
+// SetPrimary handles HTTP PUT /products/{id}/images/{imageID}/primary
+func (i *ImageHandler) SetPrimary(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	imageID, ok := pathInt(w, r, "imageID")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := i.Service.GetByID(r.Context(), productID, imageID)
+		if err != nil {
+			return err
+		}
+		if err := i.Service.SetPrimary(r.Context(), productID, imageID); err != nil {
+			return err
+		}
+		after, err := i.Service.GetByID(r.Context(), productID, imageID)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "product_image", imageID, before, after)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "image")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Primary image updated successfully")
+}
+
+// Delete handles HTTP DELETE /products/{id}/images/{imageID}
+func (i *ImageHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	imageID, ok := pathInt(w, r, "imageID")
+	if !ok {
+		return
+	}
+
+	var image domain.ProductImage
+	err := middleware.Audited(r, func(r *http.Request) (err error) {
+		image, err = i.Service.GetByID(r.Context(), productID, imageID)
+		if err != nil {
+			return err
+		}
+		if err := i.Service.Delete(r.Context(), productID, imageID); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "product_image", imageID, image, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "image")
+		return
+	}
+
+	// The image row is gone, leftover blobs are only logged
+	keys := []string{image.Key}
+	for _, variant := range image.Variants {
+		keys = append(keys, variant.Key)
+	}
+	for _, key := range keys {
+		if err := i.Store.Delete(r.Context(), key); err != nil {
+			logrus.Error(err)
+		}
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Image deleted successfully")
+}
@@ -0,0 +1,139 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// InventoryService represent the stock ledger usecases
+type InventoryService interface {
+	FetchMovements(ctx context.Context, productID, offset, limit int) (total int, result []domain.StockMovement, err error)
+	RecordMovement(ctx context.Context, movement *domain.StockMovement) error
+	FetchLowStock(ctx context.Context) (result []domain.Product, err error)
+	// SetReorderThreshold returns the previous threshold, failing with
+	// domain.ErrVersionMismatch unless the product is at version, 0 matching
+	// any version
+	SetReorderThreshold(ctx context.Context, productID, version, threshold int) (previous int, err error)
+}
+
+// reorderThresholdRequest represent the payload of PUT /products/{id}/reorder-threshold
+type reorderThresholdRequest struct {
+	ReorderThreshold *int `json:"reorder_threshold" validate:"required,gte=0"`
+}
+
+// InventoryHandler represent the http handler for stock movements and low-stock alerts
+type InventoryHandler struct {
+	Service InventoryService
+}
+
+// NewInventoryHandler initializes the inventory HTTP handler
+func NewInventoryHandler(r *mux.Router, service InventoryService) {
+	handler := &InventoryHandler{Service: service}
+
+	r.HandleFunc("/products/{id}/stock-movements", handler.FetchMovements).Methods("GET")
+	r.HandleFunc("/products/{id}/stock-movements", handler.RecordMovement).Methods("POST")
+	r.HandleFunc("/products/{id}/reorder-threshold", handler.SetReorderThreshold).Methods("PUT")
+	r.HandleFunc("/inventory/low-stock", handler.FetchLowStock).Methods("GET")
+}
+
+// FetchMovements handles HTTP GET /products/{id}/stock-movements
+func (i *InventoryHandler) FetchMovements(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	page, perPage, offset := pagination(r)
+
+	total, movements, err := i.Service.FetchMovements(r.Context(), productID, offset, perPage)
+	if err != nil {
+		respondWithServiceError(w, err, "stock movement")
+		return
+	}
+
+	response := map[string]interface{}{
+		"metadata":        paginationMetadata(page, perPage, len(movements), total),
+		"stock_movements": movements,
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: response})
+}
+
+// RecordMovement handles HTTP POST /products/{id}/stock-movements
+func (i *InventoryHandler) RecordMovement(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var movement domain.StockMovement
+	if err := json.NewDecoder(r.Body).Decode(&movement); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, movement) {
+		return
+	}
+	movement.ProductID = productID
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		if err := i.Service.RecordMovement(r.Context(), &movement); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCreate, "stock_movement", movement.ID, nil, movement)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "stock movement")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: movement})
+}
+
+// SetReorderThreshold handles HTTP PUT /products/{id}/reorder-threshold
+func (i *InventoryHandler) SetReorderThreshold(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r, "product")
+	if !ok {
+		return
+	}
+
+	var req reorderThresholdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		previous, err := i.Service.SetReorderThreshold(r.Context(), productID, version, *req.ReorderThreshold)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "product", productID,
+			map[string]int{"reorder_threshold": previous}, map[string]int{"reorder_threshold": *req.ReorderThreshold})
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Reorder threshold updated successfully")
+}
+
+// FetchLowStock handles HTTP GET /inventory/low-stock
+func (i *InventoryHandler) FetchLowStock(w http.ResponseWriter, r *http.Request) {
+	products, err := i.Service.FetchLowStock(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: products})
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/audit"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditStore represent the storage of the audit logs
+type AuditStore interface {
+	// Append appends an entry, chained to the last entry
+	Append(ctx context.Context, entry *domain.AuditLog) error
+}
+
+// Transactor runs functions in a transaction carried by their context
+type Transactor interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type auditor struct {
+	store        AuditStore
+	transactions Transactor
+}
+
+const auditorContextKey contextKey = "auditor"
+
+// AuditMiddleware makes store record the changes of the requests, see
+// RecordChange, in the transactions of transactions begun by Audited
+func AuditMiddleware(store AuditStore, transactions Transactor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), auditorContextKey, auditor{store: store, transactions: transactions})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Audited calls change with r carrying a transaction, so that the changes
+// it makes and records with RecordChange commit or roll back together.
+// change may be called several times, it must not have side effects outside
+// of the database. Without AuditMiddleware change is called with r.
+func Audited(r *http.Request, change func(r *http.Request) error) error {
+	a, ok := r.Context().Value(auditorContextKey).(auditor)
+	if !ok || a.transactions == nil {
+		return change(r)
+	}
+	return a.transactions.Do(r.Context(), func(ctx context.Context) error {
+		return change(r.WithContext(ctx))
+	})
+}
+
+// RecordChange appends to the audit logs the change made by a request to an
+// entity, from before to after, nil for creations and deletions. The actor
+// is the user authenticated by JWTMiddleware. Called by the change of
+// Audited, the entry is appended in the transaction of the change.
+func RecordChange(r *http.Request, action domain.AuditAction, entityType, entityID string, before, after interface{}) error {
+	a, ok := r.Context().Value(auditorContextKey).(auditor)
+	if !ok {
+		return nil
+	}
+
+	changes, err := audit.Diff(before, after)
+	if err != nil {
+		return err
+	}
+	entry := domain.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Changes:    changes,
+		RequestID:  RequestIDFromContext(r.Context()),
+		IP:         clientIP(r),
+	}
+	if user, ok := UserFromContext(r.Context()); ok {
+		entry.ActorID, entry.ActorEmail = &user.ID, user.Email
+	}
+	if err := a.store.Append(r.Context(), &entry); err != nil {
+		logrus.Error("failed to record change: ", err)
+		return err
+	}
+	return nil
+}
+
+// clientIP returns the address of the client, proxies are not trusted
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -6,9 +6,10 @@ import "net/http"
 func CORSMiddleware(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
-		w.Header().Set("Access-Control-Allow-Origin", "*")                                // Allow all origins (modify as needed)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS") // Allowed methods
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")     // Allowed headers
+		w.Header().Set("Access-Control-Allow-Origin", "*")                                                                                                     // Allow all origins (modify as needed)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")                                                               // Allowed methods
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, Idempotency-Key, X-Request-ID, Accept-Currency") // Allowed headers
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed, X-Request-ID")                                                             // Headers readable by scripts
 
 		// Handle preflight request (OPTIONS)
 		if r.Method == http.MethodOptions {
@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// IdempotencyKeyHeader is the header identifying the retries of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// DefaultIdempotencyMaxBodySize is the default size limit of the bodies
+	// of requests made with an Idempotency-Key, above the limits of image
+	// uploads and imports
+	DefaultIdempotencyMaxBodySize = 64 << 20
+)
+
+const (
+	// maxIdempotencyKeyLength is the length of the idempotency_key column
+	maxIdempotencyKeyLength = 255
+	// idempotencyLockTimeout bounds how long a request in flight holds its
+	// key without renewing it, so that requests interrupted by a crash can be
+	// retried. Requests in flight renew it every idempotencyLockRenewal.
+	idempotencyLockTimeout = time.Minute
+	idempotencyLockRenewal = idempotencyLockTimeout / 3
+)
+
+// IdempotencyStore represent the storage of the requests made with an Idempotency-Key header
+type IdempotencyStore interface {
+	// Claim stores record, unless an unexpired record is stored under its
+	// key, which is returned instead
+	Claim(ctx context.Context, record domain.IdempotencyRecord) (*domain.IdempotencyRecord, error)
+	// Renew extends the lock of a claimed record whose request is in flight
+	Renew(ctx context.Context, key string, expiresAt time.Time) error
+	// Complete stores the response of a claimed record
+	Complete(ctx context.Context, record domain.IdempotencyRecord) error
+	// Release deletes a record, so that its key may be used again
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyMiddleware replays the response of POST and PATCH requests to
+// their retries made with the same Idempotency-Key header within ttl. A retry
+// arriving while the request is in flight gets 409, and a key used again for
+// another request gets 422. Keys are scoped to the user authenticated by a
+// token signed with secret, or else to the session cookie of anonymous
+// visitors, and bound to the method, path, credentials, session cookie and
+// body of their request, bodies larger than maxBodySize being rejected with
+// 413. Requests failing with a server error release their key so that they
+// can be retried.
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration, maxBodySize int64, secret []byte, sessionCookie string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientKey := r.Header.Get(IdempotencyKeyHeader)
+			if clientKey == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(clientKey) > maxIdempotencyKeyLength {
+				utils.RespondWithError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "request body is too large")
+				return
+			}
+			if err != nil {
+				utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// The request goes on when the client goes away, so does the bookkeeping
+			ctx := context.WithoutCancel(r.Context())
+			key := idempotencyKey(idempotencyScope(r, secret, sessionCookie), clientKey)
+			record := domain.IdempotencyRecord{
+				Key:         key,
+				Fingerprint: requestFingerprint(r, body, sessionCookie),
+				ExpiresAt:   time.Now().Add(idempotencyLockTimeout),
+			}
+			existing, err := store.Claim(ctx, record)
+			if err != nil {
+				logrus.Error(err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+			if existing != nil {
+				switch {
+				case existing.Fingerprint != record.Fingerprint:
+					utils.RespondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key was used for another request")
+				case !existing.Completed():
+					utils.RespondWithError(w, http.StatusConflict, "a request with this Idempotency-Key is in progress")
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			recorder := &responseRecorder{ResponseWriter: w}
+			stopRenewal := renewWhileInFlight(ctx, store, key)
+			defer func() {
+				if p := recover(); p != nil {
+					stopRenewal()
+					release(ctx, store, key)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(recorder, r)
+			stopRenewal()
+
+			if recorder.status == 0 {
+				recorder.status = http.StatusOK
+			}
+			if recorder.status >= http.StatusInternalServerError {
+				release(ctx, store, key)
+				return
+			}
+			record.StatusCode = recorder.status
+			record.Header = recorder.Header().Clone()
+			// Replays are requests of their own, identified by their own id
+			delete(record.Header, http.CanonicalHeaderKey(RequestIDHeader))
+			record.Body = recorder.body.Bytes()
+			record.ExpiresAt = time.Now().Add(ttl)
+			if err := store.Complete(ctx, record); err != nil {
+				logrus.Error("failed to store idempotent response: ", err)
+			}
+		})
+	}
+}
+
+// idempotencyScope returns the namespace of the keys of the caller of r, the
+// authenticated user or else the session of an anonymous visitor, callers
+// without either sharing a namespace
+func idempotencyScope(r *http.Request, secret []byte, sessionCookie string) string {
+	if user, ok := parseToken(secret, r); ok {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+	if session := cookieValue(r, sessionCookie); session != "" {
+		return "session:" + session
+	}
+	return "anonymous"
+}
+
+// idempotencyKey returns the stored key of a key sent by a client in scope,
+// hashed to fit the key column whatever the length of the scope
+func idempotencyKey(scope, key string) string {
+	hash := sha256.Sum256([]byte(scope + "\x00" + key))
+	return hex.EncodeToString(hash[:])
+}
+
+// cookieValue returns the value of the cookie name of r, empty when missing
+func cookieValue(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// requestFingerprint hashes what identifies a request, so that a key used
+// again by another user or for another request is told apart
+func requestFingerprint(r *http.Request, body []byte, sessionCookie string) string {
+	hash := sha256.New()
+	for _, part := range []string{r.Method, r.URL.RequestURI(), r.Header.Get("Authorization"), cookieValue(r, sessionCookie)} {
+		io.WriteString(hash, part)
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// renewWhileInFlight renews the lock of key until the returned function is
+// called, so that the retries of a request outlasting idempotencyLockTimeout
+// don't run it a second time
+func renewWhileInFlight(ctx context.Context, store IdempotencyStore, key string) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(idempotencyLockRenewal)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := store.Renew(ctx, key, time.Now().Add(idempotencyLockTimeout)); err != nil {
+					logrus.Error("failed to renew idempotency key: ", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func release(ctx context.Context, store IdempotencyStore, key string) {
+	if err := store.Release(ctx, key); err != nil {
+		logrus.Error("failed to release idempotency key: ", err)
+	}
+}
+
+// replay writes the stored response of a request
+func replay(w http.ResponseWriter, record *domain.IdempotencyRecord) {
+	for name, values := range record.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// responseRecorder keeps a copy of the response written through it
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,199 @@
+package middleware_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	memoryRepo "github.com/bimbims125/clean-arch/internal/repository/memory"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+const testCookie = "cart_token"
+
+// newIdempotentHandler returns a handler numbering the requests it serves
+// behind the idempotency middleware
+func newIdempotentHandler() http.Handler {
+	var served int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "response %d", served)
+	})
+	store := memoryRepo.NewMemoryIdempotencyRepository()
+	return middleware.IdempotencyMiddleware(store, time.Hour, middleware.DefaultIdempotencyMaxBodySize, testSecret, testCookie)(next)
+}
+
+func bearer(t *testing.T, id int) string {
+	t.Helper()
+	token, err := middleware.GenerateToken(testSecret, domain.User{ID: id, Role: domain.RoleCustomer}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + token
+}
+
+// post sends a request with the Idempotency-Key "order-1" and the given
+// Authorization header and session cookie, returning the response body
+func post(t *testing.T, handler http.Handler, authorization, session string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set(middleware.IdempotencyKeyHeader, "order-1")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	if session != "" {
+		req.AddCookie(&http.Cookie{Name: testCookie, Value: session})
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body)
+	}
+	return rec.Body.String()
+}
+
+func TestIdempotencyKeysAreScopedToTheCaller(t *testing.T) {
+	handler := newIdempotentHandler()
+	alice, bob := bearer(t, 1), bearer(t, 2)
+
+	tests := []struct {
+		name                   string
+		authorization, session string
+		want                   string
+	}{
+		{"first user", alice, "", "response 1"},
+		{"first user again", alice, "", "response 1"},
+		{"another user", bob, "", "response 2"},
+		{"anonymous visitor", "", "cart-a", "response 3"},
+		{"visitor retrying", "", "cart-a", "response 3"},
+		{"another visitor", "", "cart-b", "response 4"},
+	}
+	for _, tt := range tests {
+		if got := post(t, handler, tt.authorization, tt.session); got != tt.want {
+			t.Errorf("%s: response = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIdempotencyFingerprintCoversTheSessionCookie(t *testing.T) {
+	handler := newIdempotentHandler()
+	alice := bearer(t, 1)
+	post(t, handler, alice, "cart-a")
+
+	// The same user retrying with another cart sends another request
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set(middleware.IdempotencyKeyHeader, "order-1")
+	req.Header.Set("Authorization", alice)
+	req.AddCookie(&http.Cookie{Name: testCookie, Value: "cart-b"})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+// send sends a request with the Idempotency-Key "order-1" and the given body
+// as the first user
+func send(t *testing.T, handler http.Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set(middleware.IdempotencyKeyHeader, "order-1")
+	req.Header.Set("Authorization", bearer(t, 1))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysTheResponse(t *testing.T) {
+	handler := newIdempotentHandler()
+	first := send(t, handler, `{"qty":1}`)
+	retry := send(t, handler, `{"qty":1}`)
+
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %q, want %d %q", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if got := retry.Header().Get("Idempotent-Replayed"); got != "true" {
+		t.Errorf("Idempotent-Replayed = %q, want %q", got, "true")
+	}
+	if got := first.Header().Get("Idempotent-Replayed"); got != "" {
+		t.Errorf("first response Idempotent-Replayed = %q, want none", got)
+	}
+}
+
+func TestIdempotencyRejectsAnotherBodyForTheKey(t *testing.T) {
+	handler := newIdempotentHandler()
+	send(t, handler, `{"qty":1}`)
+
+	if rec := send(t, handler, `{"qty":2}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyConflictsWhileInFlight(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-finish
+		w.WriteHeader(http.StatusCreated)
+	})
+	store := memoryRepo.NewMemoryIdempotencyRepository()
+	handler := middleware.IdempotencyMiddleware(store, time.Hour, middleware.DefaultIdempotencyMaxBodySize, testSecret, testCookie)(next)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- send(t, handler, `{}`) }()
+	<-started
+
+	if rec := send(t, handler, `{}`); rec.Code != http.StatusConflict {
+		t.Errorf("status while in flight = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	close(finish)
+	if rec := <-done; rec.Code != http.StatusCreated {
+		t.Errorf("first request status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyReleasesTheKeyOfServerErrors(t *testing.T) {
+	var served int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		if served == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	store := memoryRepo.NewMemoryIdempotencyRepository()
+	handler := middleware.IdempotencyMiddleware(store, time.Hour, middleware.DefaultIdempotencyMaxBodySize, testSecret, testCookie)(next)
+
+	if rec := send(t, handler, `{}`); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	rec := send(t, handler, `{}`)
+	if rec.Code != http.StatusCreated || served != 2 {
+		t.Errorf("retry status = %d after %d requests, want %d after 2", rec.Code, served, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyLimitsTheBodySize(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	store := memoryRepo.NewMemoryIdempotencyRepository()
+	handler := middleware.IdempotencyMiddleware(store, time.Hour, 8, testSecret, testCookie)(next)
+
+	if rec := send(t, handler, `{"qty":1000}`); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+	if rec := send(t, handler, `{}`); rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+}
@@ -1 +1,124 @@
 package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type contextKey string
+
+const userContextKey contextKey = "user"
+
+// Claims represent the JWT claims identifying the authenticated user
+type Claims struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// GenerateToken signs a token for the given user, valid for ttl
+func GenerateToken(secret []byte, user domain.User, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Role:   user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// parseToken validates the bearer token of a request and returns its user
+func parseToken(secret []byte, r *http.Request) (domain.User, bool) {
+	header := r.Header.Get("Authorization")
+	tokenString, found := strings.CutPrefix(header, "Bearer ")
+	if !found || tokenString == "" {
+		return domain.User{}, false
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return domain.User{}, false
+	}
+	return domain.User{ID: claims.UserID, Email: claims.Email, Role: claims.Role}, true
+}
+
+// JWTMiddleware rejects requests without a valid bearer token and stores the
+// authenticated user in the request context
+func JWTMiddleware(secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := parseToken(secret, r)
+			if !ok {
+				utils.RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// OptionalJWTMiddleware lets anonymous requests through and stores the
+// authenticated user in the request context when a bearer token is sent.
+// Requests with an invalid token are rejected.
+func OptionalJWTMiddleware(secret []byte) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			user, ok := parseToken(secret, r)
+			if !ok {
+				utils.RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
+
+// RequireRole rejects authenticated users without one of the given roles.
+// It must run after JWTMiddleware.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				utils.RespondWithError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+			for _, role := range roles {
+				if user.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			utils.RespondWithError(w, http.StatusForbidden, "forbidden")
+		})
+	}
+}
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user domain.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext returns the authenticated user stored by JWTMiddleware
+func UserFromContext(ctx context.Context) (domain.User, bool) {
+	user, ok := ctx.Value(userContextKey).(domain.User)
+	return user, ok
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader is the header identifying a request in logs and audit logs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the length of the request_id column of the audit logs
+const maxRequestIDLength = 128
+
+const requestIDContextKey contextKey = "request_id"
+
+// RequestIDMiddleware identifies every request by the X-Request-ID header
+// given by the client or a proxy, or by a random id when missing or invalid,
+// and echoes the id in the response
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = ""
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				logrus.Error(err)
+			} else {
+				id = hex.EncodeToString(b)
+			}
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey, id)))
+	})
+}
+
+// validRequestID reports whether id is printable ASCII fitting the audit logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFromContext returns the id of the request stored by RequestIDMiddleware
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
@@ -0,0 +1,253 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// OrderService represent the checkout and order lifecycle usecases
+type OrderService interface {
+	Checkout(ctx context.Context, userID int, region string, charges domain.CartCharges) (domain.Order, error)
+	Reserve(ctx context.Context, userID int, ttl time.Duration) ([]domain.StockReservation, error)
+	ReleaseReservations(ctx context.Context, userID int) error
+	Fetch(ctx context.Context, filter domain.OrderFilter, offset, limit int) (total int, result []domain.Order, err error)
+	GetByID(ctx context.Context, id int) (domain.Order, error)
+	UpdateStatus(ctx context.Context, id int, change *domain.OrderStatusChange) error
+}
+
+// OrderHandler represent the http handler for orders
+type OrderHandler struct {
+	Service        OrderService
+	Charges        domain.CartCharges
+	ReservationTTL time.Duration
+}
+
+// checkoutRequest represent the payload of POST /checkout
+type checkoutRequest struct {
+	Region string `json:"region" validate:"required,max=20"`
+}
+
+// cancelOrderRequest represent the optional payload of POST /orders/{id}/cancel
+type cancelOrderRequest struct {
+	Note string `json:"note" validate:"max=255"`
+}
+
+// NewOrderHandler initializes the order HTTP handler. Customer routes are
+// registered on r and staff routes on staff, both must authenticate users.
+// Stock reserved for checkout is held for reservationTTL.
+func NewOrderHandler(r *mux.Router, staff *mux.Router, service OrderService, charges domain.CartCharges, reservationTTL time.Duration) {
+	handler := &OrderHandler{Service: service, Charges: charges, ReservationTTL: reservationTTL}
+
+	r.HandleFunc("/checkout", handler.Checkout).Methods("POST")
+	r.HandleFunc("/checkout/reservation", handler.Reserve).Methods("POST")
+	r.HandleFunc("/checkout/reservation", handler.ReleaseReservations).Methods("DELETE")
+	r.HandleFunc("/orders", handler.Fetch).Methods("GET")
+	r.HandleFunc("/orders/{id}", handler.GetByID).Methods("GET")
+	r.HandleFunc("/orders/{id}/cancel", handler.Cancel).Methods("POST")
+
+	staff.HandleFunc("/admin/orders", handler.FetchAll).Methods("GET")
+	staff.HandleFunc("/admin/orders/{id}", handler.GetAnyByID).Methods("GET")
+	staff.HandleFunc("/admin/orders/{id}/status", handler.UpdateStatus).Methods("PUT")
+}
+
+// Checkout handles HTTP POST /checkout, turning the cart of the user into an
+// order shipped to the region of the payload
+func (o *OrderHandler) Checkout(w http.ResponseWriter, r *http.Request) {
+	var req checkoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	order, err := o.Service.Checkout(r.Context(), user.ID, req.Region, o.Charges)
+	if err != nil {
+		respondWithServiceError(w, err, "order")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: order})
+}
+
+// Reserve handles HTTP POST /checkout/reservation, holding the stock of the
+// cart of the user while they check out
+func (o *OrderHandler) Reserve(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	reservations, err := o.Service.Reserve(r.Context(), user.ID, o.ReservationTTL)
+	if err != nil {
+		respondWithServiceError(w, err, "reservation")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: reservations})
+}
+
+// ReleaseReservations handles HTTP DELETE /checkout/reservation, giving the
+// stock held for the cart of the user back
+func (o *OrderHandler) ReleaseReservations(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	if err := o.Service.ReleaseReservations(r.Context(), user.ID); err != nil {
+		respondWithServiceError(w, err, "reservation")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Reservation released successfully")
+}
+
+// respondWithOrders responds with a page of the orders matching filter
+func (o *OrderHandler) respondWithOrders(w http.ResponseWriter, r *http.Request, filter domain.OrderFilter) {
+	page, perPage, offset := pagination(r)
+
+	total, orders, err := o.Service.Fetch(r.Context(), filter, offset, perPage)
+	if err != nil {
+		respondWithServiceError(w, err, "order")
+		return
+	}
+
+	response := map[string]interface{}{
+		"metadata": paginationMetadata(page, perPage, len(orders), total),
+		"orders":   orders,
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: response})
+}
+
+// Fetch handles HTTP GET /orders, listing the orders of the user
+func (o *OrderHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+	o.respondWithOrders(w, r, domain.OrderFilter{
+		UserID: user.ID,
+		Status: domain.OrderStatus(r.URL.Query().Get("status")),
+	})
+}
+
+// FetchAll handles HTTP GET /admin/orders, optionally filtered by status and user_id
+func (o *OrderHandler) FetchAll(w http.ResponseWriter, r *http.Request) {
+	filter := domain.OrderFilter{Status: domain.OrderStatus(r.URL.Query().Get("status"))}
+	if userID := r.URL.Query().Get("user_id"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		filter.UserID = id
+	}
+	o.respondWithOrders(w, r, filter)
+}
+
+// ownOrder returns the order of the path when it belongs to the authenticated user
+func ownOrder(w http.ResponseWriter, r *http.Request, service OrderService) (domain.Order, bool) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return domain.Order{}, false
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	order, err := service.GetByID(r.Context(), id)
+	if err == nil && order.UserID != user.ID {
+		err = domain.ErrNotFound
+	}
+	if err != nil {
+		respondWithServiceError(w, err, "order")
+		return domain.Order{}, false
+	}
+	return order, true
+}
+
+// GetByID handles HTTP GET /orders/{id}
+func (o *OrderHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	order, ok := ownOrder(w, r, o.Service)
+	if !ok {
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: order})
+}
+
+// GetAnyByID handles HTTP GET /admin/orders/{id}
+func (o *OrderHandler) GetAnyByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	order, err := o.Service.GetByID(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "order")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: order})
+}
+
+// Cancel handles HTTP POST /orders/{id}/cancel, customers can only cancel pending orders
+func (o *OrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	var req cancelOrderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+		if respondWithValidationError(w, req) {
+			return
+		}
+	}
+
+	order, ok := ownOrder(w, r, o.Service)
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	change := domain.OrderStatusChange{To: domain.OrderCancelled, Note: req.Note, ChangedBy: &user.ID}
+	o.updateStatus(w, r, order.ID, &change)
+}
+
+// UpdateStatus handles HTTP PUT /admin/orders/{id}/status
+func (o *OrderHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var change domain.OrderStatusChange
+	if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, change) {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+	change.ChangedBy = &user.ID
+
+	o.updateStatus(w, r, id, &change)
+}
+
+// updateStatus applies a status change and responds with the updated order
+func (o *OrderHandler) updateStatus(w http.ResponseWriter, r *http.Request, id int, change *domain.OrderStatusChange) {
+	var order domain.Order
+	err := middleware.Audited(r, func(r *http.Request) (err error) {
+		if err := o.Service.UpdateStatus(r.Context(), id, change); err != nil {
+			return err
+		}
+		order, err = o.Service.GetByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "order", id,
+			map[string]domain.OrderStatus{"status": change.From}, map[string]domain.OrderStatus{"status": change.To})
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "order")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: order})
+}
@@ -0,0 +1,209 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/payment"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// maxWebhookSize is the size limit of a payment webhook payload
+const maxWebhookSize = 1 << 20
+
+// PaymentService represent the payment usecases
+type PaymentService interface {
+	FetchByOrder(ctx context.Context, orderID int) (result []domain.Payment, err error)
+	GetByID(ctx context.Context, id int) (domain.Payment, error)
+	Create(ctx context.Context, payment *domain.Payment) error
+	ApplyEvent(ctx context.Context, provider string, event domain.PaymentEvent) error
+}
+
+// PaymentHandler represent the http handler for order payments
+type PaymentHandler struct {
+	Service  PaymentService
+	Orders   OrderService
+	Provider payment.Provider
+}
+
+// NewPaymentHandler initializes the payment HTTP handler. Webhooks are
+// registered on public, customer routes on r and staff routes on staff.
+func NewPaymentHandler(public, r, staff *mux.Router, service PaymentService, orders OrderService, provider payment.Provider) {
+	handler := &PaymentHandler{Service: service, Orders: orders, Provider: provider}
+
+	public.HandleFunc("/payments/webhooks/{provider}", handler.Webhook).Methods("POST")
+	r.HandleFunc("/orders/{id}/payments", handler.Fetch).Methods("GET")
+	r.HandleFunc("/orders/{id}/payments", handler.Create).Methods("POST")
+	staff.HandleFunc("/admin/payments/{id}/capture", handler.Capture).Methods("POST")
+	staff.HandleFunc("/admin/payments/{id}/refund", handler.Refund).Methods("POST")
+}
+
+// Fetch handles HTTP GET /orders/{id}/payments
+func (p *PaymentHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	order, ok := ownOrder(w, r, p.Orders)
+	if !ok {
+		return
+	}
+
+	payments, err := p.Service.FetchByOrder(r.Context(), order.ID)
+	if err != nil {
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: payments})
+}
+
+// Create handles HTTP POST /orders/{id}/payments, starting the payment of a
+// pending order. The open payment of the order is returned when there is one.
+func (p *PaymentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	order, ok := ownOrder(w, r, p.Orders)
+	if !ok {
+		return
+	}
+	if order.Status != domain.OrderPending {
+		respondWithServiceError(w, domain.ErrInvalidTransition, "payment")
+		return
+	}
+
+	payments, err := p.Service.FetchByOrder(r.Context(), order.ID)
+	if err != nil {
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	for _, existing := range payments {
+		if existing.Provider == p.Provider.Name() && existing.Status == domain.PaymentPending && existing.Amount == order.Total {
+			utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: existing})
+			return
+		}
+	}
+
+	intent, err := p.Provider.CreateIntent(r.Context(), order)
+	if err != nil {
+		logrus.Error(err)
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	if err := p.Service.Create(r.Context(), &intent); err != nil {
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: intent})
+}
+
+// providerPayment returns the payment of the path when it belongs to the configured provider
+func (p *PaymentHandler) providerPayment(w http.ResponseWriter, r *http.Request) (domain.Payment, bool) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return domain.Payment{}, false
+	}
+
+	pm, err := p.Service.GetByID(r.Context(), id)
+	if err == nil && pm.Provider != p.Provider.Name() {
+		err = domain.ErrBadRequest
+	}
+	if err != nil {
+		respondWithServiceError(w, err, "payment")
+		return domain.Payment{}, false
+	}
+	return pm, true
+}
+
+// settle applies the outcome of a capture or refund and responds with the payment
+func (p *PaymentHandler) settle(w http.ResponseWriter, r *http.Request, pm domain.Payment, eventType domain.PaymentEventType) {
+	event := domain.PaymentEvent{Type: eventType, ExternalID: pm.ExternalID, Amount: pm.Amount}
+	action := domain.AuditCapture
+	if eventType == domain.PaymentEventRefunded {
+		action = domain.AuditRefund
+	}
+
+	var settled domain.Payment
+	err := middleware.Audited(r, func(r *http.Request) (err error) {
+		if err := p.Service.ApplyEvent(r.Context(), pm.Provider, event); err != nil {
+			return err
+		}
+		settled, err = p.Service.GetByID(r.Context(), pm.ID)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, action, "payment", pm.ID, pm, settled)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: settled})
+}
+
+// Capture handles HTTP POST /admin/payments/{id}/capture
+func (p *PaymentHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	pm, ok := p.providerPayment(w, r)
+	if !ok {
+		return
+	}
+	if !pm.Status.CanTransitionTo(domain.PaymentCaptured) {
+		respondWithServiceError(w, domain.ErrInvalidTransition, "payment")
+		return
+	}
+
+	if err := p.Provider.Capture(r.Context(), pm); err != nil {
+		logrus.Error(err)
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	p.settle(w, r, pm, domain.PaymentEventSucceeded)
+}
+
+// Refund handles HTTP POST /admin/payments/{id}/refund, refunding the whole payment
+func (p *PaymentHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	pm, ok := p.providerPayment(w, r)
+	if !ok {
+		return
+	}
+	order, err := p.Orders.GetByID(r.Context(), pm.OrderID)
+	if err != nil {
+		respondWithServiceError(w, err, "order")
+		return
+	}
+	if !pm.Status.CanTransitionTo(domain.PaymentRefunded) || !order.Status.CanTransitionTo(domain.OrderRefunded) {
+		respondWithServiceError(w, domain.ErrInvalidTransition, "payment")
+		return
+	}
+
+	if err := p.Provider.Refund(r.Context(), pm, pm.Amount); err != nil {
+		logrus.Error(err)
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	p.settle(w, r, pm, domain.PaymentEventRefunded)
+}
+
+// Webhook handles HTTP POST /payments/webhooks/{provider}. Events are applied
+// once, so providers retrying a delivery get the same response.
+func (p *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if mux.Vars(r)["provider"] != p.Provider.Name() {
+		respondWithServiceError(w, domain.ErrNotFound, "payment provider")
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookSize))
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	event, err := p.Provider.VerifyWebhook(payload, r.Header)
+	if err != nil {
+		respondWithServiceError(w, err, "payment event")
+		return
+	}
+
+	if err := p.Service.ApplyEvent(r.Context(), p.Provider.Name(), event); err != nil {
+		respondWithServiceError(w, err, "payment")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Event processed")
+}
@@ -0,0 +1,147 @@
+package rest_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/payment"
+	memoryRepo "github.com/bimbims125/clean-arch/internal/repository/memory"
+	"github.com/bimbims125/clean-arch/internal/rest"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/gorilla/mux"
+)
+
+// paymentServer routes requests to the payment handler backed by the fake
+// provider and the memory payment repository, holding a pending payment
+type paymentServer struct {
+	testServer
+	provider *payment.FakeProvider
+	payments *memoryRepo.PaymentRepository
+	pending  domain.Payment
+}
+
+func newPaymentServer(t *testing.T) *paymentServer {
+	t.Helper()
+	s := &paymentServer{
+		testServer: testServer{router: mux.NewRouter()},
+		provider:   payment.NewFakeProvider(testSecret),
+		payments:   memoryRepo.NewMemoryPaymentRepository(),
+	}
+
+	customer := s.router.NewRoute().Subrouter()
+	customer.Use(middleware.JWTMiddleware(testSecret))
+	staff := s.router.NewRoute().Subrouter()
+	staff.Use(middleware.JWTMiddleware(testSecret), middleware.RequireRole(domain.RoleAdmin, domain.RoleStaff))
+	rest.NewPaymentHandler(s.router, customer, staff, s.payments, nil, s.provider)
+
+	order := domain.Order{ID: 1, Total: domain.NewMoney(1500000, domain.DefaultCurrency)}
+	intent, err := s.provider.CreateIntent(context.Background(), order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.payments.Create(context.Background(), &intent); err != nil {
+		t.Fatal(err)
+	}
+	s.pending = intent
+	return s
+}
+
+// webhook delivers a payment event signed with signature, the fake provider
+// signature when empty
+func (s *paymentServer) webhook(t *testing.T, event domain.PaymentEvent, signature string) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, valid, err := s.provider.SignedEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature == "" {
+		signature = valid
+	}
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhooks/fake", bytes.NewReader(payload))
+	req.Header.Set(payment.FakeSignatureHeader, signature)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// status returns the status of the pending payment
+func (s *paymentServer) status(t *testing.T) domain.PaymentStatus {
+	t.Helper()
+	pm, err := s.payments.GetByID(context.Background(), s.pending.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pm.Status
+}
+
+func TestPaymentCapture(t *testing.T) {
+	s := newPaymentServer(t)
+	path := "/admin/payments/" + strconv.Itoa(s.pending.ID) + "/capture"
+
+	expectStatus(t, s.do(http.MethodPost, path, "", token(t, 2, domain.RoleCustomer)), http.StatusForbidden)
+
+	rec := s.do(http.MethodPost, path, "", token(t, 1, domain.RoleStaff))
+	expectStatus(t, rec, http.StatusOK)
+	var captured domain.Payment
+	decodeData(t, rec, &captured)
+	if captured.Status != domain.PaymentCaptured || captured.Amount != s.pending.Amount {
+		t.Errorf("payment = %s of %v, want captured of %v", captured.Status, captured.Amount, s.pending.Amount)
+	}
+
+	// Captured payments can't be captured again
+	expectStatus(t, s.do(http.MethodPost, path, "", token(t, 1, domain.RoleStaff)), http.StatusConflict)
+}
+
+func TestPaymentWebhookSignature(t *testing.T) {
+	s := newPaymentServer(t)
+	event := domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventSucceeded, ExternalID: s.pending.ExternalID, Amount: s.pending.Amount}
+
+	for _, signature := range []string{"not hex", s.provider.Sign([]byte("another payload"))} {
+		expectStatus(t, s.webhook(t, event, signature), http.StatusUnauthorized)
+	}
+	if got := s.status(t); got != domain.PaymentPending {
+		t.Fatalf("status after unsigned events = %s, want %s", got, domain.PaymentPending)
+	}
+
+	// Events aren't accepted for another provider either
+	payload, signature, err := s.provider.SignedEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/payments/webhooks/stripe", bytes.NewReader(payload))
+	req.Header.Set(payment.FakeSignatureHeader, signature)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	expectStatus(t, rec, http.StatusNotFound)
+
+	expectStatus(t, s.webhook(t, event, ""), http.StatusOK)
+	if got := s.status(t); got != domain.PaymentCaptured {
+		t.Errorf("status = %s, want %s", got, domain.PaymentCaptured)
+	}
+}
+
+func TestPaymentWebhookReplay(t *testing.T) {
+	s := newPaymentServer(t)
+	authorized := domain.PaymentEvent{ID: "evt_1", Type: domain.PaymentEventAuthorized, ExternalID: s.pending.ExternalID}
+	failed := domain.PaymentEvent{ID: "evt_2", Type: domain.PaymentEventFailed, ExternalID: s.pending.ExternalID}
+
+	expectStatus(t, s.webhook(t, authorized, ""), http.StatusOK)
+	expectStatus(t, s.webhook(t, failed, ""), http.StatusOK)
+
+	// Failed payments may be authorized again, but not by a replayed event
+	expectStatus(t, s.webhook(t, authorized, ""), http.StatusOK)
+	if got := s.status(t); got != domain.PaymentFailed {
+		t.Errorf("status after the replay = %s, want %s", got, domain.PaymentFailed)
+	}
+
+	authorized.ID = "evt_3"
+	expectStatus(t, s.webhook(t, authorized, ""), http.StatusOK)
+	if got := s.status(t); got != domain.PaymentAuthorized {
+		t.Errorf("status after a new event = %s, want %s", got, domain.PaymentAuthorized)
+	}
+}
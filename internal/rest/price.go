@@ -0,0 +1,202 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// PriceService represent the price change and price schedule usecases
+type PriceService interface {
+	// UpdatePrice fails with domain.ErrVersionMismatch unless the product is
+	// at version, 0 matching any version
+	UpdatePrice(ctx context.Context, change *domain.PriceChange, version int) error
+	FetchHistory(ctx context.Context, productID, offset, limit int) (total int, result []domain.PriceChange, err error)
+	FetchSchedules(ctx context.Context, productID int) ([]domain.PriceSchedule, error)
+	CreateSchedule(ctx context.Context, schedule *domain.PriceSchedule) error
+	CancelSchedule(ctx context.Context, productID, id int) (domain.PriceSchedule, error)
+}
+
+// PriceHandler represent the http handler for product prices
+type PriceHandler struct {
+	Service PriceService
+}
+
+// updatePriceRequest represent the payload of PUT /products/{id}/price
+type updatePriceRequest struct {
+	Price *domain.Money `json:"price" validate:"required,gte=0"`
+	Note  string        `json:"note" validate:"max=255"`
+}
+
+// priceScheduleRequest represent the payload of POST /products/{id}/price-schedules
+type priceScheduleRequest struct {
+	Price    *domain.Money `json:"price" validate:"required,gte=0"`
+	StartsAt time.Time     `json:"starts_at" validate:"required"`
+	EndsAt   *time.Time    `json:"ends_at"`
+	Note     string        `json:"note" validate:"max=255"`
+}
+
+// NewPriceHandler initializes the price HTTP handler. The price history is
+// registered on r and price changes on staff.
+func NewPriceHandler(r, staff *mux.Router, service PriceService) {
+	handler := &PriceHandler{Service: service}
+
+	r.HandleFunc("/products/{id}/price-history", handler.FetchHistory).Methods("GET")
+	staff.HandleFunc("/products/{id}/price", handler.UpdatePrice).Methods("PUT")
+	staff.HandleFunc("/products/{id}/price-schedules", handler.FetchSchedules).Methods("GET")
+	staff.HandleFunc("/products/{id}/price-schedules", handler.CreateSchedule).Methods("POST")
+	staff.HandleFunc("/products/{id}/price-schedules/{schedule_id}", handler.CancelSchedule).Methods("DELETE")
+}
+
+// UpdatePrice handles HTTP PUT /products/{id}/price, recording the change in the price history
+func (h *PriceHandler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r, "product")
+	if !ok {
+		return
+	}
+
+	var req updatePriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	change := domain.PriceChange{ProductID: productID, NewPrice: *req.Price, ChangedBy: &user.ID, Note: req.Note}
+	err := middleware.Audited(r, func(r *http.Request) error {
+		if err := h.Service.UpdatePrice(r.Context(), &change, version); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "product", productID,
+			map[string]domain.Money{"price": change.OldPrice}, map[string]domain.Money{"price": change.NewPrice})
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: change})
+}
+
+// FetchHistory handles HTTP GET /products/{id}/price-history, latest change first
+func (h *PriceHandler) FetchHistory(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	page, perPage, offset := pagination(r)
+
+	total, changes, err := h.Service.FetchHistory(r.Context(), productID, offset, perPage)
+	if err != nil {
+		respondWithServiceError(w, err, "price change")
+		return
+	}
+
+	response := map[string]interface{}{
+		"metadata": paginationMetadata(page, perPage, len(changes), total),
+		"changes":  changes,
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: response})
+}
+
+// FetchSchedules handles HTTP GET /products/{id}/price-schedules
+func (h *PriceHandler) FetchSchedules(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	schedules, err := h.Service.FetchSchedules(r.Context(), productID)
+	if err != nil {
+		respondWithServiceError(w, err, "price schedule")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: schedules})
+}
+
+// CreateSchedule handles HTTP POST /products/{id}/price-schedules. Schedules
+// of a product can't overlap.
+func (h *PriceHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req priceScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	schedule := domain.PriceSchedule{
+		ProductID: productID,
+		Price:     *req.Price,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		Note:      req.Note,
+		CreatedBy: &user.ID,
+	}
+	if err := schedule.Validate(); err != nil {
+		respondWithServiceError(w, err, "price schedule")
+		return
+	}
+	err := middleware.Audited(r, func(r *http.Request) error {
+		if err := h.Service.CreateSchedule(r.Context(), &schedule); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCreate, "price_schedule", schedule.ID, nil, schedule)
+	})
+	if err != nil {
+		resource := "price schedule"
+		if errors.Is(err, domain.ErrNotFound) {
+			resource = "product"
+		}
+		respondWithServiceError(w, err, resource)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: schedule})
+}
+
+// CancelSchedule handles HTTP DELETE /products/{id}/price-schedules/{schedule_id}.
+// Cancelling an active schedule restores the price from before it, unless the
+// price was changed meanwhile.
+func (h *PriceHandler) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	id, ok := pathInt(w, r, "schedule_id")
+	if !ok {
+		return
+	}
+
+	var schedule domain.PriceSchedule
+	err := middleware.Audited(r, func(r *http.Request) (err error) {
+		schedule, err = h.Service.CancelSchedule(r.Context(), productID, id)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCancel, "price_schedule", id, nil, schedule)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "price schedule")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: schedule})
+}
@@ -8,25 +8,47 @@ import (
 	"strings"
 
 	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/catalog"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
 	"github.com/bimbims125/clean-arch/utils"
 	"github.com/gorilla/mux"
 )
 
 type ProductService interface {
 	Fetch(ctx context.Context) (result []domain.Product, err error)
-	FetchPaginated(ctx context.Context, offset, limit int) (total int, products []domain.Product, err error)
+	FetchPaginated(ctx context.Context, filter domain.ProductFilter, offset, limit int) (total int, products []domain.Product, err error)
+	Export(ctx context.Context, filter domain.ProductFilter, fn func(domain.Product) error) error
 	GetByID(ctx context.Context, id int) (result domain.Product, err error)
+	// UpdateShippingDetails fails with domain.ErrVersionMismatch unless the
+	// product is at version, 0 matching any version
+	UpdateShippingDetails(ctx context.Context, productID, version int, details domain.ShippingDetails) error
+	// Delete moves a product to the trash, it fails like UpdateShippingDetails
+	Delete(ctx context.Context, productID, version int) error
+}
+
+// PriceBookProvider prices catalog products in other currencies
+type PriceBookProvider interface {
+	PriceBook(ctx context.Context, currency string, productIDs []int) (domain.PriceBook, error)
 }
 
 type ProductHandler struct {
 	Service ProductService
+	// Prices is nil when prices are only published in the default currency
+	Prices PriceBookProvider
+	Feed   catalog.Feed
 }
 
-func NewProductHandler(r *mux.Router, service ProductService) {
-	handler := &ProductHandler{Service: service}
+// NewProductHandler initializes the product HTTP handler. Catalog routes are
+// registered on r and staff routes on staff, feed describes the storefront in
+// exported product feeds.
+func NewProductHandler(r, staff *mux.Router, service ProductService, prices PriceBookProvider, feed catalog.Feed) {
+	handler := &ProductHandler{Service: service, Prices: prices, Feed: feed}
 
 	r.HandleFunc("/products", handler.FetchPaginatedProduct).Methods("GET")
+	r.HandleFunc("/products/export", handler.Export).Methods("GET")
 	r.HandleFunc("/products/{id}", handler.GetByID).Methods("GET")
+	staff.HandleFunc("/products/{id}", handler.Delete).Methods("DELETE")
+	staff.HandleFunc("/products/{id}/shipping", handler.UpdateShippingDetails).Methods("PUT")
 }
 
 func (p *ProductHandler) FetchProduct(w http.ResponseWriter, r *http.Request) {
@@ -68,19 +90,27 @@ func (p *ProductHandler) FetchPaginatedProduct(w http.ResponseWriter, r *http.Re
 	}
 	if perPageStr != "" {
 		if p, err := strconv.Atoi(perPageStr); err == nil {
-			perPage = p
+			perPage = min(p, maxPerPage)
 		}
 	}
 
 	// Calculate offset
 	offset := (page - 1) * perPage
 
+	filter, ok := productFilter(w, r)
+	if !ok {
+		return
+	}
+
 	// Fetch data
-	total, products, err := p.Service.FetchPaginated(ctx, offset, perPage)
+	total, products, err := p.Service.FetchPaginated(ctx, filter, offset, perPage)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if !p.localize(w, r, products) {
+		return
+	}
 
 	// Calculate metadata
 	totalPages := (total + perPage - 1) / perPage
@@ -98,9 +128,54 @@ func (p *ProductHandler) FetchPaginatedProduct(w http.ResponseWriter, r *http.Re
 		"products": products,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(utils.ResponseData{Data: response})
+	respondWithETag(w, r, 0, utils.ResponseData{Data: response})
+}
+
+// productFilter reads the category_id, q, min_price, max_price and in_stock
+// query parameters, responding with 400 when one is malformed. Price bounds
+// are in the catalog currency.
+func productFilter(w http.ResponseWriter, r *http.Request) (filter domain.ProductFilter, ok bool) {
+	query := r.URL.Query()
+	if value := query.Get("category_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "invalid category_id")
+			return filter, false
+		}
+		filter.CategoryID = id
+	}
+	filter.Search = strings.TrimSpace(query.Get("q"))
+
+	if filter.MinPrice, ok = queryPrice(w, r, "min_price"); !ok {
+		return filter, false
+	}
+	if filter.MaxPrice, ok = queryPrice(w, r, "max_price"); !ok {
+		return filter, false
+	}
+
+	if value := query.Get("in_stock"); value != "" {
+		inStock, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "invalid in_stock")
+			return filter, false
+		}
+		filter.InStock = inStock
+	}
+	return filter, true
+}
+
+// queryPrice reads an optional amount in the catalog currency from a query parameter
+func queryPrice(w http.ResponseWriter, r *http.Request, name string) (*domain.Money, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, true
+	}
+	price, err := domain.ParseMoney(value, domain.DefaultCurrency)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "invalid "+name)
+		return nil, false
+	}
+	return &price, true
 }
 
 func (p *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -130,9 +205,111 @@ func (p *ProductHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(utils.ResponseError{Message: "internal server error"})
 		return
 	}
+	products := []domain.Product{product}
+	if !p.localize(w, r, products) {
+		return
+	}
+	product = products[0]
 
 	// Respond with the fetched product in JSON format
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(utils.ResponseData{Data: product})
+	respondWithETag(w, r, product.Version, utils.ResponseData{Data: product})
+}
+
+// localize prices products in the currency requested with the currency query
+// parameter or the Accept-Currency header, responding with 422 when it isn't
+// supported
+func (p *ProductHandler) localize(w http.ResponseWriter, r *http.Request, products []domain.Product) bool {
+	w.Header().Add("Vary", "Accept-Currency")
+	currency := requestCurrency(r)
+	if currency == "" || currency == domain.DefaultCurrency {
+		return true
+	}
+	if p.Prices == nil {
+		respondWithServiceError(w, domain.ErrUnsupportedCurrency, "currency")
+		return false
+	}
+
+	productIDs := make([]int, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+	book, err := p.Prices.PriceBook(r.Context(), currency, productIDs)
+	if err != nil {
+		respondWithServiceError(w, err, "currency")
+		return false
+	}
+	for n := range products {
+		book.Localize(&products[n])
+	}
+	return true
+}
+
+// UpdateShippingDetails handles HTTP PUT /products/{id}/shipping, setting the weight and dimensions of a product
+func (p *ProductHandler) UpdateShippingDetails(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r, "product")
+	if !ok {
+		return
+	}
+
+	var details domain.ShippingDetails
+	if err := json.NewDecoder(r.Body).Decode(&details); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, details) {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := p.Service.GetByID(r.Context(), productID)
+		if err != nil {
+			return err
+		}
+		if err := p.Service.UpdateShippingDetails(r.Context(), productID, version, details); err != nil {
+			return err
+		}
+		after, err := p.Service.GetByID(r.Context(), productID)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "product", productID, before, after)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Shipping details updated successfully")
+}
+
+// Delete handles HTTP DELETE /products/{id}, moving the product to the trash
+// where it can be restored until purged
+func (p *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	version, ok := ifMatchVersion(w, r, "product")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		product, err := p.Service.GetByID(r.Context(), productID)
+		if err != nil {
+			return err
+		}
+		if err := p.Service.Delete(r.Context(), productID, version); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "product", productID, product, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Product deleted successfully")
 }
@@ -0,0 +1,61 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/catalog"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// Export handles HTTP GET /products/export?format=csv|ndjson|xml, streaming
+// the products matching the listing filters. The xml format is a Google
+// Merchant Center feed.
+func (p *ProductHandler) Export(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "csv"
+	}
+	format, ok := catalog.Formats[name]
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, "invalid format")
+		return
+	}
+	filter, ok := productFilter(w, r)
+	if !ok {
+		return
+	}
+
+	// The response starts with the first product, so errors occurring before
+	// it are still reported with a status code
+	var writer catalog.Writer
+	start := func() {
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="products`+format.Extension+`"`)
+		w.WriteHeader(http.StatusOK)
+		writer = format.NewWriter(w, p.Feed)
+	}
+
+	err := p.Service.Export(r.Context(), filter, func(product domain.Product) error {
+		if writer == nil {
+			start()
+		}
+		return writer.Write(product)
+	})
+	if err != nil && writer == nil {
+		respondWithServiceError(w, err, "product")
+		return
+	}
+	if writer == nil {
+		start()
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		// Abort the response so the client doesn't take a truncated export for a complete one
+		logrus.Error("failed to export products: ", err)
+		panic(http.ErrAbortHandler)
+	}
+}
@@ -0,0 +1,186 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/catalog"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// DefaultMaxImportSize is the default size limit of an imported spreadsheet
+	DefaultMaxImportSize = 20 << 20
+	// syncImportRows is the number of rows up to which an import runs within the request
+	syncImportRows = 500
+)
+
+// ImportService represent the product import job usecases
+type ImportService interface {
+	CreateImportJob(ctx context.Context, job *domain.ImportJob) error
+	GetImportJob(ctx context.Context, id int) (domain.ImportJob, error)
+	UpdateImportJob(ctx context.Context, job *domain.ImportJob) error
+}
+
+// ImportRunner represent the import of the validated rows of a job
+type ImportRunner interface {
+	Import(ctx context.Context, job *domain.ImportJob, rows []domain.ProductImportRow) error
+	Enqueue(job domain.ImportJob, rows []domain.ProductImportRow) bool
+}
+
+// ImportHandler represent the http handler for product imports
+type ImportHandler struct {
+	Service ImportService
+	Runner  ImportRunner
+	MaxSize int64
+}
+
+// NewImportHandler initializes the product import HTTP handler
+func NewImportHandler(staff *mux.Router, service ImportService, runner ImportRunner, maxSize int64) {
+	handler := &ImportHandler{Service: service, Runner: runner, MaxSize: maxSize}
+
+	staff.HandleFunc("/products/import", handler.Import).Methods("POST")
+	staff.HandleFunc("/products/import/{id}", handler.GetByID).Methods("GET")
+}
+
+// Import handles HTTP POST /products/import with a multipart CSV or XLSX
+// "file", an optional JSON "mapping" of product fields to column headers and
+// an optional "dry_run" flag. Dry runs only validate the rows. Small files
+// are imported within the request, larger ones in the background, their job
+// being returned with 202 to poll its progress.
+func (h *ImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxSize+1<<20)
+	if err := r.ParseMultipartForm(h.MaxSize); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondWithServiceError(w, domain.ErrFileTooLarge, "file")
+			return
+		}
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid multipart payload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	var mapping catalog.Mapping
+	if value := r.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "invalid mapping")
+			return
+		}
+	}
+	dryRun := false
+	if value := r.FormValue("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "invalid dry_run")
+			return
+		}
+	}
+
+	sheet, err := catalog.ReadSheet(file, header.Size, header.Filename)
+	if errors.Is(err, catalog.ErrUnsupportedFormat) {
+		utils.RespondWithError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "invalid spreadsheet: "+err.Error())
+		return
+	}
+	rows, rowErrs, err := catalog.ParseProducts(sheet, mapping)
+	var mappingErr *catalog.MappingError
+	if errors.As(err, &mappingErr) {
+		utils.RespondWithError(w, http.StatusBadRequest, mappingErr.Error())
+		return
+	}
+	if err != nil {
+		respondWithServiceError(w, err, "import")
+		return
+	}
+
+	user, _ := middleware.UserFromContext(r.Context())
+	job := domain.ImportJob{
+		Filename:  header.Filename,
+		Status:    domain.ImportPending,
+		DryRun:    dryRun,
+		TotalRows: catalog.CountRows(sheet),
+		Errors:    []domain.ImportRowError{},
+		CreatedBy: &user.ID,
+	}
+	// Rows rejected by the validation are processed already
+	job.AddErrors(rowErrs...)
+	job.ProcessedRows = job.FailedRows
+
+	if dryRun {
+		now := time.Now()
+		job.Status = domain.ImportCompleted
+		job.ProcessedRows = job.TotalRows
+		job.CreatedAt = now
+		job.FinishedAt = &now
+		utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: job})
+		return
+	}
+
+	// The products are recorded by the job, which reports the rows imported
+	err = middleware.Audited(r, func(r *http.Request) error {
+		if err := h.Service.CreateImportJob(r.Context(), &job); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditImport, "import_job", job.ID, nil, job)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "import")
+		return
+	}
+
+	if len(rows) <= syncImportRows {
+		// Finish the import even if the client goes away
+		if err := h.Runner.Import(context.WithoutCancel(r.Context()), &job, rows); err != nil {
+			respondWithServiceError(w, err, "import")
+			return
+		}
+		utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: job})
+		return
+	}
+
+	if !h.Runner.Enqueue(job, rows) {
+		now := time.Now()
+		job.Status = domain.ImportFailed
+		job.Error = "import queue is full"
+		job.FinishedAt = &now
+		if err := h.Service.UpdateImportJob(r.Context(), &job); err != nil {
+			respondWithServiceError(w, err, "import")
+			return
+		}
+		utils.RespondWithError(w, http.StatusServiceUnavailable, "Too many imports in progress, try again later")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusAccepted, utils.ResponseData{Data: job})
+}
+
+// GetByID handles HTTP GET /products/import/{id}, reporting the progress of an import
+func (h *ImportHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	job, err := h.Service.GetImportJob(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "import")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: job})
+}
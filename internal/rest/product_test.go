@@ -0,0 +1,278 @@
+package rest_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// rupiah returns an amount of whole rupiah, amounts being in minor units
+func rupiah(amount int64) domain.Money {
+	return domain.Money{Amount: amount * 100, Currency: domain.DefaultCurrency}
+}
+
+// seedProducts adds a shoe with variants, a bag out of stock and a hat
+func (s *testServer) seedProducts(t *testing.T) []domain.Product {
+	t.Helper()
+	shoes := domain.Category{ID: 1, Name: "Shoes"}
+	accessories := domain.Category{ID: 2, Name: "Accessories"}
+	override := rupiah(175000)
+	seeds := []domain.Product{
+		{
+			SKU: "SHOE-1", Name: "Running Shoe", Description: "Light and fast", Price: rupiah(150000),
+			Stock: 5, Category: shoes,
+			Options: []domain.ProductOption{{ID: 1, ProductID: 1, Name: "Size", Values: []domain.ProductOptionValue{{ID: 1, Value: "42"}, {ID: 2, Value: "43"}}}},
+			Variants: []domain.ProductVariant{
+				{ID: 1, ProductID: 1, SKU: "SHOE-1-42", Stock: 2, Options: []domain.ProductOptionValue{{ID: 1, Value: "42"}}},
+				{ID: 2, ProductID: 1, SKU: "SHOE-1-43", Price: &override, Stock: 3, Options: []domain.ProductOptionValue{{ID: 2, Value: "43"}}},
+			},
+		},
+		{SKU: "BAG-1", Name: "Leather Bag", Description: "Roomy", Price: rupiah(300000), Category: accessories},
+		{SKU: "HAT-1", Name: "Sun Hat", Price: rupiah(50000), Stock: 10, Category: accessories},
+	}
+
+	products := make([]domain.Product, 0, len(seeds))
+	for _, seed := range seeds {
+		product, err := s.products.Add(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		products = append(products, product)
+	}
+	return products
+}
+
+// productPage is the data of the GET /products response
+type productPage struct {
+	Metadata struct {
+		Page       int `json:"page"`
+		PerPage    int `json:"per_page"`
+		SubTotal   int `json:"sub_total"`
+		Total      int `json:"total"`
+		TotalPages int `json:"total_pages"`
+	} `json:"metadata"`
+	Products []domain.Product `json:"products"`
+}
+
+func productSKUs(products []domain.Product) string {
+	skus := make([]string, 0, len(products))
+	for _, p := range products {
+		skus = append(skus, p.SKU)
+	}
+	return strings.Join(skus, ",")
+}
+
+func TestProductFetchPaginated(t *testing.T) {
+	s := newTestServer(t)
+	s.seedProducts(t)
+
+	rec := s.do(http.MethodGet, "/products?page=2&per_page=2", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	var page productPage
+	decodeData(t, rec, &page)
+	if page.Metadata.Page != 2 || page.Metadata.Total != 3 || page.Metadata.TotalPages != 2 || page.Metadata.SubTotal != 1 {
+		t.Errorf("metadata = %+v, want page 2 of 2 with 1 of 3 products", page.Metadata)
+	}
+	if got := productSKUs(page.Products); got != "HAT-1" {
+		t.Errorf("products = %s, want HAT-1", got)
+	}
+
+	rec = s.do(http.MethodGet, "/products?per_page=1000", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	page = productPage{}
+	decodeData(t, rec, &page)
+	if page.Metadata.PerPage != 100 || len(page.Products) != 3 {
+		t.Errorf("metadata = %+v, want pages capped at 100 products", page.Metadata)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "SHOE-1,BAG-1,HAT-1"},
+		{"category_id=2", "BAG-1,HAT-1"},
+		{"q=shoe", "SHOE-1"},
+		{"q=hat-1", "HAT-1"},
+		{"in_stock=true", "SHOE-1,HAT-1"},
+		{"min_price=100000", "SHOE-1,BAG-1"},
+		{"max_price=100000", "HAT-1"},
+		{"min_price=100000&max_price=200000", "SHOE-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := s.do(http.MethodGet, "/products?"+tt.query, "", "")
+			expectStatus(t, rec, http.StatusOK)
+			var page productPage
+			decodeData(t, rec, &page)
+			if got := productSKUs(page.Products); got != tt.want {
+				t.Errorf("products = %s, want %s", got, tt.want)
+			}
+			for _, p := range page.Products {
+				if p.Description != "" || p.Variants != nil || p.Options != nil {
+					t.Errorf("listed product %s has details: %+v", p.SKU, p)
+				}
+			}
+		})
+	}
+
+	for _, query := range []string{"category_id=x", "in_stock=maybe", "min_price=abc", "max_price=abc"} {
+		expectStatus(t, s.do(http.MethodGet, "/products?"+query, "", ""), http.StatusBadRequest)
+	}
+}
+
+func TestProductFetchLocalized(t *testing.T) {
+	s := newTestServer(t)
+	products := s.seedProducts(t)
+
+	rec := s.do(http.MethodGet, "/products?per_page=1", "", "", "Accept-Currency", "usd")
+	expectStatus(t, rec, http.StatusOK)
+	var page productPage
+	decodeData(t, rec, &page)
+	rate := domain.ExchangeRate{Currency: "USD", Rate: 0.0001}
+	if want := rate.Convert(products[0].Price); len(page.Products) != 1 || page.Products[0].Price != want {
+		t.Errorf("products = %+v, want priced %v", page.Products, want)
+	}
+	if vary := rec.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Accept-Currency" {
+		t.Errorf("Vary = %v, want Accept-Currency", vary)
+	}
+
+	expectStatus(t, s.do(http.MethodGet, "/products?currency=EUR", "", ""), http.StatusUnprocessableEntity)
+}
+
+func TestProductGetByID(t *testing.T) {
+	s := newTestServer(t)
+	products := s.seedProducts(t)
+
+	rec := s.do(http.MethodGet, "/products/1", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"1-`) {
+		t.Errorf("ETag = %s, want a tag of version 1", etag)
+	}
+	var product domain.Product
+	decodeData(t, rec, &product)
+	want := products[0]
+	if product.SKU != want.SKU || product.Description != want.Description || product.Price != want.Price ||
+		len(product.Options) != 1 || len(product.Variants) != 2 || product.Variants[1].Price == nil {
+		t.Errorf("product = %+v, want %+v", product, want)
+	}
+
+	expectStatus(t, s.do(http.MethodGet, "/products/1", "", "", "If-None-Match", etag), http.StatusNotModified)
+	expectStatus(t, s.do(http.MethodGet, "/products/9", "", ""), http.StatusNotFound)
+	expectStatus(t, s.do(http.MethodGet, "/products/x", "", ""), http.StatusNotFound)
+
+	// Variant prices are localized along with the product price
+	rec = s.do(http.MethodGet, "/products/1?currency=USD", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	decodeData(t, rec, &product)
+	if product.Price.Currency != "USD" || product.Variants[1].Price.Currency != "USD" {
+		t.Errorf("product = %+v, want prices in USD", product)
+	}
+	expectStatus(t, s.do(http.MethodGet, "/products/1?currency=EUR", "", ""), http.StatusUnprocessableEntity)
+}
+
+func TestProductExport(t *testing.T) {
+	s := newTestServer(t)
+	s.seedProducts(t)
+
+	rec := s.do(http.MethodGet, "/products/export?category_id=2", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="products.csv"` {
+		t.Errorf("Content-Disposition = %s", got)
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0][0] != "id" || records[1][1] != "BAG-1" || records[2][1] != "HAT-1" {
+		t.Errorf("records = %v, want the header, BAG-1 and HAT-1", records)
+	}
+	if records[1][6] != "Roomy" {
+		t.Errorf("description = %q, want exported", records[1][6])
+	}
+
+	rec = s.do(http.MethodGet, "/products/export?format=ndjson", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	var skus []string
+	for lines := bufio.NewScanner(rec.Body); lines.Scan(); {
+		var product domain.Product
+		if err := json.Unmarshal(lines.Bytes(), &product); err != nil {
+			t.Fatal(err)
+		}
+		skus = append(skus, product.SKU)
+	}
+	if got := strings.Join(skus, ","); got != "SHOE-1,BAG-1,HAT-1" {
+		t.Errorf("exported products = %s, want SHOE-1,BAG-1,HAT-1", got)
+	}
+
+	rec = s.do(http.MethodGet, "/products/export?format=xml&q=hat", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	if body := rec.Body.String(); !strings.Contains(body, "<g:id>HAT-1</g:id>") || strings.Contains(body, "<g:id>SHOE-1</g:id>") {
+		t.Errorf("feed = %s, want the hat only", body)
+	}
+
+	expectStatus(t, s.do(http.MethodGet, "/products/export?format=pdf", "", ""), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodGet, "/products/export?in_stock=maybe", "", ""), http.StatusBadRequest)
+}
+
+func TestProductDelete(t *testing.T) {
+	s := newTestServer(t)
+	s.seedProducts(t)
+
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", ""), http.StatusUnauthorized)
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", token(t, 1, domain.RoleCustomer)), http.StatusForbidden)
+
+	staff := token(t, 1, domain.RoleStaff)
+	expectStatus(t, s.do(http.MethodDelete, "/products/x", "", staff), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", staff, "If-Match", `"7-abc"`), http.StatusPreconditionFailed)
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", staff, "If-Match", `W/"1-abc"`), http.StatusPreconditionFailed)
+
+	etag := s.do(http.MethodGet, "/products/1", "", "").Header().Get("ETag")
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", staff, "If-Match", etag), http.StatusOK)
+	expectStatus(t, s.do(http.MethodGet, "/products/1", "", ""), http.StatusNotFound)
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", staff), http.StatusNotFound)
+
+	// Unconditional deletes are allowed
+	expectStatus(t, s.do(http.MethodDelete, "/products/2", "", token(t, 2, domain.RoleAdmin)), http.StatusOK)
+
+	var page productPage
+	decodeData(t, s.do(http.MethodGet, "/products", "", ""), &page)
+	if got := productSKUs(page.Products); got != "HAT-1" {
+		t.Errorf("products = %s, want HAT-1", got)
+	}
+}
+
+func TestProductUpdateShippingDetails(t *testing.T) {
+	s := newTestServer(t)
+	s.seedProducts(t)
+	staff := token(t, 1, domain.RoleStaff)
+	details := `{"weight":800,"length":30,"width":20,"height":12.5}`
+
+	expectStatus(t, s.do(http.MethodPut, "/products/1/shipping", details, ""), http.StatusUnauthorized)
+	expectStatus(t, s.do(http.MethodPut, "/products/1/shipping", details, token(t, 1, domain.RoleCustomer)), http.StatusForbidden)
+	expectStatus(t, s.do(http.MethodPut, "/products/1/shipping", `{"weight":`, staff), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodPut, "/products/1/shipping", `{"weight":-1}`, staff), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodPut, "/products/9/shipping", details, staff), http.StatusNotFound)
+
+	etag := s.do(http.MethodGet, "/products/1", "", "").Header().Get("ETag")
+	expectStatus(t, s.do(http.MethodPut, "/products/1/shipping", details, staff, "If-Match", etag), http.StatusOK)
+
+	rec := s.do(http.MethodGet, "/products/1", "", "")
+	var product domain.Product
+	decodeData(t, rec, &product)
+	want := domain.ShippingDetails{Weight: 800, Length: 30, Width: 20, Height: 12.5}
+	if product.ShippingDetails != want {
+		t.Errorf("shipping details = %+v, want %+v", product.ShippingDetails, want)
+	}
+	if got := rec.Header().Get("ETag"); !strings.HasPrefix(got, `"2-`) {
+		t.Errorf("ETag = %s, want a tag of version 2", got)
+	}
+
+	// The edit was based on version 1, which is gone
+	expectStatus(t, s.do(http.MethodPut, "/products/1/shipping", details, staff, "If-Match", etag), http.StatusPreconditionFailed)
+}
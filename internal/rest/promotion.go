@@ -0,0 +1,153 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// PromotionService represent the promotion and coupon usecases
+type PromotionService interface {
+	Fetch(ctx context.Context) (result []domain.Promotion, err error)
+	GetByID(ctx context.Context, id int) (domain.Promotion, error)
+	Create(ctx context.Context, promotion *domain.Promotion) error
+	Update(ctx context.Context, promotion *domain.Promotion) error
+	Delete(ctx context.Context, id int) error
+}
+
+// PromotionHandler represent the http handler for promotions
+type PromotionHandler struct {
+	Service PromotionService
+}
+
+// NewPromotionHandler initializes the promotion HTTP handler, the router is expected to be staff only
+func NewPromotionHandler(r *mux.Router, service PromotionService) {
+	handler := &PromotionHandler{Service: service}
+
+	r.HandleFunc("/admin/promotions", handler.Fetch).Methods("GET")
+	r.HandleFunc("/admin/promotions", handler.Create).Methods("POST")
+	r.HandleFunc("/admin/promotions/{id}", handler.GetByID).Methods("GET")
+	r.HandleFunc("/admin/promotions/{id}", handler.Update).Methods("PUT")
+	r.HandleFunc("/admin/promotions/{id}", handler.Delete).Methods("DELETE")
+}
+
+// decodePromotion reads and validates a promotion payload, responding with 400 when it is invalid
+func decodePromotion(w http.ResponseWriter, r *http.Request) (domain.Promotion, bool) {
+	var promotion domain.Promotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return domain.Promotion{}, false
+	}
+	if respondWithValidationError(w, promotion) {
+		return domain.Promotion{}, false
+	}
+	if err := promotion.Validate(); err != nil {
+		respondWithServiceError(w, err, "promotion")
+		return domain.Promotion{}, false
+	}
+	promotion.Code = domain.NormalizeCoupon(promotion.Code)
+	return promotion, true
+}
+
+// Fetch handles HTTP GET /admin/promotions
+func (p *PromotionHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	promotions, err := p.Service.Fetch(r.Context())
+	if err != nil {
+		respondWithServiceError(w, err, "promotion")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: promotions})
+}
+
+// GetByID handles HTTP GET /admin/promotions/{id}
+func (p *PromotionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	promotion, err := p.Service.GetByID(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "promotion")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: promotion})
+}
+
+// Create handles HTTP POST /admin/promotions
+func (p *PromotionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	promotion, ok := decodePromotion(w, r)
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		if err := p.Service.Create(r.Context(), &promotion); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCreate, "promotion", promotion.ID, nil, promotion)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "promotion")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: promotion})
+}
+
+// Update handles HTTP PUT /admin/promotions/{id}
+func (p *PromotionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	promotion, ok := decodePromotion(w, r)
+	if !ok {
+		return
+	}
+	promotion.ID = id
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := p.Service.GetByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := p.Service.Update(r.Context(), &promotion); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "promotion", id, before, promotion)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "promotion")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: promotion})
+}
+
+// Delete handles HTTP DELETE /admin/promotions/{id}
+func (p *PromotionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := p.Service.GetByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := p.Service.Delete(r.Context(), id); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "promotion", id, before, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "promotion")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Promotion deleted successfully")
+}
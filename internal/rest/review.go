@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// ReviewService represent the product review and moderation usecases
+type ReviewService interface {
+	Fetch(ctx context.Context, filter domain.ReviewFilter, offset, limit int) (total int, result []domain.Review, err error)
+	GetByID(ctx context.Context, id int) (domain.Review, error)
+	Create(ctx context.Context, review *domain.Review) error
+	UpdateStatus(ctx context.Context, id int, status domain.ReviewStatus) (domain.Review, error)
+}
+
+// ReviewHandler represent the http handler for product reviews
+type ReviewHandler struct {
+	Service ReviewService
+}
+
+// createReviewRequest represent the payload of POST /products/{id}/reviews
+type createReviewRequest struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Title  string `json:"title" validate:"max=255"`
+	Body   string `json:"body" validate:"max=5000"`
+}
+
+// moderateReviewRequest represent the payload of PUT /admin/reviews/{id}/status
+type moderateReviewRequest struct {
+	Status domain.ReviewStatus `json:"status" validate:"required,oneof=pending approved rejected"`
+}
+
+// NewReviewHandler initializes the review HTTP handler. Approved reviews are
+// listed on public, customers review on r and staff moderate on staff.
+func NewReviewHandler(public, r, staff *mux.Router, service ReviewService) {
+	handler := &ReviewHandler{Service: service}
+
+	public.HandleFunc("/products/{id}/reviews", handler.Fetch).Methods("GET")
+	r.HandleFunc("/products/{id}/reviews", handler.Create).Methods("POST")
+	staff.HandleFunc("/admin/reviews", handler.FetchAll).Methods("GET")
+	staff.HandleFunc("/admin/reviews/{id}", handler.GetByID).Methods("GET")
+	staff.HandleFunc("/admin/reviews/{id}/status", handler.UpdateStatus).Methods("PUT")
+}
+
+// respondWithReviews responds with a page of the reviews matching filter
+func (h *ReviewHandler) respondWithReviews(w http.ResponseWriter, r *http.Request, filter domain.ReviewFilter) {
+	page, perPage, offset := pagination(r)
+
+	total, reviews, err := h.Service.Fetch(r.Context(), filter, offset, perPage)
+	if err != nil {
+		respondWithServiceError(w, err, "review")
+		return
+	}
+
+	response := map[string]interface{}{
+		"metadata": paginationMetadata(page, perPage, len(reviews), total),
+		"reviews":  reviews,
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: response})
+}
+
+// Fetch handles HTTP GET /products/{id}/reviews, listing the approved reviews of a product
+func (h *ReviewHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	h.respondWithReviews(w, r, domain.ReviewFilter{ProductID: productID, Status: domain.ReviewApproved})
+}
+
+// FetchAll handles HTTP GET /admin/reviews, optionally filtered by status
+func (h *ReviewHandler) FetchAll(w http.ResponseWriter, r *http.Request) {
+	h.respondWithReviews(w, r, domain.ReviewFilter{Status: domain.ReviewStatus(r.URL.Query().Get("status"))})
+}
+
+// GetByID handles HTTP GET /admin/reviews/{id}
+func (h *ReviewHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	review, err := h.Service.GetByID(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "review")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: review})
+}
+
+// Create handles HTTP POST /products/{id}/reviews. The review awaits
+// moderation, and a user reviews a product once.
+func (h *ReviewHandler) Create(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req createReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	review := domain.Review{
+		ProductID: productID,
+		UserID:    user.ID,
+		Rating:    req.Rating,
+		Title:     req.Title,
+		Body:      req.Body,
+	}
+	if err := h.Service.Create(r.Context(), &review); err != nil {
+		resource := "review"
+		if errors.Is(err, domain.ErrNotFound) {
+			resource = "product"
+		}
+		respondWithServiceError(w, err, resource)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: review})
+}
+
+// UpdateStatus handles HTTP PUT /admin/reviews/{id}/status, approving or
+// rejecting a review
+func (h *ReviewHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req moderateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	var review domain.Review
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := h.Service.GetByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		review, err = h.Service.UpdateStatus(r.Context(), id, req.Status)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "review", id, before, review)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "review")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: review})
+}
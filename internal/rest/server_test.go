@@ -0,0 +1,119 @@
+package rest_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/catalog"
+	memoryRepo "github.com/bimbims125/clean-arch/internal/repository/memory"
+	"github.com/bimbims125/clean-arch/internal/rest"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/gorilla/mux"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// testServer routes requests to the user, category, product and trash
+// handlers backed by the memory repositories, the way main does
+type testServer struct {
+	router     *mux.Router
+	users      *memoryRepo.UserRepository
+	categories *memoryRepo.CategoryRepository
+	products   *memoryRepo.ProductRepository
+}
+
+// priceBooks prices products in the currencies it holds a book for
+type priceBooks map[string]domain.PriceBook
+
+func (p priceBooks) PriceBook(ctx context.Context, currency string, productIDs []int) (domain.PriceBook, error) {
+	book, ok := p[currency]
+	if !ok {
+		return domain.PriceBook{}, domain.ErrUnsupportedCurrency
+	}
+	return book, nil
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := &testServer{
+		router:     mux.NewRouter(),
+		users:      memoryRepo.NewMemoryUserRepository(),
+		categories: memoryRepo.NewMemoryCategoryRepository(),
+		products:   memoryRepo.NewMemoryProductRepository(),
+	}
+
+	staff := s.router.NewRoute().Subrouter()
+	staff.Use(middleware.JWTMiddleware(testSecret), middleware.RequireRole(domain.RoleAdmin, domain.RoleStaff))
+	admin := s.router.NewRoute().Subrouter()
+	admin.Use(middleware.JWTMiddleware(testSecret), middleware.RequireRole(domain.RoleAdmin))
+
+	prices := priceBooks{
+		"USD": {Rate: domain.ExchangeRate{Currency: "USD", Rate: 0.0001}},
+	}
+	rest.NewUserHandler(s.router, admin, s.users, nil, testSecret, time.Hour)
+	rest.NewCategoryHandler(s.router, staff, s.categories)
+	rest.NewProductHandler(s.router, staff, s.products, prices, catalog.Feed{Title: "Shop", Link: "https://shop.example.com"})
+	rest.NewTrashHandler(admin, map[string]rest.TrashService{
+		"users":      s.users,
+		"categories": s.categories,
+		"products":   s.products,
+	})
+	return s
+}
+
+// token returns a bearer token of a user with the given role
+func token(t *testing.T, id int, role string) string {
+	t.Helper()
+	token, err := middleware.GenerateToken(testSecret, domain.User{ID: id, Email: role + "@example.com", Role: role}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// do serves a request with an optional JSON body and bearer token, and the
+// headers given as name and value pairs
+func (s *testServer) do(method, path, body, token string, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for n := 0; n+1 < len(headers); n += 2 {
+		req.Header.Set(headers[n], headers[n+1])
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expectStatus fails the test when a response doesn't have the wanted status
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, want, rec.Body)
+	}
+}
+
+// decodeData decodes the data member of a JSON response into v
+func decodeData(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	response := struct {
+		Data interface{} `json:"data"`
+	}{Data: v}
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+}
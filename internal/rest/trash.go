@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// TrashService represent the usecases of the deleted items of a resource
+type TrashService interface {
+	FetchTrashed(ctx context.Context, offset, limit int) (total int, items []domain.TrashedItem, err error)
+	GetTrashed(ctx context.Context, id int) (domain.TrashedItem, error)
+	Restore(ctx context.Context, id int) error
+}
+
+// trashState is the state of a deleted item recorded in the audit logs,
+// without deletion time once restored
+type trashState struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// TrashHandler represent the http handler for deleted users, categories and products
+type TrashHandler struct {
+	// Services holds the trash of every resource by its name in paths
+	Services map[string]TrashService
+}
+
+// NewTrashHandler initializes the trash HTTP handler on an admin only router,
+// services holding the trash of every resource by its name in paths
+func NewTrashHandler(admin *mux.Router, services map[string]TrashService) {
+	handler := &TrashHandler{Services: services}
+
+	admin.HandleFunc("/admin/trash/{resource}", handler.Fetch).Methods("GET")
+	admin.HandleFunc("/admin/trash/{resource}/{id}/restore", handler.Restore).Methods("POST")
+}
+
+// service returns the trash of the resource named in the path, responding
+// with 404 for unknown resources
+func (t *TrashHandler) service(w http.ResponseWriter, r *http.Request) (TrashService, bool) {
+	service, ok := t.Services[mux.Vars(r)["resource"]]
+	if !ok || service == nil {
+		utils.RespondWithError(w, http.StatusNotFound, "resource not found")
+		return nil, false
+	}
+	return service, true
+}
+
+// Fetch handles HTTP GET /admin/trash/{resource}, listing the deleted items
+// of users, categories or products, last deleted first
+func (t *TrashHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	service, ok := t.service(w, r)
+	if !ok {
+		return
+	}
+	page, perPage, offset := pagination(r)
+
+	total, items, err := service.FetchTrashed(r.Context(), offset, perPage)
+	if err != nil {
+		respondWithServiceError(w, err, "item")
+		return
+	}
+
+	response := map[string]interface{}{
+		"metadata": paginationMetadata(page, perPage, len(items), total),
+		"items":    items,
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: response})
+}
+
+// Restore handles HTTP POST /admin/trash/{resource}/{id}/restore. Products
+// are restored once their category is.
+func (t *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	service, ok := t.service(w, r)
+	if !ok {
+		return
+	}
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		item, err := service.GetTrashed(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := service.Restore(r.Context(), id); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditRestore, mux.Vars(r)["resource"], id,
+			trashState{ID: item.ID, Name: item.Name, DeletedAt: &item.DeletedAt}, trashState{ID: item.ID, Name: item.Name})
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "deleted item")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Item restored successfully")
+}
@@ -0,0 +1,97 @@
+package rest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// trashPage is the data of the GET /admin/trash/{resource} response
+type trashPage struct {
+	Metadata struct {
+		PerPage int `json:"per_page"`
+		Total   int `json:"total"`
+	} `json:"metadata"`
+	Items []domain.TrashedItem `json:"items"`
+}
+
+func TestTrash(t *testing.T) {
+	s := newTestServer(t)
+	s.seedProducts(t)
+	s.signUp(t, "alice@example.com")
+	expectStatus(t, s.do(http.MethodPost, "/categories", `{"name":"Shoes"}`, ""), http.StatusCreated)
+
+	admin := token(t, 99, domain.RoleAdmin)
+	expectStatus(t, s.do(http.MethodDelete, "/users/1", "", admin, "If-Match", "*"), http.StatusOK)
+	expectStatus(t, s.do(http.MethodDelete, "/categories/1", "", admin, "If-Match", "*"), http.StatusOK)
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", admin), http.StatusOK)
+	expectStatus(t, s.do(http.MethodDelete, "/products/3", "", admin), http.StatusOK)
+
+	tests := []struct {
+		resource string
+		want     []int
+	}{
+		{"users", []int{1}},
+		{"categories", []int{1}},
+		// Last deleted first
+		{"products", []int{3, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			rec := s.do(http.MethodGet, "/admin/trash/"+tt.resource, "", admin)
+			expectStatus(t, rec, http.StatusOK)
+			var page trashPage
+			decodeData(t, rec, &page)
+			if page.Metadata.Total != len(tt.want) || len(page.Items) != len(tt.want) {
+				t.Fatalf("trash = %+v, want items %v", page, tt.want)
+			}
+			for n, id := range tt.want {
+				if page.Items[n].ID != id || page.Items[n].Name == "" || page.Items[n].DeletedAt.IsZero() {
+					t.Errorf("item %d = %+v, want item %d", n, page.Items[n], id)
+				}
+			}
+		})
+	}
+
+	rec := s.do(http.MethodGet, "/admin/trash/products?per_page=1&page=2", "", admin)
+	var page trashPage
+	decodeData(t, rec, &page)
+	if page.Metadata.Total != 2 || len(page.Items) != 1 || page.Items[0].ID != 1 {
+		t.Errorf("second page = %+v, want product 1", page)
+	}
+
+	rec = s.do(http.MethodGet, "/admin/trash/products?per_page=1000", "", admin)
+	page = trashPage{}
+	decodeData(t, rec, &page)
+	if page.Metadata.PerPage != 100 || len(page.Items) != 2 {
+		t.Errorf("page = %+v, want pages capped at 100 items", page)
+	}
+
+	expectStatus(t, s.do(http.MethodGet, "/admin/trash/orders", "", admin), http.StatusNotFound)
+	expectStatus(t, s.do(http.MethodGet, "/admin/trash/products", "", ""), http.StatusUnauthorized)
+	expectStatus(t, s.do(http.MethodGet, "/admin/trash/products", "", token(t, 1, domain.RoleStaff)), http.StatusForbidden)
+}
+
+func TestTrashRestore(t *testing.T) {
+	s := newTestServer(t)
+	s.seedProducts(t)
+	s.signUp(t, "alice@example.com")
+	admin := token(t, 99, domain.RoleAdmin)
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", admin), http.StatusOK)
+	expectStatus(t, s.do(http.MethodDelete, "/users/1", "", admin, "If-Match", "*"), http.StatusOK)
+
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/products/1/restore", "", token(t, 1, domain.RoleStaff)), http.StatusForbidden)
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/products/x/restore", "", admin), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/products/2/restore", "", admin), http.StatusNotFound)
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/orders/1/restore", "", admin), http.StatusNotFound)
+
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/products/1/restore", "", admin), http.StatusOK)
+	expectStatus(t, s.do(http.MethodGet, "/products/1", "", ""), http.StatusOK)
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/products/1/restore", "", admin), http.StatusNotFound)
+
+	// Restored users can log in again
+	expectStatus(t, s.do(http.MethodPost, "/admin/trash/users/1/restore", "", admin), http.StatusOK)
+	rec := s.do(http.MethodPost, "/login", `{"email":"alice@example.com","password":"Passw0rd!"}`, "")
+	expectStatus(t, rec, http.StatusOK)
+}
@@ -3,36 +3,68 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
 	"github.com/bimbims125/clean-arch/internal/validation"
 	"github.com/bimbims125/clean-arch/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
 )
 
 // define validator
 var validate = validator.New()
 
+func init() {
+	validate.RegisterCustomTypeFunc(validation.MoneyAmount, domain.Money{})
+}
+
 // UserService represent the user's usecases
 type UserService interface {
 	Fetch(ctx context.Context) (result []domain.User, err error)
 	Create(ctx context.Context, user domain.User) error
+	GetByID(ctx context.Context, id int) (domain.User, error)
 	GetByEmail(ctx context.Context, email string) (domain.User, error)
+	GetCredentials(ctx context.Context, email string) (domain.User, error)
+	// Delete moves a user to the trash, the user can't log in anymore. It
+	// fails with domain.ErrVersionMismatch unless the user is at version.
+	Delete(ctx context.Context, id, version int) error
+}
+
+// CartMerger merges the anonymous cart of a visitor into the cart of a user
+type CartMerger interface {
+	MergeCarts(ctx context.Context, token string, userID int) error
 }
 
 // UserHandler represent the http handler for user
 type UserHandler struct {
 	Service UserService
+	// Carts is nil when the backend has no carts
+	Carts    CartMerger
+	Secret   []byte
+	TokenTTL time.Duration
+}
+
+// loginRequest represent the payload of POST /login
+type loginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
 }
 
-// NewUserHandler initializes the user HTTP handler
-func NewUserHandler(r *mux.Router, service UserService) {
-	handler := &UserHandler{Service: service}
+// NewUserHandler initializes the user HTTP handler, admin routes being
+// registered on admin
+func NewUserHandler(r, admin *mux.Router, service UserService, carts CartMerger, secret []byte, tokenTTL time.Duration) {
+	handler := &UserHandler{Service: service, Carts: carts, Secret: secret, TokenTTL: tokenTTL}
 
 	r.HandleFunc("/users", handler.FetchUser).Methods("GET")
 	r.HandleFunc("/users", handler.Create).Methods("POST")
+	r.HandleFunc("/login", handler.Login).Methods("POST")
+	admin.HandleFunc("/users/{id}", handler.GetByID).Methods("GET")
+	admin.HandleFunc("/users/{id}", handler.Delete).Methods("DELETE")
 }
 
 // FetchUser handles HTTP GET /users
@@ -55,6 +87,7 @@ func (u *UserHandler) FetchUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(utils.ResponseData{Data: users})
 }
 
+// Create handles HTTP POST /users, signing up a customer
 func (u *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var user domain.User
 
@@ -63,6 +96,8 @@ func (u *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(utils.ResponseError{Message: "Invalid request payload"})
 		return
 	}
+	// Signing up always makes a customer, staff and admins are promoted by an admin
+	user.Role = domain.RoleCustomer
 
 	// Validation
 	// Register custom validation
@@ -94,3 +129,97 @@ func (u *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(utils.ResponseSuccess{Message: "User created successfully"})
 }
+
+// Login handles HTTP POST /login, returning a bearer token and merging the
+// anonymous cart of the visitor into the cart of the user
+func (u *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+
+	user, err := u.Service.GetCredentials(r.Context(), req.Email)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		respondWithServiceError(w, err, "user")
+		return
+	}
+	if err != nil || !user.CheckPassword(req.Password) {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
+	user.Password = ""
+
+	token, err := middleware.GenerateToken(u.Secret, user, u.TokenTTL)
+	if err != nil {
+		logrus.Error(err)
+		respondWithServiceError(w, err, "token")
+		return
+	}
+
+	// A failed merge leaves the anonymous cart in place, it does not fail the login
+	if cookie, err := r.Cookie(CartCookieName); err == nil && u.Carts != nil {
+		if err := u.Carts.MergeCarts(r.Context(), cookie.Value, user.ID); err != nil {
+			logrus.Error(err)
+		} else {
+			setCartCookie(w, r, "")
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: map[string]interface{}{
+		"token": token,
+		"user":  user,
+	}})
+}
+
+// GetByID handles HTTP GET /users/{id}, tagging the user with its version
+func (u *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	user, err := u.Service.GetByID(r.Context(), id)
+	if err != nil {
+		respondWithServiceError(w, err, "user")
+		return
+	}
+	respondWithETag(w, r, user.Version, utils.ResponseData{Data: user})
+}
+
+// Delete handles HTTP DELETE /users/{id}, moving the user to the trash. The
+// request must be conditional on the version of the user. The tokens issued
+// to the user stay valid until they expire.
+func (u *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	if user, _ := middleware.UserFromContext(r.Context()); user.ID == id {
+		utils.RespondWithError(w, http.StatusBadRequest, "users can't delete themselves")
+		return
+	}
+	version, ok := requireIfMatchVersion(w, r, "user")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		user, err := u.Service.GetByID(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if err := u.Service.Delete(r.Context(), id, version); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "user", id, user, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "user")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "User deleted successfully")
+}
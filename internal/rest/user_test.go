@@ -0,0 +1,140 @@
+package rest_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// signUp creates a customer through POST /users and returns it
+func (s *testServer) signUp(t *testing.T, email string) domain.User {
+	t.Helper()
+	rec := s.do(http.MethodPost, "/users", `{"name":"Alice","email":"`+email+`","password":"Passw0rd!"}`, "")
+	expectStatus(t, rec, http.StatusCreated)
+	user, err := s.users.GetByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestUserCreateIgnoresRole(t *testing.T) {
+	s := newTestServer(t)
+
+	for _, role := range []string{domain.RoleAdmin, domain.RoleStaff, ""} {
+		email := "user-" + role + "@example.com"
+		body := `{"name":"Mallory","email":"` + email + `","password":"Passw0rd!","role":"` + role + `"}`
+		rec := s.do(http.MethodPost, "/users", body, "")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("role %q: status = %d, want %d: %s", role, rec.Code, http.StatusCreated, rec.Body)
+		}
+		user, err := s.users.GetByEmail(context.Background(), email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Role != domain.RoleCustomer {
+			t.Errorf("role %q: stored role = %q, want %q", role, user.Role, domain.RoleCustomer)
+		}
+	}
+}
+
+func TestUserCreateInvalid(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp(t, "alice@example.com")
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"malformed payload", `{"email":`},
+		{"invalid email", `{"name":"Bob","email":"bob","password":"Passw0rd!"}`},
+		{"weak password", `{"name":"Bob","email":"bob@example.com","password":"password"}`},
+		{"email taken", `{"name":"Alice","email":"alice@example.com","password":"Passw0rd!"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, s.do(http.MethodPost, "/users", tt.body, ""), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestUserFetch(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp(t, "alice@example.com")
+	s.signUp(t, "bob@example.com")
+
+	rec := s.do(http.MethodGet, "/users", "", "")
+	expectStatus(t, rec, http.StatusOK)
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Errorf("response holds passwords: %s", rec.Body)
+	}
+	var users []domain.User
+	decodeData(t, rec, &users)
+	if len(users) != 2 || users[0].Email != "alice@example.com" || users[1].Email != "bob@example.com" {
+		t.Errorf("users = %+v, want alice and bob", users)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signUp(t, "alice@example.com")
+
+	rec := s.do(http.MethodPost, "/login", `{"email":"alice@example.com","password":"Passw0rd!"}`, "")
+	expectStatus(t, rec, http.StatusOK)
+	var login struct {
+		Token string      `json:"token"`
+		User  domain.User `json:"user"`
+	}
+	decodeData(t, rec, &login)
+	if login.Token == "" || login.User.ID != user.ID || login.User.Password != "" {
+		t.Errorf("login = %+v, want a token and the user without its password", login)
+	}
+
+	// The token authenticates the user, who isn't staff
+	expectStatus(t, s.do(http.MethodDelete, "/products/1", "", login.Token), http.StatusForbidden)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"wrong password", `{"email":"alice@example.com","password":"Wr0ngPass!"}`, http.StatusUnauthorized},
+		{"unknown email", `{"email":"bob@example.com","password":"Passw0rd!"}`, http.StatusUnauthorized},
+		{"missing password", `{"email":"alice@example.com"}`, http.StatusBadRequest},
+		{"malformed payload", `{"email":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, s.do(http.MethodPost, "/login", tt.body, ""), tt.want)
+		})
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	s := newTestServer(t)
+	user := s.signUp(t, "alice@example.com")
+	path := "/users/" + strconv.Itoa(user.ID)
+
+	expectStatus(t, s.do(http.MethodDelete, path, "", ""), http.StatusUnauthorized)
+	expectStatus(t, s.do(http.MethodDelete, path, "", token(t, 99, domain.RoleStaff)), http.StatusForbidden)
+	expectStatus(t, s.do(http.MethodDelete, path, "", token(t, user.ID, domain.RoleAdmin)), http.StatusBadRequest)
+
+	admin := token(t, 99, domain.RoleAdmin)
+	expectStatus(t, s.do(http.MethodDelete, "/users/x", "", admin, "If-Match", "*"), http.StatusBadRequest)
+	expectStatus(t, s.do(http.MethodDelete, path, "", admin), http.StatusPreconditionRequired)
+	expectStatus(t, s.do(http.MethodDelete, path, "", admin, "If-Match", `"7-abc"`), http.StatusPreconditionFailed)
+
+	expectStatus(t, s.do(http.MethodGet, path, "", token(t, 99, domain.RoleStaff)), http.StatusForbidden)
+	etag := s.do(http.MethodGet, path, "", admin).Header().Get("ETag")
+	expectStatus(t, s.do(http.MethodDelete, path, "", admin, "If-Match", etag), http.StatusOK)
+	expectStatus(t, s.do(http.MethodGet, path, "", admin), http.StatusNotFound)
+	expectStatus(t, s.do(http.MethodDelete, path, "", admin, "If-Match", "*"), http.StatusNotFound)
+
+	// Deleted users can't log in
+	rec := s.do(http.MethodPost, "/login", `{"email":"alice@example.com","password":"Passw0rd!"}`, "")
+	expectStatus(t, rec, http.StatusUnauthorized)
+}
@@ -0,0 +1,287 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// VariantService represent the product option and variant usecases
+type VariantService interface {
+	FetchOptions(ctx context.Context, productID int) (result []domain.ProductOption, err error)
+	CreateOption(ctx context.Context, option *domain.ProductOption) error
+	DeleteOption(ctx context.Context, productID, id int) error
+	FetchByProduct(ctx context.Context, productID int) (result []domain.ProductVariant, err error)
+	GetByID(ctx context.Context, productID, id int) (result domain.ProductVariant, err error)
+	Create(ctx context.Context, variant *domain.ProductVariant) error
+	// Update updates a variant but not its stock, changedBy being the staff
+	// member changing its price
+	Update(ctx context.Context, variant *domain.ProductVariant, changedBy *int) error
+	Delete(ctx context.Context, productID, id int) error
+}
+
+// VariantHandler represent the http handler for product options and variants
+type VariantHandler struct {
+	Service VariantService
+}
+
+// NewVariantHandler initializes the product variant HTTP handler. Catalog
+// routes are registered on r and staff routes on staff.
+func NewVariantHandler(r, staff *mux.Router, service VariantService) {
+	handler := &VariantHandler{Service: service}
+
+	r.HandleFunc("/products/{id}/options", handler.FetchOptions).Methods("GET")
+	r.HandleFunc("/products/{id}/variants", handler.Fetch).Methods("GET")
+	r.HandleFunc("/products/{id}/variants/{variantID}", handler.GetByID).Methods("GET")
+	staff.HandleFunc("/products/{id}/options", handler.CreateOption).Methods("POST")
+	staff.HandleFunc("/products/{id}/options/{optionID}", handler.DeleteOption).Methods("DELETE")
+	staff.HandleFunc("/products/{id}/variants", handler.Create).Methods("POST")
+	staff.HandleFunc("/products/{id}/variants/{variantID}", handler.Update).Methods("PUT")
+	staff.HandleFunc("/products/{id}/variants/{variantID}", handler.Delete).Methods("DELETE")
+}
+
+// FetchOptions handles HTTP GET /products/{id}/options
+func (v *VariantHandler) FetchOptions(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	options, err := v.Service.FetchOptions(r.Context(), productID)
+	if err != nil {
+		respondWithServiceError(w, err, "option")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: options})
+}
+
+// CreateOption handles HTTP POST /products/{id}/options
+func (v *VariantHandler) CreateOption(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var option domain.ProductOption
+	if err := json.NewDecoder(r.Body).Decode(&option); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, option) {
+		return
+	}
+	option.ProductID = productID
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		if err := v.Service.CreateOption(r.Context(), &option); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCreate, "product_option", option.ID, nil, option)
+	})
+	if err != nil {
+		respondWithParentError(w, err, "option")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: option})
+}
+
+// DeleteOption handles HTTP DELETE /products/{id}/options/{optionID}
+func (v *VariantHandler) DeleteOption(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	optionID, ok := pathInt(w, r, "optionID")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		option, err := v.option(r.Context(), productID, optionID)
+		if err != nil {
+			return err
+		}
+		if err := v.Service.DeleteOption(r.Context(), productID, optionID); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "product_option", optionID, option, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "option")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Option deleted successfully")
+}
+
+// option returns an option of a product
+func (v *VariantHandler) option(ctx context.Context, productID, id int) (domain.ProductOption, error) {
+	options, err := v.Service.FetchOptions(ctx, productID)
+	if err != nil {
+		return domain.ProductOption{}, err
+	}
+	for _, option := range options {
+		if option.ID == id {
+			return option, nil
+		}
+	}
+	return domain.ProductOption{}, domain.ErrNotFound
+}
+
+// Fetch handles HTTP GET /products/{id}/variants
+func (v *VariantHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	variants, err := v.Service.FetchByProduct(r.Context(), productID)
+	if err != nil {
+		respondWithServiceError(w, err, "variant")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: variants})
+}
+
+// GetByID handles HTTP GET /products/{id}/variants/{variantID}
+func (v *VariantHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	variantID, ok := pathInt(w, r, "variantID")
+	if !ok {
+		return
+	}
+
+	variant, err := v.Service.GetByID(r.Context(), productID, variantID)
+	if err != nil {
+		respondWithServiceError(w, err, "variant")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: variant})
+}
+
+// Create handles HTTP POST /products/{id}/variants
+func (v *VariantHandler) Create(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var variant domain.ProductVariant
+	if err := json.NewDecoder(r.Body).Decode(&variant); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, variant) {
+		return
+	}
+	variant.ProductID = productID
+
+	var created domain.ProductVariant
+	err := middleware.Audited(r, func(r *http.Request) (err error) {
+		if err := v.Service.Create(r.Context(), &variant); err != nil {
+			return err
+		}
+		created, err = v.Service.GetByID(r.Context(), productID, variant.ID)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditCreate, "product_variant", created.ID, nil, created)
+	})
+	if err != nil {
+		respondWithParentError(w, err, "variant")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: created})
+}
+
+// Update handles HTTP PUT /products/{id}/variants/{variantID}. The stock in
+// the payload is ignored, variant stock changes through stock movements.
+func (v *VariantHandler) Update(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	variantID, ok := pathInt(w, r, "variantID")
+	if !ok {
+		return
+	}
+
+	var variant domain.ProductVariant
+	if err := json.NewDecoder(r.Body).Decode(&variant); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, variant) {
+		return
+	}
+	variant.ID = variantID
+	variant.ProductID = productID
+	user, _ := middleware.UserFromContext(r.Context())
+
+	var updated domain.ProductVariant
+	err := middleware.Audited(r, func(r *http.Request) error {
+		before, err := v.Service.GetByID(r.Context(), productID, variantID)
+		if err != nil {
+			return err
+		}
+		if err := v.Service.Update(r.Context(), &variant, &user.ID); err != nil {
+			return err
+		}
+		updated, err = v.Service.GetByID(r.Context(), productID, variantID)
+		if err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditUpdate, "product_variant", variantID, before, updated)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "variant")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: updated})
+}
+
+// Delete handles HTTP DELETE /products/{id}/variants/{variantID}
+func (v *VariantHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	variantID, ok := pathInt(w, r, "variantID")
+	if !ok {
+		return
+	}
+
+	err := middleware.Audited(r, func(r *http.Request) error {
+		variant, err := v.Service.GetByID(r.Context(), productID, variantID)
+		if err != nil {
+			return err
+		}
+		if err := v.Service.Delete(r.Context(), productID, variantID); err != nil {
+			return err
+		}
+		return recordChange(r, domain.AuditDelete, "product_variant", variantID, variant, nil)
+	})
+	if err != nil {
+		respondWithServiceError(w, err, "variant")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Variant deleted successfully")
+}
+
+// respondWithParentError maps the errors of creating a resource of a product,
+// a missing product being reported as such
+func respondWithParentError(w http.ResponseWriter, err error, resource string) {
+	if errors.Is(err, domain.ErrNotFound) {
+		utils.RespondWithError(w, http.StatusNotFound, "product not found")
+		return
+	}
+	respondWithServiceError(w, err, resource)
+}
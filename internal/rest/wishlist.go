@@ -0,0 +1,248 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/rest/middleware"
+	"github.com/bimbims125/clean-arch/utils"
+	"github.com/gorilla/mux"
+)
+
+// WishlistService represent the wishlist usecases, every list belongs to a user
+type WishlistService interface {
+	Fetch(ctx context.Context, userID int) ([]domain.Wishlist, error)
+	GetByID(ctx context.Context, userID, id int) (domain.Wishlist, error)
+	GetByShareToken(ctx context.Context, token string) (domain.Wishlist, error)
+	Create(ctx context.Context, wishlist *domain.Wishlist) error
+	Rename(ctx context.Context, userID, id int, name string) error
+	Delete(ctx context.Context, userID, id int) error
+	SetShareToken(ctx context.Context, userID, id int, token string) error
+	AddItem(ctx context.Context, userID, wishlistID, productID int) error
+	RemoveItem(ctx context.Context, userID, wishlistID, productID int) error
+}
+
+// WishlistHandler represent the http handler for wishlists
+type WishlistHandler struct {
+	Service WishlistService
+}
+
+// wishlistRequest represent the payload creating or renaming a wishlist
+type wishlistRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// wishlistItemRequest represent the payload of POST /wishlists/{id}/items
+type wishlistItemRequest struct {
+	ProductID int `json:"product_id" validate:"required,gt=0"`
+}
+
+// NewWishlistHandler initializes the wishlist HTTP handler. Shared wishlists
+// are read on public, customer routes are registered on r.
+func NewWishlistHandler(public, r *mux.Router, service WishlistService) {
+	handler := &WishlistHandler{Service: service}
+
+	public.HandleFunc("/wishlists/shared/{token}", handler.GetShared).Methods("GET")
+	r.HandleFunc("/wishlists", handler.Fetch).Methods("GET")
+	r.HandleFunc("/wishlists", handler.Create).Methods("POST")
+	r.HandleFunc("/wishlists/{id}", handler.GetByID).Methods("GET")
+	r.HandleFunc("/wishlists/{id}", handler.Rename).Methods("PUT")
+	r.HandleFunc("/wishlists/{id}", handler.Delete).Methods("DELETE")
+	r.HandleFunc("/wishlists/{id}/items", handler.AddItem).Methods("POST")
+	r.HandleFunc("/wishlists/{id}/items/{product_id}", handler.RemoveItem).Methods("DELETE")
+	r.HandleFunc("/wishlists/{id}/share", handler.Share).Methods("POST")
+	r.HandleFunc("/wishlists/{id}/share", handler.Unshare).Methods("DELETE")
+}
+
+// decodeWishlist reads and validates a wishlist payload, responding with 400 when it is invalid
+func decodeWishlist(w http.ResponseWriter, r *http.Request) (wishlistRequest, bool) {
+	var req wishlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return wishlistRequest{}, false
+	}
+	if respondWithValidationError(w, req) {
+		return wishlistRequest{}, false
+	}
+	return req, true
+}
+
+// respondWithWishlist responds with a wishlist of the user after a change
+func (h *WishlistHandler) respondWithWishlist(w http.ResponseWriter, r *http.Request, userID, id int) {
+	wishlist, err := h.Service.GetByID(r.Context(), userID, id)
+	if err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: wishlist})
+}
+
+// Fetch handles HTTP GET /wishlists, listing the wishlists of the user
+func (h *WishlistHandler) Fetch(w http.ResponseWriter, r *http.Request) {
+	user, _ := middleware.UserFromContext(r.Context())
+
+	wishlists, err := h.Service.Fetch(r.Context(), user.ID)
+	if err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: wishlists})
+}
+
+// Create handles HTTP POST /wishlists
+func (h *WishlistHandler) Create(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeWishlist(w, r)
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	wishlist := domain.Wishlist{UserID: user.ID, Name: req.Name}
+	if err := h.Service.Create(r.Context(), &wishlist); err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, utils.ResponseData{Data: wishlist})
+}
+
+// GetByID handles HTTP GET /wishlists/{id}
+func (h *WishlistHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+	h.respondWithWishlist(w, r, user.ID, id)
+}
+
+// GetShared handles HTTP GET /wishlists/shared/{token}, reading a shared wishlist without authentication
+func (h *WishlistHandler) GetShared(w http.ResponseWriter, r *http.Request) {
+	wishlist, err := h.Service.GetByShareToken(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	// The owner is not disclosed to visitors of the link
+	wishlist.UserID = 0
+	utils.RespondWithJSON(w, http.StatusOK, utils.ResponseData{Data: wishlist})
+}
+
+// Rename handles HTTP PUT /wishlists/{id}
+func (h *WishlistHandler) Rename(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	req, ok := decodeWishlist(w, r)
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	if err := h.Service.Rename(r.Context(), user.ID, id, req.Name); err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	h.respondWithWishlist(w, r, user.ID, id)
+}
+
+// Delete handles HTTP DELETE /wishlists/{id}
+func (h *WishlistHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	if err := h.Service.Delete(r.Context(), user.ID, id); err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	utils.RespondWithSuccess(w, http.StatusOK, "Wishlist deleted successfully")
+}
+
+// AddItem handles HTTP POST /wishlists/{id}/items
+func (h *WishlistHandler) AddItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req wishlistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if respondWithValidationError(w, req) {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	if err := h.Service.AddItem(r.Context(), user.ID, id, req.ProductID); err != nil {
+		resource := "wishlist item"
+		if errors.Is(err, domain.ErrNotFound) {
+			resource = "wishlist or product"
+		}
+		respondWithServiceError(w, err, resource)
+		return
+	}
+	h.respondWithWishlist(w, r, user.ID, id)
+}
+
+// RemoveItem handles HTTP DELETE /wishlists/{id}/items/{product_id}
+func (h *WishlistHandler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	productID, ok := pathInt(w, r, "product_id")
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	if err := h.Service.RemoveItem(r.Context(), user.ID, id, productID); err != nil {
+		respondWithServiceError(w, err, "wishlist item")
+		return
+	}
+	h.respondWithWishlist(w, r, user.ID, id)
+}
+
+// Share handles HTTP POST /wishlists/{id}/share, issuing a new share token.
+// Links to a previous token stop working.
+func (h *WishlistHandler) Share(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	token, err := randomToken()
+	if err != nil {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to share wishlist")
+		return
+	}
+	if err := h.Service.SetShareToken(r.Context(), user.ID, id, token); err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	h.respondWithWishlist(w, r, user.ID, id)
+}
+
+// Unshare handles HTTP DELETE /wishlists/{id}/share
+func (h *WishlistHandler) Unshare(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathInt(w, r, "id")
+	if !ok {
+		return
+	}
+	user, _ := middleware.UserFromContext(r.Context())
+
+	if err := h.Service.SetShareToken(r.Context(), user.ID, id, ""); err != nil {
+		respondWithServiceError(w, err, "wishlist")
+		return
+	}
+	h.respondWithWishlist(w, r, user.ID, id)
+}
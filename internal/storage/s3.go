@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config represent the settings of an S3-compatible object storage
+type S3Config struct {
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+	// BaseURL is the public URL of the bucket, e.g. a CDN. Defaults to the endpoint path-style URL.
+	BaseURL string
+}
+
+// S3Store stores blobs in a bucket of an S3-compatible object storage (AWS S3, MinIO, ...)
+type S3Store struct {
+	Client  *minio.Client
+	Bucket  string
+	BaseURL string
+}
+
+// NewS3Store creates a store for the configured bucket
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = joinURL(client.EndpointURL().String(), cfg.Bucket)
+	}
+	return &S3Store{Client: client, Bucket: cfg.Bucket, BaseURL: baseURL}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.Client.PutObject(ctx, s.Bucket, cleaned, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.Client.GetObject(ctx, s.Bucket, cleaned, minio.GetObjectOptions{})
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	return s.Client.RemoveObject(ctx, s.Bucket, cleaned, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Store) URL(key string) string {
+	return joinURL(s.BaseURL, key)
+}
@@ -0,0 +1,200 @@
+package storage_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/internal/storage"
+)
+
+// fakeS3 is a stand-in for an S3-compatible server holding the objects of
+// path-style requests in memory. It doesn't check signatures.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readObject(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		body, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// readObject reads the object of a PUT request, decoding the aws-chunked
+// encoding of streaming signed uploads
+func readObject(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var object []byte
+	body := bufio.NewReader(r.Body)
+	for {
+		header, err := body.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return object, nil
+		}
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(body, chunk); err != nil {
+			return nil, err
+		}
+		object = append(object, chunk[:n]...)
+	}
+}
+
+func newS3Store(t *testing.T, fake *fakeS3) *storage.S3Store {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    "images",
+		Region:    "us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3Store(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3()
+	store := newS3Store(t, fake)
+
+	content := []byte("image bytes")
+	if err := store.Put(ctx, "/products/1/a.png", bytes.NewReader(content), int64(len(content)), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.objects["images/products/1/a.png"]; !bytes.Equal(got, content) {
+		t.Fatalf("stored object = %q, want %q", got, content)
+	}
+	if got := fake.types["images/products/1/a.png"]; got != "image/png" {
+		t.Errorf("content type = %q, want image/png", got)
+	}
+
+	r, err := store.Get(ctx, "products/1/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Get = %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, "products/1/a.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.objects["images/products/1/a.png"]; ok {
+		t.Error("object still stored after Delete")
+	}
+	r, err = store.Get(ctx, "products/1/a.png")
+	if err == nil {
+		_, err = io.ReadAll(r)
+		r.Close()
+	}
+	if err == nil {
+		t.Error("Get of a deleted object succeeded")
+	}
+}
+
+func TestS3StoreInvalidKey(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3()
+	store := newS3Store(t, fake)
+
+	for _, key := range []string{"", "/", "../secret", "products/../../secret"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want %v", key, err, storage.ErrInvalidKey)
+		}
+		if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Get(%q) error = %v, want %v", key, err, storage.ErrInvalidKey)
+		}
+		if err := store.Delete(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Delete(%q) error = %v, want %v", key, err, storage.ErrInvalidKey)
+		}
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("stored objects = %v, want none", fake.objects)
+	}
+}
+
+func TestS3StoreURL(t *testing.T) {
+	store, err := storage.NewS3Store(storage.S3Config{Endpoint: "s3.example.com", Bucket: "images", UseSSL: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.URL("products/1/a.png"), "https://s3.example.com/images/products/1/a.png"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	store, err = storage.NewS3Store(storage.S3Config{Endpoint: "s3.example.com", Bucket: "images", BaseURL: "https://cdn.example.com/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.URL("products/1/a.png"), "https://cdn.example.com/products/1/a.png"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+}
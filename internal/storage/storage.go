@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrInvalidKey is returned for keys escaping the store, e.g. containing ".."
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore represent a storage of binary objects addressed by key
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL the blob is served from
+	URL(key string) string
+}
+
+// cleanKey normalizes a key to a relative slash separated path inside the store
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return strings.TrimPrefix(cleaned, "/"), nil
+}
+
+// joinURL joins a base URL and a key with exactly one slash
+func joinURL(base, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
@@ -1,9 +1,11 @@
 package validation
 
 import (
+	"reflect"
 	"strings"
 	"unicode"
 
+	"github.com/bimbims125/clean-arch/domain"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -62,3 +64,11 @@ func getErrorMessage(fe validator.FieldError) string {
 		return "Invalid value"
 	}
 }
+
+// MoneyAmount lets numeric tags such as gte=0 validate the minor units of domain.Money fields
+func MoneyAmount(field reflect.Value) interface{} {
+	if money, ok := field.Interface().(domain.Money); ok {
+		return money.Amount
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/notification"
+	"github.com/sirupsen/logrus"
+)
+
+// BackInStockStore represent the repository tracking restocked wishlisted products
+type BackInStockStore interface {
+	SyncBackInStockAlerts(ctx context.Context, now time.Time) error
+	FetchPendingStockAlerts(ctx context.Context) ([]domain.BackInStockAlert, error)
+	MarkStockAlertNotified(ctx context.Context, id int, now time.Time) error
+}
+
+// RunBackInStockCheck notifies restocked wishlisted products every interval until ctx is done
+func RunBackInStockCheck(ctx context.Context, store BackInStockStore, notifier notification.Notifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := CheckBackInStock(ctx, store, notifier, now); err != nil {
+				logrus.Error("failed to check back in stock products: ", err)
+			}
+		}
+	}
+}
+
+// CheckBackInStock opens alerts for wishlisted products whose stock went up
+// from zero and notifies each user who saved them. Failed notifications are
+// retried on the next check.
+func CheckBackInStock(ctx context.Context, store BackInStockStore, notifier notification.Notifier, now time.Time) error {
+	if err := store.SyncBackInStockAlerts(ctx, now); err != nil {
+		return err
+	}
+
+	alerts, err := store.FetchPendingStockAlerts(ctx)
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		n := domain.Notification{
+			Event:     domain.EventBackInStock,
+			Key:       fmt.Sprintf("back_in_stock_alert:%d", alert.ID),
+			Recipient: alert.Email,
+			Subject:   fmt.Sprintf("Back in stock: %s", alert.ProductName),
+			Message:   fmt.Sprintf("%s from your wishlist is available again", alert.ProductName),
+			Data:      alert,
+			CreatedAt: now,
+		}
+		if err := notifier.Notify(ctx, n); err != nil {
+			logrus.Errorf("failed to notify back in stock alert %d: %v", alert.ID, err)
+			continue
+		}
+		if err := store.MarkStockAlertNotified(ctx, alert.ID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/event"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// eventBatchSize is the number of events claimed at once
+	eventBatchSize = 20
+	// eventLease hides claimed events from other relays, a relay stopping
+	// before publishing its events having them published again once over
+	eventLease = 5 * time.Minute
+	// eventRetryDelay is the delay before the first retry of a failed event,
+	// doubled on every attempt up to maxEventRetryDelay
+	eventRetryDelay    = 10 * time.Second
+	maxEventRetryDelay = time.Hour
+	// maxEventErrorLength is the length of the last_error column of the outbox
+	maxEventErrorLength = 1000
+	eventPurgeInterval  = time.Hour
+)
+
+// EventOutbox represent the repository storing events until published
+type EventOutbox interface {
+	ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]domain.Event, error)
+	MarkEventPublished(ctx context.Context, id int64) error
+	RetryEvent(ctx context.Context, id int64, retryAt time.Time, reason string) error
+	PurgeEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RunEventRelay publishes the outbox events to sink every interval until ctx
+// is done, deleting the events published longer than retention ago
+func RunEventRelay(ctx context.Context, outbox EventOutbox, sink event.Sink, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := RelayEvents(ctx, outbox, sink, now); err != nil {
+				logrus.Error("failed to relay events: ", err)
+			}
+			if now.Sub(purged) < eventPurgeInterval {
+				continue
+			}
+			n, err := outbox.PurgeEvents(ctx, now.Add(-retention))
+			if err != nil {
+				logrus.Error("failed to purge published events: ", err)
+				continue
+			}
+			purged = now
+			if n > 0 {
+				logrus.Infof("purged %d published events", n)
+			}
+		}
+	}
+}
+
+// RelayEvents publishes the events due in the outbox, oldest first, until
+// none is left. A failed event is retried later with an exponential backoff
+// without holding back the events after it, so consumers needing order use
+// the occurred_at time of the events.
+func RelayEvents(ctx context.Context, outbox EventOutbox, sink event.Sink, now time.Time) error {
+	for {
+		events, err := outbox.ClaimEvents(ctx, eventBatchSize, eventLease)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := sink.Publish(ctx, e); err != nil {
+				logrus.Errorf("failed to publish event %d (attempt %d): %v", e.ID, e.Attempts, err)
+				reason := err.Error()
+				if len(reason) > maxEventErrorLength {
+					reason = strings.ToValidUTF8(reason[:maxEventErrorLength], "")
+				}
+				if err := outbox.RetryEvent(ctx, e.ID, now.Add(eventRetryBackoff(e.Attempts)), reason); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := outbox.MarkEventPublished(ctx, e.ID); err != nil {
+				return err
+			}
+		}
+		if len(events) < eventBatchSize {
+			return nil
+		}
+	}
+}
+
+// eventRetryBackoff returns the delay before retrying an event which failed
+// its attempts-th delivery
+func eventRetryBackoff(attempts int) time.Duration {
+	delay := eventRetryDelay
+	for i := 1; i < attempts && delay < maxEventRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxEventRetryDelay)
+}
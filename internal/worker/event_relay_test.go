@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+)
+
+// fakeOutbox hands out its pending events in batches, counting their
+// attempts as the outbox repositories do
+type fakeOutbox struct {
+	pending   []domain.Event
+	claims    int
+	published []int64
+	retries   map[int64]retry
+	claimErr  error
+}
+
+type retry struct {
+	at     time.Time
+	reason string
+}
+
+func (o *fakeOutbox) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]domain.Event, error) {
+	if o.claimErr != nil {
+		return nil, o.claimErr
+	}
+	o.claims++
+	n := min(limit, len(o.pending))
+	events := o.pending[:n]
+	o.pending = o.pending[n:]
+	for i := range events {
+		events[i].Attempts++
+	}
+	return events, nil
+}
+
+func (o *fakeOutbox) MarkEventPublished(ctx context.Context, id int64) error {
+	o.published = append(o.published, id)
+	return nil
+}
+
+func (o *fakeOutbox) RetryEvent(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	if o.retries == nil {
+		o.retries = map[int64]retry{}
+	}
+	o.retries[id] = retry{retryAt, reason}
+	return nil
+}
+
+func (o *fakeOutbox) PurgeEvents(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// fakeSink fails the events listed in failures with their error
+type fakeSink struct {
+	failures  map[int64]error
+	published []int64
+}
+
+func (s *fakeSink) Publish(ctx context.Context, event domain.Event) error {
+	if err := s.failures[event.ID]; err != nil {
+		return err
+	}
+	s.published = append(s.published, event.ID)
+	return nil
+}
+
+func events(n int) []domain.Event {
+	events := make([]domain.Event, n)
+	for i := range events {
+		events[i] = domain.Event{ID: int64(i + 1), Type: domain.EventProductUpdated, Key: "product:1"}
+	}
+	return events
+}
+
+func TestEventRetryBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{1000, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := eventRetryBackoff(tt.attempts); got != tt.want {
+			t.Errorf("eventRetryBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRelayEvents(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Two full batches and a last one, the failed events not holding back the others
+	pending := events(2*eventBatchSize + 5)
+	pending[2].Attempts = 2
+	outbox := &fakeOutbox{pending: pending}
+	long := strings.Repeat("é", maxEventErrorLength)
+	sink := &fakeSink{failures: map[int64]error{
+		3:  errors.New("unavailable"),
+		30: errors.New(long),
+	}}
+
+	if err := RelayEvents(ctx, outbox, sink, now); err != nil {
+		t.Fatal(err)
+	}
+	if outbox.claims != 3 {
+		t.Errorf("claimed %d batches, want 3", outbox.claims)
+	}
+	if len(outbox.published) != len(pending)-2 || len(sink.published) != len(pending)-2 {
+		t.Errorf("published %d events, marked %d, want %d", len(sink.published), len(outbox.published), len(pending)-2)
+	}
+	for _, id := range outbox.published {
+		if id == 3 || id == 30 {
+			t.Errorf("failed event %d marked published", id)
+		}
+	}
+
+	if len(outbox.retries) != 2 {
+		t.Fatalf("retries = %+v, want events 3 and 30", outbox.retries)
+	}
+	if r := outbox.retries[3]; !r.at.Equal(now.Add(40*time.Second)) || r.reason != "unavailable" {
+		t.Errorf("retry of event 3 = %+v, want in 40s as on its third attempt", r)
+	}
+	r := outbox.retries[30]
+	if !r.at.Equal(now.Add(eventRetryDelay)) {
+		t.Errorf("retry of event 30 at %v, want %v", r.at, now.Add(eventRetryDelay))
+	}
+	// The reason is cut to the column length without splitting a character
+	if len(r.reason) > maxEventErrorLength || !strings.HasPrefix(long, r.reason) || !strings.ContainsRune(r.reason, 'é') || strings.ContainsRune(r.reason, '�') {
+		t.Errorf("reason of %d bytes isn't a valid prefix of the error", len(r.reason))
+	}
+}
+
+func TestRelayEventsStopsOnPartialBatch(t *testing.T) {
+	outbox := &fakeOutbox{pending: events(eventBatchSize - 1)}
+	if err := RelayEvents(context.Background(), outbox, &fakeSink{}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if outbox.claims != 1 {
+		t.Errorf("claimed %d batches, want 1", outbox.claims)
+	}
+
+	// A full last batch takes an empty claim to notice
+	outbox = &fakeOutbox{pending: events(eventBatchSize)}
+	if err := RelayEvents(context.Background(), outbox, &fakeSink{}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if outbox.claims != 2 || len(outbox.published) != eventBatchSize {
+		t.Errorf("claimed %d batches publishing %d events, want 2 and %d", outbox.claims, len(outbox.published), eventBatchSize)
+	}
+}
+
+func TestRelayEventsClaimError(t *testing.T) {
+	claimErr := errors.New("connection refused")
+	outbox := &fakeOutbox{claimErr: claimErr}
+	if err := RelayEvents(context.Background(), outbox, &fakeSink{}, time.Now()); !errors.Is(err, claimErr) {
+		t.Errorf("RelayEvents error = %v, want %v", err, claimErr)
+	}
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// IdempotencyKeyPurger represent the store deleting expired idempotency keys
+type IdempotencyKeyPurger interface {
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// RunIdempotencyKeyPurge deletes expired idempotency keys every interval until ctx is done
+func RunIdempotencyKeyPurge(ctx context.Context, store IdempotencyKeyPurger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := store.DeleteExpired(ctx, now)
+			if err != nil {
+				logrus.Error("failed to delete expired idempotency keys: ", err)
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("deleted %d expired idempotency keys", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/notification"
+	"github.com/sirupsen/logrus"
+)
+
+// LowStockStore represent the repository tracking low-stock alerts
+type LowStockStore interface {
+	SyncLowStockAlerts(ctx context.Context, now time.Time) error
+	FetchPendingAlerts(ctx context.Context) ([]domain.LowStockAlert, error)
+	MarkAlertNotified(ctx context.Context, id int, now time.Time) error
+}
+
+// RunLowStockCheck raises and notifies low-stock alerts every interval until ctx is done
+func RunLowStockCheck(ctx context.Context, store LowStockStore, notifier notification.Notifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := CheckLowStock(ctx, store, notifier, now); err != nil {
+				logrus.Error("failed to check low stock: ", err)
+			}
+		}
+	}
+}
+
+// CheckLowStock opens alerts for products which crossed their reorder threshold
+// and notifies every alert not notified yet. Failed notifications are retried
+// on the next check, notified alerts are never sent again until resolved.
+func CheckLowStock(ctx context.Context, store LowStockStore, notifier notification.Notifier, now time.Time) error {
+	if err := store.SyncLowStockAlerts(ctx, now); err != nil {
+		return err
+	}
+
+	alerts, err := store.FetchPendingAlerts(ctx)
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		n := domain.Notification{
+			Event:     domain.EventLowStock,
+			Key:       fmt.Sprintf("low_stock_alert:%d", alert.ID),
+			Subject:   fmt.Sprintf("Low stock: %s", alert.ProductName),
+			Message:   fmt.Sprintf("%s has %d items left, reorder threshold is %d", alert.ProductName, alert.Stock, alert.Threshold),
+			Data:      alert,
+			CreatedAt: now,
+		}
+		if err := notifier.Notify(ctx, n); err != nil {
+			logrus.Errorf("failed to notify low stock alert %d: %v", alert.ID, err)
+			continue
+		}
+		if err := store.MarkAlertNotified(ctx, alert.ID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
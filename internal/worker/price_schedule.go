@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PriceScheduler represent the repository applying scheduled price changes
+type PriceScheduler interface {
+	ApplyPriceSchedules(ctx context.Context, now time.Time) (applied, reverted int, err error)
+}
+
+// RunPriceScheduler applies and reverts due price schedules every interval until ctx is done
+func RunPriceScheduler(ctx context.Context, repo PriceScheduler, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			applied, reverted, err := repo.ApplyPriceSchedules(ctx, now)
+			if err != nil {
+				logrus.Error("failed to apply price schedules: ", err)
+			}
+			if applied > 0 || reverted > 0 {
+				logrus.Infof("applied %d and reverted %d price schedules", applied, reverted)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// importChunkSize is the number of rows imported per transaction
+const importChunkSize = 100
+
+// ProductImportStore represent the repository importing products
+type ProductImportStore interface {
+	ImportProducts(ctx context.Context, job *domain.ImportJob, rows []domain.ProductImportRow) (created, updated int, rowErrs []domain.ImportRowError, err error)
+	UpdateImportJob(ctx context.Context, job *domain.ImportJob) error
+}
+
+// Transactor runs functions in a transaction carried by their context
+type Transactor interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type importTask struct {
+	job  domain.ImportJob
+	rows []domain.ProductImportRow
+}
+
+// ProductImporter imports products by chunks, saving the progress of the job
+// along with every chunk. Large imports are queued and run in the background, the
+// rows being held in memory only, so jobs lost on shutdown are left running.
+type ProductImporter struct {
+	Store        ProductImportStore
+	Transactions Transactor
+
+	tasks chan importTask
+}
+
+// NewProductImporter creates an importer queuing up to buffer imports
+func NewProductImporter(store ProductImportStore, transactions Transactor, buffer int) *ProductImporter {
+	return &ProductImporter{Store: store, Transactions: transactions, tasks: make(chan importTask, buffer)}
+}
+
+// Enqueue schedules an import without blocking, returning false when the queue is full
+func (i *ProductImporter) Enqueue(job domain.ImportJob, rows []domain.ProductImportRow) bool {
+	// The caller keeps its copy of the job, don't share the errors with it
+	job.Errors = append([]domain.ImportRowError{}, job.Errors...)
+	select {
+	case i.tasks <- importTask{job: job, rows: rows}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run starts the workers importing queued jobs until ctx is done
+func (i *ProductImporter) Run(ctx context.Context, workers int) {
+	for n := 0; n < workers; n++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case task := <-i.tasks:
+					if err := i.Import(ctx, &task.job, task.rows); err != nil {
+						logrus.Errorf("failed to import products of job %d: %v", task.job.ID, err)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// Import imports rows, one transaction per chunk. A chunk which fails is
+// reported as failed rows and the import goes on with the next one, the job
+// only fails when ctx is done or its progress can't be saved.
+func (i *ProductImporter) Import(ctx context.Context, job *domain.ImportJob, rows []domain.ProductImportRow) error {
+	job.Status = domain.ImportRunning
+	if err := i.Store.UpdateImportJob(ctx, job); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(rows); start += importChunkSize {
+		chunk := rows[start:min(start+importChunkSize, len(rows))]
+		// The job is only updated once the transaction commits, it may be run again
+		var progress domain.ImportJob
+		err := i.Transactions.Do(ctx, func(ctx context.Context) error {
+			progress = *job
+			created, updated, rowErrs, err := i.Store.ImportProducts(ctx, &progress, chunk)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				logrus.Errorf("failed to import rows %d to %d of job %d: %v", chunk[0].Row, chunk[len(chunk)-1].Row, job.ID, err)
+				created, updated, rowErrs = 0, 0, make([]domain.ImportRowError, len(chunk))
+				for n, row := range chunk {
+					rowErrs[n] = domain.ImportRowError{Row: row.Row, Message: "could not be saved"}
+				}
+			}
+
+			progress.CreatedRows += created
+			progress.UpdatedRows += updated
+			progress.AddErrors(rowErrs...)
+			progress.ProcessedRows += len(chunk)
+			return i.Store.UpdateImportJob(ctx, &progress)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return i.fail(job, ctx.Err())
+			}
+			return err
+		}
+		*job = progress
+	}
+
+	now := time.Now()
+	job.Status = domain.ImportCompleted
+	job.FinishedAt = &now
+	return i.Store.UpdateImportJob(ctx, job)
+}
+
+// fail marks a job as failed, saving it even though the import was cancelled
+func (i *ProductImporter) fail(job *domain.ImportJob, cause error) error {
+	now := time.Now()
+	job.Status = domain.ImportFailed
+	job.Error = cause.Error()
+	job.FinishedAt = &now
+	if err := i.Store.UpdateImportJob(context.Background(), job); err != nil {
+		logrus.Error(err)
+	}
+	return cause
+}
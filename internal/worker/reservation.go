@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReservationExpirer represent the repository expiring stale stock reservations
+type ReservationExpirer interface {
+	ExpireReservations(ctx context.Context, now time.Time) (int64, error)
+}
+
+// RunReservationExpiry expires stale stock reservations every interval until ctx is done
+func RunReservationExpiry(ctx context.Context, repo ReservationExpirer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := repo.ExpireReservations(ctx, now)
+			if err != nil {
+				logrus.Error("failed to expire stock reservations: ", err)
+				continue
+			}
+			if expired > 0 {
+				logrus.Infof("expired %d stock reservations", expired)
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bimbims125/clean-arch/domain"
+	"github.com/bimbims125/clean-arch/internal/imaging"
+	"github.com/bimbims125/clean-arch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const pendingImagesBatch = 100
+
+// ImageVariantStore represent the repository tracking the processing of product images
+type ImageVariantStore interface {
+	FetchPendingImages(ctx context.Context, limit int) ([]domain.ProductImage, error)
+	SaveVariants(ctx context.Context, imageID int, variants []domain.ImageVariant) error
+	SetStatus(ctx context.Context, imageID int, status domain.ImageStatus) error
+}
+
+// ThumbnailQueue generates the responsive variants of uploaded images in the
+// background. Images stay pending in the database until processed, so images
+// dropped from a full queue or lost on shutdown are picked up by the rescan.
+type ThumbnailQueue struct {
+	Store ImageVariantStore
+	Blobs storage.BlobStore
+	Sizes []imaging.Size
+
+	jobs     chan domain.ProductImage
+	mu       sync.Mutex
+	inFlight map[int]bool
+}
+
+// NewThumbnailQueue creates a queue buffering up to buffer images
+func NewThumbnailQueue(store ImageVariantStore, blobs storage.BlobStore, sizes []imaging.Size, buffer int) *ThumbnailQueue {
+	return &ThumbnailQueue{
+		Store:    store,
+		Blobs:    blobs,
+		Sizes:    sizes,
+		jobs:     make(chan domain.ProductImage, buffer),
+		inFlight: make(map[int]bool),
+	}
+}
+
+// Enqueue schedules an image without blocking, returning false when it is
+// already queued or the queue is full
+func (q *ThumbnailQueue) Enqueue(image domain.ProductImage) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.inFlight[image.ID] {
+		return false
+	}
+	select {
+	case q.jobs <- image:
+		q.inFlight[image.ID] = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *ThumbnailQueue) done(imageID int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.inFlight, imageID)
+}
+
+// Run starts the workers and rescans pending images every interval until ctx is done
+func (q *ThumbnailQueue) Run(ctx context.Context, workers int, interval time.Duration) {
+	for n := 0; n < workers; n++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case image := <-q.jobs:
+					if err := q.process(ctx, image); err != nil {
+						logrus.Errorf("failed to process image %d: %v", image.ID, err)
+						if err := q.Store.SetStatus(ctx, image.ID, domain.ImageStatusFailed); err != nil {
+							logrus.Error(err)
+						}
+					}
+					q.done(image.ID)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		q.enqueuePending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *ThumbnailQueue) enqueuePending(ctx context.Context) {
+	images, err := q.Store.FetchPendingImages(ctx, pendingImagesBatch)
+	if err != nil {
+		logrus.Error("failed to fetch pending images: ", err)
+		return
+	}
+	for _, image := range images {
+		q.Enqueue(image)
+	}
+}
+
+// process strips the metadata of the original upload and stores its resized variants
+func (q *ThumbnailQueue) process(ctx context.Context, image domain.ProductImage) error {
+	rc, err := q.Blobs.Get(ctx, image.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, image.Size+1))
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	if image.ContentType == "image/jpeg" {
+		sanitized, err := imaging.SanitizeJPEG(data)
+		if err != nil {
+			return err
+		}
+		if err := q.Blobs.Put(ctx, image.Key, bytes.NewReader(sanitized), int64(len(sanitized)), image.ContentType); err != nil {
+			return err
+		}
+		data = sanitized
+	}
+
+	outputs, err := imaging.Process(data, q.Sizes)
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(image.Key, path.Ext(image.Key))
+	variants := make([]domain.ImageVariant, 0, len(outputs))
+	for _, output := range outputs {
+		key := fmt.Sprintf("%s_%s%s", base, output.Name, output.Extension)
+		if err := q.Blobs.Put(ctx, key, bytes.NewReader(output.Data), int64(len(output.Data)), output.ContentType); err != nil {
+			return err
+		}
+		variants = append(variants, domain.ImageVariant{
+			Name:        output.Name,
+			Key:         key,
+			URL:         q.Blobs.URL(key),
+			ContentType: output.ContentType,
+			Width:       output.Width,
+			Height:      output.Height,
+			Size:        int64(len(output.Data)),
+		})
+	}
+	return q.Store.SaveVariants(ctx, image.ID, variants)
+}
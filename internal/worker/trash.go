@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TrashPurger represent a store permanently deleting the items deleted before a time
+type TrashPurger interface {
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RunTrashPurge permanently deletes the items deleted longer than retention
+// ago every interval until ctx is done. Purgers run in the given order, so
+// products go before the categories they reference.
+func RunTrashPurge(ctx context.Context, retention, interval time.Duration, purgers ...TrashPurger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			var purged int64
+			for _, purger := range purgers {
+				n, err := purger.Purge(ctx, now.Add(-retention))
+				if err != nil {
+					logrus.Error("failed to purge deleted items: ", err)
+					continue
+				}
+				purged += n
+			}
+			if purged > 0 {
+				logrus.Infof("purged %d deleted items", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package migrations embeds the SQL migrations applied by the application
+// itself. The Postgres and MySQL migrations are applied with external tools.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// SQLite returns the migrations of the SQLite backend, named after their version
+func SQLite() fs.FS {
+	sub, err := fs.Sub(sqliteFiles, "sqlite")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
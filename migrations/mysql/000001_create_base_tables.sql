@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS users (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT 'customer'
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS categories (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS products (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    price DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    image_url VARCHAR(255) NOT NULL DEFAULT '',
+    stock INT NOT NULL DEFAULT 0,
+    sold INT NOT NULL DEFAULT 0,
+    category_id INT NOT NULL,
+    FOREIGN KEY (category_id) REFERENCES categories (id)
+) ENGINE = InnoDB;
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS product_options (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    UNIQUE KEY uq_product_options_product_name (product_id, name),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS product_option_values (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    option_id INT NOT NULL,
+    value VARCHAR(100) NOT NULL,
+    UNIQUE KEY uq_product_option_values_option_value (option_id, value),
+    FOREIGN KEY (option_id) REFERENCES product_options (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    price DECIMAL(15, 2) NULL,
+    stock INT NOT NULL DEFAULT 0,
+    sold INT NOT NULL DEFAULT 0,
+    CHECK (stock >= 0),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS product_variant_option_values (
+    variant_id INT NOT NULL,
+    option_value_id INT NOT NULL,
+    PRIMARY KEY (variant_id, option_value_id),
+    FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE,
+    FOREIGN KEY (option_value_id) REFERENCES product_option_values (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    variant_id INT NULL,
+    type VARCHAR(20) NOT NULL,
+    quantity INT NOT NULL,
+    stock_after INT NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_stock_movements_product_id (product_id, id),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    variant_id INT NULL,
+    quantity INT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (quantity > 0),
+    INDEX idx_stock_reservations_active (product_id, status, expires_at),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+ALTER TABLE products ADD CONSTRAINT chk_products_stock CHECK (stock >= 0);
@@ -0,0 +1,24 @@
+ALTER TABLE products ADD COLUMN reorder_threshold INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS low_stock_alerts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    stock INT NOT NULL,
+    threshold INT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    notified_at DATETIME NULL,
+    resolved_at DATETIME NULL,
+    -- Only one open alert per product
+    open_product_id INT AS (IF(resolved_at IS NULL, product_id, NULL)) STORED,
+    UNIQUE KEY uq_low_stock_alerts_open (open_product_id),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at DATETIME NULL
+) ENGINE = InnoDB;
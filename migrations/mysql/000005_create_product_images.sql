@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    url VARCHAR(512) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    position INT NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Only one primary image per product
+    primary_product_id INT AS (IF(is_primary, product_id, NULL)) STORED,
+    UNIQUE KEY uq_product_images_primary (primary_product_id),
+    INDEX idx_product_images_product_id (product_id, position),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+ALTER TABLE products MODIFY image_url VARCHAR(512) NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+ALTER TABLE product_images
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    ADD INDEX idx_product_images_status (status);
+
+CREATE TABLE IF NOT EXISTS product_image_variants (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    image_id INT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    url VARCHAR(512) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    size BIGINT NOT NULL,
+    UNIQUE KEY uq_product_image_variants_name (image_id, name),
+    FOREIGN KEY (image_id) REFERENCES product_images (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
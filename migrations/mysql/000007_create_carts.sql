@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS carts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NULL UNIQUE,
+    token VARCHAR(64) NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (user_id IS NOT NULL OR token IS NOT NULL),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    cart_id INT NOT NULL,
+    product_id INT NOT NULL,
+    variant_id INT NULL,
+    name VARCHAR(255) NOT NULL,
+    sku VARCHAR(64) NOT NULL DEFAULT '',
+    unit_price DECIMAL(15, 2) NOT NULL,
+    quantity INT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (quantity > 0),
+    -- One line per product and variant
+    variant_key INT AS (COALESCE(variant_id, 0)) STORED,
+    UNIQUE KEY uq_cart_items_product (cart_id, product_id, variant_key),
+    FOREIGN KEY (cart_id) REFERENCES carts (id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+    FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
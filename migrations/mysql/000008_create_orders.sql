@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS orders (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    subtotal DECIMAL(15, 2) NOT NULL,
+    total DECIMAL(15, 2) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_orders_user_id (user_id, id),
+    INDEX idx_orders_status (status, id),
+    FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE = InnoDB;
+
+-- Ordered products and variants can't be deleted, orders keep their lines
+CREATE TABLE IF NOT EXISTS order_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    product_id INT NOT NULL,
+    variant_id INT NULL,
+    name VARCHAR(255) NOT NULL,
+    sku VARCHAR(64) NOT NULL DEFAULT '',
+    unit_price DECIMAL(15, 2) NOT NULL,
+    quantity INT NOT NULL,
+    CHECK (quantity > 0),
+    FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE,
+    FOREIGN KEY (product_id) REFERENCES products (id),
+    FOREIGN KEY (variant_id) REFERENCES product_variants (id)
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    from_status VARCHAR(20) NULL,
+    to_status VARCHAR(20) NOT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    changed_by INT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_order_status_history_order_id (order_id, id),
+    FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE = InnoDB;
@@ -0,0 +1,54 @@
+-- Promotions without a code apply automatically
+CREATE TABLE IF NOT EXISTS promotions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(50) NULL UNIQUE,
+    type VARCHAR(20) NOT NULL,
+    value DECIMAL(15, 2) NOT NULL,
+    min_subtotal DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    category_id INT NULL,
+    usage_limit_per_user INT NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    priority INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    starts_at DATETIME NULL,
+    ends_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (value > 0),
+    INDEX idx_promotions_active (active),
+    FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS cart_coupons (
+    cart_id INT NOT NULL,
+    code VARCHAR(50) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (cart_id, code),
+    FOREIGN KEY (cart_id) REFERENCES carts (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+ALTER TABLE orders ADD COLUMN discount_total DECIMAL(15, 2) NOT NULL DEFAULT 0;
+
+-- Orders keep their discounts when the promotion is deleted
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    promotion_id INT NULL,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(50) NOT NULL DEFAULT '',
+    amount DECIMAL(15, 2) NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE,
+    FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE SET NULL
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    promotion_id INT NOT NULL,
+    user_id INT NOT NULL,
+    order_id INT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_promotion_redemptions_user_id (user_id, promotion_id),
+    FOREIGN KEY (promotion_id) REFERENCES promotions (id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,20 @@
+-- Packed weight in grams and dimensions in centimeters
+ALTER TABLE products
+    ADD COLUMN weight INT NOT NULL DEFAULT 0,
+    ADD COLUMN length DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN width DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN height DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+    ADD COLUMN region VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN shipping_total DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax_total DECIMAL(15, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_taxes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    rate DECIMAL(6, 3) NOT NULL,
+    amount DECIMAL(15, 2) NOT NULL,
+    FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
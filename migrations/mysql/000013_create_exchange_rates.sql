@@ -0,0 +1,22 @@
+-- Value of one unit of the catalog currency (IDR) in each supported currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) PRIMARY KEY,
+    rate DECIMAL(24, 12) NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+) ENGINE = InnoDB;
+
+-- Price lists, overriding the converted catalog price of a product and its variants
+CREATE TABLE IF NOT EXISTS product_prices (
+    product_id INT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    price DECIMAL(15, 2) NOT NULL,
+    PRIMARY KEY (product_id, currency),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+ALTER TABLE carts
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- Rate from the catalog currency snapshotted at checkout
+ALTER TABLE orders
+    ADD COLUMN exchange_rate DECIMAL(24, 12) NOT NULL DEFAULT 1;
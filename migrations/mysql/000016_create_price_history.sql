@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS price_schedules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    price DECIMAL(15, 2) NOT NULL,
+    -- Price restored when an active schedule ends
+    previous_price DECIMAL(15, 2) NULL,
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_price_schedules_starts_at (status, starts_at),
+    INDEX idx_price_schedules_ends_at (status, ends_at),
+    INDEX idx_price_schedules_product_id (product_id, starts_at),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE = InnoDB;
+
+CREATE TABLE IF NOT EXISTS price_changes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    old_price DECIMAL(15, 2) NOT NULL,
+    new_price DECIMAL(15, 2) NOT NULL,
+    changed_by INT NULL,
+    schedule_id INT NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_price_changes_product_id (product_id, id),
+    FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES users (id) ON DELETE SET NULL,
+    FOREIGN KEY (schedule_id) REFERENCES price_schedules (id) ON DELETE SET NULL
+) ENGINE = InnoDB;
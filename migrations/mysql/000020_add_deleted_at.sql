@@ -0,0 +1,11 @@
+-- Deleted users, categories and products stay in the trash, where orders keep
+-- referencing them, until restored or purged
+ALTER TABLE users
+    ADD COLUMN deleted_at DATETIME NULL,
+    ADD INDEX idx_users_deleted_at (deleted_at);
+ALTER TABLE categories
+    ADD COLUMN deleted_at DATETIME NULL,
+    ADD INDEX idx_categories_deleted_at (deleted_at);
+ALTER TABLE products
+    ADD COLUMN deleted_at DATETIME NULL,
+    ADD INDEX idx_products_deleted_at (deleted_at);
@@ -0,0 +1,37 @@
+-- Entries are chained by hash and append only, actor_id isn't a foreign key
+-- so that entries outlive purged users
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    actor_id INT NULL,
+    actor_email VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(20) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL DEFAULT '',
+    -- Kept as text, the hash covers the changes as appended
+    changes MEDIUMTEXT NOT NULL,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    hash VARCHAR(64) NOT NULL,
+    INDEX idx_audit_logs_actor_id (actor_id, id),
+    INDEX idx_audit_logs_entity (entity_type, entity_id, id),
+    INDEX idx_audit_logs_request_id (request_id),
+    INDEX idx_audit_logs_created_at (created_at)
+) ENGINE = InnoDB;
+
+-- Hash of the last entry, locked while appending so that entries form a single chain
+CREATE TABLE IF NOT EXISTS audit_log_head (
+    id INT PRIMARY KEY CHECK (id = 1),
+    hash VARCHAR(64) NOT NULL DEFAULT ''
+) ENGINE = InnoDB;
+
+INSERT IGNORE INTO audit_log_head (id) VALUES (1);
+
+DROP TRIGGER IF EXISTS audit_logs_no_update;
+CREATE TRIGGER audit_logs_no_update BEFORE UPDATE ON audit_logs
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append only';
+
+DROP TRIGGER IF EXISTS audit_logs_no_delete;
+CREATE TRIGGER audit_logs_no_delete BEFORE DELETE ON audit_logs
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_logs is append only';
@@ -0,0 +1,15 @@
+-- Events are written in the transaction of their change and published by the relay
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    event_key VARCHAR(100) NOT NULL,
+    payload MEDIUMTEXT NOT NULL,
+    occurred_at DATETIME(6) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    -- Claimed events are hidden until published or their lease expires
+    available_at DATETIME(6) NOT NULL,
+    published_at DATETIME(6) NULL,
+    last_error VARCHAR(1000) NOT NULL DEFAULT '',
+    INDEX idx_event_outbox_pending (published_at, available_at, id),
+    INDEX idx_event_outbox_published_at (published_at)
+) ENGINE = InnoDB;
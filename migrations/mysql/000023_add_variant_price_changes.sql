@@ -0,0 +1,5 @@
+-- Changes of the price override of a variant are kept in the price history
+-- of its product, old and new prices being the effective variant prices
+ALTER TABLE price_changes
+    ADD COLUMN variant_id INT NULL,
+    ADD FOREIGN KEY (variant_id) REFERENCES product_variants (id) ON DELETE CASCADE;
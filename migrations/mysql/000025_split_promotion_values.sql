@@ -0,0 +1,13 @@
+-- Percentage and fixed promotions keep their discount in separate columns,
+-- fixed amounts being exact decimals like the other amounts
+ALTER TABLE promotions
+    ADD COLUMN percentage DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN amount DECIMAL(15, 2) NOT NULL DEFAULT 0,
+    ADD CHECK (percentage >= 0 AND percentage <= 100),
+    ADD CHECK (amount >= 0);
+
+UPDATE promotions SET percentage = value WHERE type = 'percentage';
+UPDATE promotions SET amount = value WHERE type = 'fixed';
+
+-- promotions_chk_1 is the CHECK (value > 0) of 000010
+ALTER TABLE promotions DROP CHECK promotions_chk_1, DROP COLUMN value;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants (id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    quantity INTEGER NOT NULL,
+    stock_after INTEGER NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_id ON stock_movements (product_id, id);
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants (id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL,
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_active ON stock_reservations (product_id, status, expires_at);
+
+ALTER TABLE products ADD CONSTRAINT chk_products_stock CHECK (stock >= 0);
@@ -0,0 +1,23 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS reorder_threshold INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS low_stock_alerts (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    stock INTEGER NOT NULL,
+    threshold INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ
+);
+
+-- Only one open alert per product
+CREATE UNIQUE INDEX IF NOT EXISTS uq_low_stock_alerts_open ON low_stock_alerts (product_id) WHERE resolved_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id SERIAL PRIMARY KEY,
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ
+);
@@ -0,0 +1,16 @@
+ALTER TABLE product_images ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_product_images_status ON product_images (status);
+
+CREATE TABLE IF NOT EXISTS product_image_variants (
+    id SERIAL PRIMARY KEY,
+    image_id INTEGER NOT NULL REFERENCES product_images (id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    url VARCHAR(512) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    size BIGINT NOT NULL,
+    UNIQUE (image_id, name)
+);
@@ -0,0 +1,21 @@
+-- Packed weight in grams and dimensions in centimeters
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS weight INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS length NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS width NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS height NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS region VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS shipping_total NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_total NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_taxes (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    rate NUMERIC(6, 3) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_taxes_order_id ON order_taxes (order_id);
@@ -0,0 +1,16 @@
+-- Events are written in the transaction of their change and published by the relay
+CREATE TABLE IF NOT EXISTS event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    event_key VARCHAR(100) NOT NULL,
+    payload TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- Claimed events are hidden until published or their lease expires
+    available_at TIMESTAMPTZ NOT NULL,
+    published_at TIMESTAMPTZ,
+    last_error VARCHAR(1000) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_outbox_pending ON event_outbox (available_at, id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_event_outbox_published_at ON event_outbox (published_at);
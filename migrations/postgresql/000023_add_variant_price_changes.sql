@@ -0,0 +1,4 @@
+-- Changes of the price override of a variant are kept in the price history
+-- of its product, old and new prices being the effective variant prices
+ALTER TABLE price_changes
+    ADD COLUMN IF NOT EXISTS variant_id INTEGER REFERENCES product_variants (id) ON DELETE CASCADE;
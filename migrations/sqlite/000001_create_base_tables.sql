@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    password VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT 'customer'
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    price NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    image_url VARCHAR(255) NOT NULL DEFAULT '',
+    stock INTEGER NOT NULL DEFAULT 0 CONSTRAINT chk_products_stock CHECK (stock >= 0),
+    sold INTEGER NOT NULL DEFAULT 0,
+    category_id INTEGER NOT NULL REFERENCES categories (id)
+);
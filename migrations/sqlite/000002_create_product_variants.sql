@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS product_options (
+    id INTEGER PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    UNIQUE (product_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS product_option_values (
+    id INTEGER PRIMARY KEY,
+    option_id INTEGER NOT NULL REFERENCES product_options (id) ON DELETE CASCADE,
+    value VARCHAR(100) NOT NULL,
+    UNIQUE (option_id, value)
+);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+    id INTEGER PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    price NUMERIC(15, 2),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    sold INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS product_variant_option_values (
+    variant_id INTEGER NOT NULL REFERENCES product_variants (id) ON DELETE CASCADE,
+    option_value_id INTEGER NOT NULL REFERENCES product_option_values (id) ON DELETE CASCADE,
+    PRIMARY KEY (variant_id, option_value_id)
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    id INTEGER PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    url VARCHAR(512) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    position INTEGER NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images (product_id, position);
+
+-- Only one primary image per product
+CREATE UNIQUE INDEX IF NOT EXISTS uq_product_images_primary ON product_images (product_id) WHERE is_primary;
+
+-- SQLite doesn't enforce VARCHAR lengths, products.image_url is left as is
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS carts (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER UNIQUE REFERENCES users (id) ON DELETE CASCADE,
+    token VARCHAR(64) UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (user_id IS NOT NULL OR token IS NOT NULL)
+);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id INTEGER PRIMARY KEY,
+    cart_id INTEGER NOT NULL REFERENCES carts (id) ON DELETE CASCADE,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    variant_id INTEGER REFERENCES product_variants (id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    sku VARCHAR(64) NOT NULL DEFAULT '',
+    unit_price NUMERIC(15, 2) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One line per product and variant
+CREATE UNIQUE INDEX IF NOT EXISTS uq_cart_items_product ON cart_items (cart_id, product_id, COALESCE(variant_id, 0));
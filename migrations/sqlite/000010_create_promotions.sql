@@ -0,0 +1,51 @@
+-- Promotions without a code apply automatically
+CREATE TABLE IF NOT EXISTS promotions (
+    id INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(50) UNIQUE,
+    type VARCHAR(20) NOT NULL,
+    value NUMERIC(15, 2) NOT NULL CHECK (value > 0),
+    min_subtotal NUMERIC(15, 2) NOT NULL DEFAULT 0,
+    category_id INTEGER REFERENCES categories (id) ON DELETE CASCADE,
+    usage_limit_per_user INTEGER NOT NULL DEFAULT 0,
+    stackable BOOLEAN NOT NULL DEFAULT FALSE,
+    priority INTEGER NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    starts_at DATETIME,
+    ends_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotions_active ON promotions (active);
+
+CREATE TABLE IF NOT EXISTS cart_coupons (
+    cart_id INTEGER NOT NULL REFERENCES carts (id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (cart_id, code)
+);
+
+ALTER TABLE orders ADD COLUMN discount_total NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+-- Orders keep their discounts when the promotion is deleted
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id INTEGER PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    promotion_id INTEGER REFERENCES promotions (id) ON DELETE SET NULL,
+    name VARCHAR(255) NOT NULL,
+    code VARCHAR(50) NOT NULL DEFAULT '',
+    amount NUMERIC(15, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_discounts_order_id ON order_discounts (order_id);
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    id INTEGER PRIMARY KEY,
+    promotion_id INTEGER NOT NULL REFERENCES promotions (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_user_id ON promotion_redemptions (user_id, promotion_id);
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_order_id ON promotion_redemptions (order_id);
@@ -0,0 +1,19 @@
+-- Packed weight in grams and dimensions in centimeters
+ALTER TABLE products ADD COLUMN weight INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN length NUMERIC(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN width NUMERIC(10, 2) NOT NULL DEFAULT 0;
+ALTER TABLE products ADD COLUMN height NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders ADD COLUMN region VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN shipping_total NUMERIC(15, 2) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN tax_total NUMERIC(15, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS order_taxes (
+    id INTEGER PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    rate NUMERIC(6, 3) NOT NULL,
+    amount NUMERIC(15, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_taxes_order_id ON order_taxes (order_id);
@@ -0,0 +1,6 @@
+-- ISO 4217 currency of the stored amounts, existing rows were priced in rupiah
+ALTER TABLE products ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE orders ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+ALTER TABLE payments ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
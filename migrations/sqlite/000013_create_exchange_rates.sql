@@ -0,0 +1,19 @@
+-- Value of one unit of the catalog currency (IDR) in each supported currency
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    currency CHAR(3) PRIMARY KEY,
+    rate NUMERIC(24, 12) NOT NULL CHECK (rate > 0),
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Price lists, overriding the converted catalog price of a product and its variants
+CREATE TABLE IF NOT EXISTS product_prices (
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    price NUMERIC(15, 2) NOT NULL CHECK (price >= 0),
+    PRIMARY KEY (product_id, currency)
+);
+
+ALTER TABLE carts ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR';
+
+-- Rate from the catalog currency snapshotted at checkout
+ALTER TABLE orders ADD COLUMN exchange_rate NUMERIC(24, 12) NOT NULL DEFAULT 1;
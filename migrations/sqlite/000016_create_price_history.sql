@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS price_schedules (
+    id INTEGER PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    price NUMERIC(15, 2) NOT NULL CHECK (price >= 0),
+    -- Price restored when an active schedule ends
+    previous_price NUMERIC(15, 2),
+    starts_at DATETIME NOT NULL,
+    ends_at DATETIME,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_schedules_starts_at ON price_schedules (status, starts_at);
+CREATE INDEX IF NOT EXISTS idx_price_schedules_ends_at ON price_schedules (status, ends_at);
+CREATE INDEX IF NOT EXISTS idx_price_schedules_product_id ON price_schedules (product_id, starts_at);
+
+CREATE TABLE IF NOT EXISTS price_changes (
+    id INTEGER PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    old_price NUMERIC(15, 2) NOT NULL,
+    new_price NUMERIC(15, 2) NOT NULL,
+    changed_by INTEGER REFERENCES users (id) ON DELETE SET NULL,
+    schedule_id INTEGER REFERENCES price_schedules (id) ON DELETE SET NULL,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_changes_product_id ON price_changes (product_id, id);
@@ -0,0 +1,9 @@
+-- Deleted users, categories and products stay in the trash, where orders keep
+-- referencing them, until restored or purged
+ALTER TABLE users ADD COLUMN deleted_at DATETIME;
+ALTER TABLE categories ADD COLUMN deleted_at DATETIME;
+ALTER TABLE products ADD COLUMN deleted_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
@@ -0,0 +1,40 @@
+-- Entries are chained by hash and append only, actor_id isn't a foreign key
+-- so that entries outlive purged users
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id INTEGER PRIMARY KEY,
+    actor_id INTEGER,
+    actor_email VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(20) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL DEFAULT '',
+    -- Kept as text, the hash covers the changes as appended
+    changes TEXT NOT NULL,
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    hash VARCHAR(64) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity_type, entity_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs (request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+
+-- Hash of the last entry, locked while appending so that entries form a single chain
+CREATE TABLE IF NOT EXISTS audit_log_head (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    hash VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+INSERT OR IGNORE INTO audit_log_head (id) VALUES (1);
+
+CREATE TRIGGER IF NOT EXISTS audit_logs_no_update BEFORE UPDATE ON audit_logs
+BEGIN
+    SELECT RAISE(ABORT, 'audit_logs is append only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_logs_no_delete BEFORE DELETE ON audit_logs
+BEGIN
+    SELECT RAISE(ABORT, 'audit_logs is append only');
+END;
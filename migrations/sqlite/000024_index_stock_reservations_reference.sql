@@ -0,0 +1,2 @@
+-- Checkout and releases find the reservations of a cart by their reference
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_reference ON stock_reservations (reference, status);
@@ -0,0 +1,9 @@
+-- Percentage and fixed promotions keep their discount in separate columns,
+-- fixed amounts being exact decimals like the other amounts
+ALTER TABLE promotions ADD COLUMN percentage NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (percentage >= 0 AND percentage <= 100);
+ALTER TABLE promotions ADD COLUMN amount NUMERIC(15, 2) NOT NULL DEFAULT 0 CHECK (amount >= 0);
+
+UPDATE promotions SET percentage = value WHERE type = 'percentage';
+UPDATE promotions SET amount = value WHERE type = 'fixed';
+
+ALTER TABLE promotions DROP COLUMN value;